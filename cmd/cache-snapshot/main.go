@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/database"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const snapshotVersion = 1
+
+var logger = logrus.New()
+
+type header struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Tables    []string  `json:"tables"`
+}
+
+type record struct {
+	Table string          `json:"table"`
+	Row   json.RawMessage `json:"row"`
+}
+
+type table struct {
+	name     string
+	serial   bool
+	newSlice func() interface{}
+	newRow   func() interface{}
+}
+
+var tables = []table{
+	{"registry_cache", false, func() interface{} { return &[]models.RegistryCache{} }, func() interface{} { return &models.RegistryCache{} }},
+	{"inline_objects", false, func() interface{} { return &[]models.InlineObject{} }, func() interface{} { return &models.InlineObject{} }},
+	{"tag_cache", true, func() interface{} { return &[]models.TagCache{} }, func() interface{} { return &models.TagCache{} }},
+	{"pull_counters", true, func() interface{} { return &[]models.PullCounter{} }, func() interface{} { return &models.PullCounter{} }},
+	{"repository_approvals", false, func() interface{} { return &[]models.RepositoryApproval{} }, func() interface{} { return &models.RepositoryApproval{} }},
+	{"tag_drift", true, func() interface{} { return &[]models.TagDrift{} }, func() interface{} { return &models.TagDrift{} }},
+	{"repositories", true, func() interface{} { return &[]models.Repository{} }, func() interface{} { return &models.Repository{} }},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s dump|restore [flags]\n", os.Args[0])
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	command := os.Args[1]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	path := flags.String("file", "-", "snapshot file (- for stdout/stdin, .gz is compressed)")
+	batchSize := flags.Int("batch-size", 500, "number of rows to read or write per batch")
+	only := flags.String("tables", "", "comma-separated subset of tables (default all)")
+	skipExisting := flags.Bool("skip-existing", false, "restore: keep rows that already exist instead of overwriting them")
+	flags.Parse(os.Args[2:])
+
+	logger.SetFormatter(&logrus.JSONFormatter{
+		TimestampFormat: time.RFC3339Nano,
+	})
+	logger.SetOutput(os.Stderr)
+
+	cfg, err := config.Load(logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load configuration")
+	}
+
+	db, err := database.NewPostgresDB(logger, database.PostgresConfig{
+		User:     cfg.PostgresUser,
+		Password: cfg.PostgresPassword,
+		Host:     cfg.PostgresHost,
+		Port:     cfg.PostgresPort,
+		DBName:   cfg.PostgresDatabase,
+		SSLMode:  cfg.PostgresSSLMode,
+	})
+	if err != nil {
+		logger.WithError(err).Fatal("Database initialization failed")
+	}
+
+	selected, err := selectTables(*only)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid table selection")
+	}
+
+	ctx := context.Background()
+	log := logger.WithFields(logrus.Fields{
+		"component": "cache_snapshot",
+		"command":   command,
+		"file":      *path,
+	})
+
+	switch command {
+	case "dump":
+		err = dump(ctx, db, *path, selected, *batchSize, log)
+	case "restore":
+		err = restore(ctx, db, *path, selected, *batchSize, *skipExisting, log)
+	default:
+		usage()
+	}
+	if err != nil {
+		log.WithError(err).Fatal("Snapshot failed")
+	}
+}
+
+func selectTables(only string) ([]table, error) {
+	if only == "" {
+		return tables, nil
+	}
+
+	var selected []table
+	for _, name := range strings.Split(only, ",") {
+		found := false
+		for _, t := range tables {
+			if t.name == strings.TrimSpace(name) {
+				selected = append(selected, t)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown table %q", name)
+		}
+	}
+	return selected, nil
+}
+
+func dump(ctx context.Context, db *gorm.DB, path string, selected []table, batchSize int, log *logrus.Entry) error {
+	out, closeOut, err := openOutput(path)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(out)
+	encoder := json.NewEncoder(writer)
+	hdr := header{Version: snapshotVersion, CreatedAt: time.Now().UTC()}
+	for _, t := range selected {
+		hdr.Tables = append(hdr.Tables, t.name)
+	}
+	if err := encoder.Encode(hdr); err != nil {
+		return err
+	}
+
+	for _, t := range selected {
+		count := 0
+		rows := t.newSlice()
+		err := db.WithContext(ctx).Unscoped().FindInBatches(rows, batchSize, func(tx *gorm.DB, batch int) error {
+			raw, err := json.Marshal(rows)
+			if err != nil {
+				return err
+			}
+			var items []json.RawMessage
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return err
+			}
+			for _, item := range items {
+				if err := encoder.Encode(record{Table: t.name, Row: item}); err != nil {
+					return err
+				}
+			}
+			count += len(items)
+			return nil
+		}).Error
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		log.WithFields(logrus.Fields{"table": t.name, "rows": count}).Info("Dumped table")
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return closeOut()
+}
+
+func restore(ctx context.Context, db *gorm.DB, path string, selected []table, batchSize int, skipExisting bool, log *logrus.Entry) error {
+	in, err := openInput(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(in))
+	var hdr header
+	if err := decoder.Decode(&hdr); err != nil {
+		return fmt.Errorf("read snapshot header: %w", err)
+	}
+	if hdr.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", hdr.Version)
+	}
+
+	wanted := make(map[string]table, len(selected))
+	for _, t := range selected {
+		wanted[t.name] = t
+	}
+	conflict := clause.OnConflict{UpdateAll: true}
+	if skipExisting {
+		conflict = clause.OnConflict{DoNothing: true}
+	}
+
+	pending := make(map[string][]interface{})
+	counts := make(map[string]int)
+	flush := func(name string) error {
+		rows := pending[name]
+		if len(rows) == 0 {
+			return nil
+		}
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			for _, row := range rows {
+				if err := tx.Clauses(conflict).Create(row).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		counts[name] += len(rows)
+		pending[name] = rows[:0]
+		return nil
+	}
+
+	for {
+		var rec record
+		if err := decoder.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("read snapshot record: %w", err)
+		}
+
+		t, ok := wanted[rec.Table]
+		if !ok {
+			continue
+		}
+		row := t.newRow()
+		if err := json.Unmarshal(rec.Row, row); err != nil {
+			return fmt.Errorf("decode %s row: %w", rec.Table, err)
+		}
+		pending[rec.Table] = append(pending[rec.Table], row)
+		if len(pending[rec.Table]) >= batchSize {
+			if err := flush(rec.Table); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, t := range selected {
+		if err := flush(t.name); err != nil {
+			return err
+		}
+		if t.serial {
+			sql := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE((SELECT MAX(id) FROM %[1]s), 0) + 1, false)", t.name)
+			if err := db.WithContext(ctx).Exec(sql).Error; err != nil {
+				return fmt.Errorf("database error: %w", err)
+			}
+		}
+		log.WithFields(logrus.Fields{"table": t.name, "rows": counts[t.name]}).Info("Restored table")
+	}
+	return nil
+}
+
+func openOutput(path string) (io.Writer, func() error, error) {
+	if path == "-" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return file, file.Close, nil
+	}
+	gz := gzip.NewWriter(file)
+	return gz, func() error {
+		if err := gz.Close(); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}, nil
+}
+
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return os.Stdin, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return file, nil
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, file}, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/database"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var logger = logrus.New()
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report keys that would be migrated without changing anything")
+	keepSource := flag.Bool("keep-source", false, "keep the old S3 objects after copying them")
+	batchSize := flag.Int("batch-size", 500, "number of cache entries to load per batch")
+	flag.Parse()
+
+	logger.SetFormatter(&logrus.JSONFormatter{
+		TimestampFormat: time.RFC3339Nano,
+	})
+	logger.SetOutput(os.Stdout)
+
+	cfg, err := config.Load(logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load configuration")
+	}
+
+	db, err := database.NewPostgresDB(logger, database.PostgresConfig{
+		User:     cfg.PostgresUser,
+		Password: cfg.PostgresPassword,
+		Host:     cfg.PostgresHost,
+		Port:     cfg.PostgresPort,
+		DBName:   cfg.PostgresDatabase,
+		SSLMode:  cfg.PostgresSSLMode,
+	})
+	if err != nil {
+		logger.WithError(err).Fatal("Database initialization failed")
+	}
+
+	s3Storage := storage.NewS3Storage(logger, cfg, db)
+	ctx := context.Background()
+	log := logger.WithFields(logrus.Fields{
+		"component":      "key_migration",
+		"schema_version": storage.KeySchemaVersion,
+		"dry_run":        *dryRun,
+	})
+
+	var pending []string
+	var entries []models.RegistryCache
+	if err := db.WithContext(ctx).Select("key").FindInBatches(&entries, *batchSize, func(tx *gorm.DB, batch int) error {
+		for _, entry := range entries {
+			if _, ok := storage.CurrentKey(entry.Key); ok {
+				pending = append(pending, entry.Key)
+			}
+		}
+		return nil
+	}).Error; err != nil {
+		log.WithError(err).Fatal("Failed to list cache entries")
+	}
+
+	log.WithField("count", len(pending)).Info("Found cache entries to migrate")
+
+	migrated, failed := 0, 0
+	for _, oldKey := range pending {
+		newKey, _ := storage.CurrentKey(oldKey)
+		entryLog := log.WithFields(logrus.Fields{"old_key": oldKey, "new_key": newKey})
+		if *dryRun {
+			entryLog.Info("Would migrate cache entry")
+			continue
+		}
+		if err := s3Storage.MigrateKey(ctx, oldKey, newKey, *keepSource); err != nil {
+			entryLog.WithError(err).Error("Failed to migrate cache entry")
+			failed++
+			continue
+		}
+		migrated++
+	}
+
+	log.WithFields(logrus.Fields{
+		"migrated": migrated,
+		"failed":   failed,
+	}).Info("Key migration finished")
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
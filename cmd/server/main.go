@@ -2,20 +2,38 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"net"
+	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	adminv1 "github.com/sdko-org/registry-proxy/api/admin/v1"
+	"github.com/sdko-org/registry-proxy/internal/alerts"
+	"github.com/sdko-org/registry-proxy/internal/auth"
+	"github.com/sdko-org/registry-proxy/internal/broadcast"
 	"github.com/sdko-org/registry-proxy/internal/cache"
 	"github.com/sdko-org/registry-proxy/internal/config"
 	"github.com/sdko-org/registry-proxy/internal/database"
 	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/drift"
+	"github.com/sdko-org/registry-proxy/internal/errorreport"
 	"github.com/sdko-org/registry-proxy/internal/handlers"
 	httpserver "github.com/sdko-org/registry-proxy/internal/http"
+	"github.com/sdko-org/registry-proxy/internal/jobs"
+	"github.com/sdko-org/registry-proxy/internal/leader"
+	"github.com/sdko-org/registry-proxy/internal/logsink"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/p2p"
+	"github.com/sdko-org/registry-proxy/internal/peer"
 	"github.com/sdko-org/registry-proxy/internal/storage"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 	"gorm.io/gorm"
 )
 
@@ -30,24 +48,186 @@ func main() {
 		logger.WithError(err).Fatal("Failed to load configuration")
 	}
 
-	db := initializeDatabase(cfg)
-	s3Storage := storage.NewS3Storage(logger, cfg, db)
-	dhClient := dockerhub.NewClient(logger, cfg)
-
-	router := setupRouter(cfg, db, s3Storage, dhClient)
+	if !cfg.HasDockerHubCredentials() {
+		logger.Info("No Docker Hub credentials configured, using anonymous pulls")
+	}
+	if cfg.FaultInjectionEnabled() {
+		logger.WithFields(logrus.Fields{
+			"upstream_error_rate": cfg.FaultUpstreamErrorRate,
+			"s3_latency":          cfg.FaultS3Latency,
+			"s3_latency_rate":     cfg.FaultS3LatencyRate,
+			"db_error_rate":       cfg.FaultDBErrorRate,
+		}).Warn("Fault injection enabled")
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	cachePurger := cache.NewCachePurger(logger, db, s3Storage, cfg)
-	go cachePurger.Start(ctx)
+	var db *gorm.DB
+	if cfg.MetadataBackend == config.MetadataBackendPostgres {
+		db = initializeDatabase(cfg)
+	} else {
+		logger.Info("Running without Postgres, job queue, access logs, tag cache and stats are disabled")
+	}
+	cacheStorage := initializeStorage(ctx, cfg, db)
+	dhClient := dockerhub.NewClient(logger, cfg)
+
+	go cfg.WatchSecrets(ctx, logger)
+	configureLogSinks(ctx, cfg)
+	configureErrorReporting(ctx, cfg)
+
+	var cachePurger *cache.CachePurger
+	var queue *jobs.Queue
+	var bus *broadcast.Bus
+	if db != nil {
+		var elector *leader.Elector
+		if cfg.LeaderElection {
+			elector = leader.NewElector(logger, db, "background-jobs", cfg.LeaderLeaseTTL)
+			go elector.Run(ctx)
+		}
+
+		if cfg.InvalidationBroadcast {
+			bus = broadcast.NewBus(logger, db, cfg.InvalidationChannel)
+			go bus.Start(ctx)
+		}
+
+		cachePurger = cache.NewCachePurger(logger, db, cacheStorage, cfg, elector)
+		go cachePurger.Start(ctx)
+
+		queue = jobs.NewQueue(logger, db, jobs.Config{
+			Workers:      cfg.JobWorkers,
+			MaxAttempts:  cfg.JobMaxAttempts,
+			PollInterval: cfg.JobPollInterval,
+			LockTimeout:  cfg.JobLockTimeout,
+			Retention:    cfg.JobRetention,
+		})
+		queue.Register(jobs.TypeGC, func(ctx context.Context, job *models.Job) error {
+			return cachePurger.Trigger()
+		})
+
+		if cfg.DriftCheckInterval > 0 {
+			watcher := drift.NewWatcher(logger, db, dhClient, elector, drift.Config{
+				Interval:   cfg.DriftCheckInterval,
+				BatchSize:  cfg.DriftBatchSize,
+				WebhookURL: cfg.DriftWebhookURL,
+			})
+			go watcher.Start(ctx)
+		}
+	}
+
+	var peers *peer.Cluster
+	if len(cfg.Peers) > 0 {
+		peers = peer.NewCluster(logger, peer.Config{
+			Peers:        cfg.Peers,
+			Token:        cfg.PeerCredential,
+			SyncInterval: cfg.PeerSyncInterval,
+			Timeout:      cfg.PeerTimeout,
+		})
+		go peers.Start(ctx)
+	}
+
+	var announcer *p2p.Announcer
+	if cfg.P2PAnnounceURL != "" {
+		announcer = p2p.NewAnnouncer(logger, p2p.Config{
+			AnnounceURL: cfg.P2PAnnounceURL,
+			SeedURL:     cfg.P2PSeedURL,
+			Token: func() string {
+				token, _ := cfg.P2PCredentials()
+				return token
+			},
+			BatchSize:     cfg.P2PBatchSize,
+			FlushInterval: cfg.P2PFlushInterval,
+		})
+		go announcer.Start(ctx)
+	}
+
+	router, proxyHandler := setupRouter(cfg, db, cacheStorage, dhClient, cachePurger, queue, peers, announcer, bus)
+	go queue.Start(ctx)
+
+	servers := httpserver.StartServers(logger, listeners(cfg, router))
+	grpcServer := startGRPC(cfg, proxyHandler, cachePurger)
+
+	logger.WithFields(logrus.Fields{
+		"http":  cfg.ListenHTTP,
+		"https": cfg.ListenHTTPS,
+	}).Info("Server running")
+
+	handleGracefulShutdown(servers, grpcServer, proxyHandler, cfg.ShutdownTimeout)
+}
+
+func startGRPC(cfg *config.Config, proxyHandler *handlers.ProxyHandler, purger *cache.CachePurger) *grpc.Server {
+	if cfg.ListenGRPC == "" {
+		return nil
+	}
+	log := logger.WithFields(logrus.Fields{
+		"listener": "grpc",
+		"addr":     cfg.ListenGRPC,
+	})
+
+	ln, err := net.Listen("tcp", cfg.ListenGRPC)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to bind listener")
+	}
+	srv := grpc.NewServer()
+	adminv1.RegisterAdminServiceServer(srv, handlers.NewAdminRPC(proxyHandler, purger))
 
-	httpserver.StartServers(logger, router)
+	go func() {
+		log.Info("Starting gRPC admin server")
+		if err := srv.Serve(ln); err != nil {
+			log.WithError(err).Fatal("gRPC server failed")
+		}
+	}()
+	return srv
+}
 
-	handleGracefulShutdown()
+func listeners(cfg *config.Config, router http.Handler) []httpserver.Listener {
+	public := router
+	if cfg.ListenAdmin != "" {
+		public = restrictPaths(router, func(path string) bool {
+			return !isAdminPath(path)
+		})
+	}
 
-	logger.Info("Server running on ports 8443 (HTTP) and 9443 (HTTPS)")
-	select {}
+	servers := []httpserver.Listener{
+		{Name: "http", Addr: cfg.ListenHTTP, Handler: public},
+		{Name: "https", Addr: cfg.ListenHTTPS, TLS: true, Handler: public},
+		{Name: "admin", Addr: cfg.ListenAdmin, Handler: handlers.TrustedListener(restrictPaths(router, isAdminPath))},
+		{Name: "metrics", Addr: cfg.ListenMetrics, Handler: handlers.TrustedListener(restrictPaths(router, func(path string) bool {
+			return strings.HasPrefix(path, "/admin/stats/")
+		}))},
+		{Name: "pprof", Addr: cfg.ListenPprof, Handler: pprofHandler()},
+	}
+	for i := range servers {
+		servers[i].MaxHeaderBytes = cfg.MaxHeaderBytes
+		servers[i].ReadHeaderTimeout = cfg.ReadHeaderTimeout
+		servers[i].IdleTimeout = cfg.IdleTimeout
+		servers[i].ReusePort = cfg.ListenReusePort
+	}
+	return servers
+}
+
+func pprofHandler() http.Handler {
+	debug := http.NewServeMux()
+	debug.HandleFunc("/debug/pprof/", pprof.Index)
+	debug.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	debug.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	debug.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	debug.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return debug
+}
+
+func isAdminPath(path string) bool {
+	return strings.HasPrefix(path, "/admin/") || path == "/ui" || strings.HasPrefix(path, "/ui/")
+}
+
+func restrictPaths(next http.Handler, allowed func(path string) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowed(r.URL.Path) {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
 func configureLogger() {
@@ -62,6 +242,57 @@ func configureLogger() {
 	}
 }
 
+func configureLogSinks(ctx context.Context, cfg *config.Config) {
+	if cfg.LogSyslogURL != "" {
+		hook, err := logsink.NewSyslogHook(logsink.SyslogConfig{
+			URL:      cfg.LogSyslogURL,
+			Facility: cfg.LogSyslogFacility,
+			AppName:  cfg.LogSyslogAppName,
+		})
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to configure syslog sink")
+		}
+		logger.AddHook(hook)
+		logger.WithField("url", cfg.LogSyslogURL).Info("Shipping logs to syslog")
+	}
+
+	if cfg.LogLokiURL != "" {
+		hook := logsink.NewLokiHook(logsink.LokiConfig{
+			URL:           cfg.LogLokiURL,
+			Labels:        cfg.LogLokiLabels,
+			Tenant:        cfg.LogLokiTenant,
+			Credentials:   cfg.LokiCredentials,
+			BatchSize:     cfg.LogLokiBatchSize,
+			FlushInterval: cfg.LogLokiFlushInterval,
+		})
+		go hook.Start(ctx)
+		logger.AddHook(hook)
+		logger.WithField("url", cfg.LogLokiURL).Info("Shipping logs to Loki")
+	}
+}
+
+func configureErrorReporting(ctx context.Context, cfg *config.Config) {
+	if cfg.SentryDSN == "" {
+		return
+	}
+
+	reporter, err := errorreport.New(errorreport.Config{
+		DSN:         cfg.SentryDSN,
+		Environment: cfg.SentryEnvironment,
+		Release:     cfg.SentryRelease,
+		SampleRate:  cfg.SentrySampleRate,
+	})
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to configure error reporting")
+	}
+	go reporter.Start(ctx)
+	logger.AddHook(reporter)
+	logger.WithFields(logrus.Fields{
+		"environment": cfg.SentryEnvironment,
+		"sample_rate": cfg.SentrySampleRate,
+	}).Info("Reporting errors to Sentry")
+}
+
 func initializeDatabase(cfg *config.Config) *gorm.DB {
 	db, err := database.NewPostgresDB(logger, database.PostgresConfig{
 		User:     cfg.PostgresUser,
@@ -70,6 +301,8 @@ func initializeDatabase(cfg *config.Config) *gorm.DB {
 		Port:     cfg.PostgresPort,
 		DBName:   cfg.PostgresDatabase,
 		SSLMode:  cfg.PostgresSSLMode,
+
+		FaultErrorRate: cfg.FaultDBErrorRate,
 	})
 	if err != nil {
 		logger.WithError(err).Fatal("Database initialization failed")
@@ -77,26 +310,208 @@ func initializeDatabase(cfg *config.Config) *gorm.DB {
 	return db
 }
 
-func setupRouter(cfg *config.Config, db *gorm.DB, storage storage.Storage, dhClient *dockerhub.Client) *mux.Router {
+func initializeStorage(ctx context.Context, cfg *config.Config, db *gorm.DB) storage.Storage {
+	s3Storage := storage.NewS3Storage(logger, cfg, db)
+	if db == nil {
+		if _, err := s3Storage.Reindex(ctx); err != nil {
+			logger.WithError(err).Fatal("Failed to load cache metadata from S3")
+		}
+		go s3Storage.SweepExpired(ctx, cfg.PurgeInterval, cfg.PurgeBatchSize)
+	}
+	if cfg.DiskCacheDir == "" {
+		return s3Storage
+	}
+
+	diskStorage, err := storage.NewDiskStorage(logger, cfg.DiskCacheDir, cfg.DiskCacheMaxBytes, cfg.DiskCacheMaxObjectSize)
+	if err != nil {
+		logger.WithError(err).Fatal("Disk cache initialization failed")
+	}
+	tiered := storage.NewTieredStorage(logger, cfg, diskStorage, s3Storage)
+	go tiered.FlushLastAccess(ctx, cfg.DiskCacheRevalidateInterval)
+	return tiered
+}
+
+func initializeAuthenticators(cfg *config.Config, db *gorm.DB) []auth.Authenticator {
+	var authenticators []auth.Authenticator
+
+	if cfg.PullTokensEnabled {
+		if db == nil {
+			logger.Fatal("PULL_TOKENS_ENABLED requires a database")
+		}
+		authenticators = append(authenticators, auth.NewPullTokenAuthenticator(handlers.LookupPullToken(logger, db)))
+		logger.Info("Pull token authentication enabled")
+	}
+
+	if cfg.AuthOIDCIssuer != "" {
+		rules, err := auth.ParseClaimRules(cfg.AuthOIDCRules)
+		if err != nil {
+			logger.WithError(err).Fatal("Invalid AUTH_OIDC_RULES")
+		}
+		authenticators = append(authenticators, auth.NewOIDCAuthenticator(logger, auth.OIDCConfig{
+			Issuer:       cfg.AuthOIDCIssuer,
+			Audience:     cfg.AuthOIDCAudience,
+			Rules:        rules,
+			JWKSRefresh:  cfg.AuthJWKSRefresh,
+			ClockLeeway:  cfg.AuthClockLeeway,
+			SubjectClaim: cfg.AuthOIDCSubjectClaim,
+		}))
+		logger.WithField("issuer", cfg.AuthOIDCIssuer).Info("OIDC authentication enabled")
+	}
+
+	if cfg.LDAPURL != "" {
+		rules, err := auth.ParseGroupRules(cfg.LDAPGroupRules)
+		if err != nil {
+			logger.WithError(err).Fatal("Invalid LDAP_GROUP_RULES")
+		}
+		authenticators = append(authenticators, auth.NewLDAPAuthenticator(logger, auth.LDAPConfig{
+			URL:            cfg.LDAPURL,
+			StartTLS:       cfg.LDAPStartTLS,
+			BindDN:         cfg.LDAPBindDN,
+			BindPassword:   cfg.LDAPBindCredential,
+			UserBaseDN:     cfg.LDAPUserBaseDN,
+			UserAttribute:  cfg.LDAPUserAttribute,
+			GroupAttribute: cfg.LDAPGroupAttribute,
+			GroupRules:     rules,
+			CacheTTL:       cfg.LDAPCacheTTL,
+		}))
+		logger.WithField("url", cfg.LDAPURL).Info("LDAP authentication enabled")
+	}
+
+	return authenticators
+}
+
+func initializeTokenIssuer(cfg *config.Config, authenticators []auth.Authenticator) *auth.TokenIssuer {
+	if len(authenticators) == 0 || !cfg.AuthTokenEnabled {
+		return nil
+	}
+
+	key := func() []byte { return []byte(cfg.AuthTokenSigningKey()) }
+	if cfg.AuthTokenSigningKey() == "" {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			logger.WithError(err).Fatal("Failed to generate token signing key")
+		}
+		key = func() []byte { return random }
+		logger.Warn("AUTH_TOKEN_KEY not set, using a per-process signing key; tokens will not be valid across restarts or replicas")
+	}
+
+	logger.WithField("service", cfg.AuthTokenService).Info("Token service enabled at /token")
+	return auth.NewTokenIssuer(auth.TokenConfig{
+		Key:     key,
+		Issuer:  cfg.AuthTokenIssuer,
+		Service: cfg.AuthTokenService,
+		TTL:     cfg.AuthTokenTTL,
+	})
+}
+
+func setupRouter(cfg *config.Config, db *gorm.DB, storage storage.Storage, dhClient *dockerhub.Client, purger *cache.CachePurger, queue *jobs.Queue, peers *peer.Cluster, announcer *p2p.Announcer, bus *broadcast.Bus) (*mux.Router, *handlers.ProxyHandler) {
 	r := mux.NewRouter()
 	r.Use(handlers.LoggingMiddleware(logger, db))
+	r.Use(handlers.RecoveryMiddleware(logger))
+	r.Use(handlers.RequestLimitsMiddleware(cfg))
+	r.Use(handlers.RouteTimeoutsMiddleware(logger, cfg))
 	r.Use(handlers.RateLimitMiddleware(cfg))
+	r.Use(handlers.CORSMiddleware(cfg))
+	authenticators := initializeAuthenticators(cfg, db)
+	var adminAuthenticators []auth.Authenticator
+	for _, authenticator := range authenticators {
+		if _, pullToken := authenticator.(*auth.PullTokenAuthenticator); !pullToken {
+			adminAuthenticators = append(adminAuthenticators, authenticator)
+		}
+	}
+	if len(authenticators) > 0 && cfg.ListenAdmin == "" && len(cfg.AdminSubjects) == 0 {
+		logger.Warn("Authentication is enabled but neither LISTEN_ADMIN nor ADMIN_SUBJECTS is set; the admin API is disabled")
+	}
+	tokens := initializeTokenIssuer(cfg, authenticators)
+	if tokens != nil {
+		r.Handle("/token", handlers.TokenHandler(logger, authenticators, tokens)).Methods("GET", "POST")
+		authenticators = append([]auth.Authenticator{tokens}, authenticators...)
+	}
+	r.Use(handlers.AuthMiddleware(logger, authenticators, tokens, cfg.AuthTokenRealm))
+	r.Use(handlers.AdminAuthMiddleware(logger, len(authenticators) > 0, adminAuthenticators, cfg.AdminSubjects))
+	r.Use(handlers.TenantMiddleware(logger, cfg))
 
-	proxyHandler := handlers.NewProxyHandler(logger, cfg, storage, dhClient, db)
-	handlers.RegisterRoutes(r, proxyHandler)
-	return r
+	proxyHandler := handlers.NewProxyHandler(logger, cfg, storage, dhClient, db, queue, peers, announcer, bus)
+	proxyHandler.RegisterJobHandlers(queue)
+	if cfg.ReindexOnStart {
+		proxyHandler.ReindexIfEmpty(context.Background())
+	}
+	go proxyHandler.SampleRuntime(context.Background(), cfg.RuntimeStatsInterval)
+	startAlerts(cfg, proxyHandler, storage, queue)
+	handlers.RegisterRoutes(r, proxyHandler, purger)
+	return r, proxyHandler
 }
 
-func handleGracefulShutdown() {
+func startAlerts(cfg *config.Config, proxyHandler *handlers.ProxyHandler, cacheStorage storage.Storage, queue *jobs.Queue) {
+	if cfg.AlertWebhookURL == "" && cfg.AlertSlackURL() == "" {
+		return
+	}
+
+	var probes []alerts.Probe
+	if cfg.AlertUpstreamFailureRate > 0 {
+		probes = append(probes, alerts.Probe{
+			Name:      "upstream_failure_rate",
+			Threshold: cfg.AlertUpstreamFailureRate,
+			Sample:    alerts.FailureRatio(proxyHandler.UpstreamStats, 10),
+		})
+	}
+	if counter, ok := cacheStorage.(storage.ErrorCounter); ok && cfg.AlertS3Errors > 0 {
+		probes = append(probes, alerts.Probe{
+			Name:      "s3_errors",
+			Threshold: cfg.AlertS3Errors,
+			Sample:    alerts.Increase(counter.ErrorCount),
+		})
+	}
+	if queue != nil && cfg.AlertCacheWriteBacklog > 0 {
+		probes = append(probes, alerts.Probe{
+			Name:      "cache_write_backlog",
+			Threshold: cfg.AlertCacheWriteBacklog,
+			Sample: func(ctx context.Context) (float64, error) {
+				backlog, err := queue.Backlog(ctx, jobs.TypeCacheWrite)
+				return float64(backlog), err
+			},
+		})
+	}
+	if len(probes) == 0 {
+		return
+	}
+
+	monitor := alerts.NewMonitor(logger, alerts.Config{
+		WebhookURL: cfg.AlertWebhookURL,
+		SlackURL:   cfg.AlertSlackURL,
+		Interval:   cfg.AlertInterval,
+		Sustain:    cfg.AlertSustain,
+	}, probes)
+	go monitor.Start(context.Background())
+}
+
+func handleGracefulShutdown(servers *httpserver.Servers, grpcServer *grpc.Server, proxyHandler *handlers.ProxyHandler, timeout time.Duration) {
 	sigint := make(chan os.Signal, 1)
 	signal.Notify(sigint, syscall.SIGINT, syscall.SIGTERM)
-	<-sigint
+	sig := <-sigint
+	signal.Stop(sigint)
 
-	logger.Info("Initiating graceful shutdown")
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	logger.WithFields(logrus.Fields{
+		"signal":        sig.String(),
+		"drain_timeout": timeout.String(),
+	}).Info("Initiating graceful shutdown")
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	_ = ctx
+	proxyHandler.Drain()
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+	}
+	servers.Shutdown(ctx)
 
 	logger.Info("Server shutdown complete")
 }
@@ -0,0 +1,188 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Probe struct {
+	Name      string
+	Threshold float64
+	Sample    func(ctx context.Context) (float64, error)
+}
+
+type Config struct {
+	WebhookURL string
+	SlackURL   func() string
+	Interval   time.Duration
+	Sustain    time.Duration
+}
+
+type Notification struct {
+	Alert     string    `json:"alert"`
+	State     string    `json:"state"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Since     time.Time `json:"since"`
+	Time      time.Time `json:"time"`
+}
+
+type probeState struct {
+	breachedAt time.Time
+	firing     bool
+}
+
+type Monitor struct {
+	cfg        Config
+	probes     []Probe
+	state      map[string]*probeState
+	httpClient *http.Client
+	log        *logrus.Entry
+}
+
+func NewMonitor(logger *logrus.Logger, cfg Config, probes []Probe) *Monitor {
+	state := make(map[string]*probeState, len(probes))
+	for _, probe := range probes {
+		state[probe.Name] = &probeState{}
+	}
+	return &Monitor{
+		cfg:        cfg,
+		probes:     probes,
+		state:      state,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		log:        logger.WithField("component", "alerts"),
+	}
+}
+
+func (m *Monitor) Start(ctx context.Context) {
+	m.log.WithFields(logrus.Fields{
+		"probes":   len(m.probes),
+		"interval": m.cfg.Interval,
+		"sustain":  m.cfg.Sustain,
+	}).Info("Starting alert monitor")
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.evaluate(ctx, time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *Monitor) evaluate(ctx context.Context, now time.Time) {
+	for _, probe := range m.probes {
+		value, err := probe.Sample(ctx)
+		if err != nil {
+			m.log.WithFields(logrus.Fields{
+				"alert": probe.Name,
+				"error": err,
+			}).Warn("Failed to sample alert probe")
+			continue
+		}
+
+		state := m.state[probe.Name]
+		if value < probe.Threshold {
+			if state.firing {
+				m.notify(ctx, Notification{Alert: probe.Name, State: "resolved", Value: value, Threshold: probe.Threshold, Since: state.breachedAt, Time: now})
+			}
+			state.breachedAt, state.firing = time.Time{}, false
+			continue
+		}
+
+		if state.breachedAt.IsZero() {
+			state.breachedAt = now
+		}
+		if !state.firing && now.Sub(state.breachedAt) >= m.cfg.Sustain {
+			state.firing = true
+			m.notify(ctx, Notification{Alert: probe.Name, State: "firing", Value: value, Threshold: probe.Threshold, Since: state.breachedAt, Time: now})
+		}
+	}
+}
+
+func (m *Monitor) notify(ctx context.Context, n Notification) {
+	log := m.log.WithFields(logrus.Fields{
+		"alert":     n.Alert,
+		"state":     n.State,
+		"value":     n.Value,
+		"threshold": n.Threshold,
+	})
+	if n.State == "firing" {
+		log.Warn("Alert threshold exceeded")
+	} else {
+		log.Info("Alert resolved")
+	}
+
+	if m.cfg.WebhookURL != "" {
+		if err := m.post(ctx, m.cfg.WebhookURL, n); err != nil {
+			log.WithError(err).Error("Failed to deliver alert webhook")
+		}
+	}
+	if slackURL := m.cfg.SlackURL(); slackURL != "" {
+		text := fmt.Sprintf(":rotating_light: *%s* is firing: %.4g (threshold %.4g) since %s",
+			n.Alert, n.Value, n.Threshold, n.Since.UTC().Format(time.RFC3339))
+		if n.State == "resolved" {
+			text = fmt.Sprintf(":white_check_mark: *%s* resolved: %.4g (threshold %.4g)", n.Alert, n.Value, n.Threshold)
+		}
+		if err := m.post(ctx, slackURL, map[string]string{"text": text}); err != nil {
+			log.WithError(err).Error("Failed to deliver Slack alert")
+		}
+	}
+}
+
+func (m *Monitor) post(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func Increase(read func() uint64) func(context.Context) (float64, error) {
+	last := read()
+	return func(context.Context) (float64, error) {
+		current := read()
+		delta := current - last
+		if current < last {
+			delta = 0
+		}
+		last = current
+		return float64(delta), nil
+	}
+}
+
+func FailureRatio(read func() (total, failed uint64), minTotal uint64) func(context.Context) (float64, error) {
+	lastTotal, lastFailed := read()
+	return func(context.Context) (float64, error) {
+		total, failed := read()
+		deltaTotal, deltaFailed := total-lastTotal, failed-lastFailed
+		lastTotal, lastFailed = total, failed
+		if deltaTotal < minTotal || deltaTotal == 0 {
+			return 0, nil
+		}
+		return float64(deltaFailed) / float64(deltaTotal), nil
+	}
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+)
+
+var (
+	ErrNoCredentials      = errors.New("no credentials provided")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+type Principal struct {
+	Subject      string
+	Method       string
+	Repositories []string
+}
+
+type Authenticator interface {
+	Name() string
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+type contextKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(contextKey{}).(*Principal)
+	return p
+}
+
+func (p *Principal) CanPull(repository string) bool {
+	if p.Repositories == nil {
+		return true
+	}
+	for _, pattern := range p.Repositories {
+		if config.MatchPattern(pattern, repository) {
+			return true
+		}
+	}
+	return false
+}
+
+func BearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+func ParseClaimRules(value string) ([]ClaimRule, error) {
+	var rules []ClaimRule
+	err := parseRules(value, func(match string, repositories []string) error {
+		claim, claimValue, ok := strings.Cut(match, "=")
+		if !ok || strings.TrimSpace(claim) == "" {
+			return errors.New("claim rule must be claim=value:repo[,repo]")
+		}
+		rules = append(rules, ClaimRule{
+			Claim:        strings.TrimSpace(claim),
+			Value:        strings.TrimSpace(claimValue),
+			Repositories: repositories,
+		})
+		return nil
+	})
+	return rules, err
+}
+
+func ParseGroupRules(value string) ([]GroupRule, error) {
+	var rules []GroupRule
+	err := parseRules(value, func(group string, repositories []string) error {
+		if group = strings.TrimSpace(group); group == "" {
+			return errors.New("group rule must be group:repo[,repo]")
+		}
+		rules = append(rules, GroupRule{Group: group, Repositories: repositories})
+		return nil
+	})
+	return rules, err
+}
+
+func parseRules(value string, add func(match string, repositories []string) error) error {
+	for _, item := range strings.Split(value, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		match, repos, ok := strings.Cut(item, ":")
+		if !ok {
+			return fmt.Errorf("invalid rule %q: missing repository list", item)
+		}
+
+		var repositories []string
+		for _, repo := range strings.Split(repos, ",") {
+			if repo = strings.TrimSpace(repo); repo != "" {
+				repositories = append(repositories, repo)
+			}
+		}
+		if err := add(match, repositories); err != nil {
+			return err
+		}
+	}
+	return nil
+}
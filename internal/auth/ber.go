@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	berBoolean     = 0x01
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berEnumerated  = 0x0a
+	berSequence    = 0x30
+	berSet         = 0x31
+)
+
+type berPacket struct {
+	tag      byte
+	content  []byte
+	children []berPacket
+}
+
+func berLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var raw []byte
+	for n > 0 {
+		raw = append([]byte{byte(n)}, raw...)
+		n >>= 8
+	}
+	return append([]byte{0x80 | byte(len(raw))}, raw...)
+}
+
+func berTLV(tag byte, content ...[]byte) []byte {
+	var body []byte
+	for _, c := range content {
+		body = append(body, c...)
+	}
+	out := append([]byte{tag}, berLength(len(body))...)
+	return append(out, body...)
+}
+
+func berInt(tag byte, v int) []byte {
+	raw := []byte{byte(v)}
+	for v > 0x7f || v < -0x80 {
+		v >>= 8
+		raw = append([]byte{byte(v)}, raw...)
+	}
+	return berTLV(tag, raw)
+}
+
+func berString(tag byte, s string) []byte {
+	return berTLV(tag, []byte(s))
+}
+
+func berBool(v bool) []byte {
+	if v {
+		return berTLV(berBoolean, []byte{0xff})
+	}
+	return berTLV(berBoolean, []byte{0x00})
+}
+
+func readBERPacket(r *bufio.Reader) (berPacket, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return berPacket{}, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return berPacket{}, io.ErrUnexpectedEOF
+	}
+
+	length := int(first)
+	if first&0x80 != 0 {
+		count := int(first & 0x7f)
+		if count == 0 || count > 4 {
+			return berPacket{}, fmt.Errorf("unsupported ber length encoding")
+		}
+		length = 0
+		for i := 0; i < count; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return berPacket{}, io.ErrUnexpectedEOF
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	if length > 16<<20 {
+		return berPacket{}, fmt.Errorf("ber packet too large: %d bytes", length)
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return berPacket{}, err
+	}
+	return parseBER(tag, content)
+}
+
+func parseBER(tag byte, content []byte) (berPacket, error) {
+	p := berPacket{tag: tag, content: content}
+	if tag&0x20 == 0 {
+		return p, nil
+	}
+
+	r := bufio.NewReader(bytes.NewReader(content))
+	for {
+		child, err := readBERPacket(r)
+		if errors.Is(err, io.EOF) {
+			return p, nil
+		}
+		if err != nil {
+			return berPacket{}, fmt.Errorf("malformed ber packet: %w", err)
+		}
+		p.children = append(p.children, child)
+	}
+}
+
+func (p berPacket) int() int {
+	if len(p.content) == 0 {
+		return 0
+	}
+	v := int(int8(p.content[0]))
+	for _, b := range p.content[1:] {
+		v = v<<8 | int(b)
+	}
+	return v
+}
+
+func (p berPacket) str() string {
+	return string(p.content)
+}
@@ -0,0 +1,426 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ldapBindRequest      = 0x60
+	ldapUnbindRequest    = 0x42
+	ldapSearchRequest    = 0x63
+	ldapSearchEntry      = 0x64
+	ldapSearchDone       = 0x65
+	ldapSearchReference  = 0x73
+	ldapExtendedRequest  = 0x77
+	ldapExtendedResponse = 0x78
+	ldapFilterEquality   = 0xa3
+	ldapSimpleAuth       = 0x80
+	ldapExtendedName     = 0x80
+
+	ldapResultSuccess            = 0
+	ldapResultSizeLimitExceeded  = 4
+	ldapResultInvalidCredentials = 49
+	ldapStartTLSOID              = "1.3.6.1.4.1.1466.20037"
+	ldapScopeSubtree             = 2
+	ldapDerefNever               = 0
+	ldapMaxCachedPrincipals      = 10000
+	ldapDefaultUserAttribute     = "uid"
+	ldapDefaultGroupAttribute    = "memberOf"
+	ldapDefaultTimeout           = 10 * time.Second
+)
+
+type GroupRule struct {
+	Group        string
+	Repositories []string
+}
+
+type LDAPConfig struct {
+	URL            string
+	StartTLS       bool
+	BindDN         string
+	BindPassword   func() string
+	UserBaseDN     string
+	UserAttribute  string
+	GroupAttribute string
+	GroupRules     []GroupRule
+	CacheTTL       time.Duration
+	Timeout        time.Duration
+}
+
+type LDAPAuthenticator struct {
+	cfg LDAPConfig
+	log *logrus.Entry
+
+	mu    sync.Mutex
+	cache map[[32]byte]ldapCacheEntry
+}
+
+type ldapCacheEntry struct {
+	principal *Principal
+	expiresAt time.Time
+}
+
+type ldapResultError struct {
+	code    int
+	message string
+}
+
+func (e *ldapResultError) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("ldap result code %d", e.code)
+	}
+	return fmt.Sprintf("ldap result code %d: %s", e.code, e.message)
+}
+
+func NewLDAPAuthenticator(logger *logrus.Logger, cfg LDAPConfig) *LDAPAuthenticator {
+	if cfg.UserAttribute == "" {
+		cfg.UserAttribute = ldapDefaultUserAttribute
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = ldapDefaultGroupAttribute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = ldapDefaultTimeout
+	}
+	return &LDAPAuthenticator{
+		cfg:   cfg,
+		log:   logger.WithField("component", "ldap_auth"),
+		cache: make(map[[32]byte]ldapCacheEntry),
+	}
+}
+
+func (a *LDAPAuthenticator) Name() string {
+	return "ldap"
+}
+
+func (a *LDAPAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	cacheKey := sha256.Sum256([]byte(username + "\x00" + password))
+	if principal := a.cached(cacheKey); principal != nil {
+		return principal, nil
+	}
+
+	groups, err := a.login(r.Context(), username, password)
+	if err != nil {
+		if !errors.Is(err, ErrInvalidCredentials) {
+			a.log.WithFields(logrus.Fields{
+				"username": username,
+				"error":    err,
+			}).Warn("LDAP authentication failed")
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	principal := &Principal{
+		Subject:      username,
+		Method:       a.Name(),
+		Repositories: a.repositories(groups),
+	}
+	a.store(cacheKey, principal)
+
+	a.log.WithFields(logrus.Fields{
+		"username": username,
+		"groups":   len(groups),
+	}).Debug("LDAP user authenticated")
+	return principal, nil
+}
+
+func (a *LDAPAuthenticator) cached(key [32]byte) *Principal {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.cache[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(a.cache, key)
+		return nil
+	}
+	return entry.principal
+}
+
+func (a *LDAPAuthenticator) store(key [32]byte, principal *Principal) {
+	if a.cfg.CacheTTL <= 0 {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.cache) >= ldapMaxCachedPrincipals {
+		now := time.Now()
+		for k, entry := range a.cache {
+			if now.After(entry.expiresAt) {
+				delete(a.cache, k)
+			}
+		}
+		if len(a.cache) >= ldapMaxCachedPrincipals {
+			a.cache = make(map[[32]byte]ldapCacheEntry)
+		}
+	}
+	a.cache[key] = ldapCacheEntry{principal: principal, expiresAt: time.Now().Add(a.cfg.CacheTTL)}
+}
+
+func (a *LDAPAuthenticator) repositories(groups []string) []string {
+	if len(a.cfg.GroupRules) == 0 {
+		return nil
+	}
+
+	repositories := []string{}
+	for _, rule := range a.cfg.GroupRules {
+		for _, group := range groups {
+			if groupMatches(rule.Group, group) {
+				repositories = append(repositories, rule.Repositories...)
+				break
+			}
+		}
+	}
+	return repositories
+}
+
+func groupMatches(rule, groupDN string) bool {
+	if strings.EqualFold(rule, groupDN) {
+		return true
+	}
+	rdn, _, _ := strings.Cut(groupDN, ",")
+	_, name, ok := strings.Cut(rdn, "=")
+	return ok && strings.EqualFold(rule, strings.TrimSpace(name))
+}
+
+func (a *LDAPAuthenticator) login(ctx context.Context, username, password string) ([]string, error) {
+	conn, err := a.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	if a.cfg.BindDN != "" {
+		bindPassword := ""
+		if a.cfg.BindPassword != nil {
+			bindPassword = a.cfg.BindPassword()
+		}
+		if err := conn.bind(a.cfg.BindDN, bindPassword); err != nil {
+			return nil, fmt.Errorf("service account bind failed: %w", err)
+		}
+	}
+
+	dn, groups, err := conn.findUser(a.cfg.UserBaseDN, a.cfg.UserAttribute, username, a.cfg.GroupAttribute, a.cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.bind(dn, password); err != nil {
+		var resultErr *ldapResultError
+		if errors.As(err, &resultErr) && resultErr.code == ldapResultInvalidCredentials {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("user bind failed: %w", err)
+	}
+	return groups, nil
+}
+
+type ldapConn struct {
+	conn  net.Conn
+	r     *bufio.Reader
+	msgID int
+}
+
+func (a *LDAPAuthenticator) dial(ctx context.Context) (*ldapConn, error) {
+	u, err := url.Parse(a.cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ldap url: %w", err)
+	}
+
+	host := u.Hostname()
+	port := u.Port()
+	switch u.Scheme {
+	case "ldap":
+		if port == "" {
+			port = "389"
+		}
+	case "ldaps":
+		if port == "" {
+			port = "636"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported ldap scheme %q", u.Scheme)
+	}
+
+	dialer := &net.Dialer{Timeout: a.cfg.Timeout}
+	raw, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, fmt.Errorf("ldap connect failed: %w", err)
+	}
+	raw.SetDeadline(time.Now().Add(a.cfg.Timeout))
+
+	if u.Scheme == "ldaps" {
+		raw = tls.Client(raw, &tls.Config{ServerName: host})
+	}
+	conn := &ldapConn{conn: raw, r: bufio.NewReader(raw)}
+
+	if u.Scheme == "ldap" && a.cfg.StartTLS {
+		if err := conn.startTLS(host); err != nil {
+			raw.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c *ldapConn) send(op []byte) (int, error) {
+	c.msgID++
+	_, err := c.conn.Write(berTLV(berSequence, berInt(berInteger, c.msgID), op))
+	return c.msgID, err
+}
+
+func (c *ldapConn) receive(id int) (berPacket, error) {
+	for {
+		p, err := readBERPacket(c.r)
+		if err != nil {
+			return berPacket{}, fmt.Errorf("ldap read failed: %w", err)
+		}
+		if p.tag != berSequence || len(p.children) < 2 {
+			return berPacket{}, fmt.Errorf("malformed ldap message")
+		}
+		if p.children[0].int() == id {
+			return p.children[1], nil
+		}
+	}
+}
+
+func ldapResult(op berPacket) error {
+	if len(op.children) < 3 {
+		return fmt.Errorf("malformed ldap result")
+	}
+	if code := op.children[0].int(); code != ldapResultSuccess {
+		return &ldapResultError{code: code, message: op.children[2].str()}
+	}
+	return nil
+}
+
+func (c *ldapConn) startTLS(host string) error {
+	id, err := c.send(berTLV(ldapExtendedRequest, berString(ldapExtendedName, ldapStartTLSOID)))
+	if err != nil {
+		return fmt.Errorf("starttls request failed: %w", err)
+	}
+	resp, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if resp.tag != ldapExtendedResponse {
+		return fmt.Errorf("unexpected starttls response tag 0x%x", resp.tag)
+	}
+	if err := ldapResult(resp); err != nil {
+		return fmt.Errorf("starttls rejected: %w", err)
+	}
+
+	tlsConn := tls.Client(c.conn, &tls.Config{ServerName: host})
+	if err := tlsConn.Handshake(); err != nil {
+		return fmt.Errorf("starttls handshake failed: %w", err)
+	}
+	c.conn = tlsConn
+	c.r = bufio.NewReader(tlsConn)
+	return nil
+}
+
+func (c *ldapConn) bind(dn, password string) error {
+	id, err := c.send(berTLV(ldapBindRequest,
+		berInt(berInteger, 3),
+		berString(berOctetString, dn),
+		berString(ldapSimpleAuth, password),
+	))
+	if err != nil {
+		return fmt.Errorf("bind request failed: %w", err)
+	}
+	resp, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	return ldapResult(resp)
+}
+
+func (c *ldapConn) findUser(baseDN, attribute, username, groupAttribute string, timeout time.Duration) (string, []string, error) {
+	id, err := c.send(berTLV(ldapSearchRequest,
+		berString(berOctetString, baseDN),
+		berInt(berEnumerated, ldapScopeSubtree),
+		berInt(berEnumerated, ldapDerefNever),
+		berInt(berInteger, 2),
+		berInt(berInteger, int(timeout.Seconds())),
+		berBool(false),
+		berTLV(ldapFilterEquality, berString(berOctetString, attribute), berString(berOctetString, username)),
+		berTLV(berSequence, berString(berOctetString, groupAttribute)),
+	))
+	if err != nil {
+		return "", nil, fmt.Errorf("search request failed: %w", err)
+	}
+
+	var dns []string
+	var groups []string
+	for {
+		resp, err := c.receive(id)
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch resp.tag {
+		case ldapSearchEntry:
+			if len(resp.children) < 2 {
+				return "", nil, fmt.Errorf("malformed search entry")
+			}
+			dns = append(dns, resp.children[0].str())
+			for _, attr := range resp.children[1].children {
+				if len(attr.children) < 2 || !strings.EqualFold(attr.children[0].str(), groupAttribute) {
+					continue
+				}
+				for _, value := range attr.children[1].children {
+					groups = append(groups, value.str())
+				}
+			}
+		case ldapSearchReference:
+		case ldapSearchDone:
+			if err := ldapResult(resp); err != nil {
+				var resultErr *ldapResultError
+				if errors.As(err, &resultErr) && resultErr.code == ldapResultSizeLimitExceeded {
+					return "", nil, fmt.Errorf("multiple directory entries match %s=%s", attribute, username)
+				}
+				return "", nil, fmt.Errorf("search failed: %w", err)
+			}
+			switch len(dns) {
+			case 0:
+				return "", nil, ErrInvalidCredentials
+			case 1:
+				return dns[0], groups, nil
+			default:
+				return "", nil, fmt.Errorf("multiple directory entries match %s=%s", attribute, username)
+			}
+		default:
+			return "", nil, fmt.Errorf("unexpected search response tag 0x%x", resp.tag)
+		}
+	}
+}
+
+func (c *ldapConn) close() {
+	c.send(berTLV(ldapUnbindRequest))
+	c.conn.Close()
+}
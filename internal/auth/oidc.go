@@ -0,0 +1,361 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ClaimRule struct {
+	Claim        string
+	Value        string
+	Repositories []string
+}
+
+type OIDCConfig struct {
+	Issuer       string
+	Audience     string
+	Rules        []ClaimRule
+	JWKSRefresh  time.Duration
+	ClockLeeway  time.Duration
+	SubjectClaim string
+}
+
+type OIDCAuthenticator struct {
+	cfg        OIDCConfig
+	httpClient *http.Client
+	log        *logrus.Entry
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func NewOIDCAuthenticator(logger *logrus.Logger, cfg OIDCConfig) *OIDCAuthenticator {
+	if cfg.SubjectClaim == "" {
+		cfg.SubjectClaim = "sub"
+	}
+	return &OIDCAuthenticator{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		log:        logger.WithField("component", "oidc_auth"),
+		keys:       make(map[string]crypto.PublicKey),
+	}
+}
+
+func (a *OIDCAuthenticator) Name() string {
+	return "oidc"
+}
+
+func (a *OIDCAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token := BearerToken(r)
+	if token == "" {
+		if _, password, ok := r.BasicAuth(); ok && strings.Count(password, ".") == 2 {
+			token = password
+		}
+	}
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := a.Verify(r.Context(), token)
+	if err != nil {
+		a.log.WithError(err).Debug("JWT validation failed")
+		return nil, ErrInvalidCredentials
+	}
+
+	subject, _ := claims[a.cfg.SubjectClaim].(string)
+	return &Principal{
+		Subject:      subject,
+		Method:       a.Name(),
+		Repositories: a.repositories(claims),
+	}, nil
+}
+
+func (a *OIDCAuthenticator) repositories(claims map[string]interface{}) []string {
+	if len(a.cfg.Rules) == 0 {
+		return nil
+	}
+
+	repositories := []string{}
+	for _, rule := range a.cfg.Rules {
+		if claimMatches(claims[rule.Claim], rule.Value) {
+			repositories = append(repositories, rule.Repositories...)
+		}
+	}
+	return repositories
+}
+
+func claimMatches(claim interface{}, value string) bool {
+	switch v := claim.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if fmt.Sprint(item) == value {
+				return true
+			}
+		}
+		return false
+	case nil:
+		return false
+	default:
+		return fmt.Sprint(v) == value
+	}
+}
+
+func (a *OIDCAuthenticator) Verify(ctx context.Context, token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	key, err := a.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+	if err := a.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (a *OIDCAuthenticator) validateClaims(claims map[string]interface{}) error {
+	if iss, _ := claims["iss"].(string); iss != a.cfg.Issuer {
+		return fmt.Errorf("unexpected issuer %q", iss)
+	}
+	if a.cfg.Audience != "" && !claimMatches(claims["aud"], a.cfg.Audience) {
+		return fmt.Errorf("audience mismatch")
+	}
+
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("missing exp claim")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(a.cfg.ClockLeeway)) {
+		return fmt.Errorf("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(a.cfg.ClockLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("token not yet valid")
+	}
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("algorithm %q does not match RSA key", alg)
+		}
+		return rsa.VerifyPKCS1v15(k, hash, digest, signature)
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			return fmt.Errorf("algorithm %q does not match EC key", alg)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return fmt.Errorf("signature verification failed")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported key type")
+}
+
+func (a *OIDCAuthenticator) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	a.mu.RLock()
+	key, ok := a.keys[kid]
+	stale := time.Since(a.fetchedAt) > a.cfg.JWKSRefresh
+	a.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+
+	if err := a.refreshKeys(ctx); err != nil {
+		if ok {
+			a.log.WithError(err).Warn("JWKS refresh failed, using cached key")
+			return key, nil
+		}
+		return nil, err
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if key, ok := a.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (a *OIDCAuthenticator) refreshKeys(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if time.Since(a.lastAttempt) < time.Minute && time.Since(a.fetchedAt) <= a.cfg.JWKSRefresh {
+		return nil
+	}
+	a.lastAttempt = time.Now()
+
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := a.getJSON(ctx, strings.TrimSuffix(a.cfg.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return fmt.Errorf("oidc discovery failed: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := a.getJSON(ctx, discovery.JWKSURI, &set); err != nil {
+		return fmt.Errorf("jwks fetch failed: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			a.log.WithFields(logrus.Fields{"kid": k.Kid, "error": err}).Warn("Skipping unsupported JWK")
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	a.keys = keys
+	a.fetchedAt = time.Now()
+	a.log.WithField("keys", len(keys)).Debug("Refreshed JWKS")
+	return nil
+}
+
+func (a *OIDCAuthenticator) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeSegment(segment string, out interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const PullTokenPrefix = "rpt_"
+
+type PullTokenLookup func(ctx context.Context, hash string) (*Principal, error)
+
+type PullTokenAuthenticator struct {
+	lookup PullTokenLookup
+}
+
+func NewPullTokenAuthenticator(lookup PullTokenLookup) *PullTokenAuthenticator {
+	return &PullTokenAuthenticator{lookup: lookup}
+}
+
+func (a *PullTokenAuthenticator) Name() string {
+	return "pull_token"
+}
+
+// Accepts the token as a bearer token or as the password of docker login, so
+// CI jobs can use it with any client.
+func (a *PullTokenAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token := BearerToken(r)
+	if token == "" {
+		_, token, _ = r.BasicAuth()
+	}
+	if !strings.HasPrefix(token, PullTokenPrefix) {
+		return nil, ErrNoCredentials
+	}
+	return a.lookup(r.Context(), HashPullToken(token))
+}
+
+func GeneratePullToken() (string, error) {
+	var random [32]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return "", err
+	}
+	return PullTokenPrefix + base64.RawURLEncoding.EncodeToString(random[:]), nil
+}
+
+func HashPullToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
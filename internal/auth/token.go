@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Scope struct {
+	Type    string
+	Name    string
+	Actions []string
+}
+
+type TokenConfig struct {
+	Key     func() []byte
+	Issuer  string
+	Service string
+	TTL     time.Duration
+}
+
+type TokenIssuer struct {
+	cfg TokenConfig
+}
+
+type tokenAccess struct {
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Actions []string `json:"actions"`
+}
+
+type tokenClaims struct {
+	Issuer    string        `json:"iss"`
+	Subject   string        `json:"sub"`
+	Audience  string        `json:"aud"`
+	ExpiresAt int64         `json:"exp"`
+	NotBefore int64         `json:"nbf"`
+	IssuedAt  int64         `json:"iat"`
+	ID        string        `json:"jti"`
+	Method    string        `json:"method,omitempty"`
+	Access    []tokenAccess `json:"access"`
+}
+
+func NewTokenIssuer(cfg TokenConfig) *TokenIssuer {
+	return &TokenIssuer{cfg: cfg}
+}
+
+func (t *TokenIssuer) Name() string {
+	return "token"
+}
+
+func (t *TokenIssuer) Service() string {
+	return t.cfg.Service
+}
+
+func (t *TokenIssuer) TTL() time.Duration {
+	return t.cfg.TTL
+}
+
+func ParseScope(value string) (Scope, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) < 3 {
+		return Scope{}, fmt.Errorf("invalid scope %q", value)
+	}
+	scope := Scope{
+		Type:    parts[0],
+		Name:    strings.Join(parts[1:len(parts)-1], ":"),
+		Actions: strings.Split(parts[len(parts)-1], ","),
+	}
+	if scope.Type == "" || scope.Name == "" {
+		return Scope{}, fmt.Errorf("invalid scope %q", value)
+	}
+	return scope, nil
+}
+
+func (t *TokenIssuer) Issue(subject, method string, scopes []Scope, now time.Time) (string, time.Time, error) {
+	var jti [16]byte
+	if _, err := rand.Read(jti[:]); err != nil {
+		return "", time.Time{}, err
+	}
+
+	expires := now.Add(t.cfg.TTL)
+	claims := tokenClaims{
+		Issuer:    t.cfg.Issuer,
+		Subject:   subject,
+		Audience:  t.cfg.Service,
+		ExpiresAt: expires.Unix(),
+		NotBefore: now.Add(-time.Second).Unix(),
+		IssuedAt:  now.Unix(),
+		ID:        hex.EncodeToString(jti[:]),
+		Method:    method,
+		Access:    []tokenAccess{},
+	}
+	for _, scope := range scopes {
+		claims.Access = append(claims.Access, tokenAccess{Type: scope.Type, Name: scope.Name, Actions: scope.Actions})
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(t.sign(signed)), expires, nil
+}
+
+func (t *TokenIssuer) sign(signed string) []byte {
+	mac := hmac.New(sha256.New, t.cfg.Key())
+	mac.Write([]byte(signed))
+	return mac.Sum(nil)
+}
+
+func (t *TokenIssuer) Authenticate(r *http.Request) (*Principal, error) {
+	token := BearerToken(r)
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrNoCredentials
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, ErrNoCredentials
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, t.sign(parts[0]+"."+parts[1])) {
+		return nil, ErrInvalidCredentials
+	}
+
+	var claims tokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	now := time.Now().Unix()
+	if claims.Issuer != t.cfg.Issuer || claims.Audience != t.cfg.Service || now >= claims.ExpiresAt || now < claims.NotBefore {
+		return nil, ErrInvalidCredentials
+	}
+
+	repositories := []string{}
+	for _, access := range claims.Access {
+		if access.Type == "repository" && containsAction(access.Actions, "pull") {
+			repositories = append(repositories, access.Name)
+		}
+	}
+	return &Principal{
+		Subject:      claims.Subject,
+		Method:       t.Name(),
+		Repositories: repositories,
+	}, nil
+}
+
+func containsAction(actions []string, action string) bool {
+	for _, a := range actions {
+		if a == action || a == "*" {
+			return true
+		}
+	}
+	return false
+}
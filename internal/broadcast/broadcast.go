@@ -0,0 +1,180 @@
+package broadcast
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const maxPayloadBytes = 7000
+
+type Message struct {
+	Origin       string   `json:"origin"`
+	Keys         []string `json:"keys,omitempty"`
+	Repositories []string `json:"repositories,omitempty"`
+}
+
+type Handler func(Message)
+
+type Bus struct {
+	db       *gorm.DB
+	channel  string
+	origin   string
+	log      *logrus.Entry
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewBus(logger *logrus.Logger, db *gorm.DB, channel string) *Bus {
+	hostname, _ := os.Hostname()
+	return &Bus{
+		db:      db,
+		channel: channel,
+		origin:  fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		log: logger.WithFields(logrus.Fields{
+			"component": "broadcast",
+			"channel":   channel,
+		}),
+	}
+}
+
+func (b *Bus) Subscribe(handler Handler) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *Bus) Publish(ctx context.Context, msg Message) error {
+	if b == nil {
+		return nil
+	}
+	for _, chunk := range b.split(msg) {
+		payload, err := json.Marshal(chunk)
+		if err != nil {
+			return fmt.Errorf("encode broadcast: %w", err)
+		}
+		if err := b.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", b.channel, string(payload)).Error; err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+	}
+	return nil
+}
+
+func (b *Bus) split(msg Message) []Message {
+	var chunks []Message
+	current := Message{Origin: b.origin}
+	size := 0
+	add := func(value string, repository bool) {
+		if size+len(value) > maxPayloadBytes && size > 0 {
+			chunks = append(chunks, current)
+			current, size = Message{Origin: b.origin}, 0
+		}
+		if repository {
+			current.Repositories = append(current.Repositories, value)
+		} else {
+			current.Keys = append(current.Keys, value)
+		}
+		size += len(value) + 3
+	}
+	for _, key := range msg.Keys {
+		add(key, false)
+	}
+	for _, repository := range msg.Repositories {
+		add(repository, true)
+	}
+	if size > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+func (b *Bus) Start(ctx context.Context) {
+	retryDelay := time.Second
+	for {
+		started := time.Now()
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			retryDelay = time.Second
+		}
+		b.log.WithError(err).WithField("retry_in", retryDelay).Warn("Broadcast listener disconnected")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+		if retryDelay < 30*time.Second {
+			retryDelay *= 2
+		}
+	}
+}
+
+func (b *Bus) listen(ctx context.Context) error {
+	sqlDB, err := b.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pgConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unsupported database driver %T", driverConn)
+		}
+		listener := pgConn.Conn()
+		if _, err := listener.Exec(ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+			return fmt.Errorf("listen failed: %w", err)
+		}
+		b.log.Info("Listening for cache invalidation broadcasts")
+
+		for {
+			notification, err := listener.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			b.dispatch(notification.Payload)
+		}
+	})
+}
+
+func (b *Bus) dispatch(payload string) {
+	var msg Message
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		b.log.WithError(err).Warn("Ignoring malformed broadcast")
+		return
+	}
+	if msg.Origin == b.origin {
+		return
+	}
+
+	b.log.WithFields(logrus.Fields{
+		"origin":       msg.Origin,
+		"keys":         len(msg.Keys),
+		"repositories": len(msg.Repositories),
+	}).Debug("Received cache invalidation broadcast")
+
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(msg)
+	}
+}
@@ -2,77 +2,299 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/leader"
 	"github.com/sdko-org/registry-proxy/internal/models"
 	"github.com/sdko-org/registry-proxy/internal/storage"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrPurgeRunning = errors.New("purge already running or queued")
+	ErrNotLeader    = errors.New("this replica is not the leader")
+)
+
+type PurgeRun struct {
+	Trigger          string    `json:"trigger"`
+	StartedAt        time.Time `json:"started_at"`
+	FinishedAt       time.Time `json:"finished_at"`
+	Duration         string    `json:"duration"`
+	ExpiredRegistry  int       `json:"expired_registry"`
+	ExpiredTags      int       `json:"expired_tags"`
+	InvalidatedItems int       `json:"invalidated_items"`
+	QuotaEvictions   int       `json:"quota_evictions"`
+	RetentionEvicted int       `json:"retention_evictions"`
+	RetainedItems    int       `json:"retained_items"`
+	Errors           int       `json:"errors"`
+
+	DanglingReferences int `json:"dangling_references"`
+}
+
+type PurgeStatus struct {
+	Running  bool      `json:"running"`
+	Leader   bool      `json:"leader"`
+	Schedule string    `json:"schedule"`
+	NextRun  time.Time `json:"next_run"`
+	LastRun  *PurgeRun `json:"last_run,omitempty"`
+}
+
 type CachePurger struct {
-	logger  *logrus.Logger
-	db      *gorm.DB
-	storage storage.Storage
-	cfg     *config.Config
+	logger   *logrus.Logger
+	db       *gorm.DB
+	storage  storage.Storage
+	cfg      *config.Config
+	schedule schedule
+	trigger  chan string
+	elector  *leader.Elector
+
+	mu      sync.Mutex
+	status  PurgeStatus
+	running bool
 }
 
-func NewCachePurger(logger *logrus.Logger, db *gorm.DB, storage storage.Storage, cfg *config.Config) *CachePurger {
-	return &CachePurger{
-		logger:  logger,
-		db:      db,
-		storage: storage,
-		cfg:     cfg,
+func NewCachePurger(logger *logrus.Logger, db *gorm.DB, storage storage.Storage, cfg *config.Config, elector *leader.Elector) *CachePurger {
+	c := &CachePurger{
+		logger:   logger,
+		db:       db,
+		storage:  storage,
+		cfg:      cfg,
+		elector:  elector,
+		schedule: intervalSchedule{interval: cfg.PurgeInterval},
+		trigger:  make(chan string, 1),
+	}
+	c.status.Schedule = "every " + cfg.PurgeInterval.String()
+
+	if cfg.PurgeSchedule != "" {
+		cron, err := parseCron(cfg.PurgeSchedule)
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"component": "cache_purger",
+				"schedule":  cfg.PurgeSchedule,
+				"error":     err,
+			}).Error("Invalid purge schedule, falling back to interval")
+		} else {
+			c.schedule = cron
+			c.status.Schedule = cfg.PurgeSchedule
+		}
 	}
+	return c
 }
 
 func (c *CachePurger) Start(ctx context.Context) {
-	ticker := time.NewTicker(30 * time.Minute)
-	defer ticker.Stop()
-
 	logEntry := c.logger.WithField("component", "cache_purger")
-	logEntry.Info("Starting cache purger")
+	logEntry.WithField("schedule", c.status.Schedule).Info("Starting cache purger")
 
 	for {
+		next := c.schedule.Next(time.Now())
+		c.mu.Lock()
+		c.status.NextRun = next
+		c.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
 		select {
-		case <-ticker.C:
-			c.purgeExpiredCache(ctx, logEntry)
+		case <-timer.C:
+			if !c.elector.IsLeader() {
+				logEntry.Debug("Skipping scheduled purge on non-leader replica")
+				continue
+			}
+			c.run(ctx, logEntry, "schedule")
+		case trigger := <-c.trigger:
+			timer.Stop()
+			c.run(ctx, logEntry, trigger)
 		case <-ctx.Done():
+			timer.Stop()
 			logEntry.Info("Stopping cache purger")
 			return
 		}
 	}
 }
 
-func (c *CachePurger) purgeExpiredCache(ctx context.Context, log *logrus.Entry) {
+func (c *CachePurger) Trigger() error {
+	if !c.elector.IsLeader() {
+		return ErrNotLeader
+	}
+
+	c.mu.Lock()
+	running := c.running
+	c.mu.Unlock()
+	if running {
+		return ErrPurgeRunning
+	}
+
+	select {
+	case c.trigger <- "manual":
+		return nil
+	default:
+		return ErrPurgeRunning
+	}
+}
+
+func (c *CachePurger) Status() PurgeStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := c.status
+	status.Running = c.running
+	status.Leader = c.elector.IsLeader()
+	return status
+}
+
+func (c *CachePurger) run(ctx context.Context, log *logrus.Entry, trigger string) {
+	c.mu.Lock()
+	c.running = true
+	c.mu.Unlock()
+
+	run := &PurgeRun{Trigger: trigger, StartedAt: time.Now()}
+	c.purgeExpiredCache(ctx, log, run)
+	c.purgeSoftDeleted(ctx, log, run)
+	c.enforceKeepLast(ctx, log, run)
+	c.enforceQuotas(ctx, log, run)
+	c.reconcileRepositories(ctx, log, run)
+	c.pruneReferences(ctx, log, run)
+	run.FinishedAt = time.Now()
+	run.Duration = run.FinishedAt.Sub(run.StartedAt).String()
+
+	log.WithFields(logrus.Fields{
+		"trigger":           run.Trigger,
+		"duration":          run.Duration,
+		"expired_registry":  run.ExpiredRegistry,
+		"expired_tags":      run.ExpiredTags,
+		"invalidated_items": run.InvalidatedItems,
+		"quota_evictions":   run.QuotaEvictions,
+		"retention_evicted": run.RetentionEvicted,
+		"retained_items":    run.RetainedItems,
+		"dangling_refs":     run.DanglingReferences,
+		"errors":            run.Errors,
+	}).Info("Cache purge finished")
+
+	c.mu.Lock()
+	c.running = false
+	c.status.LastRun = run
+	c.mu.Unlock()
+}
+
+func (c *CachePurger) purgeExpiredCache(ctx context.Context, log *logrus.Entry, run *PurgeRun) {
 	log = log.WithField("operation", "cache_purge")
+	now := time.Now()
 
-	var registryEntries []models.RegistryCache
-	if err := c.db.WithContext(ctx).
-		Where("expires_at < ? OR last_access < ?", time.Now(), time.Now().Add(-7*24*time.Hour)).
-		Find(&registryEntries).Error; err != nil {
-		log.WithError(err).Error("Registry cache purge query failed")
+	deleted, failed := c.purgeRegistryEntries(ctx, log, c.db.WithContext(ctx).
+		Where("expires_at < ? OR last_access < ?", now, now.Add(-7*24*time.Hour)),
+		func(entries []models.RegistryCache) []string {
+			return c.applyRetention(ctx, log, run, entries)
+		})
+	run.ExpiredRegistry += deleted
+	run.Errors += failed
+
+	result := c.db.WithContext(ctx).Unscoped().
+		Where("expires_at < ?", now).
+		Delete(&models.TagCache{})
+	if result.Error != nil {
+		log.WithError(result.Error).Error("Failed to delete expired tag cache entries")
+		run.Errors++
 	}
+	run.ExpiredTags += int(result.RowsAffected)
+}
+
+func (c *CachePurger) purgeSoftDeleted(ctx context.Context, log *logrus.Entry, run *PurgeRun) {
+	log = log.WithField("operation", "soft_delete_purge")
+	cutoff := time.Now().Add(-c.cfg.InvalidationGracePeriod)
+
+	deleted, failed := c.purgeRegistryEntries(ctx, log, c.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff), nil)
+	run.InvalidatedItems += deleted
+	run.Errors += failed
 
-	var tagEntries []models.TagCache
-	if err := c.db.WithContext(ctx).
-		Where("expires_at < ?", time.Now()).
-		Find(&tagEntries).Error; err != nil {
-		log.WithError(err).Error("Tag cache purge query failed")
+	result := c.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Delete(&models.TagCache{})
+	if result.Error != nil {
+		log.WithError(result.Error).Error("Failed to remove invalidated tag cache entries")
+		run.Errors++
 	}
+	run.InvalidatedItems += int(result.RowsAffected)
+}
+
+func (c *CachePurger) purgeRegistryEntries(ctx context.Context, log *logrus.Entry, query *gorm.DB, filter func([]models.RegistryCache) []string) (int, int) {
+	deleted, failed := 0, 0
 
-	log.WithField("count", len(registryEntries)+len(tagEntries)).Info("Processing expired cache entries")
+	var entries []models.RegistryCache
+	err := query.Select("key", "last_access", "expires_at").FindInBatches(&entries, c.cfg.PurgeBatchSize, func(tx *gorm.DB, batch int) error {
+		var keys []string
+		if filter != nil {
+			keys = filter(entries)
+		} else {
+			keys = make([]string, 0, len(entries))
+			for _, entry := range entries {
+				keys = append(keys, entry.Key)
+			}
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		if expanded, err := c.closure(ctx, keys); err != nil {
+			log.WithError(err).Warn("Failed to expand purge to dependent entries")
+		} else {
+			keys = expanded
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+
+		batchDeleted, batchFailed := c.deleteKeys(ctx, log, keys)
+		deleted += batchDeleted
+		failed += batchFailed
+
+		log.WithFields(logrus.Fields{
+			"batch":   batch,
+			"deleted": deleted,
+			"failed":  failed,
+		}).Info("Purge batch processed")
+		return nil
+	}).Error
+	if err != nil {
+		log.WithError(err).Error("Registry cache purge query failed")
+		failed++
+	}
+	return deleted, failed
+}
+
+func (c *CachePurger) reconcileRepositories(ctx context.Context, log *logrus.Entry, run *PurgeRun) {
+	err := c.db.WithContext(ctx).Exec(`
+		UPDATE repositories SET total_cached_bytes = COALESCE(usage.bytes, 0)
+		FROM repositories AS r
+		LEFT JOIN (
+			SELECT regexp_replace(key, '^v[0-9]+/[a-z]+s/(.*)/[^/]+$', '\1') AS name, SUM(GREATEST(size_bytes, 0)) AS bytes
+			FROM registry_cache
+			WHERE deleted_at IS NULL
+			GROUP BY 1
+		) AS usage ON usage.name = r.name
+		WHERE repositories.id = r.id AND repositories.total_cached_bytes <> COALESCE(usage.bytes, 0)`).Error
+	if err != nil {
+		log.WithError(err).Error("Failed to reconcile repository sizes")
+		run.Errors++
+	}
+}
 
-	for _, entry := range registryEntries {
-		if err := c.storage.Delete(ctx, entry.Key); err != nil {
-			log.WithFields(logrus.Fields{"key": entry.Key, "error": err}).Error("Failed to delete registry cache entry")
+func (c *CachePurger) deleteKeys(ctx context.Context, log *logrus.Entry, keys []string) (int, int) {
+	if batch, ok := c.storage.(storage.BatchDeleter); ok {
+		deleted, failedKeys, err := batch.DeleteBatch(ctx, keys)
+		if err != nil {
+			log.WithError(err).Error("Batch delete failed")
+			return deleted, len(keys) - deleted
 		}
+		return deleted, len(failedKeys)
 	}
 
-	for _, entry := range tagEntries {
-		if err := c.db.Delete(&entry).Error; err != nil {
-			log.WithFields(logrus.Fields{"repository": entry.Repository, "error": err}).Error("Failed to delete tag cache entry")
+	failed := 0
+	for _, key := range keys {
+		if err := c.storage.Delete(ctx, key); err != nil {
+			log.WithFields(logrus.Fields{"key": key, "error": err}).Error("Failed to delete registry cache entry")
+			failed++
 		}
 	}
+	return len(keys) - failed, failed
 }
@@ -0,0 +1,236 @@
+package cache
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type NamespaceUsage struct {
+	Pattern    string `json:"pattern"`
+	UsedBytes  int64  `json:"used_bytes"`
+	MaxBytes   int64  `json:"max_bytes"`
+	Entries    int64  `json:"entries"`
+	OverBudget bool   `json:"over_budget"`
+}
+
+func PatternExpr(pattern string) string {
+	if pattern == "*" || pattern == "**" {
+		return ".*"
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return globExpr(prefix) + "/.+"
+	}
+	return globExpr(pattern)
+}
+
+func KeyRegexp(pattern, reference string, classes ...string) string {
+	prefixes := make([]string, 0, len(classes))
+	for _, class := range classes {
+		prefixes = append(prefixes, regexp.QuoteMeta(storage.ClassPrefix(class)))
+	}
+	referenceExpr := "[^/]+"
+	if reference != "" {
+		referenceExpr = globExpr(reference)
+	}
+	return "^(" + strings.Join(prefixes, "|") + ")" + PatternExpr(pattern) + "/" + referenceExpr + "$"
+}
+
+func globExpr(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch glob[i] {
+		case '*':
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			}
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end <= 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if negated, ok := strings.CutPrefix(class, "^"); ok {
+				class = "^/" + negated
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+type TenantUsage struct {
+	Tenant     string   `json:"tenant"`
+	Namespaces []string `json:"namespaces"`
+	UsedBytes  int64    `json:"used_bytes"`
+	MaxBytes   int64    `json:"max_bytes"`
+	Entries    int64    `json:"entries"`
+	OverBudget bool     `json:"over_budget"`
+}
+
+func namespaceScope(patterns ...string) func(*gorm.DB) *gorm.DB {
+	var conditions []string
+	var args []interface{}
+	for _, pattern := range patterns {
+		conditions = append(conditions, "key ~ ?")
+		args = append(args, KeyRegexp(pattern, "", "blob", "manifest", "referrer"))
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(strings.Join(conditions, " OR "), args...)
+	}
+}
+
+func NamespaceUsages(ctx context.Context, db *gorm.DB, quotas []config.NamespaceQuota) ([]NamespaceUsage, error) {
+	usages := make([]NamespaceUsage, 0, len(quotas))
+	for _, quota := range quotas {
+		var usage NamespaceUsage
+		if err := db.WithContext(ctx).
+			Model(&models.RegistryCache{}).
+			Scopes(namespaceScope(quota.Pattern)).
+			Select("COALESCE(SUM(GREATEST(size_bytes, 0)), 0) AS used_bytes, COUNT(*) AS entries").
+			Scan(&usage).Error; err != nil {
+			return nil, err
+		}
+		usage.Pattern = quota.Pattern
+		usage.MaxBytes = quota.MaxBytes
+		usage.OverBudget = usage.UsedBytes > quota.MaxBytes
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+func TenantUsages(ctx context.Context, db *gorm.DB, tenants []config.Tenant) ([]TenantUsage, error) {
+	usages := make([]TenantUsage, 0, len(tenants))
+	for _, tenant := range tenants {
+		usage := TenantUsage{Tenant: tenant.Name, Namespaces: tenant.Namespaces, MaxBytes: tenant.MaxBytes}
+		if len(tenant.Namespaces) > 0 {
+			if err := db.WithContext(ctx).
+				Model(&models.RegistryCache{}).
+				Scopes(namespaceScope(tenant.Namespaces...)).
+				Select("COALESCE(SUM(GREATEST(size_bytes, 0)), 0) AS used_bytes, COUNT(*) AS entries").
+				Scan(&usage).Error; err != nil {
+				return nil, err
+			}
+		}
+		usage.OverBudget = tenant.MaxBytes > 0 && usage.UsedBytes > tenant.MaxBytes
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+func (c *CachePurger) enforceQuotas(ctx context.Context, log *logrus.Entry, run *PurgeRun) {
+	if len(c.cfg.NamespaceQuotas) == 0 && len(c.cfg.Tenants) == 0 {
+		return
+	}
+	log = log.WithField("operation", "quota_enforcement")
+
+	usages, err := NamespaceUsages(ctx, c.db, c.cfg.NamespaceQuotas)
+	if err != nil {
+		log.WithError(err).Error("Namespace usage query failed")
+		run.Errors++
+		return
+	}
+	for _, usage := range usages {
+		if !usage.OverBudget {
+			continue
+		}
+		nsLog := log.WithFields(logrus.Fields{
+			"namespace":  usage.Pattern,
+			"used_bytes": usage.UsedBytes,
+			"max_bytes":  usage.MaxBytes,
+		})
+		nsLog.Warn("Namespace over cache quota")
+		c.evictOverBudget(ctx, nsLog, run, namespaceScope(usage.Pattern), usage.UsedBytes, usage.MaxBytes)
+	}
+
+	tenantUsages, err := TenantUsages(ctx, c.db, c.cfg.Tenants)
+	if err != nil {
+		log.WithError(err).Error("Tenant usage query failed")
+		run.Errors++
+		return
+	}
+	for _, usage := range tenantUsages {
+		if !usage.OverBudget {
+			continue
+		}
+		tenantLog := log.WithFields(logrus.Fields{
+			"tenant":     usage.Tenant,
+			"used_bytes": usage.UsedBytes,
+			"max_bytes":  usage.MaxBytes,
+		})
+		tenantLog.Warn("Tenant over cache quota")
+		c.evictOverBudget(ctx, tenantLog, run, namespaceScope(usage.Namespaces...), usage.UsedBytes, usage.MaxBytes)
+	}
+}
+
+func (c *CachePurger) evictOverBudget(ctx context.Context, log *logrus.Entry, run *PurgeRun, scope func(*gorm.DB) *gorm.DB, used, maxBytes int64) {
+	var entries []models.RegistryCache
+	if err := c.db.WithContext(ctx).
+		Scopes(scope).
+		Order("last_access ASC").
+		Find(&entries).Error; err != nil {
+		log.WithError(err).Error("Failed to list namespace entries")
+		run.Errors++
+		return
+	}
+
+	sizes := make(map[string]int64, len(entries))
+	for _, entry := range entries {
+		sizes[entry.Key] = entry.SizeBytes
+	}
+
+	evicted := 0
+	evictedKeys := make(map[string]bool)
+	now := time.Now()
+	for _, entry := range entries {
+		if used <= maxBytes {
+			break
+		}
+		if keep, _ := c.retained(entry, now); keep {
+			continue
+		}
+		if evictedKeys[entry.Key] {
+			continue
+		}
+		keys, err := c.closure(ctx, []string{entry.Key})
+		if err != nil {
+			log.WithFields(logrus.Fields{"key": entry.Key, "error": err}).Warn("Failed to expand eviction to dependent entries")
+			keys = []string{entry.Key}
+		}
+		for _, key := range keys {
+			if err := c.storage.Delete(ctx, key); err != nil {
+				log.WithFields(logrus.Fields{"key": key, "error": err}).Error("Failed to evict cache entry")
+				run.Errors++
+				continue
+			}
+			evictedKeys[key] = true
+			if sizes[key] > 0 {
+				used -= sizes[key]
+			}
+			evicted++
+		}
+	}
+	run.QuotaEvictions += evicted
+
+	log.WithFields(logrus.Fields{
+		"evicted":         evicted,
+		"remaining_bytes": used,
+	}).Info("Evicted entries to enforce quota")
+}
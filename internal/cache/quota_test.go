@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+)
+
+func TestPatternExprAgreesWithMatchPattern(t *testing.T) {
+	patterns := []string{
+		"*", "**", "library/nginx", "myorg/*", "myorg/**", "*/team/**", "myorg/dev-*",
+		"myorg/dev-?", "myorg/[a-c]pp", "myorg/[^a]pp", "*/nginx", "a.b/c+d", "library/[",
+	}
+	names := []string{
+		"library/nginx", "library/nginx2", "myorg", "myorg/app", "myorg/team/app", "myorganisation/app",
+		"myorg/other/app", "myorg/dev-api", "myorg/dev-a", "myorg/bpp", "a/b/nginx", "a.b/c+d", "axb/c+d",
+		"library/[",
+	}
+	for _, pattern := range patterns {
+		re := regexp.MustCompile("^" + PatternExpr(pattern) + "$")
+		for _, name := range names {
+			if got, want := re.MatchString(name), config.MatchPattern(pattern, name); got != want {
+				t.Errorf("pattern %q on %q: regexp %v, MatchPattern %v", pattern, name, got, want)
+			}
+		}
+	}
+}
+
+func TestKeyRegexp(t *testing.T) {
+	for _, tc := range []struct {
+		pattern   string
+		reference string
+		key       string
+		want      bool
+	}{
+		{"myorg/*", "", storage.BlobKey("myorg/app", "sha256:abc"), true},
+		{"myorg/*", "", storage.ReferrersKey("myorg/app", "sha256:abc"), true},
+		{"myorg/*", "", storage.BlobKey("myorg/team/app", "sha256:abc"), false},
+		{"myorg/**", "", storage.ManifestKey("myorg/team/app", "latest"), true},
+		{"myorg/app", "", storage.ManifestKey("myorg/app", "latest"), true},
+		{"myorg/app", "", storage.ManifestKey("myorg/app2", "latest"), false},
+		{"myorg/app", "v1.*", storage.ManifestKey("myorg/app", "v1.2"), true},
+		{"myorg/app", "v1.*", storage.ManifestKey("myorg/app", "v2.0"), false},
+		{"myorg/app", "v1.*", storage.BlobKey("myorg/app", "v1.2"), false},
+	} {
+		classes := []string{"blob", "manifest", "referrer"}
+		if tc.reference != "" {
+			classes = []string{"manifest"}
+		}
+		re := regexp.MustCompile(KeyRegexp(tc.pattern, tc.reference, classes...))
+		if got := re.MatchString(tc.key); got != tc.want {
+			t.Errorf("KeyRegexp(%q, %q) on %q = %v, want %v", tc.pattern, tc.reference, tc.key, got, tc.want)
+		}
+	}
+}
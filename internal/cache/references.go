@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type DanglingReference struct {
+	ParentKey string `json:"parent_key"`
+	ChildKey  string `json:"child_key"`
+	Kind      string `json:"kind"`
+}
+
+type ConsistencyReport struct {
+	CheckedAt  time.Time           `json:"checked_at"`
+	References int64               `json:"references"`
+	Dangling   int64               `json:"dangling"`
+	Stale      int64               `json:"stale"`
+	Sample     []DanglingReference `json:"sample"`
+}
+
+const danglingReferences = `
+	FROM cache_references
+	JOIN registry_cache AS parent ON parent.key = cache_references.parent_key AND parent.deleted_at IS NULL
+	WHERE NOT EXISTS (
+		SELECT 1 FROM registry_cache AS child
+		WHERE child.key = cache_references.child_key AND child.deleted_at IS NULL
+	)`
+
+func CheckConsistency(ctx context.Context, db *gorm.DB, limit int) (*ConsistencyReport, error) {
+	report := &ConsistencyReport{CheckedAt: time.Now(), Sample: []DanglingReference{}}
+
+	if err := db.WithContext(ctx).Model(&models.CacheReference{}).Count(&report.References).Error; err != nil {
+		return nil, err
+	}
+	if err := db.WithContext(ctx).Raw("SELECT COUNT(*)" + danglingReferences).Scan(&report.Dangling).Error; err != nil {
+		return nil, err
+	}
+	if err := db.WithContext(ctx).Raw(`
+		SELECT COUNT(*) FROM cache_references
+		WHERE NOT EXISTS (SELECT 1 FROM registry_cache WHERE registry_cache.key = cache_references.parent_key)`).
+		Scan(&report.Stale).Error; err != nil {
+		return nil, err
+	}
+	if err := db.WithContext(ctx).Raw(`
+		SELECT cache_references.parent_key, cache_references.child_key, cache_references.kind`+danglingReferences+`
+		ORDER BY cache_references.parent_key, cache_references.child_key
+		LIMIT ?`, limit).Scan(&report.Sample).Error; err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (c *CachePurger) closure(ctx context.Context, keys []string) ([]string, error) {
+	now := time.Now()
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		seen[key] = true
+	}
+
+	var levels [][]string
+	for frontier := keys; len(frontier) > 0; {
+		var parents []models.RegistryCache
+		if err := c.db.WithContext(ctx).
+			Select("key", "last_access", "expires_at").
+			Where("key IN (?)", c.db.Model(&models.CacheReference{}).Select("parent_key").Where("child_key IN ?", frontier)).
+			Find(&parents).Error; err != nil {
+			return nil, err
+		}
+		frontier = nil
+		for _, parent := range parents {
+			if seen[parent.Key] {
+				continue
+			}
+			seen[parent.Key] = true
+			if keep, _ := c.retained(parent, now); keep {
+				continue
+			}
+			frontier = append(frontier, parent.Key)
+		}
+		if len(frontier) > 0 {
+			levels = append(levels, frontier)
+		}
+	}
+
+	ordered := make([]string, 0, len(seen))
+	for i := len(levels) - 1; i >= 0; i-- {
+		ordered = append(ordered, levels[i]...)
+	}
+	ordered = append(ordered, keys...)
+
+	for frontier := ordered; len(frontier) > 0; {
+		var children []models.RegistryCache
+		if err := c.db.WithContext(ctx).Raw(`
+			SELECT key, last_access, expires_at FROM registry_cache
+			WHERE deleted_at IS NULL AND key IN (SELECT child_key FROM cache_references WHERE parent_key IN ?)
+			AND NOT EXISTS (
+				SELECT 1 FROM cache_references AS other
+				JOIN registry_cache AS parent ON parent.key = other.parent_key
+				WHERE other.child_key = registry_cache.key AND other.parent_key NOT IN ?
+			)`, frontier, ordered).Scan(&children).Error; err != nil {
+			return nil, err
+		}
+		frontier = nil
+		for _, child := range children {
+			if seen[child.Key] {
+				continue
+			}
+			seen[child.Key] = true
+			if keep, _ := c.retained(child, now); keep {
+				continue
+			}
+			frontier = append(frontier, child.Key)
+		}
+		ordered = append(ordered, frontier...)
+	}
+	return ordered, nil
+}
+
+func (c *CachePurger) pruneReferences(ctx context.Context, log *logrus.Entry, run *PurgeRun) {
+	if err := c.db.WithContext(ctx).Exec(`
+		DELETE FROM cache_references
+		WHERE NOT EXISTS (SELECT 1 FROM registry_cache WHERE registry_cache.key = cache_references.parent_key)`).Error; err != nil {
+		log.WithError(err).Error("Failed to prune cache references")
+		run.Errors++
+		return
+	}
+
+	report, err := CheckConsistency(ctx, c.db, 0)
+	if err != nil {
+		log.WithError(err).Error("Cache consistency check failed")
+		run.Errors++
+		return
+	}
+	run.DanglingReferences = int(report.Dangling)
+	if report.Dangling > 0 {
+		log.WithField("dangling", report.Dangling).Info("Cached manifests reference entries missing from the cache")
+	}
+}
@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type RetentionUsage struct {
+	Pattern      string `json:"pattern"`
+	Protect      bool   `json:"protect"`
+	KeepLast     int    `json:"keep_last,omitempty"`
+	KeepAccessed string `json:"keep_accessed,omitempty"`
+	Entries      int64  `json:"entries"`
+	UsedBytes    int64  `json:"used_bytes"`
+}
+
+func RetentionRuleFor(rules []config.RetentionRule, repository string) *config.RetentionRule {
+	for i := range rules {
+		if config.MatchPattern(rules[i].Pattern, repository) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+func RetentionUsages(ctx context.Context, db *gorm.DB, rules []config.RetentionRule) ([]RetentionUsage, error) {
+	usages := make([]RetentionUsage, 0, len(rules))
+	for _, rule := range rules {
+		usage := RetentionUsage{
+			Pattern:  rule.Pattern,
+			Protect:  rule.Protect,
+			KeepLast: rule.KeepLast,
+		}
+		if rule.KeepAccessed > 0 {
+			usage.KeepAccessed = rule.KeepAccessed.String()
+		}
+
+		if err := db.WithContext(ctx).
+			Model(&models.RegistryCache{}).
+			Where("key ~ ?", KeyRegexp(rule.Pattern, "", "blob", "manifest", "referrer")).
+			Select("COALESCE(SUM(GREATEST(size_bytes, 0)), 0) AS used_bytes, COUNT(*) AS entries").
+			Scan(&usage).Error; err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+func (c *CachePurger) retained(entry models.RegistryCache, now time.Time) (bool, bool) {
+	parsed := storage.ParseKey(entry.Key)
+	rule := RetentionRuleFor(c.cfg.RetentionRules, parsed.Repository)
+	if rule == nil {
+		return false, false
+	}
+	if !rule.Protect && (rule.KeepAccessed <= 0 || now.Sub(entry.LastAccess) >= rule.KeepAccessed) {
+		return false, false
+	}
+
+	contentAddressed := parsed.Class == "blob" || strings.HasPrefix(parsed.Reference, "sha256:")
+	return true, contentAddressed && entry.ExpiresAt.Before(now)
+}
+
+func (c *CachePurger) applyRetention(ctx context.Context, log *logrus.Entry, run *PurgeRun, entries []models.RegistryCache) []string {
+	now := time.Now()
+	var keys, renewBlobs, renewManifests []string
+	for _, entry := range entries {
+		keep, renew := c.retained(entry, now)
+		switch {
+		case !keep:
+			keys = append(keys, entry.Key)
+		case renew && storage.ParseKey(entry.Key).Class == "blob":
+			renewBlobs = append(renewBlobs, entry.Key)
+		case renew:
+			renewManifests = append(renewManifests, entry.Key)
+		}
+		if keep {
+			run.RetainedItems++
+		}
+	}
+
+	c.renew(ctx, log, run, renewBlobs, now.Add(c.cfg.BlobCacheTTL))
+	c.renew(ctx, log, run, renewManifests, now.Add(c.cfg.ManifestCacheTTL))
+	return keys
+}
+
+func (c *CachePurger) renew(ctx context.Context, log *logrus.Entry, run *PurgeRun, keys []string, expiresAt time.Time) {
+	if len(keys) == 0 {
+		return
+	}
+	if err := c.db.WithContext(ctx).Model(&models.RegistryCache{}).
+		Where("key IN ?", keys).
+		Update("expires_at", expiresAt).Error; err != nil {
+		log.WithError(err).Error("Failed to renew retained cache entries")
+		run.Errors++
+	}
+}
+
+func (c *CachePurger) enforceKeepLast(ctx context.Context, log *logrus.Entry, run *PurgeRun) {
+	log = log.WithField("operation", "retention_keep_last")
+
+	for i := range c.cfg.RetentionRules {
+		rule := &c.cfg.RetentionRules[i]
+		if rule.KeepLast <= 0 || rule.Protect {
+			continue
+		}
+
+		var entries []models.RegistryCache
+		if err := c.db.WithContext(ctx).
+			Select("key").
+			Where("key ~ ?", KeyRegexp(rule.Pattern, "", "manifest")).
+			Order("last_access DESC").
+			Find(&entries).Error; err != nil {
+			log.WithFields(logrus.Fields{"pattern": rule.Pattern, "error": err}).Error("Failed to list tagged manifests")
+			run.Errors++
+			continue
+		}
+
+		seen := make(map[string]int)
+		var evict []string
+		for _, entry := range entries {
+			parsed := storage.ParseKey(entry.Key)
+			if strings.HasPrefix(parsed.Reference, "sha256:") || RetentionRuleFor(c.cfg.RetentionRules, parsed.Repository) != rule {
+				continue
+			}
+			seen[parsed.Repository]++
+			if seen[parsed.Repository] > rule.KeepLast {
+				evict = append(evict, entry.Key)
+			}
+		}
+
+		if expanded, err := c.closure(ctx, evict); err != nil {
+			log.WithFields(logrus.Fields{"pattern": rule.Pattern, "error": err}).Warn("Failed to expand retention eviction to dependent entries")
+		} else {
+			evict = expanded
+		}
+		for start := 0; start < len(evict); start += c.cfg.PurgeBatchSize {
+			end := min(start+c.cfg.PurgeBatchSize, len(evict))
+			deleted, failed := c.deleteKeys(ctx, log, evict[start:end])
+			run.RetentionEvicted += deleted
+			run.Errors += failed
+		}
+
+		if len(evict) > 0 {
+			log.WithFields(logrus.Fields{
+				"pattern":      rule.Pattern,
+				"keep_last":    rule.KeepLast,
+				"repositories": len(seen),
+				"evicted":      len(evict),
+			}).Info("Evicted tags beyond retention limit")
+		}
+	}
+}
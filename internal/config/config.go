@@ -1,66 +1,951 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	S3MinPartSize    = 5 * 1024 * 1024
+	S3MaxPartSize    = 5 * 1024 * 1024 * 1024
+	S3MaxObjectSize  = 5 * 1024 * 1024 * 1024 * 1024
+	S3MaxUploadParts = 10000
+)
+
+const (
+	S3BackendAWS       = "aws"
+	S3BackendMinIO     = "minio"
+	S3BackendSeaweedFS = "seaweedfs"
+	S3BackendGeneric   = "generic"
+)
+
+const (
+	S3RetryStandard = "standard"
+	S3RetryAdaptive = "adaptive"
+)
+
+const (
+	SBOMFormatSPDX      = "spdx-json"
+	SBOMFormatCycloneDX = "cyclonedx-json"
+)
+
+const (
+	UnknownManifestReject      = "reject"
+	UnknownManifestPassthrough = "passthrough"
+)
+
+const (
+	Schema1Allow       = "allow"
+	Schema1Passthrough = "passthrough"
+	Schema1Reject      = "reject"
+)
+
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+const (
+	MetadataBackendPostgres = "postgres"
+	MetadataBackendS3       = "s3"
+)
+
+type NamespaceQuota struct {
+	Pattern  string
+	MaxBytes int64
+}
+
+type Tenant struct {
+	Name       string
+	Hosts      []string
+	Subjects   []string
+	Namespaces []string
+	MaxBytes   int64
+	RateLimit  int
+}
+
+type UpstreamCredential struct {
+	Pattern  string
+	Username string
+	Password string
+}
+
+type BandwidthRule struct {
+	Pattern        string
+	BytesPerSecond int64
+}
+
+type RetentionRule struct {
+	Pattern      string
+	Protect      bool
+	KeepLast     int
+	KeepAccessed time.Duration
+}
+
+type VanityRoute struct {
+	Host     string
+	Prefix   string
+	Upstream string
+}
+
+type ImageRewrite struct {
+	Pattern     string
+	Regex       *regexp.Regexp
+	Replacement string
+}
+
+type SigV4Upstream struct {
+	Pattern string
+	Region  string
+	Service string
+}
+
 type Config struct {
-	S3Bucket          string
-	S3Region          string
-	S3Endpoint        string
-	S3AccessKey       string
-	S3SecretKey       string
-	DockerHubUser     string
-	DockerHubPassword string
-	TagCacheTTL       time.Duration
-	ManifestCacheTTL  time.Duration
-	BlobCacheTTL      time.Duration
-	RateLimit         int
-	RateLimitWindow   time.Duration
-	PostgresUser      string
-	PostgresPassword  string
-	PostgresHost      string
-	PostgresPort      string
-	PostgresDatabase  string
-	PostgresSSLMode   string
-	TempDir           string
+	S3Bucket        string
+	S3Region        string
+	S3Endpoint      string
+	S3AccessKey     string
+	S3SecretKey     string
+	S3SSE           string
+	S3SSEKMSKeyID   string
+	S3ObjectTagging bool
+	S3StorageClass  string
+	S3Backend       string
+
+	StorageEncryptionKey    string
+	StorageEncryptionKMSKey string
+
+	StorageCompression      string
+	StorageCompressionLevel int
+
+	S3PartSize            int64
+	S3UploadConcurrency   int
+	S3DownloadConcurrency int
+	S3MaxRetries          int
+	S3MaxBlobSize         int64
+	S3Dedup               bool
+
+	S3RetryMode          string
+	S3ChecksumValidation bool
+
+	S3LargeBucket         string
+	S3LargeEndpoint       string
+	S3LargeStorageClass   string
+	S3TierSizeThreshold   int64
+	S3TierSmallMediaTypes []string
+	DockerHubUser         string
+	DockerHubPassword     string
+	MirrorNamespaces      []string
+	VanityRoutes          []VanityRoute
+	ImageRewrites         []ImageRewrite
+	PushRejectStatus      int
+	CachePlatforms        []string
+	TagCacheTTL           time.Duration
+	ManifestCacheTTL      time.Duration
+	BlobCacheTTL          time.Duration
+	RateLimit             int
+	RateLimitWindow       time.Duration
+	PostgresUser          string
+	PostgresPassword      string
+	PostgresHost          string
+	PostgresPort          string
+	PostgresDatabase      string
+	PostgresSSLMode       string
+	TempDir               string
+
+	DockerHubCredentialMap string
+
+	GHCRUser  string
+	GHCRToken string
+
+	RegistryCredentialMap string
+	SigV4Upstreams        []SigV4Upstream
+
+	UpstreamBreakerThreshold int
+	UpstreamBreakerCooldown  time.Duration
+
+	UpstreamUserAgent      string
+	ForwardClientUserAgent bool
+	ViaPseudonym           string
+
+	InlineMaxSize int64
+
+	MemoryBlobThreshold int64
+	MemoryBlobPool      int64
+
+	RuntimeStatsInterval time.Duration
+	SelfTestImage        string
+
+	ResumeDownloads bool
+
+	SearchProxy    bool
+	SearchCacheTTL time.Duration
+
+	PrefetchLayers       bool
+	PrefetchMaxLayerSize int64
+
+	SBOMGeneration   bool
+	SBOMFormat       string
+	SBOMMaxImageSize int64
+
+	MaxCacheBlobSize int64
+	MaxManifestSize  int64
+
+	ManifestExtraMediaTypes []string
+	UnknownManifestTypes    string
+	Schema1Manifests        string
+
+	ArtifactCacheTTLs map[string]time.Duration
+
+	ManifestPlatformFilter bool
+	ManifestPlatformRules  string
+	CacheStatusHeaders     bool
+	PreservedHeaders       []string
+	ReindexOnStart         bool
+	MetadataBackend        string
+
+	ListenHTTP    string
+	ListenHTTPS   string
+	ListenAdmin   string
+	ListenMetrics string
+	ListenPprof   string
+	ListenGRPC    string
+
+	ListenReusePort bool
+	ShutdownTimeout time.Duration
+
+	MaxHeaderBytes int
+	MaxURLLength   int
+	MaxBodyBytes   int64
+
+	ReadHeaderTimeout time.Duration
+	IdleTimeout       time.Duration
+	APITimeout        time.Duration
+	BlobTimeout       time.Duration
+
+	BandwidthUpstream  int64
+	BandwidthPerClient int64
+	BandwidthRules     []BandwidthRule
+
+	QoSRules          string
+	QoSDefaultClass   string
+	QoSUpstreamSlots  int
+	QoSYieldBandwidth int64
+
+	NamespaceQuotas []NamespaceQuota
+	Tenants         []Tenant
+	RetentionRules  []RetentionRule
+
+	ChargebackCostPerGB float64
+
+	QuarantineEnabled bool
+	QuarantineExempt  []string
+
+	PassthroughRepositories []string
+
+	DriftCheckInterval time.Duration
+	DriftBatchSize     int
+	DriftWebhookURL    string
+
+	InvalidationBroadcast bool
+	InvalidationChannel   string
+
+	InvalidationGracePeriod time.Duration
+	PurgeInterval           time.Duration
+	PurgeSchedule           string
+	PurgeBatchSize          int
+
+	LeaderElection bool
+	LeaderLeaseTTL time.Duration
+
+	AuthOIDCIssuer       string
+	AuthOIDCAudience     string
+	AuthOIDCRules        string
+	AuthOIDCSubjectClaim string
+	AuthJWKSRefresh      time.Duration
+	AuthClockLeeway      time.Duration
+
+	AuthTokenEnabled bool
+	AuthTokenRealm   string
+	AuthTokenService string
+	AuthTokenIssuer  string
+	AuthTokenKey     string
+	AuthTokenTTL     time.Duration
+
+	PullTokensEnabled   bool
+	PullTokenDefaultTTL time.Duration
+	PullTokenMaxTTL     time.Duration
+
+	AdminSubjects []string
+
+	DockerHubRegistry string
+
+	CORSAllowedOrigins   []string
+	CORSAllowedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+
+	LDAPURL            string
+	LDAPStartTLS       bool
+	LDAPBindDN         string
+	LDAPBindPassword   string
+	LDAPUserBaseDN     string
+	LDAPUserAttribute  string
+	LDAPGroupAttribute string
+	LDAPGroupRules     string
+	LDAPCacheTTL       time.Duration
+
+	JobWorkers      int
+	JobMaxAttempts  int
+	JobPollInterval time.Duration
+	JobLockTimeout  time.Duration
+	JobRetention    time.Duration
+
+	DiskCacheDir                string
+	DiskCacheMaxBytes           int64
+	DiskCacheMaxObjectSize      int64
+	DiskCacheRevalidateInterval time.Duration
+
+	Peers            []string
+	PeerToken        string
+	PeerSyncInterval time.Duration
+	PeerTimeout      time.Duration
+
+	P2PSeedURL       string
+	P2PAnnounceURL   string
+	P2PAnnounceToken string
+	P2PServeToken    string
+	P2PBatchSize     int
+	P2PFlushInterval time.Duration
+
+	LogSyslogURL         string
+	LogSyslogFacility    string
+	LogSyslogAppName     string
+	LogLokiURL           string
+	LogLokiLabels        map[string]string
+	LogLokiTenant        string
+	LogLokiUser          string
+	LogLokiPassword      string
+	LogLokiBatchSize     int
+	LogLokiFlushInterval time.Duration
+
+	AlertWebhookURL          string
+	AlertSlackWebhookURL     string
+	AlertInterval            time.Duration
+	AlertSustain             time.Duration
+	AlertUpstreamFailureRate float64
+	AlertS3Errors            float64
+	AlertCacheWriteBacklog   float64
+
+	SentryDSN         string
+	SentryEnvironment string
+	SentryRelease     string
+	SentrySampleRate  float64
+
+	FaultUpstreamErrorRate float64
+	FaultS3Latency         time.Duration
+	FaultS3LatencyRate     float64
+	FaultDBErrorRate       float64
+
+	ShardSelf           string
+	ShardMembers        []string
+	ShardVirtualNodes   int
+	ShardForwardTimeout time.Duration
+
+	SecretReloadInterval time.Duration
+
+	mu                  sync.RWMutex
+	secretFiles         map[string]string
+	upstreamCredentials []UpstreamCredential
 }
 
 func Load(log *logrus.Logger) (*Config, error) {
+	secrets := newSecretLoader(log)
 	cfg := &Config{
 		S3Bucket:          getEnv("S3_BUCKET", "registry-cache"),
 		S3Region:          getEnv("AWS_REGION", "us-east-1"),
-		S3Endpoint:        mustGetEnv(log, "S3_ENDPOINT"),
-		S3AccessKey:       mustGetEnv(log, "AWS_ACCESS_KEY_ID"),
-		S3SecretKey:       mustGetEnv(log, "AWS_SECRET_ACCESS_KEY"),
-		DockerHubUser:     mustGetEnv(log, "DOCKERHUB_USER"),
-		DockerHubPassword: mustGetEnv(log, "DOCKERHUB_PASSWORD"),
+		S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+		S3AccessKey:       secrets.get("AWS_ACCESS_KEY_ID", ""),
+		S3SecretKey:       secrets.get("AWS_SECRET_ACCESS_KEY", ""),
+		S3SSE:             getEnv("S3_SSE", ""),
+		S3SSEKMSKeyID:     getEnv("S3_SSE_KMS_KEY_ID", ""),
+		S3ObjectTagging:   getEnvBool(log, "S3_OBJECT_TAGGING", false),
+		S3StorageClass:    getEnv("S3_STORAGE_CLASS", ""),
+		S3Backend:         strings.ToLower(getEnv("S3_BACKEND", S3BackendAWS)),
+		DockerHubUser:     secrets.get("DOCKERHUB_USER", ""),
+		DockerHubPassword: secrets.get("DOCKERHUB_PASSWORD", ""),
+		MirrorNamespaces:  getEnvList("MIRROR_NAMESPACES", nil),
+		VanityRoutes:      getEnvVanityRoutes(log, "VANITY_ROUTES"),
+		ImageRewrites:     getEnvImageRewrites(log, "IMAGE_REWRITES"),
+		PushRejectStatus:  getEnvInt(log, "PUSH_REJECT_STATUS", 405),
+		CachePlatforms:    getEnvList("CACHE_PLATFORMS", nil),
 		TagCacheTTL:       getEnvDuration(log, "TAG_CACHE_TTL", 1*time.Hour),
 		ManifestCacheTTL:  getEnvDuration(log, "MANIFEST_CACHE_TTL", 48*time.Hour),
 		BlobCacheTTL:      getEnvDuration(log, "BLOB_CACHE_TTL", 48*time.Hour),
 		RateLimit:         getEnvInt(log, "RATE_LIMIT", 100),
 		RateLimitWindow:   getEnvDuration(log, "RATE_LIMIT_WINDOW", time.Minute),
 		PostgresUser:      getEnv("POSTGRES_USER", "registry"),
-		PostgresPassword:  getEnv("POSTGRES_PASSWORD", "password"),
+		PostgresPassword:  secrets.get("POSTGRES_PASSWORD", "password"),
 		PostgresHost:      getEnv("POSTGRES_HOST", "localhost"),
 		PostgresPort:      getEnv("POSTGRES_PORT", "5432"),
 		PostgresDatabase:  getEnv("POSTGRES_DATABASE", "registry_proxy"),
 		PostgresSSLMode:   getEnv("POSTGRES_SSL_MODE", "disable"),
 		TempDir:           getEnv("TEMP_DIR", "/tmp/registry-proxy"),
+
+		S3PartSize:            getEnvByteSize(log, "S3_PART_SIZE", 16*1024*1024),
+		S3UploadConcurrency:   getEnvInt(log, "S3_UPLOAD_CONCURRENCY", 3),
+		S3DownloadConcurrency: getEnvInt(log, "S3_DOWNLOAD_CONCURRENCY", 4),
+		S3MaxRetries:          getEnvInt(log, "S3_MAX_RETRIES", 5),
+		S3MaxBlobSize:         getEnvByteSize(log, "S3_MAX_BLOB_SIZE", 150*1024*1024*1024),
+		S3Dedup:               getEnvBool(log, "S3_UPLOAD_DEDUP", true),
+
+		S3RetryMode:          strings.ToLower(getEnv("S3_RETRY_MODE", S3RetryAdaptive)),
+		S3ChecksumValidation: getEnvBool(log, "S3_CHECKSUM_VALIDATION", true),
+
+		StorageEncryptionKey:    secrets.get("STORAGE_ENCRYPTION_KEY", ""),
+		StorageEncryptionKMSKey: getEnv("STORAGE_ENCRYPTION_KMS_KEY", ""),
+
+		StorageCompression:      strings.ToLower(getEnv("STORAGE_COMPRESSION", CompressionNone)),
+		StorageCompressionLevel: getEnvInt(log, "STORAGE_COMPRESSION_LEVEL", 3),
+
+		S3LargeBucket:       getEnv("S3_LARGE_BUCKET", ""),
+		S3LargeEndpoint:     getEnv("S3_LARGE_ENDPOINT", ""),
+		S3LargeStorageClass: getEnv("S3_LARGE_STORAGE_CLASS", ""),
+		S3TierSizeThreshold: getEnvInt64(log, "S3_TIER_SIZE_THRESHOLD", 1024*1024),
+		S3TierSmallMediaTypes: getEnvList("S3_TIER_SMALL_MEDIA_TYPES", []string{
+			"application/vnd.docker.container.image.v1+json",
+			"application/vnd.oci.image.config.v1+json",
+		}),
+
+		InlineMaxSize: getEnvInt64(log, "INLINE_MAX_SIZE", 0),
+
+		MemoryBlobThreshold: getEnvByteSize(log, "MEMORY_BLOB_THRESHOLD", 0),
+		MemoryBlobPool:      getEnvByteSize(log, "MEMORY_BLOB_POOL", 64*1024*1024),
+
+		RuntimeStatsInterval: getEnvDuration(log, "RUNTIME_STATS_INTERVAL", 30*time.Second),
+		SelfTestImage:        getEnv("SELFTEST_IMAGE", "library/hello-world:latest"),
+
+		DockerHubCredentialMap: secrets.get("DOCKERHUB_CREDENTIALS", ""),
+
+		GHCRUser:  getEnv("GHCR_USER", "token"),
+		GHCRToken: secrets.get("GHCR_TOKEN", ""),
+
+		RegistryCredentialMap: secrets.get("REGISTRY_CREDENTIALS", ""),
+
+		UpstreamBreakerThreshold: getEnvInt(log, "UPSTREAM_BREAKER_THRESHOLD", 5),
+		UpstreamBreakerCooldown:  getEnvDuration(log, "UPSTREAM_BREAKER_COOLDOWN", 30*time.Second),
+
+		SigV4Upstreams: getEnvSigV4Upstreams(log, "UPSTREAM_SIGV4"),
+
+		UpstreamUserAgent:      getEnv("UPSTREAM_USER_AGENT", "RegistryProxy/1.0"),
+		ForwardClientUserAgent: getEnvBool(log, "UPSTREAM_FORWARD_USER_AGENT", false),
+		ViaPseudonym:           getEnv("VIA_PSEUDONYM", ""),
+
+		ResumeDownloads: getEnvBool(log, "RESUME_DOWNLOADS", true),
+
+		SearchProxy:    getEnvBool(log, "SEARCH_PROXY", true),
+		SearchCacheTTL: getEnvDuration(log, "SEARCH_CACHE_TTL", 5*time.Minute),
+
+		PrefetchLayers:       getEnvBool(log, "PREFETCH_LAYERS", false),
+		PrefetchMaxLayerSize: getEnvInt64(log, "PREFETCH_MAX_LAYER_SIZE", 0),
+
+		SBOMGeneration:   getEnvBool(log, "SBOM_GENERATION", false),
+		SBOMFormat:       strings.ToLower(getEnv("SBOM_FORMAT", SBOMFormatSPDX)),
+		SBOMMaxImageSize: getEnvByteSize(log, "SBOM_MAX_IMAGE_SIZE", 4*1024*1024*1024),
+
+		MaxCacheBlobSize: getEnvInt64(log, "MAX_CACHE_BLOB_SIZE", 0),
+		MaxManifestSize:  getEnvInt64(log, "MAX_MANIFEST_SIZE", 4<<20),
+
+		ManifestExtraMediaTypes: getEnvList("MANIFEST_EXTRA_MEDIA_TYPES", nil),
+		UnknownManifestTypes:    getEnv("MANIFEST_UNKNOWN_TYPES", UnknownManifestReject),
+		Schema1Manifests:        getEnv("SCHEMA1_MANIFESTS", Schema1Reject),
+
+		ArtifactCacheTTLs: getEnvArtifactTTLs(log, "ARTIFACT_CACHE_TTLS"),
+
+		ManifestPlatformFilter: getEnvBool(log, "MANIFEST_PLATFORM_FILTER", false),
+		ManifestPlatformRules:  getEnv("MANIFEST_PLATFORM_RULES", ""),
+		CacheStatusHeaders:     getEnvBool(log, "CACHE_STATUS_HEADERS", false),
+		PreservedHeaders:       getEnvList("PRESERVE_UPSTREAM_HEADERS", nil),
+		ReindexOnStart:         getEnvBool(log, "CACHE_REINDEX_ON_START", true),
+		MetadataBackend:        getEnv("METADATA_BACKEND", MetadataBackendPostgres),
+
+		ListenHTTP:    getEnvListen("LISTEN_HTTP", ":8443"),
+		ListenHTTPS:   getEnvListen("LISTEN_HTTPS", ":9443"),
+		ListenAdmin:   getEnvListen("LISTEN_ADMIN", ""),
+		ListenMetrics: getEnvListen("LISTEN_METRICS", ""),
+		ListenPprof:   getEnvListen("LISTEN_PPROF", ""),
+		ListenGRPC:    getEnvListen("LISTEN_GRPC", ""),
+
+		ListenReusePort: getEnvBool(log, "LISTEN_REUSEPORT", false),
+		ShutdownTimeout: getEnvDuration(log, "SHUTDOWN_DRAIN_TIMEOUT", 5*time.Minute),
+
+		MaxHeaderBytes: getEnvInt(log, "MAX_HEADER_BYTES", 32*1024),
+		MaxURLLength:   getEnvInt(log, "MAX_URL_LENGTH", 4096),
+		MaxBodyBytes:   getEnvInt64(log, "MAX_BODY_BYTES", 10*1024*1024),
+
+		ReadHeaderTimeout: getEnvDuration(log, "SERVER_READ_HEADER_TIMEOUT", 10*time.Second),
+		IdleTimeout:       getEnvDuration(log, "SERVER_IDLE_TIMEOUT", 2*time.Minute),
+		APITimeout:        getEnvDuration(log, "API_TIMEOUT", 30*time.Second),
+		BlobTimeout:       getEnvDuration(log, "BLOB_TIMEOUT", 0),
+
+		BandwidthUpstream:  getEnvBandwidth(log, "BANDWIDTH_UPSTREAM", 0),
+		BandwidthPerClient: getEnvBandwidth(log, "BANDWIDTH_PER_CLIENT", 0),
+		BandwidthRules:     getEnvBandwidthRules(log, "BANDWIDTH_REPOSITORIES"),
+
+		QoSRules:          getEnv("QOS_RULES", ""),
+		QoSDefaultClass:   getEnv("QOS_DEFAULT_CLASS", "normal"),
+		QoSUpstreamSlots:  getEnvInt(log, "QOS_UPSTREAM_SLOTS", 0),
+		QoSYieldBandwidth: getEnvBandwidth(log, "QOS_YIELD_BANDWIDTH", 1024*1024),
+
+		NamespaceQuotas: getEnvQuotas(log, "NAMESPACE_QUOTAS"),
+		Tenants:         getEnvTenants(log, "TENANTS"),
+		RetentionRules:  getEnvRetentionRules(log, "RETENTION_RULES"),
+
+		ChargebackCostPerGB: getEnvFloat(log, "CHARGEBACK_COST_PER_GB", 0),
+
+		QuarantineEnabled: getEnvBool(log, "QUARANTINE_ENABLED", false),
+		QuarantineExempt:  getEnvList("QUARANTINE_EXEMPT", nil),
+
+		PassthroughRepositories: getEnvList("PASSTHROUGH_REPOSITORIES", nil),
+
+		DriftCheckInterval: getEnvDuration(log, "DRIFT_CHECK_INTERVAL", 0),
+		DriftBatchSize:     getEnvInt(log, "DRIFT_BATCH_SIZE", 500),
+		DriftWebhookURL:    getEnv("DRIFT_WEBHOOK_URL", ""),
+
+		InvalidationBroadcast: getEnvBool(log, "INVALIDATION_BROADCAST", true),
+		InvalidationChannel:   getEnv("INVALIDATION_CHANNEL", "registry_proxy_invalidation"),
+
+		InvalidationGracePeriod: getEnvDuration(log, "INVALIDATION_GRACE_PERIOD", 24*time.Hour),
+		PurgeInterval:           getEnvDuration(log, "PURGE_INTERVAL", 30*time.Minute),
+		PurgeSchedule:           getEnv("PURGE_SCHEDULE", ""),
+		PurgeBatchSize:          getEnvInt(log, "PURGE_BATCH_SIZE", 1000),
+
+		LeaderElection: getEnvBool(log, "LEADER_ELECTION", true),
+		LeaderLeaseTTL: getEnvDuration(log, "LEADER_LEASE_TTL", 30*time.Second),
+
+		AuthOIDCIssuer:       getEnv("AUTH_OIDC_ISSUER", ""),
+		AuthOIDCAudience:     getEnv("AUTH_OIDC_AUDIENCE", ""),
+		AuthOIDCRules:        getEnv("AUTH_OIDC_RULES", ""),
+		AuthOIDCSubjectClaim: getEnv("AUTH_OIDC_SUBJECT_CLAIM", "sub"),
+		AuthJWKSRefresh:      getEnvDuration(log, "AUTH_JWKS_REFRESH", time.Hour),
+		AuthClockLeeway:      getEnvDuration(log, "AUTH_CLOCK_LEEWAY", 30*time.Second),
+
+		AuthTokenEnabled: getEnvBool(log, "AUTH_TOKEN_ENABLED", true),
+		AuthTokenRealm:   getEnv("AUTH_TOKEN_REALM", ""),
+		AuthTokenService: getEnv("AUTH_TOKEN_SERVICE", "registry-proxy"),
+		AuthTokenIssuer:  getEnv("AUTH_TOKEN_ISSUER", "registry-proxy"),
+		AuthTokenKey:     secrets.get("AUTH_TOKEN_KEY", ""),
+		AuthTokenTTL:     getEnvDuration(log, "AUTH_TOKEN_TTL", 5*time.Minute),
+
+		PullTokensEnabled:   getEnvBool(log, "PULL_TOKENS_ENABLED", false),
+		PullTokenDefaultTTL: getEnvDuration(log, "PULL_TOKEN_DEFAULT_TTL", time.Hour),
+		PullTokenMaxTTL:     getEnvDuration(log, "PULL_TOKEN_MAX_TTL", 24*time.Hour),
+
+		AdminSubjects: getEnvList("ADMIN_SUBJECTS", nil),
+
+		DockerHubRegistry: getEnv("DOCKERHUB_REGISTRY", "registry-1.docker.io"),
+
+		CORSAllowedOrigins:   getEnvList("CORS_ALLOWED_ORIGINS", nil),
+		CORSAllowedHeaders:   getEnvList("CORS_ALLOWED_HEADERS", []string{"Authorization", "Accept", "Content-Type", "Range"}),
+		CORSAllowCredentials: getEnvBool(log, "CORS_ALLOW_CREDENTIALS", false),
+		CORSMaxAge:           getEnvDuration(log, "CORS_MAX_AGE", 10*time.Minute),
+
+		LDAPURL:            getEnv("LDAP_URL", ""),
+		LDAPStartTLS:       getEnvBool(log, "LDAP_START_TLS", false),
+		LDAPBindDN:         getEnv("LDAP_BIND_DN", ""),
+		LDAPBindPassword:   secrets.get("LDAP_BIND_PASSWORD", ""),
+		LDAPUserBaseDN:     getEnv("LDAP_USER_BASE_DN", ""),
+		LDAPUserAttribute:  getEnv("LDAP_USER_ATTRIBUTE", "uid"),
+		LDAPGroupAttribute: getEnv("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+		LDAPGroupRules:     getEnv("LDAP_GROUP_RULES", ""),
+		LDAPCacheTTL:       getEnvDuration(log, "LDAP_CACHE_TTL", 5*time.Minute),
+
+		JobWorkers:      getEnvInt(log, "JOB_WORKERS", 4),
+		JobMaxAttempts:  getEnvInt(log, "JOB_MAX_ATTEMPTS", 5),
+		JobPollInterval: getEnvDuration(log, "JOB_POLL_INTERVAL", 2*time.Second),
+		JobLockTimeout:  getEnvDuration(log, "JOB_LOCK_TIMEOUT", 30*time.Minute),
+		JobRetention:    getEnvDuration(log, "JOB_RETENTION", 24*time.Hour),
+
+		DiskCacheDir:                getEnv("DISK_CACHE_DIR", ""),
+		DiskCacheMaxBytes:           getEnvInt64(log, "DISK_CACHE_MAX_BYTES", 10*1024*1024*1024),
+		DiskCacheMaxObjectSize:      getEnvInt64(log, "DISK_CACHE_MAX_OBJECT_SIZE", 512*1024*1024),
+		DiskCacheRevalidateInterval: getEnvDuration(log, "DISK_CACHE_REVALIDATE_INTERVAL", 30*time.Second),
+
+		Peers:            getEnvList("PEERS", nil),
+		PeerToken:        secrets.get("PEER_TOKEN", ""),
+		PeerSyncInterval: getEnvDuration(log, "PEER_SYNC_INTERVAL", 30*time.Second),
+		PeerTimeout:      getEnvDuration(log, "PEER_TIMEOUT", 30*time.Second),
+
+		P2PSeedURL:       getEnv("P2P_SEED_URL", ""),
+		P2PAnnounceURL:   getEnv("P2P_ANNOUNCE_URL", ""),
+		P2PAnnounceToken: secrets.get("P2P_ANNOUNCE_TOKEN", ""),
+		P2PServeToken:    secrets.get("P2P_SERVE_TOKEN", ""),
+		P2PBatchSize:     getEnvInt(log, "P2P_BATCH_SIZE", 100),
+		P2PFlushInterval: getEnvDuration(log, "P2P_FLUSH_INTERVAL", 5*time.Second),
+
+		LogSyslogURL:         getEnv("LOG_SYSLOG_URL", ""),
+		LogSyslogFacility:    getEnv("LOG_SYSLOG_FACILITY", "local0"),
+		LogSyslogAppName:     getEnv("LOG_SYSLOG_APP_NAME", "registry-proxy"),
+		LogLokiURL:           getEnv("LOG_LOKI_URL", ""),
+		LogLokiLabels:        getEnvLabels(log, "LOG_LOKI_LABELS", map[string]string{"app": "registry-proxy"}),
+		LogLokiTenant:        getEnv("LOG_LOKI_TENANT", ""),
+		LogLokiUser:          getEnv("LOG_LOKI_USER", ""),
+		LogLokiPassword:      secrets.get("LOG_LOKI_PASSWORD", ""),
+		LogLokiBatchSize:     getEnvInt(log, "LOG_LOKI_BATCH_SIZE", 500),
+		LogLokiFlushInterval: getEnvDuration(log, "LOG_LOKI_FLUSH_INTERVAL", 2*time.Second),
+
+		AlertWebhookURL:          getEnv("ALERT_WEBHOOK_URL", ""),
+		AlertSlackWebhookURL:     secrets.get("ALERT_SLACK_WEBHOOK_URL", ""),
+		AlertInterval:            getEnvDuration(log, "ALERT_INTERVAL", time.Minute),
+		AlertSustain:             getEnvDuration(log, "ALERT_SUSTAIN", 5*time.Minute),
+		AlertUpstreamFailureRate: getEnvFloat(log, "ALERT_UPSTREAM_FAILURE_RATE", 0.25),
+		AlertS3Errors:            getEnvFloat(log, "ALERT_S3_ERRORS", 10),
+		AlertCacheWriteBacklog:   getEnvFloat(log, "ALERT_CACHE_WRITE_BACKLOG", 1000),
+
+		SentryDSN:         getEnv("SENTRY_DSN", ""),
+		SentryEnvironment: getEnv("SENTRY_ENVIRONMENT", "production"),
+		SentryRelease:     getEnv("SENTRY_RELEASE", ""),
+		SentrySampleRate:  getEnvFloat(log, "SENTRY_SAMPLE_RATE", 1),
+
+		FaultUpstreamErrorRate: getEnvFloat(log, "FAULT_UPSTREAM_ERROR_RATE", 0),
+		FaultS3Latency:         getEnvDuration(log, "FAULT_S3_LATENCY", 0),
+		FaultS3LatencyRate:     getEnvFloat(log, "FAULT_S3_LATENCY_RATE", 1),
+		FaultDBErrorRate:       getEnvFloat(log, "FAULT_DB_ERROR_RATE", 0),
+
+		ShardSelf:           getEnv("SHARD_SELF", ""),
+		ShardMembers:        getEnvList("SHARD_MEMBERS", nil),
+		ShardVirtualNodes:   getEnvInt(log, "SHARD_VIRTUAL_NODES", 128),
+		ShardForwardTimeout: getEnvDuration(log, "SHARD_FORWARD_TIMEOUT", 30*time.Second),
+
+		SecretReloadInterval: getEnvDuration(log, "SECRET_RELOAD_INTERVAL", time.Minute),
+	}
+	cfg.secretFiles = secrets.files
+
+	if (cfg.DockerHubUser == "") != (cfg.DockerHubPassword == "") {
+		return nil, fmt.Errorf("DOCKERHUB_USER and DOCKERHUB_PASSWORD must be provided together")
+	}
+	if (cfg.S3AccessKey == "") != (cfg.S3SecretKey == "") {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be provided together")
+	}
+
+	if _, err := ParseUpstreamCredentials(cfg.RegistryCredentialMap); err != nil {
+		return nil, fmt.Errorf("invalid REGISTRY_CREDENTIALS: %w", err)
+	}
+	credentials, err := ParseUpstreamCredentials(cfg.DockerHubCredentialMap)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DOCKERHUB_CREDENTIALS: %w", err)
+	}
+	cfg.upstreamCredentials = credentials
+
+	switch cfg.S3Backend {
+	case S3BackendAWS, S3BackendMinIO, S3BackendSeaweedFS, S3BackendGeneric:
+	default:
+		return nil, fmt.Errorf("S3_BACKEND must be one of %q, %q, %q or %q", S3BackendAWS, S3BackendMinIO, S3BackendSeaweedFS, S3BackendGeneric)
 	}
 
-	if cfg.S3AccessKey == "" || cfg.S3SecretKey == "" || cfg.S3Endpoint == "" {
-		return nil, fmt.Errorf("AWS credentials must be provided")
+	switch cfg.MetadataBackend {
+	case MetadataBackendPostgres, MetadataBackendS3:
+	default:
+		return nil, fmt.Errorf("METADATA_BACKEND must be %q or %q", MetadataBackendPostgres, MetadataBackendS3)
+	}
+
+	if err := validateListeners(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.MaxHeaderBytes < 4096 || cfg.MaxURLLength < 256 || cfg.MaxBodyBytes < 0 {
+		return nil, fmt.Errorf("MAX_HEADER_BYTES must be at least 4096, MAX_URL_LENGTH at least 256 and MAX_BODY_BYTES non-negative")
+	}
+	if cfg.InlineMaxSize < 0 || cfg.InlineMaxSize > 1024*1024 {
+		return nil, fmt.Errorf("INLINE_MAX_SIZE must be between 0 and 1MiB")
+	}
+	if cfg.ShutdownTimeout < 0 {
+		return nil, fmt.Errorf("SHUTDOWN_DRAIN_TIMEOUT must be non-negative")
+	}
+	if cfg.RuntimeStatsInterval <= 0 {
+		return nil, fmt.Errorf("RUNTIME_STATS_INTERVAL must be positive")
+	}
+	if cfg.MemoryBlobThreshold < 0 || cfg.MemoryBlobPool < cfg.MemoryBlobThreshold {
+		return nil, fmt.Errorf("MEMORY_BLOB_THRESHOLD must be non-negative and not larger than MEMORY_BLOB_POOL")
+	}
+	if cfg.ReadHeaderTimeout <= 0 || cfg.APITimeout < 0 || cfg.BlobTimeout < 0 {
+		return nil, fmt.Errorf("SERVER_READ_HEADER_TIMEOUT must be positive, API_TIMEOUT and BLOB_TIMEOUT non-negative")
+	}
+
+	if cfg.PurgeInterval <= 0 {
+		return nil, fmt.Errorf("PURGE_INTERVAL must be positive")
+	}
+	if cfg.DiskCacheRevalidateInterval <= 0 {
+		return nil, fmt.Errorf("DISK_CACHE_REVALIDATE_INTERVAL must be positive")
+	}
+	if cfg.LeaderLeaseTTL < 3*time.Second {
+		return nil, fmt.Errorf("LEADER_LEASE_TTL must be at least 3s")
+	}
+	if cfg.JobWorkers <= 0 || cfg.JobMaxAttempts <= 0 {
+		return nil, fmt.Errorf("JOB_WORKERS and JOB_MAX_ATTEMPTS must be positive")
+	}
+	if cfg.JobPollInterval <= 0 || cfg.JobLockTimeout <= 0 {
+		return nil, fmt.Errorf("JOB_POLL_INTERVAL and JOB_LOCK_TIMEOUT must be positive")
+	}
+	if cfg.PurgeBatchSize <= 0 || cfg.PurgeBatchSize > 1000 {
+		return nil, fmt.Errorf("PURGE_BATCH_SIZE must be between 1 and 1000")
+	}
+
+	switch cfg.S3SSE {
+	case "", "AES256", "aws:kms":
+	default:
+		return nil, fmt.Errorf("unsupported S3_SSE value %q", cfg.S3SSE)
+	}
+	if cfg.S3SSEKMSKeyID != "" && cfg.S3SSE != "aws:kms" {
+		return nil, fmt.Errorf("S3_SSE_KMS_KEY_ID requires S3_SSE=aws:kms")
+	}
+	if cfg.PushRejectStatus != 401 && cfg.PushRejectStatus != 405 {
+		return nil, fmt.Errorf("PUSH_REJECT_STATUS must be 401 or 405")
+	}
+	if err := cfg.validateS3Transfer(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateStorageEncryption(); err != nil {
+		return nil, err
+	}
+	switch cfg.StorageCompression {
+	case CompressionNone, CompressionZstd:
+	default:
+		return nil, fmt.Errorf("STORAGE_COMPRESSION must be %q or %q", CompressionNone, CompressionZstd)
+	}
+	if cfg.StorageCompressionLevel < 1 || cfg.StorageCompressionLevel > 22 {
+		return nil, fmt.Errorf("STORAGE_COMPRESSION_LEVEL must be between 1 and 22")
+	}
+	if cfg.ChargebackCostPerGB < 0 {
+		return nil, fmt.Errorf("CHARGEBACK_COST_PER_GB must be non-negative")
+	}
+	if cfg.PullTokenDefaultTTL <= 0 || cfg.PullTokenMaxTTL < cfg.PullTokenDefaultTTL {
+		return nil, fmt.Errorf("PULL_TOKEN_DEFAULT_TTL must be positive and not exceed PULL_TOKEN_MAX_TTL")
+	}
+	if cfg.AuthTokenTTL < time.Minute {
+		return nil, fmt.Errorf("AUTH_TOKEN_TTL must be at least 1m")
+	}
+	if cfg.LDAPURL != "" && cfg.LDAPUserBaseDN == "" {
+		return nil, fmt.Errorf("LDAP_USER_BASE_DN is required when LDAP_URL is set")
+	}
+
+	if len(cfg.Peers) > 0 {
+		if cfg.PeerToken == "" {
+			return nil, fmt.Errorf("PEER_TOKEN is required when PEERS is set")
+		}
+		if cfg.PeerSyncInterval <= 0 || cfg.PeerTimeout <= 0 {
+			return nil, fmt.Errorf("PEER_SYNC_INTERVAL and PEER_TIMEOUT must be positive")
+		}
+	}
+
+	if cfg.AlertInterval <= 0 || cfg.AlertSustain < 0 {
+		return nil, fmt.Errorf("ALERT_INTERVAL must be positive and ALERT_SUSTAIN non-negative")
+	}
+	if cfg.AlertUpstreamFailureRate < 0 || cfg.AlertUpstreamFailureRate > 1 {
+		return nil, fmt.Errorf("ALERT_UPSTREAM_FAILURE_RATE must be between 0 and 1")
+	}
+	if cfg.DriftCheckInterval < 0 || cfg.DriftBatchSize <= 0 {
+		return nil, fmt.Errorf("DRIFT_CHECK_INTERVAL must be non-negative and DRIFT_BATCH_SIZE positive")
+	}
+	if cfg.SentrySampleRate < 0 || cfg.SentrySampleRate > 1 {
+		return nil, fmt.Errorf("SENTRY_SAMPLE_RATE must be between 0 and 1")
+	}
+	if err := cfg.validateFaultInjection(); err != nil {
+		return nil, err
+	}
+	if cfg.LogLokiURL != "" && (cfg.LogLokiBatchSize <= 0 || cfg.LogLokiFlushInterval <= 0) {
+		return nil, fmt.Errorf("LOG_LOKI_BATCH_SIZE and LOG_LOKI_FLUSH_INTERVAL must be positive")
+	}
+	if cfg.P2PSeedURL != "" && cfg.P2PServeToken == "" {
+		return nil, fmt.Errorf("P2P_SERVE_TOKEN is required when P2P_SEED_URL is set")
+	}
+	if cfg.P2PAnnounceURL != "" {
+		if cfg.P2PSeedURL == "" {
+			return nil, fmt.Errorf("P2P_SEED_URL is required when P2P_ANNOUNCE_URL is set")
+		}
+		if cfg.P2PBatchSize <= 0 || cfg.P2PFlushInterval <= 0 {
+			return nil, fmt.Errorf("P2P_BATCH_SIZE and P2P_FLUSH_INTERVAL must be positive")
+		}
+	}
+	switch cfg.QoSDefaultClass {
+	case "high", "normal", "low":
+	default:
+		return nil, fmt.Errorf("QOS_DEFAULT_CLASS must be high, normal or low")
+	}
+	if cfg.UpstreamBreakerThreshold < 0 || cfg.UpstreamBreakerCooldown <= 0 {
+		return nil, fmt.Errorf("UPSTREAM_BREAKER_THRESHOLD must not be negative and UPSTREAM_BREAKER_COOLDOWN must be positive")
+	}
+	if strings.TrimSpace(cfg.UpstreamUserAgent) == "" {
+		return nil, fmt.Errorf("UPSTREAM_USER_AGENT must not be empty")
+	}
+	if strings.ContainsAny(cfg.ViaPseudonym, " \t,") {
+		return nil, fmt.Errorf("VIA_PSEUDONYM must be a single token without spaces or commas")
+	}
+	if cfg.InvalidationBroadcast && (cfg.InvalidationChannel == "" || len(cfg.InvalidationChannel) > 63) {
+		return nil, fmt.Errorf("INVALIDATION_CHANNEL must be 1-63 characters")
+	}
+	if cfg.SearchCacheTTL < 0 {
+		return nil, fmt.Errorf("SEARCH_CACHE_TTL must not be negative")
+	}
+	if cfg.PrefetchMaxLayerSize < 0 {
+		return nil, fmt.Errorf("PREFETCH_MAX_LAYER_SIZE must not be negative")
+	}
+	if cfg.SBOMFormat != SBOMFormatSPDX && cfg.SBOMFormat != SBOMFormatCycloneDX {
+		return nil, fmt.Errorf("SBOM_FORMAT must be %q or %q", SBOMFormatSPDX, SBOMFormatCycloneDX)
+	}
+	if cfg.SBOMMaxImageSize <= 0 {
+		return nil, fmt.Errorf("SBOM_MAX_IMAGE_SIZE must be positive")
+	}
+	for _, pattern := range cfg.PassthroughRepositories {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("PASSTHROUGH_REPOSITORIES: invalid pattern %q: %w", pattern, err)
+		}
+	}
+	if cfg.MaxCacheBlobSize < 0 {
+		return nil, fmt.Errorf("MAX_CACHE_BLOB_SIZE must not be negative")
+	}
+	if cfg.MaxManifestSize <= 0 {
+		return nil, fmt.Errorf("MAX_MANIFEST_SIZE must be positive")
+	}
+	switch cfg.UnknownManifestTypes {
+	case UnknownManifestReject, UnknownManifestPassthrough:
+	default:
+		return nil, fmt.Errorf("MANIFEST_UNKNOWN_TYPES must be %q or %q", UnknownManifestReject, UnknownManifestPassthrough)
+	}
+	switch cfg.Schema1Manifests {
+	case Schema1Allow, Schema1Passthrough, Schema1Reject:
+	default:
+		return nil, fmt.Errorf("SCHEMA1_MANIFESTS must be %q, %q or %q", Schema1Allow, Schema1Passthrough, Schema1Reject)
+	}
+	if cfg.QoSUpstreamSlots < 0 {
+		return nil, fmt.Errorf("QOS_UPSTREAM_SLOTS must not be negative")
+	}
+	if cfg.QoSUpstreamSlots > 0 && cfg.QoSYieldBandwidth <= 0 {
+		return nil, fmt.Errorf("QOS_YIELD_BANDWIDTH must be positive")
+	}
+	if len(cfg.ShardMembers) > 0 {
+		if err := cfg.validateSharding(); err != nil {
+			return nil, err
+		}
 	}
 
 	return cfg, nil
 }
 
+func (c *Config) validateFaultInjection() error {
+	for name, rate := range map[string]float64{
+		"FAULT_UPSTREAM_ERROR_RATE": c.FaultUpstreamErrorRate,
+		"FAULT_S3_LATENCY_RATE":     c.FaultS3LatencyRate,
+		"FAULT_DB_ERROR_RATE":       c.FaultDBErrorRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+	if c.FaultS3Latency < 0 {
+		return fmt.Errorf("FAULT_S3_LATENCY must not be negative")
+	}
+	if c.FaultInjectionEnabled() && c.SentryEnvironment == "production" {
+		return fmt.Errorf("fault injection is refused with SENTRY_ENVIRONMENT=production")
+	}
+	return nil
+}
+
+func (c *Config) FaultInjectionEnabled() bool {
+	return c.FaultUpstreamErrorRate > 0 || (c.FaultS3Latency > 0 && c.FaultS3LatencyRate > 0) || c.FaultDBErrorRate > 0
+}
+
+func (c *Config) validateSharding() error {
+	if c.ShardSelf == "" {
+		return fmt.Errorf("SHARD_SELF is required when SHARD_MEMBERS is set")
+	}
+	if c.PeerToken == "" {
+		return fmt.Errorf("PEER_TOKEN is required when SHARD_MEMBERS is set")
+	}
+	if c.ShardVirtualNodes <= 0 {
+		return fmt.Errorf("SHARD_VIRTUAL_NODES must be positive")
+	}
+	if c.ShardForwardTimeout <= 0 {
+		return fmt.Errorf("SHARD_FORWARD_TIMEOUT must be positive")
+	}
+
+	self := strings.TrimSuffix(c.ShardSelf, "/")
+	found := false
+	for _, member := range c.ShardMembers {
+		u, err := url.Parse(member)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid SHARD_MEMBERS entry %q", member)
+		}
+		if strings.TrimSuffix(member, "/") == self {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("SHARD_SELF must be listed in SHARD_MEMBERS")
+	}
+	return nil
+}
+
+func (c *Config) validateS3Transfer() error {
+	if c.S3PartSize < S3MinPartSize || c.S3PartSize > S3MaxPartSize {
+		return fmt.Errorf("S3_PART_SIZE must be between 5MB and 5GB")
+	}
+	if c.S3UploadConcurrency < 1 || c.S3UploadConcurrency > 64 {
+		return fmt.Errorf("S3_UPLOAD_CONCURRENCY must be between 1 and 64")
+	}
+	if c.S3DownloadConcurrency < 1 || c.S3DownloadConcurrency > 64 {
+		return fmt.Errorf("S3_DOWNLOAD_CONCURRENCY must be between 1 and 64")
+	}
+	if c.S3MaxRetries < 1 {
+		return fmt.Errorf("S3_MAX_RETRIES must be positive")
+	}
+	if c.S3RetryMode != S3RetryStandard && c.S3RetryMode != S3RetryAdaptive {
+		return fmt.Errorf("S3_RETRY_MODE must be %q or %q", S3RetryStandard, S3RetryAdaptive)
+	}
+	if c.S3MaxBlobSize <= 0 || c.S3MaxBlobSize > S3MaxObjectSize {
+		return fmt.Errorf("S3_MAX_BLOB_SIZE must be between 1 byte and 5TB")
+	}
+	if c.S3MaxBlobSize > c.S3PartSize*S3MaxUploadParts {
+		minPartSize := (c.S3MaxBlobSize + S3MaxUploadParts - 1) / S3MaxUploadParts
+		return fmt.Errorf("S3_PART_SIZE of %d bytes cannot upload S3_MAX_BLOB_SIZE in %d parts; use at least %d bytes",
+			c.S3PartSize, S3MaxUploadParts, minPartSize)
+	}
+	return nil
+}
+
+func (c *Config) validateStorageEncryption() error {
+	if c.StorageEncryptionKey != "" && c.StorageEncryptionKMSKey != "" {
+		return fmt.Errorf("STORAGE_ENCRYPTION_KEY and STORAGE_ENCRYPTION_KMS_KEY are mutually exclusive")
+	}
+	if c.StorageEncryptionKey != "" {
+		key, err := base64.StdEncoding.DecodeString(c.StorageEncryptionKey)
+		if err != nil || len(key) != 32 {
+			return fmt.Errorf("STORAGE_ENCRYPTION_KEY must be 32 bytes encoded as base64")
+		}
+	}
+	if c.StorageEncryptionKMSKey != "" {
+		if _, err := base64.StdEncoding.DecodeString(c.StorageEncryptionKMSKey); err != nil {
+			return fmt.Errorf("STORAGE_ENCRYPTION_KMS_KEY must be a base64 encoded KMS ciphertext blob: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) StorageEncryptionEnabled() bool {
+	return c.StorageEncryptionKey != "" || c.StorageEncryptionKMSKey != ""
+}
+
 func mustGetEnv(log *logrus.Logger, key string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -93,6 +978,565 @@ func getEnvInt(log *logrus.Logger, key string, defaultValue int) int {
 	return intValue
 }
 
+func getEnvFloat(log *logrus.Logger, key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"variable": key,
+			"value":    value,
+		}).Warn("Invalid number value, using default")
+		return defaultValue
+	}
+	return floatValue
+}
+
+func getEnvInt64(log *logrus.Logger, key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	intValue, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"variable": key,
+			"value":    value,
+		}).Warn("Invalid integer value, using default")
+		return defaultValue
+	}
+	return intValue
+}
+
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvQuotas(log *logrus.Logger, key string) []NamespaceQuota {
+	var quotas []NamespaceQuota
+	for _, item := range getEnvList(key, nil) {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			log.WithFields(logrus.Fields{
+				"variable": key,
+				"value":    item,
+			}).Warn("Invalid quota entry, ignoring")
+			continue
+		}
+		size, err := parseByteSize(kv[1])
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"variable": key,
+				"value":    item,
+				"error":    err,
+			}).Warn("Invalid quota size, ignoring")
+			continue
+		}
+		quotas = append(quotas, NamespaceQuota{
+			Pattern:  strings.TrimSpace(kv[0]),
+			MaxBytes: size,
+		})
+	}
+	return quotas
+}
+
+func getEnvTenants(log *logrus.Logger, key string) []Tenant {
+	var tenants []Tenant
+	for _, item := range strings.Split(os.Getenv(key), ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, directives, ok := strings.Cut(item, "=")
+		if name = strings.TrimSpace(name); !ok || name == "" || len(name) > 64 {
+			log.WithFields(logrus.Fields{
+				"variable": key,
+				"value":    item,
+			}).Warn("Invalid tenant, ignoring")
+			continue
+		}
+
+		tenant := Tenant{Name: name}
+		valid := true
+		for _, directive := range strings.Split(directives, ",") {
+			field, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			value = strings.TrimSpace(value)
+			var err error
+			switch {
+			case value == "":
+				err = fmt.Errorf("missing value")
+			case field == "host":
+				tenant.Hosts = append(tenant.Hosts, strings.ToLower(value))
+			case field == "subject":
+				tenant.Subjects = append(tenant.Subjects, value)
+			case field == "namespace":
+				_, err = path.Match(value, "")
+				tenant.Namespaces = append(tenant.Namespaces, value)
+			case field == "quota":
+				tenant.MaxBytes, err = parseByteSize(value)
+			case field == "rate":
+				tenant.RateLimit, err = strconv.Atoi(value)
+				if err == nil && tenant.RateLimit <= 0 {
+					err = fmt.Errorf("must be positive")
+				}
+			default:
+				err = fmt.Errorf("unknown directive %q", field)
+			}
+			if err != nil {
+				log.WithFields(logrus.Fields{
+					"variable":  key,
+					"value":     item,
+					"directive": directive,
+					"error":     err,
+				}).Warn("Invalid tenant directive, ignoring tenant")
+				valid = false
+				break
+			}
+		}
+		if valid && tenant.MaxBytes > 0 && len(tenant.Namespaces) == 0 {
+			log.WithField("tenant", name).Warn("Tenant quota needs at least one namespace, ignoring tenant")
+			valid = false
+		}
+		if valid {
+			tenants = append(tenants, tenant)
+		}
+	}
+	return tenants
+}
+
+func (c *Config) TenantFor(host, subject string) *Tenant {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	for i := range c.Tenants {
+		for _, candidate := range c.Tenants[i].Hosts {
+			if candidate == host {
+				return &c.Tenants[i]
+			}
+		}
+	}
+	if subject == "" {
+		return nil
+	}
+	for i := range c.Tenants {
+		for _, pattern := range c.Tenants[i].Subjects {
+			if MatchPattern(pattern, subject) {
+				return &c.Tenants[i]
+			}
+		}
+	}
+	return nil
+}
+
+func parseBandwidth(value string) (int64, error) {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "/s")
+	size, err := parseByteSize(value)
+	if err != nil {
+		return 0, err
+	}
+	if size > 0 && size < 1024 {
+		return 0, fmt.Errorf("bandwidth %q is below 1KB/s", value)
+	}
+	return size, nil
+}
+
+func getEnvListen(key, defaultValue string) string {
+	switch value := strings.TrimSpace(os.Getenv(key)); strings.ToLower(value) {
+	case "":
+		return defaultValue
+	case "off", "disabled", "none", "false":
+		return ""
+	default:
+		return value
+	}
+}
+
+func validateListeners(cfg *Config) error {
+	if cfg.ListenHTTP == "" && cfg.ListenHTTPS == "" {
+		return fmt.Errorf("at least one of LISTEN_HTTP and LISTEN_HTTPS must be enabled")
+	}
+
+	seen := make(map[string]string)
+	for _, listener := range []struct{ key, addr string }{
+		{"LISTEN_HTTP", cfg.ListenHTTP},
+		{"LISTEN_HTTPS", cfg.ListenHTTPS},
+		{"LISTEN_ADMIN", cfg.ListenAdmin},
+		{"LISTEN_METRICS", cfg.ListenMetrics},
+		{"LISTEN_PPROF", cfg.ListenPprof},
+		{"LISTEN_GRPC", cfg.ListenGRPC},
+	} {
+		if listener.addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(listener.addr); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", listener.key, listener.addr, err)
+		}
+		if other, ok := seen[listener.addr]; ok {
+			return fmt.Errorf("%s and %s both listen on %s", other, listener.key, listener.addr)
+		}
+		seen[listener.addr] = listener.key
+	}
+	return nil
+}
+
+func getEnvBandwidth(log *logrus.Logger, key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	bandwidth, err := parseBandwidth(value)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"variable": key,
+			"value":    value,
+			"error":    err,
+		}).Warn("Invalid bandwidth value, using default")
+		return defaultValue
+	}
+	return bandwidth
+}
+
+func getEnvBandwidthRules(log *logrus.Logger, key string) []BandwidthRule {
+	var rules []BandwidthRule
+	for _, item := range getEnvList(key, nil) {
+		pattern, value, ok := strings.Cut(item, "=")
+		bandwidth, err := parseBandwidth(value)
+		if !ok || err != nil || bandwidth == 0 {
+			log.WithFields(logrus.Fields{
+				"variable": key,
+				"value":    item,
+			}).Warn("Invalid bandwidth rule, ignoring")
+			continue
+		}
+		rules = append(rules, BandwidthRule{
+			Pattern:        strings.TrimSpace(pattern),
+			BytesPerSecond: bandwidth,
+		})
+	}
+	return rules
+}
+
+func getEnvVanityRoutes(log *logrus.Logger, key string) []VanityRoute {
+	var routes []VanityRoute
+	for _, item := range strings.Split(os.Getenv(key), ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		match, upstream, ok := strings.Cut(item, "=")
+		match, upstream = strings.TrimSpace(match), strings.Trim(strings.TrimSpace(upstream), "/")
+		host, prefix, _ := strings.Cut(match, "/")
+		if !ok || upstream == "" || (host == "" && prefix == "") {
+			log.WithFields(logrus.Fields{
+				"variable": key,
+				"value":    item,
+			}).Warn("Invalid vanity route, ignoring")
+			continue
+		}
+
+		routes = append(routes, VanityRoute{
+			Host:     strings.ToLower(host),
+			Prefix:   strings.Trim(prefix, "/"),
+			Upstream: upstream,
+		})
+	}
+	return routes
+}
+
+func getEnvArtifactTTLs(log *logrus.Logger, key string) map[string]time.Duration {
+	ttls := make(map[string]time.Duration)
+	for _, item := range strings.Split(os.Getenv(key), ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		artifactType, value, ok := strings.Cut(item, "=")
+		ttl, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || err != nil || ttl <= 0 || strings.TrimSpace(artifactType) == "" {
+			log.WithFields(logrus.Fields{
+				"variable": key,
+				"value":    item,
+			}).Warn("Invalid artifact cache TTL, ignoring")
+			continue
+		}
+		ttls[strings.ToLower(strings.TrimSpace(artifactType))] = ttl
+	}
+	return ttls
+}
+
+func getEnvSigV4Upstreams(log *logrus.Logger, key string) []SigV4Upstream {
+	var upstreams []SigV4Upstream
+	for _, item := range strings.Split(os.Getenv(key), ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		pattern, signing, _ := strings.Cut(item, "=")
+		region, service, _ := strings.Cut(strings.TrimSpace(signing), "/")
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if _, err := path.Match(pattern, ""); pattern == "" || err != nil {
+			log.WithFields(logrus.Fields{
+				"variable": key,
+				"value":    item,
+			}).Warn("Invalid SigV4 upstream, ignoring")
+			continue
+		}
+
+		upstreams = append(upstreams, SigV4Upstream{
+			Pattern: pattern,
+			Region:  strings.TrimSpace(region),
+			Service: strings.TrimSpace(service),
+		})
+	}
+	return upstreams
+}
+
+func (c *Config) SigV4Upstream(host string) (SigV4Upstream, bool) {
+	for _, upstream := range c.SigV4Upstreams {
+		if MatchPattern(upstream.Pattern, strings.ToLower(host)) {
+			return upstream, true
+		}
+	}
+	return SigV4Upstream{}, false
+}
+
+func getEnvImageRewrites(log *logrus.Logger, key string) []ImageRewrite {
+	var rewrites []ImageRewrite
+	for _, item := range strings.Split(os.Getenv(key), ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		pattern, replacement, ok := strings.Cut(item, "=")
+		pattern, replacement = strings.TrimSpace(pattern), strings.TrimSpace(replacement)
+		if !ok || pattern == "" || replacement == "" {
+			log.WithFields(logrus.Fields{
+				"variable": key,
+				"value":    item,
+			}).Warn("Invalid image rewrite, ignoring")
+			continue
+		}
+
+		rewrite := ImageRewrite{Pattern: pattern, Replacement: replacement}
+		if expr, isRegex := strings.CutPrefix(pattern, "~"); isRegex {
+			regex, err := regexp.Compile("^(?:" + expr + ")$")
+			if err != nil {
+				log.WithFields(logrus.Fields{
+					"variable": key,
+					"value":    item,
+					"error":    err,
+				}).Warn("Invalid image rewrite expression, ignoring")
+				continue
+			}
+			rewrite.Regex = regex
+		}
+		rewrites = append(rewrites, rewrite)
+	}
+	return rewrites
+}
+
+func getEnvRetentionRules(log *logrus.Logger, key string) []RetentionRule {
+	var rules []RetentionRule
+	for _, item := range strings.Split(os.Getenv(key), ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		pattern, directives, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(pattern) == "" {
+			log.WithFields(logrus.Fields{
+				"variable": key,
+				"value":    item,
+			}).Warn("Invalid retention rule, ignoring")
+			continue
+		}
+
+		rule := RetentionRule{Pattern: strings.TrimSpace(pattern)}
+		valid := true
+		for _, directive := range strings.Split(directives, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			var err error
+			switch name {
+			case "protect":
+				rule.Protect = true
+			case "keep_last":
+				rule.KeepLast, err = strconv.Atoi(value)
+				if err == nil && rule.KeepLast <= 0 {
+					err = fmt.Errorf("must be positive")
+				}
+			case "keep_accessed":
+				rule.KeepAccessed, err = parseRetentionDuration(value)
+			default:
+				err = fmt.Errorf("unknown directive %q", name)
+			}
+			if err != nil {
+				log.WithFields(logrus.Fields{
+					"variable":  key,
+					"value":     item,
+					"directive": directive,
+					"error":     err,
+				}).Warn("Invalid retention directive, ignoring rule")
+				valid = false
+				break
+			}
+		}
+		if valid {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func ParseUpstreamCredentials(value string) ([]UpstreamCredential, error) {
+	var credentials []UpstreamCredential
+	for _, item := range strings.Split(value, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		pattern, account, ok := strings.Cut(item, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("entry %q must be pattern=user:password or pattern=anonymous", item)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+
+		credential := UpstreamCredential{Pattern: pattern}
+		if account = strings.TrimSpace(account); account != "anonymous" {
+			credential.Username, credential.Password, ok = strings.Cut(account, ":")
+			if !ok || credential.Username == "" || credential.Password == "" {
+				return nil, fmt.Errorf("entry for %q must be user:password or anonymous", pattern)
+			}
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
+}
+
+func parseRetentionDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
+
+func parseByteSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"TB", 1 << 40},
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			multiplier = unit.multiplier
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return n * multiplier, nil
+}
+
+func getEnvLabels(log *logrus.Logger, key string, defaultValue map[string]string) map[string]string {
+	entries := getEnvList(key, nil)
+	if len(entries) == 0 {
+		return defaultValue
+	}
+	labels := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			log.WithFields(logrus.Fields{
+				"variable": key,
+				"entry":    entry,
+			}).Warn("Ignoring invalid label, expected name=value")
+			continue
+		}
+		labels[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return labels
+}
+
+func getEnvByteSize(log *logrus.Logger, key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	size, err := parseByteSize(value)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"variable": key,
+			"value":    value,
+		}).Warn("Invalid size value, using default")
+		return defaultValue
+	}
+	return size
+}
+
+func getEnvBool(log *logrus.Logger, key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"variable": key,
+			"value":    value,
+		}).Warn("Invalid boolean value, using default")
+		return defaultValue
+	}
+	return boolValue
+}
+
 func getEnvDuration(log *logrus.Logger, key string, defaultValue time.Duration) time.Duration {
 	value := os.Getenv(key)
 	if value == "" {
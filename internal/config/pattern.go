@@ -0,0 +1,34 @@
+package config
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchPattern is the matcher for every repository, host and subject pattern
+// in the configuration and the auth rules. Patterns are path.Match globs over
+// "/"-separated names, so "*" stays within one segment ("myorg/*" matches
+// myorg/app but not myorg/team/app). A trailing "/**" matches everything below
+// the prefix at any depth, and "*" or "**" on its own matches every name.
+func MatchPattern(pattern, name string) bool {
+	if pattern == "*" || pattern == "**" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		for i := range name {
+			if name[i] == '/' && matchGlob(prefix, name[:i]) {
+				return true
+			}
+		}
+		return false
+	}
+	return matchGlob(pattern, name)
+}
+
+func matchGlob(pattern, name string) bool {
+	if pattern == name {
+		return true
+	}
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
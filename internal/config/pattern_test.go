@@ -0,0 +1,45 @@
+package config
+
+import "testing"
+
+var patternCases = []struct {
+	pattern string
+	name    string
+	want    bool
+}{
+	{"*", "library/nginx", true},
+	{"*", "ghcr.io/org/app", true},
+	{"**", "ghcr.io/org/app", true},
+	{"library/nginx", "library/nginx", true},
+	{"library/nginx", "library/nginx2", false},
+	{"myorg/*", "myorg/app", true},
+	{"myorg/*", "myorg/team/app", false},
+	{"myorg/*", "myorg", false},
+	{"myorg/**", "myorg/app", true},
+	{"myorg/**", "myorg/team/app", true},
+	{"myorg/**", "myorg", false},
+	{"myorg/**", "myorganisation/app", false},
+	{"*/team/**", "myorg/team/app", true},
+	{"*/team/**", "myorg/other/app", false},
+	{"myorg/dev-*", "myorg/dev-api", true},
+	{"myorg/dev-*", "myorg/prod-api", false},
+	{"myorg/dev-?", "myorg/dev-a", true},
+	{"myorg/[a-c]pp", "myorg/app", true},
+	{"myorg/[^a]pp", "myorg/app", false},
+	{"*/nginx", "library/nginx", true},
+	{"*/nginx", "a/b/nginx", false},
+	{"svc-a-*", "svc-a-ci", true},
+	{"quay.io", "quay.io", true},
+	{"*.dkr.ecr.*.amazonaws.com", "123.dkr.ecr.eu-west-1.amazonaws.com", true},
+	{"*.dkr.ecr.*.amazonaws.com", "public.ecr.aws", false},
+	{"library/[", "library/[", true},
+	{"library/[a", "library/a", false},
+}
+
+func TestMatchPattern(t *testing.T) {
+	for _, tc := range patternCases {
+		if got := MatchPattern(tc.pattern, tc.name); got != tc.want {
+			t.Errorf("MatchPattern(%q, %q) = %v, want %v", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+}
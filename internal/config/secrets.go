@@ -0,0 +1,257 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type secretLoader struct {
+	log   *logrus.Logger
+	dir   string
+	files map[string]string
+}
+
+func newSecretLoader(log *logrus.Logger) *secretLoader {
+	return &secretLoader{
+		log:   log,
+		dir:   os.Getenv("SECRETS_DIR"),
+		files: make(map[string]string),
+	}
+}
+
+func (s *secretLoader) get(key, defaultValue string) string {
+	path := s.path(key)
+	if path == "" {
+		return getEnv(key, defaultValue)
+	}
+
+	value, err := readSecretFile(path)
+	if err != nil {
+		s.log.WithFields(logrus.Fields{
+			"variable": key,
+			"path":     path,
+			"error":    err,
+		}).Fatal("Failed to read secret file")
+	}
+	s.files[key] = path
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func (s *secretLoader) path(key string) string {
+	if path := os.Getenv(key + "_FILE"); path != "" {
+		return path
+	}
+	if s.dir != "" {
+		path := filepath.Join(s.dir, key)
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+func readSecretFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+func (c *Config) secretField(key string) *string {
+	switch key {
+	case "AWS_ACCESS_KEY_ID":
+		return &c.S3AccessKey
+	case "AWS_SECRET_ACCESS_KEY":
+		return &c.S3SecretKey
+	case "DOCKERHUB_USER":
+		return &c.DockerHubUser
+	case "DOCKERHUB_PASSWORD":
+		return &c.DockerHubPassword
+	case "DOCKERHUB_CREDENTIALS":
+		return &c.DockerHubCredentialMap
+	case "GHCR_TOKEN":
+		return &c.GHCRToken
+	case "REGISTRY_CREDENTIALS":
+		return &c.RegistryCredentialMap
+	case "LDAP_BIND_PASSWORD":
+		return &c.LDAPBindPassword
+	case "AUTH_TOKEN_KEY":
+		return &c.AuthTokenKey
+	case "PEER_TOKEN":
+		return &c.PeerToken
+	case "P2P_ANNOUNCE_TOKEN":
+		return &c.P2PAnnounceToken
+	case "P2P_SERVE_TOKEN":
+		return &c.P2PServeToken
+	case "LOG_LOKI_PASSWORD":
+		return &c.LogLokiPassword
+	case "ALERT_SLACK_WEBHOOK_URL":
+		return &c.AlertSlackWebhookURL
+	}
+	return nil
+}
+
+func (c *Config) DockerHubCredentials() (string, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.DockerHubUser, c.DockerHubPassword
+}
+
+func (c *Config) UpstreamCredentials(repository string) (string, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, credential := range c.upstreamCredentials {
+		if MatchPattern(credential.Pattern, repository) {
+			return credential.Username, credential.Password
+		}
+	}
+	return c.DockerHubUser, c.DockerHubPassword
+}
+
+func (c *Config) GHCRCredentials() (string, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.GHCRUser, c.GHCRToken
+}
+
+func (c *Config) RegistryCredentials(host string) (string, string) {
+	c.mu.RLock()
+	value := c.RegistryCredentialMap
+	c.mu.RUnlock()
+
+	credentials, _ := ParseUpstreamCredentials(value)
+	for _, credential := range credentials {
+		if MatchPattern(credential.Pattern, host) {
+			return credential.Username, credential.Password
+		}
+	}
+	return "", ""
+}
+
+func (c *Config) HasDockerHubCredentials() bool {
+	user, password := c.DockerHubCredentials()
+	return user != "" && password != ""
+}
+
+func (c *Config) S3Credentials() (string, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.S3AccessKey, c.S3SecretKey
+}
+
+func (c *Config) LDAPBindCredential() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.LDAPBindPassword
+}
+
+func (c *Config) AuthTokenSigningKey() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.AuthTokenKey
+}
+
+func (c *Config) PeerCredential() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.PeerToken
+}
+
+func (c *Config) P2PCredentials() (string, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.P2PAnnounceToken, c.P2PServeToken
+}
+
+func (c *Config) LokiCredentials() (string, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.LogLokiUser, c.LogLokiPassword
+}
+
+func (c *Config) AlertSlackURL() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.AlertSlackWebhookURL
+}
+
+func (c *Config) HasStaticS3Credentials() bool {
+	accessKey, secretKey := c.S3Credentials()
+	return accessKey != "" && secretKey != ""
+}
+
+func (c *Config) WatchSecrets(ctx context.Context, logger *logrus.Logger) {
+	if len(c.secretFiles) == 0 || c.SecretReloadInterval <= 0 {
+		return
+	}
+
+	log := logger.WithField("component", "secret_watcher")
+	ticker := time.NewTicker(c.SecretReloadInterval)
+	defer ticker.Stop()
+
+	log.WithField("secrets", len(c.secretFiles)).Info("Watching secret files for rotation")
+	for {
+		select {
+		case <-ticker.C:
+			c.reloadSecrets(log)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Config) reloadSecrets(log *logrus.Entry) {
+	for key, path := range c.secretFiles {
+		value, err := readSecretFile(path)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"variable": key,
+				"path":     path,
+				"error":    err,
+			}).Warn("Failed to reload secret file")
+			continue
+		}
+		if value == "" {
+			continue
+		}
+
+		var credentials []UpstreamCredential
+		if key == "DOCKERHUB_CREDENTIALS" {
+			if credentials, err = ParseUpstreamCredentials(value); err != nil {
+				log.WithFields(logrus.Fields{
+					"variable": key,
+					"path":     path,
+					"error":    err,
+				}).Warn("Ignoring invalid rotated credentials mapping")
+				continue
+			}
+		}
+
+		field := c.secretField(key)
+		if field == nil {
+			continue
+		}
+
+		c.mu.Lock()
+		changed := *field != value
+		*field = value
+		if key == "DOCKERHUB_CREDENTIALS" {
+			c.upstreamCredentials = credentials
+		}
+		c.mu.Unlock()
+
+		if changed {
+			log.WithField("variable", key).Info("Reloaded rotated secret")
+		}
+	}
+}
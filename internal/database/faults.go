@@ -0,0 +1,36 @@
+package database
+
+import (
+	"errors"
+	"math/rand/v2"
+
+	"gorm.io/gorm"
+)
+
+var errInjectedFault = errors.New("injected database fault")
+
+func injectFaults(db *gorm.DB, rate float64) error {
+	if rate <= 0 {
+		return nil
+	}
+	fail := func(tx *gorm.DB) {
+		if rand.Float64() < rate {
+			tx.AddError(errInjectedFault)
+		}
+	}
+
+	callbacks := db.Callback()
+	for _, register := range []func() error{
+		func() error { return callbacks.Create().Before("gorm:create").Register("fault:create", fail) },
+		func() error { return callbacks.Query().Before("gorm:query").Register("fault:query", fail) },
+		func() error { return callbacks.Update().Before("gorm:update").Register("fault:update", fail) },
+		func() error { return callbacks.Delete().Before("gorm:delete").Register("fault:delete", fail) },
+		func() error { return callbacks.Row().Before("gorm:row").Register("fault:row", fail) },
+		func() error { return callbacks.Raw().Before("gorm:raw").Register("fault:raw", fail) },
+	} {
+		if err := register(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
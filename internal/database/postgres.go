@@ -17,6 +17,8 @@ type PostgresConfig struct {
 	Port     string
 	DBName   string
 	SSLMode  string
+
+	FaultErrorRate float64
 }
 
 func NewPostgresDB(logger *logrus.Logger, cfg PostgresConfig) (*gorm.DB, error) {
@@ -56,11 +58,15 @@ func NewPostgresDB(logger *logrus.Logger, cfg PostgresConfig) (*gorm.DB, error)
 		return nil, fmt.Errorf("database connection failed: %w", err)
 	}
 
-	if err := db.AutoMigrate(&models.AccessLog{}, &models.RegistryCache{}, &models.TagCache{}); err != nil {
+	if err := db.AutoMigrate(&models.AccessLog{}, &models.RegistryCache{}, &models.InlineObject{}, &models.TagCache{}, &models.PullCounter{}, &models.Lease{}, &models.Job{}, &models.RepositoryApproval{}, &models.TagDrift{}, &models.BlobDownload{}, &models.Repository{}, &models.PullToken{}, &models.CacheReference{}, &models.UsageRollup{}); err != nil {
 		log.WithError(err).Error("Database migration failed")
 		return nil, fmt.Errorf("database migration failed: %w", err)
 	}
 
+	if err := injectFaults(db, cfg.FaultErrorRate); err != nil {
+		return nil, fmt.Errorf("fault injection setup failed: %w", err)
+	}
+
 	log.Info("Database connection established")
 	return db, nil
 }
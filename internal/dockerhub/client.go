@@ -7,42 +7,140 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sdko-org/registry-proxy/internal/config"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	dockerHubRegistry = "registry-1.docker.io"
+	ghcrRegistry      = "ghcr.io"
+)
+
+const tokenFetchTimeout = 30 * time.Second
+
+const (
+	SearchURL    = "https://index.docker.io/v1/search"
+	HubSearchURL = "https://hub.docker.com/v2/search/repositories/"
+)
+
 type Client struct {
 	httpClient *http.Client
 	config     *config.Config
 	log        *logrus.Entry
-	token      string
-	tokenExp   time.Time
+	mu         sync.Mutex
+	tokens     map[string]cachedToken
+	health     *healthTracker
+
+	fetching map[string]*tokenFetch
+}
+
+type tokenFetch struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+type cachedToken struct {
+	token   string
+	expires time.Time
 }
 
 type tokenResponse struct {
-	Token     string    `json:"token"`
-	ExpiresIn int       `json:"expires_in"`
-	IssuedAt  time.Time `json:"issued_at"`
+	Token       string    `json:"token"`
+	AccessToken string    `json:"access_token"`
+	ExpiresIn   int       `json:"expires_in"`
+	IssuedAt    time.Time `json:"issued_at"`
 }
 
 type loggingTransport struct {
-	log *logrus.Entry
+	log    *logrus.Entry
+	health *healthTracker
+	signer *sigV4Signer
+
+	faultRate float64
+
+	base http.RoundTripper
 }
 
 func NewClient(logger *logrus.Logger, cfg *config.Config) *Client {
+	return NewClientWithTransport(logger, cfg, http.DefaultTransport)
+}
+
+func NewClientWithTransport(logger *logrus.Logger, cfg *config.Config, base http.RoundTripper) *Client {
+	health := newHealthTracker(cfg.UpstreamBreakerThreshold, cfg.UpstreamBreakerCooldown)
+	signer, err := newSigV4Signer(cfg)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize SigV4 upstream signing")
+	}
 	return &Client{
 		httpClient: &http.Client{
-			Timeout:   30 * time.Second,
-			Transport: &loggingTransport{log: logger.WithField("component", "dockerhub_transport")},
+			Timeout: 30 * time.Second,
+			Transport: &loggingTransport{
+				log:    logger.WithField("component", "dockerhub_transport"),
+				health: health,
+				signer: signer,
+
+				faultRate: cfg.FaultUpstreamErrorRate,
+
+				base: base,
+			},
 		},
 		config: cfg,
 		log:    logger.WithField("component", "dockerhub_client"),
+		tokens: make(map[string]cachedToken),
+		health: health,
+
+		fetching: make(map[string]*tokenFetch),
+	}
+}
+
+func (c *Client) ForgetTokens(image string) {
+	host, repository := c.splitRegistry(image)
+	c.mu.Lock()
+	delete(c.tokens, host+"/"+repository)
+	c.mu.Unlock()
+}
+
+func (c *Client) UpstreamStatuses() []UpstreamStatus {
+	return c.health.statuses()
+}
+
+// Concurrent 401s for the same repository share one token request instead of
+// each hitting the auth realm. The request runs detached from the caller that
+// started it, so one client disconnecting does not fail the others.
+func (c *Client) getToken(ctx context.Context, cacheKey, registry, repository, realm, service, scope string) (string, error) {
+	fetchKey := cacheKey + " " + scope
+	c.mu.Lock()
+	fetch, ok := c.fetching[fetchKey]
+	if !ok {
+		fetch = &tokenFetch{done: make(chan struct{})}
+		c.fetching[fetchKey] = fetch
+		go c.runTokenFetch(context.WithoutCancel(ctx), fetch, fetchKey, cacheKey, registry, repository, realm, service, scope)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-fetch.done:
+		return fetch.token, fetch.err
+	case <-ctx.Done():
+		return "", ctx.Err()
 	}
 }
 
-func (c *Client) getToken(ctx context.Context, realm string, service string, scope string) error {
+func (c *Client) runTokenFetch(ctx context.Context, fetch *tokenFetch, fetchKey, cacheKey, registry, repository, realm, service, scope string) {
+	ctx, cancel := context.WithTimeout(ctx, tokenFetchTimeout)
+	defer cancel()
+	fetch.token, fetch.err = c.fetchToken(ctx, cacheKey, registry, repository, realm, service, scope)
+	c.mu.Lock()
+	delete(c.fetching, fetchKey)
+	c.mu.Unlock()
+	close(fetch.done)
+}
+
+func (c *Client) fetchToken(ctx context.Context, cacheKey, registry, repository, realm, service, scope string) (string, error) {
 	start := time.Now()
 	log := c.log.WithFields(logrus.Fields{
 		"operation": "token_auth",
@@ -59,43 +157,65 @@ func (c *Client) getToken(ctx context.Context, realm string, service string, sco
 
 	tokenURL := fmt.Sprintf("%s?%s", realm, params.Encode())
 	req, _ := http.NewRequest("GET", tokenURL, nil)
+	c.setProxyHeaders(ctx, req)
 
-	if c.config.DockerHubUser != "" && c.config.DockerHubPassword != "" {
-		req.SetBasicAuth(c.config.DockerHubUser, c.config.DockerHubPassword)
+	if user, password := c.realmCredentials(realm, registry, repository); user != "" && password != "" {
+		req.SetBasicAuth(user, password)
+		log = log.WithField("username", user)
 	}
 
 	resp, err := c.httpClient.Do(req.WithContext(ctx))
 	if err != nil {
 		log.WithError(err).Error("Token request failed")
-		return fmt.Errorf("token request failed: %w", err)
+		return "", fmt.Errorf("token request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		log.WithField("status_code", resp.StatusCode).Error("Token auth failed")
-		return fmt.Errorf("token auth failed with status %d", resp.StatusCode)
+		return "", fmt.Errorf("token auth failed with status %d", resp.StatusCode)
 	}
 
 	var tokenResp tokenResponse
 	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
 		log.WithError(err).Error("Failed to decode token response")
-		return fmt.Errorf("failed to decode token response: %w", err)
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if tokenResp.Token == "" {
+		tokenResp.Token = tokenResp.AccessToken
+	}
+	if tokenResp.ExpiresIn <= 0 {
+		tokenResp.ExpiresIn = 60
 	}
 
-	c.token = tokenResp.Token
-	c.tokenExp = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	c.mu.Lock()
+	for key, cached := range c.tokens {
+		if time.Now().After(cached.expires) {
+			delete(c.tokens, key)
+		}
+	}
+	c.tokens[cacheKey] = cachedToken{
+		token:   tokenResp.Token,
+		expires: time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+	}
+	c.mu.Unlock()
 	log.WithFields(logrus.Fields{
 		"duration":   time.Since(start),
 		"expires_in": tokenResp.ExpiresIn,
-	}).Debug("Acquired Docker Hub token")
-	return nil
+	}).Debug("Acquired upstream token")
+	return tokenResp.Token, nil
 }
 
 func (c *Client) DoRequestWithAuth(ctx context.Context, req *http.Request) (*http.Response, error) {
-	req.Header.Set("User-Agent", "RegistryProxy/1.0")
+	c.setProxyHeaders(ctx, req)
 
-	if c.token != "" && time.Now().Before(c.tokenExp) {
-		req.Header.Set("Authorization", "Bearer "+c.token)
+	repository := repositoryFromURL(req.URL.Path)
+	cacheKey := req.URL.Host + "/" + repository
+	c.mu.Lock()
+	cached, ok := c.tokens[cacheKey]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		req.Header.Set("Authorization", "Bearer "+cached.token)
 	}
 
 	resp, err := c.httpClient.Do(req)
@@ -111,17 +231,33 @@ func (c *Client) DoRequestWithAuth(ctx context.Context, req *http.Request) (*htt
 		}
 
 		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		if len(parts) == 2 && strings.EqualFold(parts[0], "Basic") {
+			user, password := c.config.RegistryCredentials(req.URL.Host)
+			if user == "" || password == "" {
+				return resp, nil
+			}
+			resp.Body.Close()
+			newReq := req.Clone(req.Context())
+			newReq.SetBasicAuth(user, password)
+			return c.httpClient.Do(newReq)
+		}
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
 			return resp, nil
 		}
 
 		params := parseAuthParams(parts[1])
-		if err := c.getToken(ctx, params["realm"], params["service"], params["scope"]); err != nil {
+		scope := params["scope"]
+		if scope == "" {
+			scope = "repository:" + repository + ":pull"
+		}
+		token, err := c.getToken(ctx, cacheKey, req.URL.Host, repository, params["realm"], params["service"], scope)
+		if err != nil {
 			return nil, fmt.Errorf("failed to get token: %w", err)
 		}
 
+		resp.Body.Close()
 		newReq := req.Clone(req.Context())
-		newReq.Header.Set("Authorization", "Bearer "+c.token)
+		newReq.Header.Set("Authorization", "Bearer "+token)
 		return c.httpClient.Do(newReq)
 	}
 
@@ -135,7 +271,25 @@ func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error)
 		"url":    req.URL.String(),
 	})
 
-	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err := t.health.allow(req.URL.Host); err != nil {
+		log.WithError(err).Warn("Upstream request short-circuited")
+		return nil, err
+	}
+
+	req, err := t.signer.sign(req)
+	if err != nil {
+		log.WithError(err).Error("Upstream request signing failed")
+		return nil, err
+	}
+
+	if resp := injectUpstreamFault(req, t.faultRate); resp != nil {
+		log.WithField("status_code", resp.StatusCode).Warn("Injected upstream fault")
+		t.health.record(req.URL.Host, resp, nil, time.Since(start))
+		return resp, nil
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	t.health.record(req.URL.Host, resp, err, time.Since(start))
 	if err != nil {
 		log.WithError(err).Error("HTTP request failed")
 		return nil, err
@@ -148,34 +302,165 @@ func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	return resp, nil
 }
 
+func repositoryFromURL(urlPath string) string {
+	repository := strings.TrimPrefix(urlPath, "/v2/")
+	for _, marker := range []string{"/manifests/", "/blobs/", "/tags/"} {
+		if idx := strings.LastIndex(repository, marker); idx >= 0 {
+			return repository[:idx]
+		}
+	}
+	return repository
+}
+
+// Quoted values may contain commas, e.g. scope="repository:org/app:pull,push".
 func parseAuthParams(header string) map[string]string {
 	params := make(map[string]string)
-	for _, part := range strings.Split(header, ",") {
-		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
-		if len(kv) == 2 {
-			params[kv[0]] = strings.Trim(kv[1], `"`)
+	for rest := header; rest != ""; {
+		key, value, found := strings.Cut(rest, "=")
+		if !found {
+			break
+		}
+		key = strings.TrimSpace(strings.TrimLeft(key, ", "))
+		value = strings.TrimLeft(value, " ")
+		if quoted, ok := strings.CutPrefix(value, `"`); ok {
+			end := strings.Index(quoted, `"`)
+			if end < 0 {
+				end = len(quoted)
+			}
+			value, rest = quoted[:end], quoted[min(end+1, len(quoted)):]
+		} else if end := strings.Index(value, ","); end >= 0 {
+			value, rest = value[:end], value[end:]
+		} else {
+			rest = ""
 		}
+		params[key] = strings.TrimSpace(value)
 	}
 	return params
 }
 
-func (c *Client) GetManifest(ctx context.Context, image, reference, acceptHeader string) (*http.Response, error) {
-	url := fmt.Sprintf("https://registry-1.docker.io/v2/%s/manifests/%s", normalizeImageName(image), reference)
+var validatorHeaders = []string{"If-None-Match", "If-Modified-Since"}
+
+func ConditionalHeaders(r *http.Request) http.Header {
+	validators := make(http.Header)
+	for _, name := range validatorHeaders {
+		if value := r.Header.Get(name); value != "" {
+			validators.Set(name, value)
+		}
+	}
+	return validators
+}
+
+func setValidators(req *http.Request, validators http.Header) {
+	for _, name := range validatorHeaders {
+		if value := validators.Get(name); value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+}
+
+const defaultManifestAccept = "application/vnd.docker.distribution.manifest.v2+json, application/vnd.oci.image.manifest.v1+json"
+
+func (c *Client) GetManifest(ctx context.Context, image, reference, acceptHeader string, validators http.Header) (*http.Response, error) {
+	url := c.RepositoryURL(image, "manifests/"+reference)
 	req, _ := http.NewRequest("GET", url, nil)
+	setValidators(req, validators)
+	if acceptHeader != "" {
+		req.Header.Set("Accept", acceptHeader)
+	} else {
+		req.Header.Set("Accept", defaultManifestAccept)
+	}
+	return c.DoRequestWithAuth(ctx, req)
+}
+
+func (c *Client) HeadManifest(ctx context.Context, image, reference, acceptHeader string) (*http.Response, error) {
+	url := c.RepositoryURL(image, "manifests/"+reference)
+	req, _ := http.NewRequest("HEAD", url, nil)
 	if acceptHeader != "" {
 		req.Header.Set("Accept", acceptHeader)
 	} else {
-		req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.v2+json")
+		req.Header.Set("Accept", defaultManifestAccept)
 	}
 	return c.DoRequestWithAuth(ctx, req)
 }
 
-func (c *Client) GetBlob(ctx context.Context, image, digest string) (*http.Response, error) {
-	url := fmt.Sprintf("https://registry-1.docker.io/v2/%s/blobs/%s", normalizeImageName(image), digest)
+func (c *Client) GetBlob(ctx context.Context, image, digest string, validators http.Header) (*http.Response, error) {
+	url := c.RepositoryURL(image, "blobs/"+digest)
+	req, _ := http.NewRequest("GET", url, nil)
+	setValidators(req, validators)
+	return c.DoRequestWithAuth(ctx, req)
+}
+
+func (c *Client) GetBlobRange(ctx context.Context, image, digest string, offset int64, etag string) (*http.Response, error) {
+	url := c.RepositoryURL(image, "blobs/"+digest)
 	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	if etag != "" {
+		req.Header.Set("If-Range", etag)
+	}
 	return c.DoRequestWithAuth(ctx, req)
 }
 
+func IsRegistryHost(component string) bool {
+	return strings.ContainsAny(component, ".:") || component == "localhost"
+}
+
+func IsDockerHub(host string) bool {
+	switch host {
+	case "docker.io", "index.docker.io", dockerHubRegistry:
+		return true
+	}
+	return false
+}
+
+func (c *Client) dockerHubRegistry() string {
+	if c.config.DockerHubRegistry != "" {
+		return c.config.DockerHubRegistry
+	}
+	return dockerHubRegistry
+}
+
+func isDockerHubRealm(realm string) bool {
+	u, err := url.Parse(realm)
+	return err == nil && u.Host == "auth.docker.io"
+}
+
+func isGHCRRealm(realm string) bool {
+	u, err := url.Parse(realm)
+	return err == nil && u.Host == ghcrRegistry
+}
+
+func (c *Client) realmCredentials(realm, registry, repository string) (string, string) {
+	switch {
+	case isDockerHubRealm(realm):
+		return c.config.UpstreamCredentials(repository)
+	case isGHCRRealm(realm):
+		if user, token := c.config.GHCRCredentials(); token != "" {
+			return user, token
+		}
+	}
+	return c.config.RegistryCredentials(registry)
+}
+
+func (c *Client) splitRegistry(image string) (string, string) {
+	if host, rest, found := strings.Cut(image, "/"); found && IsRegistryHost(host) {
+		if IsDockerHub(host) {
+			return c.dockerHubRegistry(), normalizeImageName(rest)
+		}
+		return host, rest
+	}
+	return c.dockerHubRegistry(), normalizeImageName(image)
+}
+
+func (c *Client) UpstreamHost(image string) string {
+	host, _ := c.splitRegistry(image)
+	return host
+}
+
+func (c *Client) RepositoryURL(image, suffix string) string {
+	host, repository := c.splitRegistry(image)
+	return fmt.Sprintf("https://%s/v2/%s/%s", host, repository, suffix)
+}
+
 func normalizeImageName(image string) string {
 	if !strings.Contains(image, "/") {
 		return "library/" + image
@@ -184,7 +469,52 @@ func normalizeImageName(image string) string {
 }
 
 func (c *Client) GetTags(ctx context.Context, image string) (*http.Response, error) {
-	url := fmt.Sprintf("https://registry-1.docker.io/v2/%s/tags/list", normalizeImageName(image))
+	url := c.RepositoryURL(image, "tags/list")
 	req, _ := http.NewRequest("GET", url, nil)
 	return c.DoRequestWithAuth(ctx, req)
 }
+
+func (c *Client) Search(ctx context.Context, searchURL string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", searchURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setProxyHeaders(ctx, req)
+	req.Header.Set("Accept", "application/json")
+	return c.httpClient.Do(req.WithContext(ctx))
+}
+
+func (c *Client) GetPage(ctx context.Context, pageURL string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.DoRequestWithAuth(ctx, req)
+}
+
+// NextPageURL returns the rel="next" target of a paginated response, or "" on
+// the last page. Targets on another host are rejected rather than followed.
+func NextPageURL(resp *http.Response) (string, error) {
+	for _, header := range resp.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			target, params, _ := strings.Cut(link, ";")
+			if !strings.Contains(strings.ReplaceAll(params, `"`, ""), "rel=next") {
+				continue
+			}
+			target = strings.TrimSpace(target)
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") || resp.Request == nil {
+				return "", fmt.Errorf("invalid Link header %q", header)
+			}
+			next, err := url.Parse(target[1 : len(target)-1])
+			if err != nil {
+				return "", fmt.Errorf("invalid Link header %q: %w", header, err)
+			}
+			next = resp.Request.URL.ResolveReference(next)
+			if next.Scheme != resp.Request.URL.Scheme || next.Host != resp.Request.URL.Host {
+				return "", fmt.Errorf("next page link points to another host: %s", next.Redacted())
+			}
+			return next.String(), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,390 @@
+package dockerhub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/testing/fakeregistry"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestClient(t *testing.T, fake *fakeregistry.Registry, cfg *config.Config) *Client {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	cfg.DockerHubRegistry = fake.Host()
+	return NewClientWithTransport(logger, cfg, fake.Client().Transport)
+}
+
+func TestClientPullsFromConfiguredRegistry(t *testing.T) {
+	fake := fakeregistry.New(fakeregistry.Options{TokenAuth: true})
+	defer fake.Close()
+	image := fake.AddImage("library/alpine", "latest", fakeregistry.Layer(map[string]string{"etc/os-release": "alpine"}))
+	client := newTestClient(t, fake, nil)
+
+	if host := client.UpstreamHost("alpine"); host != fake.Host() {
+		t.Fatalf("UpstreamHost = %q, want %q", host, fake.Host())
+	}
+	if host := client.UpstreamHost("ghcr.io/org/app"); host != ghcrRegistry {
+		t.Fatalf("UpstreamHost = %q, want %q", host, ghcrRegistry)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.GetManifest(context.Background(), "alpine", "latest", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GetManifest status = %d", resp.StatusCode)
+		}
+		if digest := resp.Header.Get("Docker-Content-Digest"); digest != image.Digest {
+			t.Fatalf("digest = %q, want %q", digest, image.Digest)
+		}
+	}
+
+	resp, err := client.GetBlob(context.Background(), "alpine", image.Layers[0].Digest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GetBlob status = %d", resp.StatusCode)
+	}
+	if n := fake.TokensIssued(); n != 1 {
+		t.Fatalf("tokens issued = %d, want the cached token reused", n)
+	}
+}
+
+func TestGetTokenSendsRegistryCredentials(t *testing.T) {
+	fake := fakeregistry.New(fakeregistry.Options{TokenAuth: true, Username: "robot", Password: "s3cret"})
+	defer fake.Close()
+	fake.AddImage("team/app", "v1")
+
+	anonymous := newTestClient(t, fake, nil)
+	if _, err := anonymous.GetManifest(context.Background(), "team/app", "v1", "", nil); err == nil {
+		t.Fatal("token request without credentials succeeded")
+	}
+
+	client := newTestClient(t, fake, &config.Config{RegistryCredentialMap: fake.Host() + "=robot:s3cret"})
+	resp, err := client.GetManifest(context.Background(), "team/app", "v1", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GetManifest status = %d", resp.StatusCode)
+	}
+}
+
+type blockingRealm struct {
+	*httptest.Server
+	requests atomic.Int32
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func newBlockingRealm(t *testing.T) *blockingRealm {
+	t.Helper()
+	realm := &blockingRealm{started: make(chan struct{}, 16), release: make(chan struct{})}
+	realm.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := realm.requests.Add(1)
+		realm.started <- struct{}{}
+		<-realm.release
+		fmt.Fprintf(w, `{"token":"token-%d","expires_in":300}`, n)
+	}))
+	t.Cleanup(realm.Close)
+	return realm
+}
+
+func newRealmClient(realm *blockingRealm) *Client {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewClientWithTransport(logger, &config.Config{}, realm.Client().Transport)
+}
+
+func TestGetTokenSharesConcurrentFetch(t *testing.T) {
+	realm := newBlockingRealm(t)
+	client := newRealmClient(realm)
+
+	const callers = 32
+	tokens := make([]string, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], errs[i] = client.getToken(context.Background(), "registry.test/team/app", "registry.test", "team/app",
+				realm.URL, "registry.test", "repository:team/app:pull")
+		}(i)
+	}
+	<-realm.started
+	close(realm.release)
+	wg.Wait()
+
+	for i := range tokens {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: %v", i, errs[i])
+		}
+		if tokens[i] != tokens[0] {
+			t.Fatalf("caller %d got %q, want the shared %q", i, tokens[i], tokens[0])
+		}
+	}
+	if n := realm.requests.Load(); n != 1 {
+		t.Fatalf("token requests = %d, want 1", n)
+	}
+}
+
+func TestGetTokenSurvivesFirstCallerCancel(t *testing.T) {
+	realm := newBlockingRealm(t)
+	client := newRealmClient(realm)
+	get := func(ctx context.Context) (string, error) {
+		return client.getToken(ctx, "registry.test/team/app", "registry.test", "team/app",
+			realm.URL, "registry.test", "repository:team/app:pull")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := get(ctx)
+		first <- err
+	}()
+	<-realm.started
+
+	second := make(chan error, 1)
+	var token string
+	go func() {
+		var err error
+		token, err = get(context.Background())
+		second <- err
+	}()
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first caller: err = %v, want context.Canceled", err)
+	}
+	close(realm.release)
+	if err := <-second; err != nil {
+		t.Fatalf("waiter failed after the first caller disconnected: %v", err)
+	}
+	if token != "token-1" {
+		t.Fatalf("token = %q, want token-1", token)
+	}
+	if n := realm.requests.Load(); n != 1 {
+		t.Fatalf("token requests = %d, want 1", n)
+	}
+}
+
+// rerouteTransport sends requests for any host to a local test server, so
+// host-specific logic such as the ghcr.io realm can be exercised.
+type rerouteTransport struct {
+	target string
+}
+
+func (t rerouteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = "http", t.target
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+type ghcrRequest struct {
+	user, password string
+	scope          string
+}
+
+func newFakeGHCR(t *testing.T, scope func(repository string) string, private map[string]bool) (*httptest.Server, *[]ghcrRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []ghcrRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			user, password, _ := r.BasicAuth()
+			requested := r.URL.Query().Get("scope")
+			mu.Lock()
+			requests = append(requests, ghcrRequest{user: user, password: password, scope: requested})
+			mu.Unlock()
+			repository := strings.Split(requested, ":")[1]
+			if private[repository] && (user == "" || password != "ghp_test") {
+				http.Error(w, `{"errors":[{"code":"DENIED"}]}`, http.StatusForbidden)
+				return
+			}
+			fmt.Fprintf(w, `{"token":%q}`, "granted:"+requested)
+			return
+		}
+		repository, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/manifests/")
+		if r.Header.Get("Authorization") != "Bearer granted:"+scope(repository) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="https://ghcr.io/token",service="ghcr.io",scope=%q`, scope(repository)))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", fakeregistry.MediaTypeOCIManifest)
+		w.Write([]byte(`{"schemaVersion":2}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func newGHCRClient(server *httptest.Server, cfg *config.Config) *Client {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewClientWithTransport(logger, cfg, rerouteTransport{target: server.Listener.Addr().String()})
+}
+
+func pullScope(repository string) string {
+	return "repository:" + repository + ":pull"
+}
+
+func TestGHCRTokenRequestsUsePersonalAccessToken(t *testing.T) {
+	server, requests := newFakeGHCR(t, pullScope, map[string]bool{"acme/platform/internal-api": true})
+	client := newGHCRClient(server, &config.Config{
+		GHCRUser:              "token",
+		GHCRToken:             "ghp_test",
+		DockerHubUser:         "hub-user",
+		DockerHubPassword:     "hub-password",
+		RegistryCredentialMap: "ghcr.io=other:other",
+	})
+
+	resp, err := client.GetManifest(context.Background(), "ghcr.io/acme/platform/internal-api", "v1", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("token requests = %d, want 1", len(*requests))
+	}
+	got := (*requests)[0]
+	if got.user != "token" || got.password != "ghp_test" {
+		t.Fatalf("realm received %s:%s, want the ghcr token", got.user, got.password)
+	}
+	if got.scope != "repository:acme/platform/internal-api:pull" {
+		t.Fatalf("scope = %q", got.scope)
+	}
+}
+
+func TestGHCRFallsBackToRegistryCredentials(t *testing.T) {
+	server, requests := newFakeGHCR(t, pullScope, map[string]bool{"acme/internal": true})
+
+	anonymous := newGHCRClient(server, &config.Config{})
+	if _, err := anonymous.GetManifest(context.Background(), "ghcr.io/acme/internal", "v1", "", nil); err == nil {
+		t.Fatal("org-internal package pulled without credentials")
+	}
+	if got := (*requests)[0]; got.user != "" {
+		t.Fatalf("anonymous token request sent credentials for %q", got.user)
+	}
+
+	client := newGHCRClient(server, &config.Config{RegistryCredentialMap: "ghcr.io=ci-bot:ghp_test"})
+	resp, err := client.GetManifest(context.Background(), "ghcr.io/acme/internal", "v1", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := (*requests)[1]; got.user != "ci-bot" || got.password != "ghp_test" {
+		t.Fatalf("realm received %s:%s, want REGISTRY_CREDENTIALS for ghcr.io", got.user, got.password)
+	}
+}
+
+func TestGHCRChallengeScopeIsForwardedVerbatim(t *testing.T) {
+	scope := func(repository string) string {
+		return "repository:" + repository + ":pull,push repository:acme/base:pull"
+	}
+	server, requests := newFakeGHCR(t, scope, nil)
+	client := newGHCRClient(server, &config.Config{GHCRToken: "ghp_test"})
+
+	resp, err := client.GetManifest(context.Background(), "ghcr.io/acme/team/app", "v1", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if got, want := (*requests)[0].scope, scope("acme/team/app"); got != want {
+		t.Fatalf("scope = %q, want %q", got, want)
+	}
+}
+
+func TestParseAuthParams(t *testing.T) {
+	tests := []struct {
+		header string
+		want   map[string]string
+	}{
+		{
+			header: `realm="https://ghcr.io/token",service="ghcr.io",scope="repository:acme/app:pull"`,
+			want:   map[string]string{"realm": "https://ghcr.io/token", "service": "ghcr.io", "scope": "repository:acme/app:pull"},
+		},
+		{
+			header: `realm="https://auth.docker.io/token", service="registry.docker.io", scope="repository:samalba/my-app:pull,push"`,
+			want:   map[string]string{"realm": "https://auth.docker.io/token", "service": "registry.docker.io", "scope": "repository:samalba/my-app:pull,push"},
+		},
+		{
+			header: `realm=https://registry.test/token,service=registry.test`,
+			want:   map[string]string{"realm": "https://registry.test/token", "service": "registry.test"},
+		},
+		{
+			header: `realm="https://registry.test/token",error="insufficient_scope"`,
+			want:   map[string]string{"realm": "https://registry.test/token", "error": "insufficient_scope"},
+		},
+	}
+	for _, tt := range tests {
+		got := parseAuthParams(tt.header)
+		if len(got) != len(tt.want) {
+			t.Fatalf("parseAuthParams(%s) = %v, want %v", tt.header, got, tt.want)
+		}
+		for key, value := range tt.want {
+			if got[key] != value {
+				t.Fatalf("parseAuthParams(%s)[%s] = %q, want %q", tt.header, key, got[key], value)
+			}
+		}
+	}
+}
+
+func TestNextPageURL(t *testing.T) {
+	request, _ := http.NewRequest(http.MethodGet, "https://quay.test/v2/org/app/tags/list?n=2", nil)
+	tests := []struct {
+		name    string
+		links   []string
+		want    string
+		wantErr bool
+	}{
+		{name: "last page"},
+		{name: "relative", links: []string{`</v2/org/app/tags/list?last=b&n=2>; rel="next"`}, want: "https://quay.test/v2/org/app/tags/list?last=b&n=2"},
+		{name: "unquoted rel", links: []string{`</v2/org/app/tags/list?last=b&n=2>; rel=next`}, want: "https://quay.test/v2/org/app/tags/list?last=b&n=2"},
+		{name: "absolute same host", links: []string{`<https://quay.test/v2/org/app/tags/list?last=b&n=2>; rel="next"`}, want: "https://quay.test/v2/org/app/tags/list?last=b&n=2"},
+		{name: "other relations first", links: []string{`</v2/org/app/tags/list>; rel="first"`, `</v2/org/app/tags/list?last=d>; rel="next"`}, want: "https://quay.test/v2/org/app/tags/list?last=d"},
+		{name: "absolute other host", links: []string{`<https://attacker.test/v2/org/app/tags/list?last=b>; rel="next"`}, wantErr: true},
+		{name: "scheme downgrade", links: []string{`<http://quay.test/v2/org/app/tags/list?last=b>; rel="next"`}, wantErr: true},
+		{name: "missing brackets", links: []string{`/v2/org/app/tags/list?last=b; rel="next"`}, wantErr: true},
+		{name: "malformed URL", links: []string{`<http://[::1/v2/org/app/tags/list>; rel="next"`}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}, Request: request}
+			for _, link := range tt.links {
+				resp.Header.Add("Link", link)
+			}
+			got, err := NextPageURL(resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("next = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package dockerhub
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+)
+
+func injectUpstreamFault(req *http.Request, rate float64) *http.Response {
+	if rate <= 0 || rand.Float64() >= rate {
+		return nil
+	}
+
+	status := http.StatusInternalServerError
+	if rand.IntN(2) == 0 {
+		status = http.StatusTooManyRequests
+	}
+	body := []byte(`{"errors":[{"code":"UNKNOWN","message":"injected fault"}]}`)
+	resp := &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	if status == http.StatusTooManyRequests {
+		resp.Header.Set("Retry-After", "1")
+	}
+	return resp
+}
@@ -0,0 +1,226 @@
+package dockerhub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half_open"
+
+	healthWindow = 256
+)
+
+type UpstreamStatus struct {
+	Host                string     `json:"host"`
+	Requests            uint64     `json:"requests"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	ErrorRate           float64    `json:"error_rate"`
+	RateLimitRemaining  *int       `json:"rate_limit_remaining,omitempty"`
+	RateLimitLimit      *int       `json:"rate_limit_limit,omitempty"`
+	CircuitState        string     `json:"circuit_state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LatencyP50          string     `json:"latency_p50"`
+	LatencyP90          string     `json:"latency_p90"`
+	LatencyP99          string     `json:"latency_p99"`
+}
+
+type upstreamHealth struct {
+	mu                  sync.Mutex
+	requests            uint64
+	lastSuccess         time.Time
+	lastFailure         time.Time
+	lastError           string
+	failures            [healthWindow]bool
+	latencies           [healthWindow]time.Duration
+	rateLimitRemaining  int
+	rateLimitLimit      int
+	consecutiveFailures int
+	state               string
+	openedAt            time.Time
+	probing             bool
+}
+
+type healthTracker struct {
+	mu        sync.Mutex
+	upstreams map[string]*upstreamHealth
+	threshold int
+	cooldown  time.Duration
+}
+
+func newHealthTracker(threshold int, cooldown time.Duration) *healthTracker {
+	return &healthTracker{
+		upstreams: make(map[string]*upstreamHealth),
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+func (t *healthTracker) get(host string) *upstreamHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, ok := t.upstreams[host]
+	if !ok {
+		u = &upstreamHealth{state: breakerClosed, rateLimitRemaining: -1, rateLimitLimit: -1}
+		t.upstreams[host] = u
+	}
+	return u
+}
+
+func (t *healthTracker) allow(host string) error {
+	if t.threshold <= 0 {
+		return nil
+	}
+	u := t.get(host)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	switch u.state {
+	case breakerOpen:
+		if time.Since(u.openedAt) < t.cooldown {
+			return fmt.Errorf("upstream %s circuit breaker is open", host)
+		}
+		u.state = breakerHalfOpen
+		u.probing = true
+	case breakerHalfOpen:
+		if u.probing {
+			return fmt.Errorf("upstream %s circuit breaker is half open", host)
+		}
+		u.probing = true
+	}
+	return nil
+}
+
+func (t *healthTracker) record(host string, resp *http.Response, err error, latency time.Duration) {
+	u := t.get(host)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.probing = false
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	slot := u.requests % healthWindow
+	u.requests++
+	u.latencies[slot] = latency
+
+	failed := err != nil || resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	u.failures[slot] = failed
+	if resp != nil {
+		if remaining, ok := parseRateLimit(resp.Header.Get("RateLimit-Remaining")); ok {
+			u.rateLimitRemaining = remaining
+		}
+		if limit, ok := parseRateLimit(resp.Header.Get("RateLimit-Limit")); ok {
+			u.rateLimitLimit = limit
+		}
+	}
+
+	if !failed {
+		u.lastSuccess = time.Now()
+		u.consecutiveFailures = 0
+		u.state = breakerClosed
+		return
+	}
+
+	u.lastFailure = time.Now()
+	if err != nil {
+		u.lastError = err.Error()
+	} else {
+		u.lastError = resp.Status
+	}
+	u.consecutiveFailures++
+	if t.threshold > 0 && (u.state == breakerHalfOpen || u.consecutiveFailures >= t.threshold) {
+		u.state = breakerOpen
+		u.openedAt = time.Now()
+	}
+}
+
+func (t *healthTracker) statuses() []UpstreamStatus {
+	t.mu.Lock()
+	hosts := make([]string, 0, len(t.upstreams))
+	for host := range t.upstreams {
+		hosts = append(hosts, host)
+	}
+	t.mu.Unlock()
+	sort.Strings(hosts)
+
+	statuses := make([]UpstreamStatus, 0, len(hosts))
+	for _, host := range hosts {
+		statuses = append(statuses, t.get(host).status(host))
+	}
+	return statuses
+}
+
+func (u *upstreamHealth) status(host string) UpstreamStatus {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	status := UpstreamStatus{
+		Host:                host,
+		Requests:            u.requests,
+		LastError:           u.lastError,
+		CircuitState:        u.state,
+		ConsecutiveFailures: u.consecutiveFailures,
+	}
+	if !u.lastSuccess.IsZero() {
+		lastSuccess := u.lastSuccess
+		status.LastSuccess = &lastSuccess
+	}
+	if !u.lastFailure.IsZero() {
+		lastFailure := u.lastFailure
+		status.LastFailure = &lastFailure
+	}
+	if u.rateLimitRemaining >= 0 {
+		remaining := u.rateLimitRemaining
+		status.RateLimitRemaining = &remaining
+	}
+	if u.rateLimitLimit >= 0 {
+		limit := u.rateLimitLimit
+		status.RateLimitLimit = &limit
+	}
+
+	samples := int(min(u.requests, healthWindow))
+	if samples == 0 {
+		return status
+	}
+	latencies := make([]time.Duration, samples)
+	copy(latencies, u.latencies[:samples])
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	failures := 0
+	for _, failed := range u.failures[:samples] {
+		if failed {
+			failures++
+		}
+	}
+
+	status.ErrorRate = float64(failures) / float64(samples)
+	status.LatencyP50 = percentile(latencies, 0.50).String()
+	status.LatencyP90 = percentile(latencies, 0.90).String()
+	status.LatencyP99 = percentile(latencies, 0.99).String()
+	return status
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
+
+func parseRateLimit(value string) (int, bool) {
+	if value == "" {
+		return 0, false
+	}
+	count, _, _ := strings.Cut(value, ";")
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	return n, err == nil
+}
@@ -0,0 +1,86 @@
+package dockerhub
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/sdko-org/registry-proxy/internal/config"
+)
+
+const ecrPublicRegistry = "public.ecr.aws"
+
+var emptyPayloadHash = sha256.Sum256(nil)
+
+type sigV4Signer struct {
+	config      *config.Config
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+}
+
+func newSigV4Signer(cfg *config.Config) (*sigV4Signer, error) {
+	if len(cfg.SigV4Upstreams) == 0 {
+		return nil, nil
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	return &sigV4Signer{
+		config:      cfg,
+		credentials: awsCfg.Credentials,
+		signer:      v4.NewSigner(),
+	}, nil
+}
+
+func (s *sigV4Signer) sign(req *http.Request) (*http.Request, error) {
+	if s == nil {
+		return req, nil
+	}
+	upstream, ok := s.config.SigV4Upstream(req.URL.Hostname())
+	if !ok {
+		return req, nil
+	}
+
+	region, service := upstream.Region, upstream.Service
+	if region == "" {
+		region = ecrRegion(req.URL.Hostname())
+	}
+	if region == "" {
+		return nil, fmt.Errorf("no SigV4 region configured for %s", req.URL.Host)
+	}
+	if service == "" {
+		service = "ecr"
+		if req.URL.Hostname() == ecrPublicRegistry {
+			service = "ecr-public"
+		}
+	}
+
+	creds, err := s.credentials.Retrieve(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS credentials for %s: %w", req.URL.Host, err)
+	}
+	signed := req.Clone(req.Context())
+	signed.Header.Del("Authorization")
+	if err := s.signer.SignHTTP(req.Context(), creds, signed, hex.EncodeToString(emptyPayloadHash[:]), service, region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign request for %s: %w", req.URL.Host, err)
+	}
+	return signed, nil
+}
+
+func ecrRegion(host string) string {
+	parts := strings.Split(host, ".")
+	for i := 0; i+2 < len(parts); i++ {
+		if parts[i] == "dkr" && parts[i+1] == "ecr" {
+			return parts[i+2]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,26 @@
+package dockerhub
+
+import (
+	"context"
+	"net/http"
+)
+
+type clientAgentKey struct{}
+
+func WithClientUserAgent(ctx context.Context, userAgent string) context.Context {
+	return context.WithValue(ctx, clientAgentKey{}, userAgent)
+}
+
+func (c *Client) setProxyHeaders(ctx context.Context, req *http.Request) {
+	agent := c.config.UpstreamUserAgent
+	if c.config.ForwardClientUserAgent {
+		if clientAgent, _ := ctx.Value(clientAgentKey{}).(string); clientAgent != "" {
+			agent += " " + clientAgent
+		}
+	}
+	if c.config.ViaPseudonym != "" {
+		agent += " via/" + c.config.ViaPseudonym
+		req.Header.Set("Via", "1.1 "+c.config.ViaPseudonym)
+	}
+	req.Header.Set("User-Agent", agent)
+}
@@ -0,0 +1,212 @@
+package drift
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/leader"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	KindChanged = "changed"
+	KindDeleted = "deleted"
+)
+
+type Config struct {
+	Interval   time.Duration
+	BatchSize  int
+	WebhookURL string
+}
+
+type Watcher struct {
+	log        *logrus.Entry
+	db         *gorm.DB
+	client     *dockerhub.Client
+	elector    *leader.Elector
+	cfg        Config
+	httpClient *http.Client
+}
+
+func NewWatcher(logger *logrus.Logger, db *gorm.DB, client *dockerhub.Client, elector *leader.Elector, cfg Config) *Watcher {
+	return &Watcher{
+		log:        logger.WithField("component", "drift_watcher"),
+		db:         db,
+		client:     client,
+		elector:    elector,
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (w *Watcher) Start(ctx context.Context) {
+	w.log.WithField("interval", w.cfg.Interval).Info("Starting tag drift watcher")
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !w.elector.IsLeader() {
+				w.log.Debug("Skipping drift check on non-leader replica")
+				continue
+			}
+			if err := w.Check(ctx); err != nil {
+				w.log.WithError(err).Error("Drift check failed")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *Watcher) Check(ctx context.Context) error {
+	start := time.Now()
+	checked, drifted := 0, 0
+	lastKey := ""
+	for {
+		var entries []models.RegistryCache
+		err := w.db.WithContext(ctx).
+			Where("type = ? AND key > ?", "manifest", lastKey).
+			Order("key").
+			Limit(w.cfg.BatchSize).
+			Find(&entries).Error
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		for _, entry := range entries {
+			parsed := storage.ParseKey(entry.Key)
+			if parsed.Repository == "" || strings.Contains(parsed.Reference, ":") {
+				continue
+			}
+			found, err := w.checkTag(ctx, parsed.Repository, parsed.Reference, entry)
+			if err != nil {
+				w.log.WithFields(logrus.Fields{
+					"repository": parsed.Repository,
+					"tag":        parsed.Reference,
+					"error":      err,
+				}).Warn("Failed to check tag against upstream")
+				continue
+			}
+			checked++
+			if found {
+				drifted++
+			}
+		}
+
+		if len(entries) < w.cfg.BatchSize {
+			break
+		}
+		lastKey = entries[len(entries)-1].Key
+	}
+
+	w.log.WithFields(logrus.Fields{
+		"checked":  checked,
+		"drifted":  drifted,
+		"duration": time.Since(start),
+	}).Info("Drift check completed")
+	return nil
+}
+
+func (w *Watcher) checkTag(ctx context.Context, repository, tag string, entry models.RegistryCache) (bool, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	resp, err := w.client.HeadManifest(reqCtx, repository, tag, entry.MediaType)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	drift := models.TagDrift{
+		Repository:   repository,
+		Tag:          tag,
+		CachedDigest: entry.Digest,
+		CheckedAt:    time.Now(),
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		drift.Kind = KindDeleted
+	case resp.StatusCode == http.StatusOK:
+		drift.UpstreamDigest = resp.Header.Get("Docker-Content-Digest")
+		if drift.UpstreamDigest == "" || drift.UpstreamDigest == entry.Digest {
+			return false, w.db.WithContext(ctx).
+				Where("repository = ? AND tag = ?", repository, tag).
+				Delete(&models.TagDrift{}).Error
+		}
+		drift.Kind = KindChanged
+	default:
+		return false, fmt.Errorf("upstream returned status %d", resp.StatusCode)
+	}
+
+	var existing models.TagDrift
+	err = w.db.WithContext(ctx).Where("repository = ? AND tag = ?", repository, tag).Take(&existing).Error
+	isNew := errors.Is(err, gorm.ErrRecordNotFound)
+	if err != nil && !isNew {
+		return true, fmt.Errorf("database error: %w", err)
+	}
+	isNew = isNew || existing.Kind != drift.Kind || existing.UpstreamDigest != drift.UpstreamDigest
+
+	drift.DetectedAt = existing.DetectedAt
+	if isNew {
+		drift.DetectedAt = drift.CheckedAt
+	}
+	err = w.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "repository"}, {Name: "tag"}},
+		DoUpdates: clause.AssignmentColumns([]string{"kind", "cached_digest", "upstream_digest", "detected_at", "checked_at"}),
+	}).Create(&drift).Error
+	if err != nil {
+		return true, fmt.Errorf("database error: %w", err)
+	}
+
+	if isNew {
+		w.log.WithFields(logrus.Fields{
+			"repository":      repository,
+			"tag":             tag,
+			"kind":            drift.Kind,
+			"cached_digest":   drift.CachedDigest,
+			"upstream_digest": drift.UpstreamDigest,
+		}).Warn("Upstream tag drifted from cached manifest")
+		w.notify(ctx, drift)
+	}
+	return true, nil
+}
+
+func (w *Watcher) notify(ctx context.Context, drift models.TagDrift) {
+	if w.cfg.WebhookURL == "" {
+		return
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"event": "tag_drift",
+		"drift": drift,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		w.log.WithError(err).Warn("Failed to build drift webhook request")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		w.log.WithError(err).Warn("Drift webhook delivery failed")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		w.log.WithField("status", resp.StatusCode).Warn("Drift webhook returned non-success status")
+	}
+}
@@ -0,0 +1,249 @@
+package errorreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Config struct {
+	DSN         string
+	Environment string
+	Release     string
+	SampleRate  float64
+}
+
+type event struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   string                 `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Platform    string                 `json:"platform"`
+	Logger      string                 `json:"logger,omitempty"`
+	ServerName  string                 `json:"server_name,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Release     string                 `json:"release,omitempty"`
+	Message     map[string]string      `json:"message,omitempty"`
+	Exception   map[string]interface{} `json:"exception,omitempty"`
+	Request     map[string]interface{} `json:"request,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+}
+
+type Reporter struct {
+	cfg        Config
+	endpoint   string
+	auth       string
+	hostname   string
+	httpClient *http.Client
+	queue      chan *event
+
+	// pending counts events that were queued but not yet sent, including the
+	// one the worker is sending, so flush can wait for them.
+	pending     atomic.Int64
+	dropped     atomic.Int64
+	lastDropLog atomic.Int64
+}
+
+// dropLogInterval limits how often a full queue is reported on stderr.
+const dropLogInterval = 10 * time.Second
+
+func New(cfg Config) (*Reporter, error) {
+	u, err := url.Parse(cfg.DSN)
+	if err != nil || u.User == nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid error reporting DSN")
+	}
+	path := strings.Trim(u.Path, "/")
+	idx := strings.LastIndex(path, "/")
+	project := path[idx+1:]
+	if project == "" {
+		return nil, fmt.Errorf("error reporting DSN is missing the project id")
+	}
+	prefix := ""
+	if idx > 0 {
+		prefix = "/" + path[:idx]
+	}
+
+	hostname, _ := os.Hostname()
+	return &Reporter{
+		cfg:        cfg,
+		endpoint:   fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, prefix, project),
+		auth:       fmt.Sprintf("Sentry sentry_version=7, sentry_client=registry-proxy/1.0, sentry_key=%s", u.User.Username()),
+		hostname:   hostname,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		queue:      make(chan *event, 100),
+	}, nil
+}
+
+func (r *Reporter) Start(ctx context.Context) {
+	for {
+		select {
+		case e := <-r.queue:
+			r.deliver(e)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Reporter) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+func (r *Reporter) Fire(entry *logrus.Entry) error {
+	recovered, isPanic := entry.Data["panic"]
+	if !isPanic && !r.sampled() {
+		return nil
+	}
+
+	e := r.newEvent(entry.Level.String())
+	if entry.Level == logrus.PanicLevel {
+		e.Level = "fatal"
+	}
+	e.Logger = fmt.Sprint(entry.Data["component"])
+	e.Message = map[string]string{"formatted": entry.Message}
+	e.Extra = make(map[string]interface{}, len(entry.Data))
+	for key, value := range entry.Data {
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		e.Extra[key] = value
+	}
+	for _, key := range []string{"component", "operation"} {
+		if value, ok := entry.Data[key]; ok {
+			e.Tags[key] = fmt.Sprint(value)
+		}
+	}
+	if isPanic {
+		e.Level = "fatal"
+		e.Exception = map[string]interface{}{
+			"values": []map[string]interface{}{{
+				"type":  "panic",
+				"value": fmt.Sprint(recovered),
+			}},
+		}
+	}
+	if method, ok := entry.Data["method"]; ok {
+		e.Request = map[string]interface{}{
+			"method":       method,
+			"url":          entry.Data["path"],
+			"query_string": entry.Data["query"],
+		}
+	}
+
+	r.enqueue(e)
+	if entry.Level <= logrus.FatalLevel {
+		r.flush(2 * time.Second)
+	}
+	return nil
+}
+
+func (r *Reporter) newEvent(level string) *event {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &event{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339Nano),
+		Level:       level,
+		Platform:    "go",
+		ServerName:  r.hostname,
+		Environment: r.cfg.Environment,
+		Release:     r.cfg.Release,
+		Tags:        make(map[string]string),
+	}
+}
+
+func (r *Reporter) sampled() bool {
+	if r.cfg.SampleRate >= 1 {
+		return true
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	return err == nil && float64(n.Int64()) < r.cfg.SampleRate*1_000_000
+}
+
+// Failures inside the reporter go to stderr rather than through logrus: the
+// reporter is itself a logrus hook, so logging an error would fire it again.
+func (r *Reporter) enqueue(e *event) {
+	r.pending.Add(1)
+	select {
+	case r.queue <- e:
+	default:
+		r.pending.Add(-1)
+		dropped := r.dropped.Add(1)
+		now := time.Now().UnixNano()
+		last := r.lastDropLog.Load()
+		if now-last >= int64(dropLogInterval) && r.lastDropLog.CompareAndSwap(last, now) {
+			r.dropped.Add(-dropped)
+			fmt.Fprintf(os.Stderr, "errorreport: queue full, dropped %d events\n", dropped)
+		}
+	}
+}
+
+// flush sends queued events and waits for the one the worker may be sending,
+// until nothing is pending or the timeout expires.
+func (r *Reporter) flush(timeout time.Duration) {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for r.pending.Load() > 0 {
+		select {
+		case e := <-r.queue:
+			r.deliver(e)
+		case <-ticker.C:
+		case <-deadline:
+			return
+		}
+	}
+}
+
+func (r *Reporter) deliver(e *event) {
+	defer r.pending.Add(-1)
+	r.send(e)
+}
+
+func (r *Reporter) send(e *event) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "errorreport: encode event: %v\n", err)
+		return
+	}
+	header, _ := json.Marshal(map[string]string{
+		"event_id": e.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+	})
+
+	var body bytes.Buffer
+	body.Write(header)
+	body.WriteString("\n{\"type\":\"event\"}\n")
+	body.Write(payload)
+	body.WriteString("\n")
+
+	req, err := http.NewRequest(http.MethodPost, r.endpoint, &body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "errorreport: build request: %v\n", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "errorreport: send failed: %v\n", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		fmt.Fprintf(os.Stderr, "errorreport: server returned status %d\n", resp.StatusCode)
+	}
+}
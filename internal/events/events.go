@@ -0,0 +1,135 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	TypePull          = "pull"
+	TypeCacheHit      = "cache_hit"
+	TypeCacheMiss     = "cache_miss"
+	TypeUpstreamFetch = "upstream_fetch"
+	TypeError         = "error"
+)
+
+type Event struct {
+	Type       string    `json:"type"`
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"kind,omitempty"`
+	Repository string    `json:"repository,omitempty"`
+	Reference  string    `json:"reference,omitempty"`
+	Digest     string    `json:"digest,omitempty"`
+	Source     string    `json:"source,omitempty"`
+	Size       int64     `json:"size,omitempty"`
+	Status     int       `json:"status,omitempty"`
+	Message    string    `json:"message,omitempty"`
+}
+
+const recentErrorLimit = 50
+
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[chan Event]struct{}
+	dropped     atomic.Uint64
+
+	statsMu      sync.Mutex
+	counts       map[string]uint64
+	recentErrors []Event
+	startedAt    time.Time
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[chan Event]struct{}),
+		counts:      make(map[string]uint64),
+		startedAt:   time.Now().UTC(),
+	}
+}
+
+func (b *Broker) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	b.record(e)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			b.dropped.Add(1)
+		}
+	}
+}
+
+func (b *Broker) record(e Event) {
+	b.statsMu.Lock()
+	defer b.statsMu.Unlock()
+
+	b.counts[e.Type]++
+	if e.Type == TypeError {
+		b.recentErrors = append(b.recentErrors, e)
+		if len(b.recentErrors) > recentErrorLimit {
+			b.recentErrors = b.recentErrors[len(b.recentErrors)-recentErrorLimit:]
+		}
+	}
+}
+
+func (b *Broker) Counts() map[string]uint64 {
+	b.statsMu.Lock()
+	defer b.statsMu.Unlock()
+
+	counts := make(map[string]uint64, len(b.counts))
+	for k, v := range b.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+func (b *Broker) RecentErrors() []Event {
+	b.statsMu.Lock()
+	defer b.statsMu.Unlock()
+
+	recent := make([]Event, len(b.recentErrors))
+	for i, e := range b.recentErrors {
+		recent[len(recent)-1-i] = e
+	}
+	return recent
+}
+
+func (b *Broker) StartedAt() time.Time {
+	return b.startedAt
+}
+
+func (b *Broker) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+		})
+	}
+}
+
+func (b *Broker) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers)
+}
+
+func (b *Broker) Dropped() uint64 {
+	return b.dropped.Load()
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/cache"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"gorm.io/gorm"
+)
+
+type accessLogEntry struct {
+	ID        uint      `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	Duration  string    `json:"duration"`
+	ClientIP  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	BytesSent int       `json:"bytes_sent"`
+	Username  string    `json:"username,omitempty"`
+
+	Repository    string `json:"repository,omitempty"`
+	Reference     string `json:"reference,omitempty"`
+	Resource      string `json:"resource,omitempty"`
+	CacheStatus   string `json:"cache_status,omitempty"`
+	CacheBytes    int    `json:"cache_bytes"`
+	UpstreamBytes int    `json:"upstream_bytes"`
+}
+
+func (h *ProxyHandler) AccessLogs(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "access_logs")
+	query := r.URL.Query()
+	db := h.db.WithContext(r.Context()).Model(&models.AccessLog{})
+
+	until := time.Now()
+	if v := query.Get("until"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid until", http.StatusBadRequest)
+			return
+		}
+		until = t
+	}
+	since := until.Add(-24 * time.Hour)
+	if v := query.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			if d, derr := time.ParseDuration(v); derr == nil && d > 0 {
+				t, err = until.Add(-d), nil
+			}
+		}
+		if err != nil || !t.Before(until) {
+			http.Error(w, "Invalid since", http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+	db = db.Where("timestamp >= ? AND timestamp < ?", since, until)
+
+	if ip := query.Get("client_ip"); ip != "" {
+		db = db.Where("client_ip = ?", ip)
+	}
+	if username := query.Get("username"); username != "" {
+		db = db.Where("username = ?", username)
+	}
+	if method := query.Get("method"); method != "" {
+		db = db.Where("method = ?", strings.ToUpper(method))
+	}
+	if repository := query.Get("repository"); repository != "" {
+		expr := cache.PatternExpr(repository)
+		db = db.Where("repository ~ ? OR path ~ ?", "^"+expr+"$", "^/v2/"+expr+"/")
+	}
+	if reference := query.Get("reference"); reference != "" {
+		db = db.Where("reference = ?", reference)
+	}
+	if resource := query.Get("resource"); resource != "" {
+		db = db.Where("resource = ?", resource)
+	}
+	if status := query.Get("cache_status"); status != "" {
+		db = db.Where("cache_status = ?", strings.ToUpper(status))
+	}
+	if v := query.Get("status"); v != "" {
+		scope, ok := statusScope(v)
+		if !ok {
+			http.Error(w, "Invalid status", http.StatusBadRequest)
+			return
+		}
+		db = scope(db)
+	}
+	if v := query.Get("min_duration"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			http.Error(w, "Invalid min_duration", http.StatusBadRequest)
+			return
+		}
+		db = db.Where("duration >= ?", int64(d))
+	}
+	if v := query.Get("cursor"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		db = db.Where("id < ?", id)
+	}
+
+	limit := 100
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 1000 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	var rows []models.AccessLog
+	if err := db.Order("id DESC").Limit(limit).Find(&rows).Error; err != nil {
+		log.WithError(err).Error("Access log query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	entries := make([]accessLogEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, accessLogEntry{
+			ID:        row.ID,
+			Timestamp: row.Timestamp,
+			Method:    row.Method,
+			Path:      row.Path,
+			Status:    row.Status,
+			Duration:  row.Duration.String(),
+			ClientIP:  row.ClientIP,
+			UserAgent: row.UserAgent,
+			BytesSent: row.BytesSent,
+			Username:  row.Username,
+
+			Repository:    row.Repository,
+			Reference:     row.Reference,
+			Resource:      row.Resource,
+			CacheStatus:   row.CacheStatus,
+			CacheBytes:    row.CacheBytes,
+			UpstreamBytes: row.UpstreamBytes,
+		})
+	}
+
+	response := map[string]interface{}{
+		"since": since.UTC(),
+		"until": until.UTC(),
+		"logs":  entries,
+	}
+	if len(rows) == limit {
+		response["next_cursor"] = strconv.FormatUint(uint64(rows[len(rows)-1].ID), 10)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.WithError(err).Error("Failed to encode access log response")
+	}
+}
+
+func statusScope(v string) (func(*gorm.DB) *gorm.DB, bool) {
+	if len(v) == 3 && strings.HasSuffix(strings.ToLower(v), "xx") && v[0] >= '1' && v[0] <= '5' {
+		low := int(v[0]-'0') * 100
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Where("status >= ? AND status < ?", low, low+100)
+		}, true
+	}
+	status, err := strconv.Atoi(v)
+	if err != nil || status < 100 || status > 599 {
+		return nil, false
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("status = ?", status)
+	}, true
+}
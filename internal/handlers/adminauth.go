@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/sdko-org/registry-proxy/internal/auth"
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type trustedListenerKey struct{}
+
+func TrustedListener(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), trustedListenerKey{}, true)))
+	})
+}
+
+func fromTrustedListener(r *http.Request) bool {
+	trusted, _ := r.Context().Value(trustedListenerKey{}).(bool)
+	return trusted
+}
+
+// Guards /admin/ on the public listeners once any authentication is enabled;
+// requests arriving on LISTEN_ADMIN or LISTEN_METRICS are marked trusted.
+func AdminAuthMiddleware(logger *logrus.Logger, enabled bool, authenticators []auth.Authenticator, subjects []string) func(http.Handler) http.Handler {
+	log := logger.WithField("component", "admin_auth")
+
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/admin/") || fromTrustedListener(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(authenticators) == 0 || len(subjects) == 0 {
+				http.Error(w, "Admin API is only available on LISTEN_ADMIN", http.StatusForbidden)
+				return
+			}
+
+			var principal *auth.Principal
+			for _, authenticator := range authenticators {
+				p, err := authenticator.Authenticate(r)
+				if err == nil {
+					principal = p
+					break
+				}
+				if !errors.Is(err, auth.ErrNoCredentials) {
+					log.WithFields(logrus.Fields{
+						"authenticator": authenticator.Name(),
+						"client_ip":     getClientIP(r),
+						"error":         err,
+					}).Debug("Admin authentication failed")
+				}
+			}
+			if principal == nil {
+				w.Header().Set("WWW-Authenticate", `Basic realm="registry-proxy-admin"`)
+				http.Error(w, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+			if lrw := accessRecord(w); lrw != nil {
+				lrw.username = principal.Subject
+			}
+			if !adminSubject(subjects, principal.Subject) {
+				log.WithFields(logrus.Fields{
+					"subject": principal.Subject,
+					"path":    r.URL.Path,
+				}).Warn("Admin access denied")
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+func adminSubject(subjects []string, subject string) bool {
+	for _, pattern := range subjects {
+		if config.MatchPattern(pattern, subject) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sdko-org/registry-proxy/internal/auth"
+	"github.com/sirupsen/logrus"
+)
+
+type staticAuthenticator map[string]string
+
+func (a staticAuthenticator) Name() string {
+	return "static"
+}
+
+func (a staticAuthenticator) Authenticate(r *http.Request) (*auth.Principal, error) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, auth.ErrNoCredentials
+	}
+	if a[user] != password {
+		return nil, auth.ErrInvalidCredentials
+	}
+	return &auth.Principal{Subject: user, Method: a.Name()}, nil
+}
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	users := staticAuthenticator{"ops": "secret", "ci": "secret"}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name           string
+		enabled        bool
+		authenticators []auth.Authenticator
+		subjects       []string
+		path           string
+		user           string
+		trusted        bool
+		want           int
+	}{
+		{name: "auth disabled", path: "/admin/pull-tokens", want: http.StatusNoContent},
+		{name: "pull tokens only", enabled: true, subjects: []string{"ops"}, path: "/admin/pull-tokens", want: http.StatusForbidden},
+		{name: "no admin subjects", enabled: true, authenticators: []auth.Authenticator{users}, path: "/admin/jobs", user: "ops", want: http.StatusForbidden},
+		{name: "anonymous", enabled: true, authenticators: []auth.Authenticator{users}, subjects: []string{"ops"}, path: "/admin/cache/purge", want: http.StatusUnauthorized},
+		{name: "not an admin", enabled: true, authenticators: []auth.Authenticator{users}, subjects: []string{"ops"}, path: "/admin/quarantine/approve", user: "ci", want: http.StatusForbidden},
+		{name: "admin", enabled: true, authenticators: []auth.Authenticator{users}, subjects: []string{"ops"}, path: "/admin/cache/restore", user: "ops", want: http.StatusNoContent},
+		{name: "trusted listener", enabled: true, path: "/admin/pull-tokens", trusted: true, want: http.StatusNoContent},
+		{name: "registry path", enabled: true, path: "/v2/", want: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handler http.Handler = AdminAuthMiddleware(logrus.New(), tt.enabled, tt.authenticators, tt.subjects)(ok)
+			if tt.trusted {
+				handler = TrustedListener(handler)
+			}
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, "secret")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
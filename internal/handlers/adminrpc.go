@@ -0,0 +1,576 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	adminv1 "github.com/sdko-org/registry-proxy/api/admin/v1"
+	"github.com/sdko-org/registry-proxy/internal/cache"
+	"github.com/sdko-org/registry-proxy/internal/events"
+	"github.com/sdko-org/registry-proxy/internal/jobs"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"google.golang.org/grpc/codes"
+	grpcpeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// AdminRPC serves adminv1.AdminService by running each call through the
+// handler of the matching /admin endpoint, so both APIs share validation and
+// behaviour.
+type AdminRPC struct {
+	adminv1.UnimplementedAdminServiceServer
+
+	h      *ProxyHandler
+	purger *cache.CachePurger
+}
+
+func NewAdminRPC(h *ProxyHandler, purger *cache.CachePurger) *AdminRPC {
+	return &AdminRPC{h: h, purger: purger}
+}
+
+var (
+	errRPCNeedsDatabase = status.Error(codes.FailedPrecondition, "requires a database")
+	errRPCNeedsPurger   = status.Error(codes.FailedPrecondition, "cache purger is not running")
+)
+
+type rpcResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *rpcResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *rpcResponse) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *rpcResponse) Write(p []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(p)
+}
+
+func rpcCode(status int) codes.Code {
+	switch status {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
+
+func rpcCaller(ctx context.Context) string {
+	if p, ok := grpcpeer.FromContext(ctx); ok && p.Addr != nil {
+		return "grpc:" + p.Addr.String()
+	}
+	return "grpc"
+}
+
+func (s *AdminRPC) call(ctx context.Context, handler http.HandlerFunc, method string, query url.Values, vars map[string]string, body interface{}, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+	r, err := http.NewRequestWithContext(ctx, method, "/?"+query.Encode(), bytes.NewReader(payload))
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if vars != nil {
+		r = mux.SetURLVars(r, vars)
+	}
+
+	resp := &rpcResponse{header: make(http.Header)}
+	handler(&loggingResponseWriter{ResponseWriter: resp, username: rpcCaller(ctx)}, r)
+	if resp.status >= http.StatusBadRequest {
+		return status.Error(rpcCode(resp.status), strings.TrimSpace(resp.body.String()))
+	}
+	if out != nil && resp.body.Len() > 0 {
+		if err := json.Unmarshal(resp.body.Bytes(), out); err != nil {
+			return status.Errorf(codes.Internal, "decode response: %v", err)
+		}
+	}
+	return nil
+}
+
+func timestampOrNil(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func durationOrNil(value string) *durationpb.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d == 0 {
+		return nil
+	}
+	return durationpb.New(d)
+}
+
+func eventMessage(e events.Event) *adminv1.Event {
+	return &adminv1.Event{
+		Type:       e.Type,
+		Time:       timestampOrNil(e.Time),
+		Kind:       e.Kind,
+		Repository: e.Repository,
+		Reference:  e.Reference,
+		Digest:     e.Digest,
+		Source:     e.Source,
+		Size:       e.Size,
+		Status:     int32(e.Status),
+		Message:    e.Message,
+	}
+}
+
+func jobMessage(job *models.Job) *adminv1.Job {
+	return &adminv1.Job{
+		Id:          uint64(job.ID),
+		Type:        job.Type,
+		Status:      job.Status,
+		Payload:     job.Payload,
+		Attempts:    int32(job.Attempts),
+		MaxAttempts: int32(job.MaxAttempts),
+		LastError:   job.LastError,
+		RunAt:       timestampOrNil(job.RunAt),
+	}
+}
+
+func approvalMessage(approval *models.RepositoryApproval) *adminv1.RepositoryApproval {
+	message := &adminv1.RepositoryApproval{
+		Repository: approval.Repository,
+		Status:     approval.Status,
+		Reference:  approval.Reference,
+		FirstSeen:  timestampOrNil(approval.FirstSeen),
+		DecidedBy:  approval.DecidedBy,
+	}
+	if approval.DecidedAt != nil {
+		message.DecidedAt = timestamppb.New(*approval.DecidedAt)
+	}
+	return message
+}
+
+func simulatedMessage(object *simulatedObject) *adminv1.SimulatedObject {
+	if object == nil {
+		return nil
+	}
+	message := &adminv1.SimulatedObject{
+		Kind:      object.Kind,
+		Key:       object.Key,
+		Digest:    object.Digest,
+		Platform:  object.Platform,
+		Status:    object.Status,
+		Size:      object.Size,
+		Cacheable: object.Cacheable,
+	}
+	if object.ExpiresAt != nil {
+		message.ExpiresAt = timestamppb.New(*object.ExpiresAt)
+	}
+	return message
+}
+
+func simulatedMessages(objects []simulatedObject) []*adminv1.SimulatedObject {
+	messages := make([]*adminv1.SimulatedObject, 0, len(objects))
+	for i := range objects {
+		messages = append(messages, simulatedMessage(&objects[i]))
+	}
+	return messages
+}
+
+func (s *AdminRPC) GetCacheSummary(ctx context.Context, _ *adminv1.GetCacheSummaryRequest) (*adminv1.CacheSummary, error) {
+	if s.h.db == nil {
+		return nil, errRPCNeedsDatabase
+	}
+	var summary struct {
+		Entries      int64             `json:"entries"`
+		SizeBytes    int64             `json:"size_bytes"`
+		Classes      []cacheClassUsage `json:"classes"`
+		Compression  compressionUsage  `json:"compression"`
+		Since        time.Time         `json:"since"`
+		Events       map[string]int64  `json:"events"`
+		HitRatio     float64           `json:"hit_ratio"`
+		RecentErrors []events.Event    `json:"recent_errors"`
+	}
+	if err := s.call(ctx, s.h.CacheSummary, http.MethodGet, nil, nil, nil, &summary); err != nil {
+		return nil, err
+	}
+
+	response := &adminv1.CacheSummary{
+		Entries:   summary.Entries,
+		SizeBytes: summary.SizeBytes,
+		Since:     timestampOrNil(summary.Since),
+		Events:    summary.Events,
+		HitRatio:  summary.HitRatio,
+		Compression: &adminv1.CompressionUsage{
+			Entries:         summary.Compression.Entries,
+			SizeBytes:       summary.Compression.SizeBytes,
+			CompressedBytes: summary.Compression.CompressedSize,
+			SavedBytes:      summary.Compression.SavedBytes,
+		},
+	}
+	for _, class := range summary.Classes {
+		response.Classes = append(response.Classes, &adminv1.CacheClass{Type: class.Type, Entries: class.Entries, SizeBytes: class.SizeBytes})
+	}
+	for _, e := range summary.RecentErrors {
+		response.RecentErrors = append(response.RecentErrors, eventMessage(e))
+	}
+	return response, nil
+}
+
+func (s *AdminRPC) ListTopImages(ctx context.Context, req *adminv1.ListTopImagesRequest) (*adminv1.ListTopImagesResponse, error) {
+	if s.h.db == nil {
+		return nil, errRPCNeedsDatabase
+	}
+	query := url.Values{}
+	if req.Window != nil {
+		query.Set("window", req.Window.AsDuration().String())
+	}
+	if req.Limit != 0 {
+		query.Set("limit", strconv.Itoa(int(req.Limit)))
+	}
+	var result struct {
+		Since  time.Time  `json:"since"`
+		Images []topImage `json:"images"`
+	}
+	if err := s.call(ctx, s.h.TopImages, http.MethodGet, query, nil, nil, &result); err != nil {
+		return nil, err
+	}
+
+	response := &adminv1.ListTopImagesResponse{Since: timestampOrNil(result.Since)}
+	for _, image := range result.Images {
+		response.Images = append(response.Images, &adminv1.TopImage{
+			Repository: image.Repository,
+			Pulls:      image.Pulls,
+			LastPull:   timestampOrNil(image.LastPull),
+		})
+	}
+	return response, nil
+}
+
+func (s *AdminRPC) ListQuotaUsage(ctx context.Context, _ *adminv1.ListQuotaUsageRequest) (*adminv1.ListQuotaUsageResponse, error) {
+	if s.h.db == nil {
+		return nil, errRPCNeedsDatabase
+	}
+	var result struct {
+		Namespaces []cache.NamespaceUsage `json:"namespaces"`
+	}
+	if err := s.call(ctx, s.h.QuotaUsage, http.MethodGet, nil, nil, nil, &result); err != nil {
+		return nil, err
+	}
+
+	response := &adminv1.ListQuotaUsageResponse{}
+	for _, usage := range result.Namespaces {
+		response.Namespaces = append(response.Namespaces, &adminv1.NamespaceUsage{
+			Pattern:    usage.Pattern,
+			UsedBytes:  usage.UsedBytes,
+			MaxBytes:   usage.MaxBytes,
+			Entries:    usage.Entries,
+			OverBudget: usage.OverBudget,
+		})
+	}
+	return response, nil
+}
+
+func (s *AdminRPC) InvalidateCache(ctx context.Context, req *adminv1.InvalidateCacheRequest) (*adminv1.InvalidateCacheResponse, error) {
+	if s.h.db == nil {
+		return nil, errRPCNeedsDatabase
+	}
+	query := url.Values{}
+	for key, value := range map[string]string{"image": req.Image, "tag": req.Tag, "digest": req.Digest} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if req.OlderThan != nil {
+		query.Set("older_than", req.OlderThan.AsDuration().String())
+	}
+	query.Set("dry_run", strconv.FormatBool(req.DryRun))
+	query.Set("immediate", strconv.FormatBool(req.Immediate))
+
+	var result invalidationResult
+	if err := s.call(ctx, s.h.InvalidateCache, http.MethodPost, query, nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return &adminv1.InvalidateCacheResponse{
+		DryRun:           result.DryRun,
+		Immediate:        result.Immediate,
+		RegistryEntries:  result.RegistryEntries,
+		TagRepositories:  result.TagRepositories,
+		RegistryAffected: result.RegistryAffected,
+		TagsAffected:     result.TagsAffected,
+	}, nil
+}
+
+func (s *AdminRPC) RestoreCache(ctx context.Context, req *adminv1.RestoreCacheRequest) (*adminv1.RestoreCacheResponse, error) {
+	if s.h.db == nil {
+		return nil, errRPCNeedsDatabase
+	}
+	query := url.Values{}
+	if req.Image != "" {
+		query.Set("image", req.Image)
+	}
+	if req.Digest != "" {
+		query.Set("digest", req.Digest)
+	}
+	if err := s.call(ctx, s.h.RestoreCache, http.MethodPost, query, nil, nil, nil); err != nil {
+		return nil, err
+	}
+	return &adminv1.RestoreCacheResponse{}, nil
+}
+
+func (s *AdminRPC) TriggerPurge(ctx context.Context, _ *adminv1.TriggerPurgeRequest) (*adminv1.TriggerPurgeResponse, error) {
+	if s.purger == nil {
+		return nil, errRPCNeedsPurger
+	}
+	if err := s.call(ctx, TriggerPurge(s.purger), http.MethodPost, nil, nil, nil, nil); err != nil {
+		return nil, err
+	}
+	return &adminv1.TriggerPurgeResponse{Started: true}, nil
+}
+
+func (s *AdminRPC) GetPurgeStatus(ctx context.Context, _ *adminv1.GetPurgeStatusRequest) (*adminv1.PurgeStatus, error) {
+	if s.purger == nil {
+		return nil, errRPCNeedsPurger
+	}
+	var result cache.PurgeStatus
+	if err := s.call(ctx, PurgeStatus(s.purger), http.MethodGet, nil, nil, nil, &result); err != nil {
+		return nil, err
+	}
+
+	response := &adminv1.PurgeStatus{
+		Running:  result.Running,
+		Leader:   result.Leader,
+		Schedule: result.Schedule,
+		NextRun:  timestampOrNil(result.NextRun),
+	}
+	if run := result.LastRun; run != nil {
+		response.LastRun = &adminv1.PurgeRun{
+			Trigger:            run.Trigger,
+			StartedAt:          timestampOrNil(run.StartedAt),
+			FinishedAt:         timestampOrNil(run.FinishedAt),
+			ExpiredRegistry:    int32(run.ExpiredRegistry),
+			ExpiredTags:        int32(run.ExpiredTags),
+			InvalidatedItems:   int32(run.InvalidatedItems),
+			QuotaEvictions:     int32(run.QuotaEvictions),
+			RetentionEvictions: int32(run.RetentionEvicted),
+			RetainedItems:      int32(run.RetainedItems),
+			Errors:             int32(run.Errors),
+		}
+	}
+	return response, nil
+}
+
+func (s *AdminRPC) Prewarm(ctx context.Context, req *adminv1.PrewarmRequest) (*adminv1.Job, error) {
+	if s.h.db == nil || s.h.jobs == nil {
+		return nil, errRPCNeedsDatabase
+	}
+	body := map[string]interface{}{
+		"type":    jobs.TypePrewarm,
+		"payload": prewarmPayload{Image: req.Image, Reference: req.Reference},
+	}
+	var job models.Job
+	if err := s.call(ctx, s.h.EnqueueJob, http.MethodPost, nil, nil, body, &job); err != nil {
+		return nil, err
+	}
+	return jobMessage(&job), nil
+}
+
+func (s *AdminRPC) ListJobs(ctx context.Context, req *adminv1.ListJobsRequest) (*adminv1.ListJobsResponse, error) {
+	if s.h.db == nil {
+		return nil, errRPCNeedsDatabase
+	}
+	query := url.Values{}
+	if req.Status != "" {
+		query.Set("status", req.Status)
+	}
+	if req.Type != "" {
+		query.Set("type", req.Type)
+	}
+	if req.Limit != 0 {
+		query.Set("limit", strconv.Itoa(int(req.Limit)))
+	}
+	var result struct {
+		Counts []struct {
+			Status string `json:"status"`
+			Count  int64  `json:"count"`
+		} `json:"counts"`
+		Jobs []models.Job `json:"jobs"`
+	}
+	if err := s.call(ctx, s.h.ListJobs, http.MethodGet, query, nil, nil, &result); err != nil {
+		return nil, err
+	}
+
+	response := &adminv1.ListJobsResponse{Counts: make(map[string]int64)}
+	for _, count := range result.Counts {
+		response.Counts[count.Status] = count.Count
+	}
+	for i := range result.Jobs {
+		response.Jobs = append(response.Jobs, jobMessage(&result.Jobs[i]))
+	}
+	return response, nil
+}
+
+func (s *AdminRPC) RetryJob(ctx context.Context, req *adminv1.RetryJobRequest) (*adminv1.Job, error) {
+	if s.h.db == nil || s.h.jobs == nil {
+		return nil, errRPCNeedsDatabase
+	}
+	id := strconv.FormatUint(req.Id, 10)
+	if err := s.call(ctx, s.h.RetryJob, http.MethodPost, nil, map[string]string{"id": id}, nil, nil); err != nil {
+		return nil, err
+	}
+	var job models.Job
+	if err := s.h.db.WithContext(ctx).First(&job, req.Id).Error; err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return jobMessage(&job), nil
+}
+
+func (s *AdminRPC) ListRetentionPolicies(ctx context.Context, req *adminv1.ListRetentionPoliciesRequest) (*adminv1.ListRetentionPoliciesResponse, error) {
+	if s.h.db == nil {
+		return nil, errRPCNeedsDatabase
+	}
+	query := url.Values{}
+	if req.Repository != "" {
+		query.Set("repository", req.Repository)
+	}
+	var result struct {
+		Rules       []cache.RetentionUsage `json:"rules"`
+		MatchedRule string                 `json:"matched_rule"`
+	}
+	if err := s.call(ctx, s.h.Retention, http.MethodGet, query, nil, nil, &result); err != nil {
+		return nil, err
+	}
+
+	response := &adminv1.ListRetentionPoliciesResponse{MatchedRule: result.MatchedRule}
+	for _, rule := range result.Rules {
+		response.Rules = append(response.Rules, &adminv1.RetentionPolicy{
+			Pattern:      rule.Pattern,
+			Protect:      rule.Protect,
+			KeepLast:     int32(rule.KeepLast),
+			KeepAccessed: durationOrNil(rule.KeepAccessed),
+			Entries:      rule.Entries,
+			UsedBytes:    rule.UsedBytes,
+		})
+	}
+	return response, nil
+}
+
+func (s *AdminRPC) SimulatePull(ctx context.Context, req *adminv1.SimulatePullRequest) (*adminv1.SimulatePullResponse, error) {
+	if s.h.db == nil {
+		return nil, errRPCNeedsDatabase
+	}
+	query := url.Values{}
+	query.Set("image", req.Image)
+	if req.Platform != "" {
+		query.Set("platform", req.Platform)
+	}
+	if req.Namespace != "" {
+		query.Set("ns", req.Namespace)
+	}
+	var result simulation
+	if err := s.call(ctx, s.h.Simulate, http.MethodGet, query, nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return &adminv1.SimulatePullResponse{
+		Image:                  result.Image,
+		Repository:             result.Repository,
+		Reference:              result.Reference,
+		Verdict:                result.Verdict,
+		Manifest:               simulatedMessage(result.Manifest),
+		Children:               simulatedMessages(result.Children),
+		SelectedPlatform:       result.SelectedPlatform,
+		Blobs:                  simulatedMessages(result.Blobs),
+		EstimatedUpstreamBytes: result.EstimatedUpstreamBytes,
+		RetentionRule:          result.RetentionRule,
+		Notes:                  result.Notes,
+	}, nil
+}
+
+func (s *AdminRPC) ListQuarantine(ctx context.Context, req *adminv1.ListQuarantineRequest) (*adminv1.ListQuarantineResponse, error) {
+	if s.h.db == nil {
+		return nil, errRPCNeedsDatabase
+	}
+	query := url.Values{}
+	if req.Status != "" {
+		query.Set("status", req.Status)
+	}
+	var result struct {
+		Repositories []models.RepositoryApproval `json:"repositories"`
+	}
+	if err := s.call(ctx, s.h.Quarantine, http.MethodGet, query, nil, nil, &result); err != nil {
+		return nil, err
+	}
+
+	response := &adminv1.ListQuarantineResponse{}
+	for i := range result.Repositories {
+		response.Repositories = append(response.Repositories, approvalMessage(&result.Repositories[i]))
+	}
+	return response, nil
+}
+
+func (s *AdminRPC) ApproveRepository(ctx context.Context, req *adminv1.DecideRepositoryRequest) (*adminv1.RepositoryApproval, error) {
+	return s.decideRepository(ctx, s.h.ApproveRepository, req)
+}
+
+func (s *AdminRPC) RejectRepository(ctx context.Context, req *adminv1.DecideRepositoryRequest) (*adminv1.RepositoryApproval, error) {
+	return s.decideRepository(ctx, s.h.RejectRepository, req)
+}
+
+func (s *AdminRPC) decideRepository(ctx context.Context, handler http.HandlerFunc, req *adminv1.DecideRepositoryRequest) (*adminv1.RepositoryApproval, error) {
+	if s.h.db == nil {
+		return nil, errRPCNeedsDatabase
+	}
+	var approval models.RepositoryApproval
+	if err := s.call(ctx, handler, http.MethodPost, url.Values{"repository": {req.Repository}}, nil, nil, &approval); err != nil {
+		return nil, err
+	}
+	return approvalMessage(&approval), nil
+}
+
+func (s *AdminRPC) WatchEvents(req *adminv1.WatchEventsRequest, stream adminv1.AdminService_WatchEventsServer) error {
+	match := eventFilter(req.Types, req.Repository)
+	events, unsubscribe := s.h.events.Subscribe(256)
+	defer unsubscribe()
+
+	for {
+		select {
+		case e := <-events:
+			if !match(e) {
+				continue
+			}
+			if err := stream.Send(eventMessage(e)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.h.draining:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+	}
+}
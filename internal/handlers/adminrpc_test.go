@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	adminv1 "github.com/sdko-org/registry-proxy/api/admin/v1"
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/events"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newAdminRPCClient(t *testing.T) (*ProxyHandler, adminv1.AdminServiceClient) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cfg := &config.Config{TempDir: t.TempDir()}
+	store, err := storage.NewDiskStorage(logger, t.TempDir(), 1<<30, 1<<30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ph := NewProxyHandler(logger, cfg, store, dockerhub.NewClient(logger, cfg), nil, nil, nil, nil, nil)
+
+	ln := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	adminv1.RegisterAdminServiceServer(srv, NewAdminRPC(ph, nil))
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return ph, adminv1.NewAdminServiceClient(conn)
+}
+
+func TestAdminRPCWatchEventsFilters(t *testing.T) {
+	ph, client := newAdminRPCClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchEvents(ctx, &adminv1.WatchEventsRequest{
+		Types:      []string{events.TypeCacheHit},
+		Repository: "library/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ph.events.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ph.events.Publish(events.Event{Type: events.TypeCacheMiss, Repository: "library/nginx"})
+	ph.events.Publish(events.Event{Type: events.TypeCacheHit, Repository: "org/app"})
+	ph.events.Publish(events.Event{Type: events.TypeCacheHit, Repository: "library/nginx", Reference: "latest", Size: 42})
+
+	e, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != events.TypeCacheHit || e.Repository != "library/nginx" || e.Reference != "latest" || e.Size != 42 {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	ph.Drain()
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable after drain, got %v", err)
+	}
+}
+
+func TestAdminRPCRequiresDatabaseAndPurger(t *testing.T) {
+	_, client := newAdminRPCClient(t)
+	ctx := context.Background()
+
+	calls := map[string]func() error{
+		"GetCacheSummary": func() error {
+			_, err := client.GetCacheSummary(ctx, &adminv1.GetCacheSummaryRequest{})
+			return err
+		},
+		"ListJobs": func() error {
+			_, err := client.ListJobs(ctx, &adminv1.ListJobsRequest{})
+			return err
+		},
+		"ApproveRepository": func() error {
+			_, err := client.ApproveRepository(ctx, &adminv1.DecideRepositoryRequest{Repository: "library/nginx"})
+			return err
+		},
+		"TriggerPurge": func() error {
+			_, err := client.TriggerPurge(ctx, &adminv1.TriggerPurgeRequest{})
+			return err
+		},
+		"GetPurgeStatus": func() error {
+			_, err := client.GetPurgeStatus(ctx, &adminv1.GetPurgeStatusRequest{})
+			return err
+		},
+	}
+	for name, call := range calls {
+		if code := status.Code(call()); code != codes.FailedPrecondition {
+			t.Errorf("%s: expected FailedPrecondition, got %v", name, code)
+		}
+	}
+}
+
+func TestAdminRPCMapsHandlerErrors(t *testing.T) {
+	s := &AdminRPC{}
+	for httpStatus, want := range map[int]codes.Code{
+		http.StatusBadRequest:          codes.InvalidArgument,
+		http.StatusNotFound:            codes.NotFound,
+		http.StatusConflict:            codes.FailedPrecondition,
+		http.StatusServiceUnavailable:  codes.Unavailable,
+		http.StatusInternalServerError: codes.Internal,
+	} {
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "handler said no", httpStatus)
+		}
+		err := s.call(context.Background(), handler, http.MethodGet, nil, nil, nil, nil)
+		st, _ := status.FromError(err)
+		if st.Code() != want || st.Message() != "handler said no" {
+			t.Errorf("HTTP %d: got %v %q, want %v", httpStatus, st.Code(), st.Message(), want)
+		}
+	}
+
+	var out struct {
+		Caller string `json:"caller"`
+		Query  string `json:"query"`
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"caller":"` + accessRecord(w).username + `","query":"` + r.URL.Query().Get("image") + `"}`))
+	}
+	if err := s.call(context.Background(), handler, http.MethodGet, map[string][]string{"image": {"library/nginx"}}, nil, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Caller != "grpc" || out.Query != "library/nginx" {
+		t.Fatalf("unexpected handler view %+v", out)
+	}
+}
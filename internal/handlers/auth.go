@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sdko-org/registry-proxy/internal/auth"
+	"github.com/sirupsen/logrus"
+)
+
+type pullChallengeKey struct{}
+
+type pullChallenge struct {
+	tokens *auth.TokenIssuer
+	realm  string
+}
+
+func AuthMiddleware(logger *logrus.Logger, authenticators []auth.Authenticator, tokens *auth.TokenIssuer, realm string) func(http.Handler) http.Handler {
+	log := logger.WithField("component", "auth_middleware")
+
+	return func(next http.Handler) http.Handler {
+		if len(authenticators) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v2" && !strings.HasPrefix(r.URL.Path, "/v2/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var principal *auth.Principal
+			for _, authenticator := range authenticators {
+				p, err := authenticator.Authenticate(r)
+				if err == nil {
+					principal = p
+					break
+				}
+				if !errors.Is(err, auth.ErrNoCredentials) {
+					log.WithFields(logrus.Fields{
+						"authenticator": authenticator.Name(),
+						"client_ip":     getClientIP(r),
+						"error":         err,
+					}).Debug("Authentication failed")
+				}
+			}
+
+			if principal == nil {
+				writeChallenge(w, r, tokens, realm, repositoryFromPath(r.URL.Path), "")
+				writeRegistryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "authentication required")
+				return
+			}
+
+			if lrw, ok := w.(*loggingResponseWriter); ok {
+				lrw.username = principal.Subject
+			}
+
+			ctx := auth.WithPrincipal(r.Context(), principal)
+			ctx = context.WithValue(ctx, pullChallengeKey{}, pullChallenge{tokens: tokens, realm: realm})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Repository access is checked here rather than in AuthMiddleware so that it
+// applies to the name left after vanity mapping, ?ns= and rewrites.
+func (h *ProxyHandler) authorizePull(w http.ResponseWriter, r *http.Request, repository string) bool {
+	principal := auth.PrincipalFromContext(r.Context())
+	if principal == nil || principal.CanPull(repository) {
+		return true
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"operation":  "authorize_pull",
+		"subject":    principal.Subject,
+		"repository": repository,
+	}).Info("Repository access denied")
+	challenge, _ := r.Context().Value(pullChallengeKey{}).(pullChallenge)
+	if challenge.tokens != nil && principal.Method == challenge.tokens.Name() {
+		writeChallenge(w, r, challenge.tokens, challenge.realm, repository, "insufficient_scope")
+		writeRegistryError(w, http.StatusUnauthorized, "DENIED", "token does not grant access to the requested resource")
+		return false
+	}
+	writeRegistryError(w, http.StatusForbidden, "DENIED", "requested access to the resource is denied")
+	return false
+}
+
+func writeChallenge(w http.ResponseWriter, r *http.Request, tokens *auth.TokenIssuer, realm, repository, errorCode string) {
+	if tokens == nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="registry-proxy"`)
+		return
+	}
+
+	challenge := fmt.Sprintf(`Bearer realm="%s",service="%s"`, tokenRealm(r, realm), tokens.Service())
+	if repository != "" {
+		challenge += fmt.Sprintf(`,scope="repository:%s:pull"`, repository)
+	}
+	if errorCode != "" {
+		challenge += fmt.Sprintf(`,error="%s"`, errorCode)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+}
+
+func repositoryFromPath(urlPath string) string {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(urlPath, "/v2"), "/"), "/")
+	if len(parts) < 3 {
+		return ""
+	}
+
+	switch parts[len(parts)-2] {
+	case "manifests", "blobs", "tags", "referrers":
+		return normalizeImageName(strings.Join(parts[:len(parts)-2], "/"))
+	}
+	return ""
+}
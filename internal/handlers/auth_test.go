@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sdko-org/registry-proxy/internal/auth"
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/testing/fakeregistry"
+	"github.com/sirupsen/logrus"
+)
+
+type grantAuthenticator map[string][]string
+
+func (a grantAuthenticator) Name() string {
+	return "grant"
+}
+
+func (a grantAuthenticator) Authenticate(r *http.Request) (*auth.Principal, error) {
+	user, _, ok := r.BasicAuth()
+	if !ok {
+		return nil, auth.ErrNoCredentials
+	}
+	repositories, ok := a[user]
+	if !ok {
+		return nil, auth.ErrInvalidCredentials
+	}
+	return &auth.Principal{Subject: user, Method: a.Name(), Repositories: repositories}, nil
+}
+
+func newAuthProxy(t *testing.T, grants grantAuthenticator, configure func(*config.Config)) http.Handler {
+	t.Helper()
+	fake := fakeregistry.New(fakeregistry.Options{})
+	t.Cleanup(fake.Close)
+	authenticate := func(logger *logrus.Logger, _ *config.Config) func(http.Handler) http.Handler {
+		return AuthMiddleware(logger, []auth.Authenticator{grants}, nil, "")
+	}
+	_, proxy := newUpstreamProxy(t, fake.Host(), fake.Client().Transport, configure, authenticate, TenantMiddleware)
+	return proxy
+}
+
+func authorizedPull(handler http.Handler, path, user, host string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if user != "" {
+		req.SetBasicAuth(user, "secret")
+	}
+	if host != "" {
+		req.Host = host
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReferrersRequireRepositoryAccess(t *testing.T) {
+	proxy := newAuthProxy(t, grantAuthenticator{"ci": {"library/nginx"}}, nil)
+	digest := sha256Digest([]byte("image"))
+
+	for _, tc := range []struct {
+		path string
+		user string
+		want int
+	}{
+		{"/v2/alpine/referrers/" + digest, "", http.StatusUnauthorized},
+		{"/v2/alpine/referrers/" + digest, "ci", http.StatusForbidden},
+		{"/v2/nginx/referrers/" + digest, "ci", http.StatusOK},
+	} {
+		if rec := authorizedPull(proxy, tc.path, tc.user, ""); rec.Code != tc.want {
+			t.Errorf("%s as %q: status = %d, want %d", tc.path, tc.user, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestPullAuthorizationUsesResolvedName(t *testing.T) {
+	proxy := newAuthProxy(t, grantAuthenticator{
+		"ci":     {"library/nginx"},
+		"mirror": {"library/alpine"},
+	}, func(cfg *config.Config) {
+		cfg.MirrorNamespaces = []string{"ghcr.io"}
+		cfg.ImageRewrites = []config.ImageRewrite{{Pattern: "nginx", Replacement: "alpine"}}
+	})
+	digest := sha256Digest([]byte("image"))
+
+	for _, tc := range []struct {
+		path string
+		user string
+		want int
+	}{
+		{"/v2/nginx/referrers/" + digest + "?ns=ghcr.io", "ci", http.StatusForbidden},
+		{"/v2/ghcr.io/library/nginx/referrers/" + digest, "ci", http.StatusForbidden},
+		{"/v2/nginx/referrers/" + digest, "ci", http.StatusForbidden},
+		{"/v2/nginx/referrers/" + digest, "mirror", http.StatusOK},
+	} {
+		if rec := authorizedPull(proxy, tc.path, tc.user, ""); rec.Code != tc.want {
+			t.Errorf("%s as %q: status = %d, want %d", tc.path, tc.user, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestPullTokenPatternsMatchResolvedNames(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		want    string
+		pulls   []string
+	}{
+		{"nginx", "library/nginx", []string{"library/nginx"}},
+		{"nginx*", "library/nginx*", []string{"library/nginx", "library/nginx-unprivileged"}},
+		{"alpine?", "library/alpine?", []string{"library/alpine3"}},
+		{"myorg/*", "myorg/*", []string{"myorg/app"}},
+		{"*", "*", []string{"library/nginx", "myorg/team/app"}},
+	} {
+		got, err := pullTokenPattern(tc.pattern)
+		if err != nil || got != tc.want {
+			t.Errorf("pullTokenPattern(%q) = %q, %v, want %q", tc.pattern, got, err, tc.want)
+			continue
+		}
+		principal := &auth.Principal{Repositories: []string{got}}
+		for _, repository := range tc.pulls {
+			if !principal.CanPull(repository) {
+				t.Errorf("token scoped to %q cannot pull %s", tc.pattern, repository)
+			}
+		}
+	}
+
+	for _, pattern := range []string{"", "a,b", "[", "Nginx"} {
+		if _, err := pullTokenPattern(pattern); err == nil {
+			t.Errorf("pullTokenPattern(%q) accepted an invalid pattern", pattern)
+		}
+	}
+}
+
+func TestRepositoryFromPath(t *testing.T) {
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{"/v2/", ""},
+		{"/v2/_catalog", ""},
+		{"/v2/nginx/tags/list", "library/nginx"},
+		{"/v2/nginx/manifests/latest", "library/nginx"},
+		{"/v2/myorg/app/blobs/sha256:abc", "myorg/app"},
+		{"/v2/ghcr.io/org/app/referrers/sha256:abc", "ghcr.io/org/app"},
+		{"/v2/nginx/uploads/abc", ""},
+		{"/v2/manifests/latest", ""},
+	} {
+		if got := repositoryFromPath(tc.path); got != tc.want {
+			t.Errorf("repositoryFromPath(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestTenantNamespacesUseResolvedName(t *testing.T) {
+	proxy := newAuthProxy(t, grantAuthenticator{"ci": nil}, func(cfg *config.Config) {
+		cfg.MirrorNamespaces = []string{"ghcr.io"}
+		cfg.ImageRewrites = []config.ImageRewrite{{Pattern: "busybox", Replacement: "alpine"}}
+		cfg.Tenants = []config.Tenant{{Name: "team-a", Hosts: []string{"team-a.example"}, Namespaces: []string{"library/nginx", "library/busybox"}}}
+	})
+	digest := sha256Digest([]byte("image"))
+
+	for _, tc := range []struct {
+		path string
+		want int
+	}{
+		{"/v2/nginx/referrers/" + digest, http.StatusOK},
+		{"/v2/alpine/referrers/" + digest, http.StatusForbidden},
+		{"/v2/busybox/referrers/" + digest, http.StatusForbidden},
+		{"/v2/nginx/referrers/" + digest + "?ns=ghcr.io", http.StatusForbidden},
+	} {
+		if rec := authorizedPull(proxy, tc.path, "ci", "team-a.example"); rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.path, rec.Code, tc.want)
+		}
+	}
+}
@@ -1,16 +1,27 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/sdko-org/registry-proxy/internal/broadcast"
 	"github.com/sdko-org/registry-proxy/internal/config"
 	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/events"
+	"github.com/sdko-org/registry-proxy/internal/jobs"
+	"github.com/sdko-org/registry-proxy/internal/p2p"
+	"github.com/sdko-org/registry-proxy/internal/peer"
+	"github.com/sdko-org/registry-proxy/internal/qos"
 	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sdko-org/registry-proxy/internal/throttle"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
@@ -18,7 +29,6 @@ import (
 var (
 	validDigestRegex  = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
 	safeFilenameChars = regexp.MustCompile(`[^a-zA-Z0-9-_]`)
-	pathValidator     = regexp.MustCompile(`^[a-zA-Z0-9-_:\\./]+$`)
 )
 
 type ProxyHandler struct {
@@ -27,11 +37,34 @@ type ProxyHandler struct {
 	dhClient    *dockerhub.Client
 	log         *logrus.Entry
 	downloadMap sync.Map
+	verified    sync.Map
+	memory      *memoryStore
+	sampled     atomic.Pointer[runtimeStats]
 	tempDir     string
+	instance    string
 	db          *gorm.DB
+	jobs        *jobs.Queue
+	platforms   *platformFilter
+	events      *events.Broker
+	peers       *peer.Cluster
+	shards      *peer.Ring
+	forwarder   http.RoundTripper
+	announcer   *p2p.Announcer
+	bus         *broadcast.Bus
+	search      *searchCache
+	throttle    *throttle.Manager
+	classes     *qos.Classifier
+	scheduler   *qos.Scheduler
+	indexes     []indexFilterRule
+
+	upstreamAttempts atomic.Uint64
+	upstreamFailures atomic.Uint64
+
+	draining  chan struct{}
+	drainOnce sync.Once
 }
 
-func NewProxyHandler(logger *logrus.Logger, cfg *config.Config, storage storage.Storage, dhClient *dockerhub.Client, db *gorm.DB) *ProxyHandler {
+func NewProxyHandler(logger *logrus.Logger, cfg *config.Config, storage storage.Storage, dhClient *dockerhub.Client, db *gorm.DB, queue *jobs.Queue, peers *peer.Cluster, announcer *p2p.Announcer, bus *broadcast.Bus) *ProxyHandler {
 	if err := os.MkdirAll(cfg.TempDir, 0700); err != nil {
 		logger.Fatal(err)
 	}
@@ -43,64 +76,182 @@ func NewProxyHandler(logger *logrus.Logger, cfg *config.Config, storage storage.
 		logger.Fatal(err)
 	}
 	os.Remove(testFile)
-	return &ProxyHandler{
-		cfg:      cfg,
-		storage:  storage,
-		dhClient: dhClient,
-		db:       db,
-		log:      logger.WithField("component", "proxy_handler"),
-		tempDir:  cfg.TempDir,
+
+	qosRules, err := qos.ParseRules(cfg.QoSRules)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid QOS_RULES")
+	}
+	indexRules, err := parseIndexFilterRules(cfg.ManifestPlatformRules)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid MANIFEST_PLATFORM_RULES")
+	}
+	var scheduler *qos.Scheduler
+	if cfg.QoSUpstreamSlots > 0 {
+		scheduler = qos.NewScheduler(cfg.QoSUpstreamSlots, cfg.QoSYieldBandwidth)
 	}
+
+	instance, _ := os.Hostname()
+	h := &ProxyHandler{
+		cfg:       cfg,
+		storage:   storage,
+		dhClient:  dhClient,
+		db:        db,
+		jobs:      queue,
+		log:       logger.WithField("component", "proxy_handler"),
+		tempDir:   cfg.TempDir,
+		memory:    newMemoryStore(cfg.MemoryBlobThreshold, cfg.MemoryBlobPool),
+		platforms: newPlatformFilter(cfg.CachePlatforms),
+		events:    events.NewBroker(),
+		draining:  make(chan struct{}),
+		instance:  instance,
+		peers:     peers,
+		announcer: announcer,
+		bus:       bus,
+		search:    &searchCache{entries: make(map[string]searchEntry)},
+		throttle:  throttle.NewManager(cfg.BandwidthUpstream, cfg.BandwidthPerClient, cfg.BandwidthRules),
+		classes:   qos.NewClassifier(qosRules, cfg.QoSDefaultClass),
+		scheduler: scheduler,
+		indexes:   indexRules,
+		shards:    newShardRing(cfg),
+		forwarder: &http.Transport{
+			ResponseHeaderTimeout: cfg.ShardForwardTimeout,
+			IdleConnTimeout:       90 * time.Second,
+			MaxIdleConnsPerHost:   32,
+		},
+	}
+	h.recoverTempFiles()
+	bus.Subscribe(h.applyInvalidation)
+	return h
 }
 
-func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/v2/")
-	if !pathValidator.MatchString(path) {
-		http.Error(w, "Invalid path", http.StatusBadRequest)
-		return
+func (h *ProxyHandler) registryEndpoint(resourceType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if isPushRequest(r) {
+			h.rejectPush(w, r)
+			return
+		}
+
+		vars := mux.Vars(r)
+		h.serveRegistry(w, r, &registryRoute{
+			name:         vars["name"],
+			resourceType: resourceType,
+			reference:    vars["reference"],
+		})
 	}
+}
 
-	parts := strings.Split(path, "/")
-	if len(parts) < 2 {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+func (h *ProxyHandler) unmatchedRegistryPath(w http.ResponseWriter, r *http.Request) {
+	if isPushRequest(r) {
+		h.rejectPush(w, r)
 		return
 	}
+	http.Error(w, "Not found", http.StatusNotFound)
+}
 
-	if len(parts) >= 3 && parts[len(parts)-2] == "tags" && parts[len(parts)-1] == "list" {
-		image := strings.Join(parts[:len(parts)-2], "/")
-		h.handleTagsList(w, r, image)
+func (h *ProxyHandler) serveRegistry(w http.ResponseWriter, r *http.Request, route *registryRoute) {
+	if h.cfg.ViaPseudonym != "" {
+		w.Header().Add("Via", fmt.Sprintf("%d.%d %s", r.ProtoMajor, r.ProtoMinor, h.cfg.ViaPseudonym))
+	}
+
+	if !validRepositoryName(route.name) {
+		writeRegistryError(w, http.StatusBadRequest, "NAME_INVALID", "invalid repository name")
 		return
 	}
 
-	if path == "_catalog" {
-		HandleCatalog(w, r)
+	image, ok := h.resolveNamespace(r, route.name)
+	if !ok {
+		http.Error(w, "Upstream namespace not allowed", http.StatusNotFound)
+		return
+	}
+	original := image
+	if rewritten, ok := h.rewriteImage(image); ok {
+		h.log.WithFields(logrus.Fields{
+			"operation": "image_rewrite",
+			"from":      image,
+			"to":        rewritten,
+		}).Info("Rewrote image name")
+		image = rewritten
+	}
+	if !h.authorizePull(w, r, image) || !h.tenantAllows(w, r, image) {
 		return
 	}
 
-	for _, part := range parts {
-		if strings.Contains(part, "..") || strings.Contains(part, "//") {
-			http.Error(w, "Invalid path component", http.StatusBadRequest)
+	reference := route.reference
+	if route.resourceType == "blobs" || route.resourceType == "referrers" || isDigestReference(reference) {
+		if reference, ok = normalizeDigest(reference); !ok {
+			writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", "invalid digest")
 			return
 		}
+	} else if route.resourceType == "manifests" && !validTag(reference) {
+		writeRegistryError(w, http.StatusBadRequest, "TAG_INVALID", "invalid tag")
+		return
+	}
+
+	if lrw := accessRecord(w); lrw != nil {
+		lrw.repository = image
+		lrw.reference = reference
+		if original != image {
+			lrw.original = original
+		}
+		lrw.resource = strings.TrimSuffix(route.resourceType, "s")
+	}
+
+	var firstReference string
+	if route.resourceType == "manifests" {
+		firstReference = reference
+	}
+	held, err := h.quarantined(r.Context(), image, firstReference)
+	if err != nil {
+		h.log.WithError(err).Error("Quarantine check failed")
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", "internal server error")
+		return
+	}
+	if held {
+		writeRegistryError(w, http.StatusForbidden, "DENIED", fmt.Sprintf("repository %s is quarantined pending approval", image))
+		return
 	}
 
-	if len(parts) < 2 {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	if h.forwardToShardOwner(w, r, shardKey(route, image, reference)) {
 		return
 	}
 
-	resourceType := parts[len(parts)-2]
-	reference := parts[len(parts)-1]
-	image := strings.Join(parts[:len(parts)-2], "/")
+	if route.resourceType != "tags" {
+		w = h.throttleResponse(w, r, image)
+	}
+	ctx := qos.WithClass(r.Context(), h.classify(r))
+	r = r.WithContext(dockerhub.WithClientUserAgent(ctx, r.UserAgent()))
 
-	switch resourceType {
+	switch route.resourceType {
+	case "tags":
+		h.handleTagsList(w, r, image)
 	case "manifests":
 		h.handleManifest(w, r, image, reference)
 	case "blobs":
 		h.handleBlob(w, r, image, reference)
-	default:
-		http.Error(w, "Not found", http.StatusNotFound)
+	case "referrers":
+		h.handleReferrers(w, r, image, reference)
+	}
+}
+
+func (h *ProxyHandler) resolveNamespace(r *http.Request, image string) (string, bool) {
+	if mapped, ok := h.vanityImage(r, image); ok {
+		return mapped, true
+	}
+
+	namespace := r.URL.Query().Get("ns")
+	if host, rest, found := strings.Cut(image, "/"); found && dockerhub.IsRegistryHost(host) {
+		namespace, image = host, rest
+	}
+
+	if namespace == "" || dockerhub.IsDockerHub(namespace) {
+		return normalizeImageName(image), true
+	}
+	for _, allowed := range h.cfg.MirrorNamespaces {
+		if namespace == allowed {
+			return namespace + "/" + image, true
+		}
 	}
+	return "", false
 }
 
 func normalizeImageName(image string) string {
@@ -1,30 +1,53 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"time"
 
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/events"
+	"github.com/sdko-org/registry-proxy/internal/qos"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sdko-org/registry-proxy/internal/throttle"
 	"github.com/sirupsen/logrus"
 )
 
 func (h *ProxyHandler) handleBlob(w http.ResponseWriter, r *http.Request, image, digest string) {
 	if !validDigestRegex.MatchString(digest) {
-		http.Error(w, "Invalid digest format", http.StatusBadRequest)
+		writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", "unsupported digest algorithm")
+		return
+	}
+	if h.passthrough(image) {
+		h.passthroughBlob(w, r, image, digest)
 		return
 	}
 	ctx := context.Background()
 
-	cacheKey := fmt.Sprintf("blobs/%s/%s", image, digest)
-	content, retrievedDigest, mediaType, err := h.storage.Get(ctx, cacheKey)
-	if err == nil {
+	cacheKey := storage.BlobKey(image, digest)
+	if r.Method == http.MethodHead && h.serveCachedBlobHead(w, r, cacheKey, digest) {
+		h.publishEvent(events.TypeCacheHit, "blob", image, "", digest, "metadata", 0)
+		return
+	}
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && h.serveCachedBlobRange(w, r, cacheKey, digest, rangeHeader) {
+		h.publishEvent(events.TypeCacheHit, "blob", image, "", digest, "s3", 0)
+		return
+	}
+	if streamer, ok := h.storage.(storage.Streamer); ok {
+		if h.serveCachedBlobStream(w, r, streamer, cacheKey, digest) {
+			h.publishEvent(events.TypeCacheHit, "blob", image, "", digest, "s3", 0)
+			return
+		}
+	} else if content, retrievedDigest, mediaType, err := h.storage.Get(ctx, cacheKey); err == nil {
 		h.log.WithFields(logrus.Fields{
 			"digest": digest,
 			"source": "s3",
@@ -32,8 +55,10 @@ func (h *ProxyHandler) handleBlob(w http.ResponseWriter, r *http.Request, image,
 		w.Header().Set("Content-Type", mediaType)
 		w.Header().Set("Docker-Content-Digest", retrievedDigest)
 		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		h.markCacheHit(w, &storage.ObjectInfo{Digest: retrievedDigest, MediaType: mediaType, Size: int64(len(content))})
 		w.WriteHeader(http.StatusOK)
 		w.Write(content)
+		h.publishEvent(events.TypeCacheHit, "blob", image, "", digest, "s3", int64(len(content)))
 		return
 	}
 
@@ -46,81 +71,378 @@ func (h *ProxyHandler) handleBlob(w http.ResponseWriter, r *http.Request, image,
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	if h.serveFromMemory(w, digest) {
+		h.publishEvent(events.TypeCacheHit, "blob", image, "", digest, "memory", 0)
+		return
+	}
 	if h.serveFromTempFile(w, tempPath, digest) {
+		h.publishEvent(events.TypeCacheHit, "blob", image, "", digest, "disk", 0)
 		return
 	}
-	if waitChan, exists := h.downloadMap.Load(digest); exists {
-		<-waitChan.(chan struct{})
-		if h.serveFromTempFile(w, tempPath, digest) {
+	if blob := h.inflightDownload(digest); blob != nil {
+		if h.serveFromInflight(w, blob, digest) {
+			h.publishEvent(events.TypeCacheHit, "blob", image, "", digest, "inflight", 0)
+			return
+		}
+		if h.serveFromMemory(w, digest) || h.serveFromTempFile(w, tempPath, digest) {
 			return
 		}
 	}
-	h.downloadMap.Store(digest, make(chan struct{}))
-	defer h.downloadMap.Delete(digest)
+	inflight := newInflightBlob()
+	inflightErr, retained := errInflightAbandoned, false
+	h.downloadMap.Store(digest, inflight)
+	defer func() {
+		inflight.finish(inflightErr)
+		if !retained {
+			h.releaseInflight(digest, inflight)
+		}
+	}()
+
+	h.publishEvent(events.TypeCacheMiss, "blob", image, "", digest, "", 0)
+	class := qos.ClassFromContext(r.Context())
+	release, err := h.scheduler.Acquire(r.Context(), class)
+	if err != nil {
+		return
+	}
+	defer release()
 
+	var resp *http.Response
+	var peerURL string
+	source, upstream := "dockerhub", h.dhClient.UpstreamHost(image)
+	resumed := h.resumeBlob(ctx, image, digest, tempPath+partialSuffix)
+	if resumed != nil {
+		resp = resumed.resp
+	} else if resp, peerURL, err = h.peers.FetchBlob(ctx, digest); resp == nil {
+		resp, err = h.dhClient.GetBlob(ctx, image, digest, dockerhub.ConditionalHeaders(r))
+	} else if u, parseErr := url.Parse(peerURL); parseErr == nil {
+		source, upstream = "peer", u.Host
+	}
 	h.log.WithFields(logrus.Fields{
 		"digest": digest,
-		"source": "dockerhub",
+		"source": source,
+		"peer":   peerURL,
 	}).Info("Downloading blob from upstream")
-	resp, err := h.dhClient.GetBlob(ctx, image, digest)
 	if err != nil {
+		h.publishError("blob", image, digest, http.StatusBadGateway, err.Error())
 		http.Error(w, "Blob fetch failed", http.StatusBadGateway)
 		return
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode == http.StatusNotModified {
+		h.log.WithField("digest", digest).Debug("Upstream blob not modified, relaying 304")
+		h.markCacheMiss(w, upstream)
 		forwardResponse(w, resp)
 		return
 	}
-	tempFile, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
-	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	if resp.StatusCode != http.StatusOK {
+		h.publishError("blob", image, digest, resp.StatusCode, "upstream returned non-200 status")
+		forwardResponse(w, resp)
 		return
 	}
-	defer tempFile.Close()
+	var buffer *bytes.Buffer
+	var tempFile *os.File
+	var tracker *downloadTracker
+	var sink io.Writer
+	var offset int64
 	hash := sha256.New()
-	multiWriter := io.MultiWriter(tempFile, hash, w)
+	passthrough := resumed == nil && h.cfg.MaxCacheBlobSize > 0 && resp.ContentLength > h.cfg.MaxCacheBlobSize
+	if passthrough {
+		h.log.WithFields(logrus.Fields{
+			"digest": digest,
+			"size":   resp.ContentLength,
+			"limit":  h.cfg.MaxCacheBlobSize,
+		}).Info("Blob exceeds MAX_CACHE_BLOB_SIZE, streaming without caching")
+		sink = io.Discard
+	} else if resumed != nil {
+		tempFile, hash, offset = resumed.file, resumed.hash, resumed.offset
+		defer tempFile.Close()
+		sink = tempFile
+	} else if h.memory.reserve(resp.ContentLength) {
+		buffer = bytes.NewBuffer(make([]byte, 0, resp.ContentLength))
+		inflight.startBuffer(buffer, resp.Header.Get("Content-Type"), resp.ContentLength)
+		sink = inflight
+	} else {
+		tempFile, err = os.OpenFile(tempPath+partialSuffix, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer tempFile.Close()
+		sink = tempFile
+	}
+	if tempFile != nil {
+		if tracker = h.trackDownload(image, digest, tempFile, offset, resp); tracker != nil {
+			sink = io.MultiWriter(tempFile, tracker)
+		}
+		size := int64(-1)
+		if resp.ContentLength >= 0 {
+			size = offset + resp.ContentLength
+		}
+		if err := inflight.startFile(tempFile.Name(), resp.Header.Get("Content-Type"), offset, size); err == nil {
+			sink = io.MultiWriter(sink, inflight)
+		}
+	}
+	limited := &cacheLimitWriter{w: sink, remaining: h.cfg.MaxCacheBlobSize - offset}
+	if tempFile != nil && h.cfg.MaxCacheBlobSize > 0 {
+		sink = limited
+	}
+	discard := func() {
+		if passthrough {
+			return
+		}
+		if buffer != nil {
+			h.memory.release(resp.ContentLength)
+			return
+		}
+		os.Remove(tempFile.Name())
+		tracker.finish()
+	}
+	multiWriter := io.MultiWriter(sink, hash, w)
 	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
 	w.Header().Set("Docker-Content-Digest", digest)
-	_, copyErr := io.Copy(multiWriter, resp.Body)
+	preserved := h.preservedHeaders(resp.Header)
+	h.replayHeaders(w, preserved)
+	h.markCacheMiss(w, upstream)
+	if passthrough {
+		w.Header().Set("Content-Length", fmt.Sprint(resp.ContentLength))
+	}
+	if resumed != nil {
+		w.Header().Del("Content-Range")
+		if resp.ContentLength >= 0 {
+			w.Header().Set("Content-Length", fmt.Sprint(offset+resp.ContentLength))
+		}
+		if err := h.replayPartial(w, tempFile.Name(), offset); err != nil {
+			return
+		}
+	}
+	written, copyErr := io.Copy(multiWriter, throttle.NewReader(ctx, h.scheduler.Reader(r.Context(), class, resp.Body), h.throttle.Upstream()))
+	recordUpstreamBytes(w, written)
+	written += offset
 	if copyErr != nil {
-		os.Remove(tempPath)
+		h.publishError("blob", image, digest, http.StatusInternalServerError, copyErr.Error())
+		if tracker != nil && !limited.exceeded {
+			tracker.checkpoint()
+			h.log.WithFields(logrus.Fields{
+				"digest":  digest,
+				"written": written,
+			}).Warn("Blob download interrupted, keeping partial file for resumption")
+			return
+		}
+		discard()
 		http.Error(w, "Download failed", http.StatusInternalServerError)
 		return
 	}
 	calculatedDigest := "sha256:" + hex.EncodeToString(hash.Sum(nil))
 	if calculatedDigest != digest {
-		os.Remove(tempPath)
+		discard()
 		h.log.WithFields(logrus.Fields{
 			"expected": digest,
 			"actual":   calculatedDigest,
-			"source":   "dockerhub",
+			"source":   source,
 		}).Error("Blob digest mismatch")
+		h.publishError("blob", image, digest, http.StatusBadGateway, "digest mismatch")
 		http.Error(w, "Digest mismatch", http.StatusBadGateway)
 		return
 	}
-	go func() {
-		defer os.Remove(tempPath)
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
-		defer cancel()
-		f, err := os.Open(tempPath)
-		if err != nil {
-			return
-		}
-		defer f.Close()
-		cacheKey := fmt.Sprintf("blobs/%s/%s", image, digest)
+	h.publishEvent(events.TypeUpstreamFetch, "blob", image, "", digest, source, written)
+	if passthrough {
+		return
+	}
+	if limited.exceeded {
 		h.log.WithFields(logrus.Fields{
 			"digest": digest,
-			"source": "s3",
-		}).Info("Storing blob in persistent cache")
-		for attempt := 1; attempt <= 5; attempt++ {
-			f.Seek(0, 0)
-			if err := h.storage.PutStream(ctx, cacheKey, f, digest, "application/octet-stream", h.cfg.BlobCacheTTL); err == nil {
-				return
-			}
-			time.Sleep(time.Duration(attempt*2) * time.Second)
+			"size":   written,
+			"limit":  h.cfg.MaxCacheBlobSize,
+		}).Info("Blob exceeded MAX_CACHE_BLOB_SIZE while streaming, not caching")
+		discard()
+		return
+	}
+	if h.platforms.skipBlob(digest) {
+		h.log.WithField("digest", digest).Debug("Skipping cache for excluded platform blob")
+		discard()
+		return
+	}
+	if buffer != nil {
+		inflightErr = nil
+		h.cacheFromMemory(image, digest, buffer.Bytes(), preserved)
+		return
+	}
+	tracker.finish()
+	if err := h.commitTempFile(tempFile, tempPath, written); err != nil {
+		h.log.WithFields(logrus.Fields{
+			"digest": digest,
+			"error":  err,
+		}).Error("Failed to commit temporary blob")
+		discard()
+		return
+	}
+	inflightErr, retained = nil, true
+	h.retainInflight(digest, inflight)
+	h.enqueueCacheWrite(image, digest, tempPath, preserved)
+}
+
+type cacheLimitWriter struct {
+	w         io.Writer
+	remaining int64
+	exceeded  bool
+}
+
+func (c *cacheLimitWriter) Write(p []byte) (int, error) {
+	if c.exceeded {
+		return len(p), nil
+	}
+	if int64(len(p)) > c.remaining {
+		c.exceeded = true
+		return len(p), nil
+	}
+	c.remaining -= int64(len(p))
+	return c.w.Write(p)
+}
+
+func (h *ProxyHandler) serveCachedBlobStream(w http.ResponseWriter, r *http.Request, streamer storage.Streamer, cacheKey, digest string) bool {
+	body, info, err := streamer.GetStream(r.Context(), cacheKey)
+	if err != nil {
+		return false
+	}
+	defer body.Close()
+
+	h.log.WithFields(logrus.Fields{
+		"digest": digest,
+		"size":   info.Size,
+		"source": "s3",
+	}).Info("Serving blob from persistent cache")
+
+	if info.Digest == "" {
+		info.Digest = digest
+	}
+	w.Header().Set("Content-Type", info.MediaType)
+	w.Header().Set("Docker-Content-Digest", info.Digest)
+	w.Header().Set("Accept-Ranges", "bytes")
+	if info.Size >= 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(info.Size))
+	}
+	h.markCacheHit(w, info)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, body); err != nil {
+		h.log.WithFields(logrus.Fields{
+			"digest": digest,
+			"error":  err,
+		}).Warn("Cached blob stream interrupted")
+	}
+	return true
+}
+
+func (h *ProxyHandler) serveCachedBlobHead(w http.ResponseWriter, r *http.Request, cacheKey, digest string) bool {
+	info, err := h.storage.Stat(r.Context(), cacheKey)
+	if err != nil {
+		return false
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"digest": digest,
+		"size":   info.Size,
+		"source": "metadata",
+	}).Debug("Answering blob HEAD from cache metadata")
+
+	w.Header().Set("Content-Type", info.MediaType)
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Content-Length", fmt.Sprint(info.Size))
+	w.Header().Set("Accept-Ranges", "bytes")
+	h.markCacheHit(w, info)
+	w.WriteHeader(http.StatusOK)
+	return true
+}
+
+func (h *ProxyHandler) serveCachedBlobRange(w http.ResponseWriter, r *http.Request, cacheKey, digest, rangeHeader string) bool {
+	ranger, ok := h.storage.(storage.RangeGetter)
+	if !ok {
+		return false
+	}
+
+	info, err := h.storage.Stat(r.Context(), cacheKey)
+	if err != nil {
+		return false
+	}
+
+	start, length, ok := parseByteRange(rangeHeader, info.Size)
+	if !ok {
+		return false
+	}
+	if length <= 0 {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", info.Size))
+		writeRegistryError(w, http.StatusRequestedRangeNotSatisfiable, "BLOB_UNKNOWN", "requested range not satisfiable")
+		return true
+	}
+
+	body, err := ranger.GetRange(r.Context(), cacheKey, start, length)
+	if err != nil {
+		h.log.WithFields(logrus.Fields{
+			"digest": digest,
+			"range":  rangeHeader,
+			"error":  err,
+		}).Warn("Cached ranged read failed")
+		return false
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", info.MediaType)
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, info.Size))
+	w.Header().Set("Content-Length", fmt.Sprint(length))
+	h.markCacheHit(w, info)
+	w.WriteHeader(http.StatusPartialContent)
+
+	if _, err := io.Copy(w, body); err != nil {
+		h.log.WithFields(logrus.Fields{
+			"digest": digest,
+			"error":  err,
+		}).Warn("Cached ranged stream interrupted")
+	}
+	return true
+}
+
+func parseByteRange(header string, size int64) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	first, last, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, false
+	}
+
+	if first == "" {
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix < 0 {
+			return 0, 0, false
 		}
-	}()
+		if suffix > size {
+			suffix = size
+		}
+		return size - suffix, suffix, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+	if start >= size {
+		return start, 0, true
+	}
+
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end - start + 1, true
 }
 
 func (h *ProxyHandler) serveFromTempFile(w http.ResponseWriter, path, digest string) bool {
@@ -133,6 +455,14 @@ func (h *ProxyHandler) serveFromTempFile(w http.ResponseWriter, path, digest str
 	if err != nil || fi.Mode().Perm() != 0600 {
 		return false
 	}
+	if !h.verifyTempFile(f, digest) {
+		h.log.WithFields(logrus.Fields{
+			"digest": digest,
+			"size":   fi.Size(),
+		}).Warn("Temporary blob failed digest verification, discarding")
+		h.removeTempFile(path)
+		return false
+	}
 
 	h.log.WithFields(logrus.Fields{
 		"digest": digest,
@@ -141,6 +471,8 @@ func (h *ProxyHandler) serveFromTempFile(w http.ResponseWriter, path, digest str
 
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Content-Length", fmt.Sprint(fi.Size()))
+	h.markCacheHit(w, nil)
 	_, err = io.Copy(w, f)
 	return err == nil
 }
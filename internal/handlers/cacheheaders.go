@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/storage"
+)
+
+const (
+	cacheStatusHit   = "HIT"
+	cacheStatusMiss  = "MISS"
+	cacheStatusStale = "STALE"
+)
+
+// markCacheHit records a hit and, for a hit served from the cache index,
+// replays the preserved upstream headers and the entry's age from info.
+// Hits served from temp files or memory pass nil.
+func (h *ProxyHandler) markCacheHit(w http.ResponseWriter, info *storage.ObjectInfo) {
+	recordCacheStatus(w, cacheStatusHit)
+	if info == nil {
+		info = &storage.ObjectInfo{}
+	}
+	h.replayHeaders(w, info.Headers)
+	if !h.cfg.CacheStatusHeaders {
+		return
+	}
+
+	status := cacheStatusHit
+	if !info.StoredAt.IsZero() {
+		w.Header().Set("X-Cache-Age", fmt.Sprint(int64(time.Since(info.StoredAt).Seconds())))
+		if !info.ExpiresAt.IsZero() && time.Now().After(info.ExpiresAt) {
+			status = cacheStatusStale
+			recordCacheStatus(w, status)
+		}
+	}
+	w.Header().Set("X-Cache", status)
+}
+
+func (h *ProxyHandler) markCacheMiss(w http.ResponseWriter, upstream string) {
+	recordCacheStatus(w, cacheStatusMiss)
+	if !h.cfg.CacheStatusHeaders {
+		return
+	}
+	w.Header().Set("X-Cache", cacheStatusMiss)
+	if upstream != "" {
+		w.Header().Set("X-Upstream", upstream)
+	}
+}
+
+func recordCacheStatus(w http.ResponseWriter, status string) {
+	if lrw := accessRecord(w); lrw != nil {
+		lrw.cacheStatus = status
+	}
+}
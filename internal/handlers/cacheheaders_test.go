@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/testing/fakeregistry"
+)
+
+func TestCacheHitReplaysHeadersWithoutDatabase(t *testing.T) {
+	fake := fakeregistry.New(fakeregistry.Options{})
+	defer fake.Close()
+	image := fake.AddImage("library/alpine", "3.20", fakeregistry.Layer(map[string]string{"etc/alpine-release": "3.20.0"}))
+	_, proxy := newUpstreamProxy(t, fake.Host(), fake.Client().Transport, func(cfg *config.Config) {
+		cfg.CacheStatusHeaders = true
+		cfg.PreservedHeaders = []string{"Etag"}
+	})
+
+	if rec := pull(t, proxy, "/v2/alpine/manifests/3.20"); rec.Header().Get("X-Cache") != cacheStatusMiss {
+		t.Fatalf("first pull: X-Cache = %q, want MISS", rec.Header().Get("X-Cache"))
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		req := httptest.NewRequest(method, "/v2/alpine/manifests/3.20", nil)
+		req.Header.Set("Accept", fakeregistry.MediaTypeDockerManifest)
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("X-Cache") != cacheStatusHit {
+			t.Fatalf("%s: status = %d, X-Cache = %q", method, rec.Code, rec.Header().Get("X-Cache"))
+		}
+		if got := rec.Header().Get("Etag"); got != `"`+image.Digest+`"` {
+			t.Errorf("%s: preserved Etag = %q", method, got)
+		}
+		if rec.Header().Get("X-Cache-Age") == "" {
+			t.Errorf("%s: X-Cache-Age missing", method)
+		}
+	}
+}
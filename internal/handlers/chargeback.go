@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/cache"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const bytesPerGB = 1e9
+
+var chargebackGroups = map[string]string{
+	"identity":   "identity",
+	"repository": "repository",
+	"tenant":     "tenant",
+}
+
+type chargebackEntry struct {
+	Key           string  `json:"key,omitempty"`
+	Requests      int64   `json:"requests"`
+	CacheBytes    int64   `json:"cache_bytes"`
+	UpstreamBytes int64   `json:"upstream_bytes"`
+	UpstreamShare float64 `json:"upstream_share"`
+	EstimatedCost float64 `json:"estimated_cost,omitempty"`
+}
+
+func recordUpstreamBytes(w http.ResponseWriter, n int64) {
+	if lrw := accessRecord(w); lrw != nil {
+		lrw.upstreamBytes += int(n)
+	}
+}
+
+func rollupUsage(ctx context.Context, db *gorm.DB, entry models.AccessLog) error {
+	if entry.Repository == "" {
+		return nil
+	}
+	identity := entry.Username
+	if identity == "" {
+		identity = entry.ClientIP
+	}
+
+	row := models.UsageRollup{
+		Day:           entry.Timestamp.UTC().Truncate(24 * time.Hour),
+		Repository:    entry.Repository,
+		Identity:      identity,
+		Tenant:        entry.Tenant,
+		Requests:      1,
+		CacheBytes:    int64(entry.CacheBytes),
+		UpstreamBytes: int64(entry.UpstreamBytes),
+		LastSeen:      entry.Timestamp,
+	}
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "day"}, {Name: "repository"}, {Name: "identity"}, {Name: "tenant"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"requests":       gorm.Expr("usage_rollups.requests + 1"),
+			"cache_bytes":    gorm.Expr("usage_rollups.cache_bytes + ?", row.CacheBytes),
+			"upstream_bytes": gorm.Expr("usage_rollups.upstream_bytes + ?", row.UpstreamBytes),
+			"last_seen":      gorm.Expr("GREATEST(usage_rollups.last_seen, ?)", row.LastSeen),
+		}),
+	}).Create(&row).Error
+}
+
+func (h *ProxyHandler) Chargeback(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "chargeback")
+	query := r.URL.Query()
+
+	until := time.Now().UTC()
+	if v := query.Get("until"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid until", http.StatusBadRequest)
+			return
+		}
+		until = t.UTC()
+	}
+	since := until.Add(-30 * 24 * time.Hour)
+	if v := query.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			if d, derr := time.ParseDuration(v); derr == nil && d > 0 {
+				t, err = until.Add(-d), nil
+			}
+		}
+		if err != nil || !t.Before(until) {
+			http.Error(w, "Invalid since", http.StatusBadRequest)
+			return
+		}
+		since = t.UTC()
+	}
+	since = since.Truncate(24 * time.Hour)
+
+	groupBy := query.Get("group_by")
+	if groupBy == "" {
+		groupBy = "identity"
+	}
+	column, ok := chargebackGroups[groupBy]
+	if !ok {
+		http.Error(w, "group_by must be identity, repository or tenant", http.StatusBadRequest)
+		return
+	}
+
+	limit := 100
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 1000 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	db := h.db.WithContext(r.Context()).Model(&models.UsageRollup{}).
+		Where("day >= ? AND day <= ?", since, until)
+	if repository := query.Get("repository"); repository != "" {
+		db = db.Where("repository ~ ?", "^"+cache.PatternExpr(repository)+"$")
+	}
+	if identity := query.Get("identity"); identity != "" {
+		db = db.Where("identity = ?", identity)
+	}
+	if tenant := query.Get("tenant"); tenant != "" {
+		db = db.Where("tenant = ?", tenant)
+	}
+
+	var totals chargebackEntry
+	if err := db.Session(&gorm.Session{}).
+		Select("COALESCE(SUM(requests), 0) AS requests, COALESCE(SUM(cache_bytes), 0) AS cache_bytes, " +
+			"COALESCE(SUM(upstream_bytes), 0) AS upstream_bytes").
+		Scan(&totals).Error; err != nil {
+		log.WithError(err).Error("Chargeback totals query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	entries := []chargebackEntry{}
+	if err := db.Session(&gorm.Session{}).
+		Select(column + " AS key, SUM(requests) AS requests, SUM(cache_bytes) AS cache_bytes, " +
+			"SUM(upstream_bytes) AS upstream_bytes").
+		Group(column).
+		Order("upstream_bytes DESC, key").
+		Limit(limit).
+		Scan(&entries).Error; err != nil {
+		log.WithError(err).Error("Chargeback query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	costPerGB := h.cfg.ChargebackCostPerGB
+	if totals.UpstreamBytes > 0 {
+		totals.UpstreamShare = 1
+	}
+	totals.EstimatedCost = float64(totals.UpstreamBytes) / bytesPerGB * costPerGB
+	for i := range entries {
+		if totals.UpstreamBytes > 0 {
+			entries[i].UpstreamShare = float64(entries[i].UpstreamBytes) / float64(totals.UpstreamBytes)
+		}
+		entries[i].EstimatedCost = float64(entries[i].UpstreamBytes) / bytesPerGB * costPerGB
+	}
+
+	response := map[string]interface{}{
+		"since":    since,
+		"until":    until,
+		"group_by": groupBy,
+		"totals":   totals,
+		"entries":  entries,
+	}
+	if costPerGB > 0 {
+		response["cost_per_gb"] = costPerGB
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.WithError(err).Error("Failed to encode chargeback response")
+	}
+}
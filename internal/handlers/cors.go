@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/sdko-org/registry-proxy/internal/config"
+)
+
+const registryAllowedMethods = "GET, HEAD, OPTIONS"
+
+var corsExposedHeaders = strings.Join([]string{
+	"Content-Length",
+	"Content-Range",
+	"Docker-Content-Digest",
+	"Docker-Distribution-API-Version",
+	"Link",
+	"WWW-Authenticate",
+}, ", ")
+
+func CORSMiddleware(cfg *config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v2" && !strings.HasPrefix(r.URL.Path, "/v2/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			origin := r.Header.Get("Origin")
+			allowed := origin != "" && corsOriginAllowed(cfg.CORSAllowedOrigins, origin)
+			if allowed {
+				w.Header().Add("Vary", "Origin")
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+				if cfg.CORSAllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+
+			if r.Method != http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Allow", registryAllowedMethods)
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Methods", registryAllowedMethods)
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(cfg.CORSAllowedHeaders, ", "))
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.CORSMaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+func corsOriginAllowed(origins []string, origin string) bool {
+	for _, allowed := range origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func methodNotAllowed(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var methods []string
+		for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			var match mux.RouteMatch
+			if router.Match(probe, &match) && match.MatchErr == nil {
+				methods = append(methods, method)
+			}
+		}
+
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+}
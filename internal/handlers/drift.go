@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sdko-org/registry-proxy/internal/models"
+)
+
+func (h *ProxyHandler) Drift(w http.ResponseWriter, r *http.Request) {
+	query := h.db.WithContext(r.Context()).Order("detected_at DESC")
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if repository := r.URL.Query().Get("repository"); repository != "" {
+		query = query.Where("repository = ?", normalizeImageName(repository))
+	}
+
+	var drifts []models.TagDrift
+	if err := query.Find(&drifts).Error; err != nil {
+		h.log.WithError(err).Error("Failed to list tag drift")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tags": drifts,
+	})
+}
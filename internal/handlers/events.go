@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/events"
+)
+
+func (h *ProxyHandler) publishEvent(eventType, kind, image, reference, digest, source string, size int64) {
+	if eventType == events.TypeUpstreamFetch {
+		h.upstreamAttempts.Add(1)
+	}
+	h.events.Publish(events.Event{
+		Type:       eventType,
+		Kind:       kind,
+		Repository: image,
+		Reference:  reference,
+		Digest:     digest,
+		Source:     source,
+		Size:       size,
+	})
+}
+
+func (h *ProxyHandler) publishError(kind, image, reference string, status int, message string) {
+	h.upstreamAttempts.Add(1)
+	if status >= 500 || status == http.StatusTooManyRequests {
+		h.upstreamFailures.Add(1)
+	}
+	h.events.Publish(events.Event{
+		Type:       events.TypeError,
+		Kind:       kind,
+		Repository: image,
+		Reference:  reference,
+		Status:     status,
+		Message:    message,
+	})
+}
+
+func (h *ProxyHandler) UpstreamStats() (uint64, uint64) {
+	return h.upstreamAttempts.Load(), h.upstreamFailures.Load()
+}
+
+func eventFilter(eventTypes []string, repository string) func(events.Event) bool {
+	types := make(map[string]bool)
+	for _, t := range eventTypes {
+		if t = strings.TrimSpace(t); t != "" {
+			types[t] = true
+		}
+	}
+	return func(e events.Event) bool {
+		if len(types) > 0 && !types[e.Type] {
+			return false
+		}
+		return repository == "" || strings.HasPrefix(e.Repository, repository)
+	}
+}
+
+func (h *ProxyHandler) Drain() {
+	h.drainOnce.Do(func() {
+		close(h.draining)
+	})
+}
+
+func (h *ProxyHandler) Events(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "events")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	match := eventFilter(strings.Split(r.URL.Query().Get("types"), ","), r.URL.Query().Get("repository"))
+
+	stream, unsubscribe := h.events.Subscribe(256)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	log.WithField("subscribers", h.events.Subscribers()).Info("Event stream client connected")
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case e := <-stream:
+			if !match(e) {
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			log.Debug("Event stream client disconnected")
+			return
+		case <-h.draining:
+			log.Debug("Closing event stream for shutdown")
+			return
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	mediaTypeOCIIndex     = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerList   = "application/vnd.docker.distribution.manifest.list.v2+json"
+	referenceDigestAnnot  = "vnd.docker.reference.digest"
+	attestationPlatformOS = "unknown"
+)
+
+type indexFilterRule struct {
+	userAgent string
+	network   *net.IPNet
+	platforms []string
+}
+
+func parseIndexFilterRules(value string) ([]indexFilterRule, error) {
+	var rules []indexFilterRule
+	for _, item := range strings.Split(value, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		match, platforms, ok := strings.Cut(item, "=")
+		kind, arg, kindOK := strings.Cut(strings.TrimSpace(match), ":")
+		if !ok || !kindOK || arg == "" {
+			return nil, fmt.Errorf("rule %q must be ua:<substring>=platforms or cidr:<network>=platforms", item)
+		}
+
+		rule := indexFilterRule{}
+		switch kind {
+		case "ua":
+			rule.userAgent = strings.ToLower(arg)
+		case "cidr":
+			_, network, err := net.ParseCIDR(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cidr %q: %w", arg, err)
+			}
+			rule.network = network
+		default:
+			return nil, fmt.Errorf("unknown matcher %q", kind)
+		}
+		for _, p := range strings.Split(platforms, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				rule.platforms = append(rule.platforms, p)
+			}
+		}
+		if len(rule.platforms) == 0 {
+			return nil, fmt.Errorf("rule %q lists no platforms", item)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (h *ProxyHandler) indexFilterFor(r *http.Request, reference string) *platformFilter {
+	if !h.cfg.ManifestPlatformFilter || isDigestReference(reference) {
+		return nil
+	}
+	if platforms := r.URL.Query().Get("platform"); platforms != "" {
+		return newPlatformFilter(strings.Split(platforms, ","))
+	}
+
+	userAgent := strings.ToLower(r.UserAgent())
+	ip := net.ParseIP(getClientIP(r))
+	for _, rule := range h.indexes {
+		if (rule.userAgent != "" && strings.Contains(userAgent, rule.userAgent)) ||
+			(rule.network != nil && ip != nil && rule.network.Contains(ip)) {
+			return newPlatformFilter(rule.platforms)
+		}
+	}
+	return nil
+}
+
+func filterIndex(body []byte, filter *platformFilter) ([]byte, bool) {
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, false
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(document["manifests"], &entries); err != nil || len(entries) == 0 {
+		return nil, false
+	}
+
+	type entry struct {
+		manifestDescriptor
+		Annotations map[string]string `json:"annotations"`
+	}
+	decoded := make([]entry, len(entries))
+	kept := make(map[string]bool)
+	for i, raw := range entries {
+		if err := json.Unmarshal(raw, &decoded[i]); err != nil {
+			return nil, false
+		}
+		p := decoded[i].Platform
+		if p == nil || p.OS == attestationPlatformOS {
+			continue
+		}
+		if filter.allows(p) {
+			kept[decoded[i].Digest] = true
+		}
+	}
+	if len(kept) == 0 {
+		return nil, false
+	}
+
+	filtered := make([]json.RawMessage, 0, len(entries))
+	for i, raw := range entries {
+		p := decoded[i].Platform
+		switch {
+		case p == nil:
+			filtered = append(filtered, raw)
+		case p.OS == attestationPlatformOS:
+			if target := decoded[i].Annotations[referenceDigestAnnot]; target == "" || kept[target] {
+				filtered = append(filtered, raw)
+			}
+		case kept[decoded[i].Digest]:
+			filtered = append(filtered, raw)
+		}
+	}
+	if len(filtered) == len(entries) {
+		return nil, false
+	}
+
+	manifests, err := json.Marshal(filtered)
+	if err != nil {
+		return nil, false
+	}
+	document["manifests"] = manifests
+	rewritten, err := json.Marshal(document)
+	if err != nil {
+		return nil, false
+	}
+	return rewritten, true
+}
+
+func (h *ProxyHandler) rewriteIndex(ctx context.Context, filter *platformFilter, image, reference string, body []byte, digest, mediaType string) ([]byte, string) {
+	if filter == nil || (mediaType != mediaTypeOCIIndex && mediaType != mediaTypeDockerList) {
+		return body, digest
+	}
+	rewritten, ok := filterIndex(body, filter)
+	if !ok {
+		return body, digest
+	}
+
+	hash := sha256.Sum256(rewritten)
+	rewrittenDigest := "sha256:" + hex.EncodeToString(hash[:])
+	log := h.log.WithFields(logrus.Fields{
+		"image":           image,
+		"reference":       reference,
+		"digest":          digest,
+		"filtered_digest": rewrittenDigest,
+		"original_size":   len(body),
+		"filtered_size":   len(rewritten),
+	})
+
+	key := storage.ManifestKey(image, rewrittenDigest)
+	if _, err := h.storage.Stat(ctx, key); err != nil {
+		if err := h.storage.Put(ctx, key, rewritten, rewrittenDigest, mediaType, h.cfg.ManifestCacheTTL); err != nil {
+			log.WithError(err).Warn("Failed to cache filtered index, serving original")
+			return body, digest
+		}
+	}
+	log.Debug("Serving platform-filtered index")
+	return rewritten, rewrittenDigest
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const inflightRetention = 10 * time.Minute
+
+var errInflightAbandoned = errors.New("in-flight download abandoned")
+
+type inflightBlob struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	mediaType string
+	size      int64
+	file      *os.File
+	buffer    *bytes.Buffer
+	refs      int
+	written   int64
+	started   bool
+	finished  bool
+	err       error
+}
+
+type inflightReader struct {
+	blob   *inflightBlob
+	offset int64
+}
+
+func newInflightBlob() *inflightBlob {
+	b := &inflightBlob{size: -1}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *inflightBlob) startFile(path, mediaType string, offset, size int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.file, b.refs = f, 1
+	b.start(mediaType, offset, size)
+	b.mu.Unlock()
+	b.cond.Broadcast()
+	return nil
+}
+
+func (b *inflightBlob) startBuffer(buffer *bytes.Buffer, mediaType string, size int64) {
+	b.mu.Lock()
+	b.buffer = buffer
+	b.start(mediaType, 0, size)
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+func (b *inflightBlob) start(mediaType string, offset, size int64) {
+	b.mediaType, b.written, b.size, b.started = mediaType, offset, size, true
+}
+
+func (b *inflightBlob) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	if b.buffer != nil {
+		b.buffer.Write(p)
+	}
+	b.written += int64(len(p))
+	b.mu.Unlock()
+	b.cond.Broadcast()
+	return len(p), nil
+}
+
+func (b *inflightBlob) finish(err error) {
+	b.mu.Lock()
+	if !b.finished {
+		b.finished, b.err = true, err
+	}
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+func (b *inflightBlob) done() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.finished && b.err == nil
+}
+
+func (b *inflightBlob) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.unref()
+}
+
+func (b *inflightBlob) unref() {
+	if b.file == nil || b.refs == 0 {
+		return
+	}
+	if b.refs--; b.refs == 0 {
+		b.file.Close()
+	}
+}
+
+func (b *inflightBlob) attach() (*inflightReader, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for !b.started && !b.finished {
+		b.cond.Wait()
+	}
+	if !b.started || b.err != nil || (b.file != nil && b.refs == 0) {
+		return nil, false
+	}
+	if b.file != nil {
+		b.refs++
+	}
+	return &inflightReader{blob: b}, true
+}
+
+func (r *inflightReader) Read(p []byte) (int, error) {
+	b := r.blob
+	b.mu.Lock()
+	for r.offset >= b.written && !b.finished {
+		b.cond.Wait()
+	}
+	if b.err != nil {
+		b.mu.Unlock()
+		return 0, b.err
+	}
+	if r.offset >= b.written {
+		b.mu.Unlock()
+		return 0, io.EOF
+	}
+	n := min(int64(len(p)), b.written-r.offset)
+	if b.buffer != nil {
+		copy(p, b.buffer.Bytes()[r.offset:r.offset+n])
+		b.mu.Unlock()
+		r.offset += n
+		return int(n), nil
+	}
+	file := b.file
+	b.mu.Unlock()
+
+	read, err := file.ReadAt(p[:n], r.offset)
+	r.offset += int64(read)
+	if int64(read) == n {
+		err = nil
+	}
+	return read, err
+}
+
+func (r *inflightReader) Close() error {
+	r.blob.release()
+	return nil
+}
+
+func (h *ProxyHandler) inflightDownload(digest string) *inflightBlob {
+	if blob, ok := h.downloadMap.Load(digest); ok {
+		return blob.(*inflightBlob)
+	}
+	return nil
+}
+
+func (h *ProxyHandler) retainInflight(digest string, blob *inflightBlob) {
+	time.AfterFunc(inflightRetention, func() {
+		h.releaseInflight(digest, blob)
+	})
+}
+
+func (h *ProxyHandler) releaseInflight(digest string, blob *inflightBlob) {
+	if h.downloadMap.CompareAndDelete(digest, blob) {
+		blob.release()
+	}
+}
+
+func (h *ProxyHandler) releaseCompletedInflight(digest string) {
+	if blob := h.inflightDownload(digest); blob != nil && blob.done() {
+		h.releaseInflight(digest, blob)
+	}
+}
+
+func (h *ProxyHandler) serveFromInflight(w http.ResponseWriter, blob *inflightBlob, digest string) bool {
+	reader, ok := blob.attach()
+	if !ok {
+		return false
+	}
+	defer reader.Close()
+
+	h.log.WithFields(logrus.Fields{
+		"digest": digest,
+		"source": "inflight",
+	}).Info("Serving blob from in-flight download")
+
+	w.Header().Set("Content-Type", blob.mediaType)
+	w.Header().Set("Docker-Content-Digest", digest)
+	if blob.size >= 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(blob.size))
+	}
+	h.markCacheHit(w, nil)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, reader); err != nil {
+		h.log.WithFields(logrus.Fields{
+			"digest": digest,
+			"error":  err,
+		}).Error("In-flight blob stream interrupted after headers were sent")
+	}
+	return true
+}
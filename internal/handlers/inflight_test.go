@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+const inflightDigest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+func newInflightProxy(t *testing.T) (*ProxyHandler, *logtest.Hook) {
+	t.Helper()
+	logger, hook := logtest.NewNullLogger()
+	cfg := &config.Config{TempDir: t.TempDir()}
+	store, err := storage.NewDiskStorage(logger, t.TempDir(), 1<<30, 1<<30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewProxyHandler(logger, cfg, store, dockerhub.NewClient(logger, cfg), nil, nil, nil, nil, nil), hook
+}
+
+func startInflightFile(t *testing.T, size int64) (*inflightBlob, *os.File) {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "blob.partial"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	blob := newInflightBlob()
+	if err := blob.startFile(f.Name(), "application/octet-stream", 0, size); err != nil {
+		t.Fatal(err)
+	}
+	return blob, f
+}
+
+func writeInflight(t *testing.T, blob *inflightBlob, f *os.File, p string) {
+	t.Helper()
+	if _, err := io.MultiWriter(f, blob).Write([]byte(p)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readAllAsync(r io.Reader) <-chan []byte {
+	out := make(chan []byte, 1)
+	go func() {
+		content, _ := io.ReadAll(r)
+		out <- content
+	}()
+	return out
+}
+
+func TestInflightReaderAttachesMidDownload(t *testing.T) {
+	blob, f := startInflightFile(t, 11)
+	writeInflight(t, blob, f, "hello ")
+
+	reader, ok := blob.attach()
+	if !ok {
+		t.Fatal("attach to a started download failed")
+	}
+	defer reader.Close()
+	result := readAllAsync(reader)
+
+	select {
+	case content := <-result:
+		t.Fatalf("reader returned %q before the download finished", content)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	writeInflight(t, blob, f, "world")
+	blob.finish(nil)
+	select {
+	case content := <-result:
+		if string(content) != "hello world" {
+			t.Fatalf("reader got %q", content)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reader did not finish")
+	}
+}
+
+func TestInflightWriterFailsAfterHeaders(t *testing.T) {
+	ph, hook := newInflightProxy(t)
+	blob := newInflightBlob()
+	blob.startBuffer(&bytes.Buffer{}, "application/octet-stream", 10)
+	blob.Write([]byte("part"))
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		blob.finish(errors.New("upstream connection reset"))
+	}()
+	rec := httptest.NewRecorder()
+	if !ph.serveFromInflight(rec, blob, inflightDigest) {
+		t.Fatal("serveFromInflight refused a started download")
+	}
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Length") != "10" {
+		t.Fatalf("status = %d, Content-Length = %q", rec.Code, rec.Header().Get("Content-Length"))
+	}
+	if rec.Body.String() != "part" {
+		t.Fatalf("body = %q", rec.Body)
+	}
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.ErrorLevel || entry.Data["digest"] != inflightDigest {
+		t.Fatalf("expected an error log for the truncated stream, got %+v", entry)
+	}
+
+	if _, ok := blob.attach(); ok {
+		t.Fatal("attach succeeded on a failed download")
+	}
+}
+
+func TestInflightReleaseAfterRetentionWithReaderAttached(t *testing.T) {
+	ph, _ := newInflightProxy(t)
+	blob, f := startInflightFile(t, 7)
+	ph.downloadMap.Store(inflightDigest, blob)
+	writeInflight(t, blob, f, "layer")
+
+	reader, ok := blob.attach()
+	if !ok {
+		t.Fatal("attach failed")
+	}
+	writeInflight(t, blob, f, "!!")
+	blob.finish(nil)
+
+	ph.releaseInflight(inflightDigest, blob)
+	if ph.inflightDownload(inflightDigest) != nil {
+		t.Fatal("released download still registered")
+	}
+
+	content, err := io.ReadAll(reader)
+	if err != nil || string(content) != "layer!!" {
+		t.Fatalf("attached reader got %q: %v", content, err)
+	}
+	reader.Close()
+
+	if _, ok := blob.attach(); ok {
+		t.Fatal("attach succeeded after the last reference was released")
+	}
+	if _, err := blob.file.Stat(); err == nil {
+		t.Fatal("temp file still open after the last reader closed")
+	}
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/broadcast"
+	"github.com/sdko-org/registry-proxy/internal/cache"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type invalidationResult struct {
+	DryRun           bool     `json:"dry_run"`
+	Immediate        bool     `json:"immediate"`
+	RegistryEntries  []string `json:"registry_entries"`
+	TagRepositories  []string `json:"tag_repositories"`
+	RegistryAffected int64    `json:"registry_affected"`
+	TagsAffected     int64    `json:"tags_affected"`
+}
+
+func (h *ProxyHandler) InvalidateCache(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "cache_invalidation")
+	query := r.URL.Query()
+	image := query.Get("image")
+	tag := query.Get("tag")
+	digest := query.Get("digest")
+	dryRun, _ := strconv.ParseBool(query.Get("dry_run"))
+	immediate, _ := strconv.ParseBool(query.Get("immediate"))
+
+	var olderThan time.Duration
+	if v := query.Get("older_than"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid older_than", http.StatusBadRequest)
+			return
+		}
+		olderThan = d
+	}
+
+	if image == "" && digest == "" && olderThan == 0 {
+		http.Error(w, "image, digest or older_than is required", http.StatusBadRequest)
+		return
+	}
+	if tag != "" && image == "" {
+		http.Error(w, "tag requires image", http.StatusBadRequest)
+		return
+	}
+
+	log = log.WithFields(logrus.Fields{
+		"repository": image,
+		"tag":        tag,
+		"digest":     digest,
+		"older_than": olderThan,
+		"dry_run":    dryRun,
+	})
+
+	registryScope := func(db *gorm.DB) *gorm.DB {
+		db = imageKeyScope(image, tag)(db)
+		if digest != "" {
+			db = db.Where("digest = ?", digest)
+		}
+		if olderThan > 0 {
+			db = db.Where("stored_at < ?", time.Now().Add(-olderThan))
+		}
+		return db
+	}
+
+	tagScope := func(db *gorm.DB) *gorm.DB {
+		if image != "" {
+			db = db.Where("repository ~ ?", "^"+cache.PatternExpr(image)+"$")
+		}
+		if olderThan > 0 {
+			db = db.Where("stored_at < ?", time.Now().Add(-olderThan))
+		}
+		return db
+	}
+	invalidateTags := tag == "" && digest == ""
+
+	result := invalidationResult{
+		DryRun:          dryRun,
+		Immediate:       immediate && !dryRun,
+		RegistryEntries: []string{},
+		TagRepositories: []string{},
+	}
+
+	if err := h.db.Model(&models.RegistryCache{}).Scopes(registryScope).
+		Pluck("key", &result.RegistryEntries).Error; err != nil {
+		log.WithError(err).Error("Registry cache invalidation query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if invalidateTags {
+		if err := h.db.Model(&models.TagCache{}).Scopes(tagScope).
+			Pluck("repository", &result.TagRepositories).Error; err != nil {
+			log.WithError(err).Error("Tag cache invalidation query failed")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if !dryRun {
+		deleted := h.db.Scopes(registryScope).Delete(&models.RegistryCache{})
+		if deleted.Error != nil {
+			log.WithError(deleted.Error).Error("Registry cache invalidation failed")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		result.RegistryAffected = deleted.RowsAffected
+
+		if invalidateTags {
+			deleted = h.db.Scopes(tagScope).Delete(&models.TagCache{})
+			if deleted.Error != nil {
+				log.WithError(deleted.Error).Error("Tag cache invalidation failed")
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			result.TagsAffected = deleted.RowsAffected
+		}
+
+		if result.Immediate {
+			go h.removeInvalidated(result.RegistryEntries)
+		}
+
+		msg := broadcast.Message{Keys: result.RegistryEntries, Repositories: result.TagRepositories}
+		if image != "" {
+			msg.Repositories = append(msg.Repositories, image)
+		}
+		h.applyInvalidation(msg)
+		if err := h.bus.Publish(r.Context(), msg); err != nil {
+			log.WithError(err).Warn("Failed to broadcast cache invalidation")
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"registry_entries": len(result.RegistryEntries),
+		"tag_repositories": len(result.TagRepositories),
+	}).Info("Processed cache invalidation")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.WithError(err).Error("Failed to encode invalidation response")
+	}
+}
+
+func imageKeyScope(image, tag string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if image == "" {
+			return db
+		}
+		if tag != "" {
+			return db.Where("key ~ ?", cache.KeyRegexp(image, tag, "manifest"))
+		}
+		return db.Where("key ~ ?", cache.KeyRegexp(image, "", "blob", "manifest", "referrer"))
+	}
+}
+
+func (h *ProxyHandler) applyInvalidation(msg broadcast.Message) {
+	forgetter, _ := h.storage.(storage.Forgetter)
+	for _, key := range msg.Keys {
+		if forgetter != nil {
+			forgetter.Forget(key)
+		}
+		parsed := storage.ParseKey(key)
+		if parsed.Class != "blob" || !validDigestRegex.MatchString(parsed.Reference) {
+			continue
+		}
+		h.memory.forget(parsed.Reference)
+		h.removeTempFile(filepath.Join(h.tempDir, safeFilename(parsed.Reference)))
+	}
+	for _, repository := range msg.Repositories {
+		h.dhClient.ForgetTokens(repository)
+	}
+}
+
+func (h *ProxyHandler) removeInvalidated(keys []string) {
+	log := h.log.WithField("operation", "cache_invalidation_removal")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	failed := 0
+	for _, key := range keys {
+		if err := h.storage.Delete(ctx, key); err != nil {
+			log.WithFields(logrus.Fields{"key": key, "error": err}).Error("Failed to remove invalidated cache entry")
+			failed++
+		}
+	}
+	log.WithFields(logrus.Fields{
+		"removed": len(keys) - failed,
+		"failed":  failed,
+	}).Info("Removed invalidated cache entries")
+}
+
+func (h *ProxyHandler) RestoreCache(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "cache_restore")
+	image := r.URL.Query().Get("image")
+	digest := r.URL.Query().Get("digest")
+
+	if image == "" && digest == "" {
+		http.Error(w, "image or digest is required", http.StatusBadRequest)
+		return
+	}
+
+	graceStart := time.Now().Add(-h.cfg.InvalidationGracePeriod)
+
+	if image != "" {
+		log = log.WithField("repository", image)
+		result := h.db.Unscoped().Model(&models.TagCache{}).
+			Where("repository = ? AND deleted_at IS NOT NULL AND deleted_at > ?", image, graceStart).
+			Update("deleted_at", nil)
+		if result.Error != nil {
+			log.WithError(result.Error).Error("Tag cache restore failed")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		log.WithField("rows_affected", result.RowsAffected).Info("Restored tag cache")
+	}
+
+	registry := h.db.Unscoped().Model(&models.RegistryCache{}).Scopes(imageKeyScope(image, ""))
+	if digest != "" {
+		log = log.WithField("digest", digest)
+		registry = registry.Where("digest = ?", digest)
+	}
+	result := registry.Where("deleted_at IS NOT NULL AND deleted_at > ?", graceStart).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		log.WithError(result.Error).Error("Registry cache restore failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.WithField("rows_affected", result.RowsAffected).Info("Restored registry cache")
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/jobs"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/qos"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sdko-org/registry-proxy/internal/throttle"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var errBlobTooLarge = errors.New("blob exceeds MAX_CACHE_BLOB_SIZE")
+
+type cacheWritePayload struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+	Path   string `json:"path,omitempty"`
+
+	Headers string `json:"headers,omitempty"`
+}
+
+type prewarmPayload struct {
+	Image     string `json:"image"`
+	Reference string `json:"reference"`
+}
+
+func (h *ProxyHandler) RegisterJobHandlers(q *jobs.Queue) {
+	q.Register(jobs.TypeCacheWrite, h.runCacheWriteJob)
+	q.Register(jobs.TypePrewarm, h.runPrewarmJob)
+	q.Register(jobs.TypeReindex, h.runReindexJob)
+	q.Register(jobs.TypeSBOM, h.runSBOMJob)
+}
+
+func (h *ProxyHandler) enqueueCacheWrite(image, digest, tempPath, headers string) {
+	payload := cacheWritePayload{Image: image, Digest: digest, Path: tempPath, Headers: headers}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := h.jobs.Enqueue(ctx, jobs.TypeCacheWrite, payload); err != nil {
+		h.log.WithFields(logrus.Fields{
+			"digest": digest,
+			"error":  err,
+		}).Warn("Failed to enqueue cache write, storing directly")
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+			defer cancel()
+			h.writeBlobToCache(ctx, payload, true)
+		}()
+	}
+}
+
+func (h *ProxyHandler) runCacheWriteJob(ctx context.Context, job *models.Job) error {
+	var payload cacheWritePayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return fmt.Errorf("decode payload: %w", err)
+	}
+	if !validDigestRegex.MatchString(payload.Digest) {
+		return fmt.Errorf("invalid digest %q", payload.Digest)
+	}
+	return h.writeBlobToCache(ctx, payload, job.Attempts >= job.MaxAttempts)
+}
+
+func (h *ProxyHandler) writeBlobToCache(ctx context.Context, payload cacheWritePayload, lastAttempt bool) error {
+	log := h.log.WithFields(logrus.Fields{
+		"digest": payload.Digest,
+		"source": "s3",
+	})
+	if h.passthrough(payload.Image) {
+		log.WithField("image", payload.Image).Debug("Pass-through repository, skipping cache write")
+		if payload.Path != "" && filepath.Dir(payload.Path) == filepath.Clean(h.tempDir) {
+			h.removeTempFile(payload.Path)
+		}
+		return nil
+	}
+
+	path := payload.Path
+	if path != "" && filepath.Dir(path) != filepath.Clean(h.tempDir) {
+		path = ""
+	}
+
+	f, err := os.Open(path)
+	if err == nil && !h.verifyTempFile(f, payload.Digest) {
+		log.Warn("Temporary blob failed digest verification, discarding")
+		f.Close()
+		h.removeTempFile(path)
+		err = os.ErrNotExist
+	}
+	if path == "" || err != nil {
+		if _, statErr := h.storage.Stat(ctx, storage.BlobKey(payload.Image, payload.Digest)); statErr == nil {
+			log.Debug("Blob already cached, skipping download")
+			return nil
+		}
+		log.Debug("Temporary blob missing, downloading from upstream")
+		path, err = h.downloadBlobToTemp(ctx, payload.Image, payload.Digest)
+		if errors.Is(err, errBlobTooLarge) {
+			log.WithError(err).Info("Skipping cache write")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if f, err = os.Open(path); err != nil {
+			h.removeTempFile(path)
+			return err
+		}
+	}
+	defer f.Close()
+
+	log.Info("Storing blob in persistent cache")
+	cacheKey := storage.BlobKey(payload.Image, payload.Digest)
+	err = h.storage.PutStream(ctx, cacheKey, f, payload.Digest, "application/octet-stream", h.cfg.BlobCacheTTL)
+	if err == nil {
+		h.storeHeaders(ctx, cacheKey, payload.Headers)
+		if fi, statErr := f.Stat(); statErr == nil {
+			h.announcer.Announce(payload.Digest, fi.Size())
+			h.recordCached(payload.Image, fi.Size())
+		}
+	}
+	if err == nil || lastAttempt {
+		h.releaseCompletedInflight(payload.Digest)
+		h.removeTempFile(path)
+	}
+	return err
+}
+
+func (h *ProxyHandler) downloadBlobToTemp(ctx context.Context, image, digest string) (string, error) {
+	release, err := h.scheduler.Acquire(ctx, qos.ClassLow)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	resp, err := h.dhClient.GetBlob(ctx, image, digest, nil)
+	if err != nil {
+		return "", fmt.Errorf("blob fetch failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("blob fetch failed with status %d", resp.StatusCode)
+	}
+	body := io.Reader(resp.Body)
+	if limit := h.cfg.MaxCacheBlobSize; limit > 0 {
+		if resp.ContentLength > limit {
+			return "", errBlobTooLarge
+		}
+		body = io.LimitReader(resp.Body, limit+1)
+	}
+
+	tempFile, err := os.CreateTemp(h.tempDir, safeFilename(digest)+".job-*"+partialSuffix)
+	if err != nil {
+		return "", err
+	}
+	defer tempFile.Close()
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tempFile, hash), throttle.NewReader(ctx, h.scheduler.Reader(ctx, qos.ClassLow, body), h.throttle.Upstream()))
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	if limit := h.cfg.MaxCacheBlobSize; limit > 0 && written > limit {
+		os.Remove(tempFile.Name())
+		return "", errBlobTooLarge
+	}
+	if calculated := "sha256:" + hex.EncodeToString(hash.Sum(nil)); calculated != digest {
+		os.Remove(tempFile.Name())
+		return "", fmt.Errorf("digest mismatch: got %s", calculated)
+	}
+	path := strings.TrimSuffix(tempFile.Name(), partialSuffix)
+	if err := h.commitTempFile(tempFile, path, written); err != nil {
+		os.Remove(tempFile.Name())
+		return "", err
+	}
+	return path, nil
+}
+
+func (h *ProxyHandler) runPrewarmJob(ctx context.Context, job *models.Job) error {
+	var payload prewarmPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return fmt.Errorf("decode payload: %w", err)
+	}
+	if payload.Image == "" || payload.Reference == "" {
+		return fmt.Errorf("image and reference are required")
+	}
+
+	log := h.log.WithFields(logrus.Fields{
+		"operation": "prewarm",
+		"image":     payload.Image,
+		"reference": payload.Reference,
+	})
+	if h.passthrough(payload.Image) {
+		log.Info("Pass-through repository, skipping prewarm")
+		return nil
+	}
+
+	resp, err := h.dhClient.GetManifest(ctx, payload.Image, payload.Reference, prewarmAccept, nil)
+	if err != nil {
+		return fmt.Errorf("manifest fetch failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("manifest fetch failed with status %d", resp.StatusCode)
+	}
+
+	body, err := h.readManifest(resp, payload.Reference)
+	if err != nil {
+		return err
+	}
+	mediaType := resp.Header.Get("Content-Type")
+	if !h.allowedManifestType(mediaType) {
+		if h.cfg.UnknownManifestTypes == config.UnknownManifestPassthrough {
+			log.WithField("content_type", mediaType).Info("Unsupported manifest media type, not prewarming")
+			return nil
+		}
+		return fmt.Errorf("unsupported manifest media type %q", mediaType)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		hash := sha256.Sum256(body)
+		digest = "sha256:" + hex.EncodeToString(hash[:])
+	}
+	cacheKey := storage.ManifestKey(payload.Image, payload.Reference)
+	if err := h.storage.Put(ctx, cacheKey, body, digest, mediaType, h.manifestTTL(body)); err != nil {
+		return fmt.Errorf("manifest cache failed: %w", err)
+	}
+	h.storeHeaders(ctx, cacheKey, h.preservedHeaders(resp.Header))
+	h.linkReferences(ctx, cacheKey, payload.Image, body)
+	h.recordCached(payload.Image, int64(len(body)))
+	h.enqueueSBOM(payload.Image, payload.Reference, digest, mediaType, body)
+
+	var manifest struct {
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+		Manifests []struct {
+			Digest string `json:"digest"`
+		} `json:"manifests"`
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return fmt.Errorf("manifest parse failed: %w", err)
+	}
+
+	for _, child := range manifest.Manifests {
+		if _, err := h.jobs.Enqueue(ctx, jobs.TypePrewarm, prewarmPayload{Image: payload.Image, Reference: child.Digest}); err != nil {
+			return err
+		}
+	}
+
+	digests := make([]string, 0, len(manifest.Layers)+1)
+	if manifest.Config.Digest != "" {
+		digests = append(digests, manifest.Config.Digest)
+	}
+	for _, layer := range manifest.Layers {
+		digests = append(digests, layer.Digest)
+	}
+
+	queued := 0
+	for _, blobDigest := range digests {
+		var count int64
+		if err := h.db.WithContext(ctx).Model(&models.RegistryCache{}).
+			Where("key = ?", storage.BlobKey(payload.Image, blobDigest)).
+			Count(&count).Error; err == nil && count > 0 {
+			continue
+		}
+		if _, err := h.jobs.Enqueue(ctx, jobs.TypeCacheWrite, cacheWritePayload{Image: payload.Image, Digest: blobDigest}); err != nil {
+			return err
+		}
+		queued++
+	}
+
+	log.WithFields(logrus.Fields{
+		"child_manifests": len(manifest.Manifests),
+		"queued_blobs":    queued,
+	}).Info("Prewarmed manifest")
+	return nil
+}
+
+const prewarmAccept = "application/vnd.docker.distribution.manifest.v2+json, " +
+	"application/vnd.docker.distribution.manifest.list.v2+json, " +
+	"application/vnd.oci.image.manifest.v1+json, " +
+	"application/vnd.oci.image.index.v1+json"
+
+func (h *ProxyHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "list_jobs")
+	query := h.db.WithContext(r.Context()).Model(&models.Job{})
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if jobType := r.URL.Query().Get("type"); jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 1000 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	var list []models.Job
+	if err := query.Order("id DESC").Limit(limit).Find(&list).Error; err != nil {
+		log.WithError(err).Error("Job list query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var counts []struct {
+		Status string `json:"status"`
+		Count  int64  `json:"count"`
+	}
+	if err := h.db.WithContext(r.Context()).Model(&models.Job{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&counts).Error; err != nil {
+		log.WithError(err).Error("Job count query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"counts": counts,
+		"jobs":   list,
+	}); err != nil {
+		log.WithError(err).Error("Failed to encode job list response")
+	}
+}
+
+func (h *ProxyHandler) EnqueueJob(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Type {
+	case jobs.TypePrewarm, jobs.TypeGC, jobs.TypeReindex, jobs.TypeSBOM:
+	default:
+		http.Error(w, "Unsupported job type", http.StatusBadRequest)
+		return
+	}
+	if len(req.Payload) == 0 {
+		req.Payload = json.RawMessage("{}")
+	}
+
+	job, err := h.jobs.Enqueue(r.Context(), req.Type, req.Payload)
+	if err != nil {
+		h.log.WithError(err).Error("Failed to enqueue job")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+func (h *ProxyHandler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid job id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.jobs.Retry(r.Context(), uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Job not found in dead letter", http.StatusNotFound)
+			return
+		}
+		h.log.WithError(err).Error("Failed to retry job")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/models"
+)
+
+type lockEntry struct {
+	Image     string    `json:"image"`
+	Tag       string    `json:"tag"`
+	Digest    string    `json:"digest"`
+	Pinned    string    `json:"pinned"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastPull  time.Time `json:"last_pull"`
+}
+
+func (h *ProxyHandler) ExportLockfile(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "export_lockfile")
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "text" {
+		http.Error(w, "Invalid format", http.StatusBadRequest)
+		return
+	}
+
+	db := h.db.WithContext(r.Context()).Model(&models.PullCounter{})
+	if v := query.Get("window"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil || window <= 0 {
+			http.Error(w, "Invalid window", http.StatusBadRequest)
+			return
+		}
+		db = db.Where("last_pull >= ?", time.Now().Add(-window))
+	}
+
+	var rows []struct {
+		Repository string
+		Reference  string
+		Digest     string
+		FirstSeen  time.Time
+		LastPull   time.Time
+	}
+	if err := db.
+		Select("repository, reference, digest, MIN(day) AS first_seen, MAX(last_pull) AS last_pull").
+		Where("reference NOT LIKE ?", "%:%").
+		Group("repository, reference, digest").
+		Order("repository, reference, last_pull DESC").
+		Scan(&rows).Error; err != nil {
+		log.WithError(err).Error("Lockfile query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	pattern := query.Get("repository")
+	entries := make([]lockEntry, 0, len(rows))
+	for i, row := range rows {
+		if i > 0 && rows[i-1].Repository == row.Repository && rows[i-1].Reference == row.Reference {
+			continue
+		}
+		if pattern != "" && !config.MatchPattern(pattern, row.Repository) {
+			continue
+		}
+		image := qualifiedImageName(row.Repository)
+		entries = append(entries, lockEntry{
+			Image:     image,
+			Tag:       row.Reference,
+			Digest:    row.Digest,
+			Pinned:    fmt.Sprintf("%s:%s@%s", image, row.Reference, row.Digest),
+			FirstSeen: row.FirstSeen,
+			LastPull:  row.LastPull,
+		})
+	}
+
+	if format == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		for _, entry := range entries {
+			fmt.Fprintln(w, entry.Pinned)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="images.lock.json"`)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"generated_at": time.Now().UTC(),
+		"images":       entries,
+	}); err != nil {
+		log.WithError(err).Error("Failed to encode lockfile response")
+	}
+}
+
+func qualifiedImageName(repository string) string {
+	if host, _, found := strings.Cut(repository, "/"); found && dockerhub.IsRegistryHost(host) {
+		return repository
+	}
+	return "docker.io/" + repository
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+)
+
+const (
+	mediaTypeDockerManifest      = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeOCIManifest         = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerSchema1       = "application/vnd.docker.distribution.manifest.v1+json"
+	mediaTypeDockerSchema1Signed = "application/vnd.docker.distribution.manifest.v1+prettyjws"
+)
+
+var errManifestTooLarge = errors.New("manifest exceeds MAX_MANIFEST_SIZE")
+
+type manifestEnvelope struct {
+	SchemaVersion int                  `json:"schemaVersion"`
+	MediaType     string               `json:"mediaType"`
+	ArtifactType  string               `json:"artifactType"`
+	Config        *manifestDescriptor  `json:"config"`
+	Layers        []manifestDescriptor `json:"layers"`
+	Manifests     []manifestDescriptor `json:"manifests"`
+}
+
+func (h *ProxyHandler) readManifest(resp *http.Response, reference string) ([]byte, error) {
+	limit := h.cfg.MaxManifestSize
+	if resp.ContentLength > limit {
+		return nil, errManifestTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("manifest read failed: %w", err)
+	}
+	if int64(len(body)) > limit {
+		return nil, errManifestTooLarge
+	}
+	if resp.ContentLength >= 0 && int64(len(body)) != resp.ContentLength {
+		return nil, fmt.Errorf("manifest truncated: got %d of %d bytes", len(body), resp.ContentLength)
+	}
+	if strings.HasPrefix(reference, "sha256:") {
+		sum := sha256.Sum256(body)
+		if actual := "sha256:" + hex.EncodeToString(sum[:]); actual != reference {
+			return nil, fmt.Errorf("manifest digest mismatch: got %s", actual)
+		}
+	}
+	if !h.allowedManifestType(resp.Header.Get("Content-Type")) {
+		return body, nil
+	}
+	if err := validateManifest(resp.Header.Get("Content-Type"), body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func validateManifest(contentType string, body []byte) error {
+	var manifest manifestEnvelope
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return fmt.Errorf("manifest is not valid JSON: %w", err)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if manifest.MediaType != "" {
+		if knownManifestMediaType(mediaType) && mediaType != manifest.MediaType {
+			return fmt.Errorf("manifest mediaType %q does not match Content-Type %q", manifest.MediaType, mediaType)
+		}
+		mediaType = manifest.MediaType
+	}
+
+	switch mediaType {
+	case mediaTypeDockerManifest, mediaTypeOCIManifest:
+		if manifest.SchemaVersion != 2 {
+			return fmt.Errorf("unexpected schemaVersion %d", manifest.SchemaVersion)
+		}
+		if manifest.Config == nil || !validDigestRegex.MatchString(manifest.Config.Digest) {
+			return errors.New("manifest config descriptor is missing or invalid")
+		}
+		for _, layer := range manifest.Layers {
+			if !validDigestRegex.MatchString(layer.Digest) || layer.Size < 0 {
+				return fmt.Errorf("invalid layer descriptor %q", layer.Digest)
+			}
+		}
+		if mediaType == mediaTypeDockerManifest && len(manifest.Layers) == 0 {
+			return errors.New("manifest has no layers")
+		}
+	case mediaTypeDockerList, mediaTypeOCIIndex:
+		if manifest.SchemaVersion != 2 {
+			return fmt.Errorf("unexpected schemaVersion %d", manifest.SchemaVersion)
+		}
+		if mediaType == mediaTypeDockerList && len(manifest.Manifests) == 0 {
+			return errors.New("manifest list is empty")
+		}
+		for _, child := range manifest.Manifests {
+			if !validDigestRegex.MatchString(child.Digest) {
+				return fmt.Errorf("invalid child manifest descriptor %q", child.Digest)
+			}
+		}
+	}
+	return nil
+}
+
+func knownManifestMediaType(mediaType string) bool {
+	switch mediaType {
+	case mediaTypeDockerManifest, mediaTypeOCIManifest, mediaTypeDockerList, mediaTypeOCIIndex:
+		return true
+	}
+	return false
+}
+
+func (h *ProxyHandler) allowedManifestType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case mediaTypeDockerSchema1, mediaTypeDockerSchema1Signed:
+		return h.cfg.Schema1Manifests == config.Schema1Allow
+	}
+	return knownManifestMediaType(mediaType) || containsString(h.cfg.ManifestExtraMediaTypes, mediaType)
+}
+
+func (h *ProxyHandler) manifestTTL(body []byte) time.Duration {
+	if len(h.cfg.ArtifactCacheTTLs) == 0 {
+		return h.cfg.ManifestCacheTTL
+	}
+	var manifest manifestEnvelope
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return h.cfg.ManifestCacheTTL
+	}
+	artifactType := manifest.ArtifactType
+	if artifactType == "" && manifest.Config != nil {
+		artifactType = manifest.Config.MediaType
+	}
+	if ttl, ok := h.cfg.ArtifactCacheTTLs[strings.ToLower(artifactType)]; ok {
+		return ttl
+	}
+	return h.cfg.ManifestCacheTTL
+}
+
+func acceptsManifest(accept, contentType string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, item := range strings.Split(accept, ",") {
+		accepted, _, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		if accepted == mediaType || accepted == "*/*" || accepted == "application/*" {
+			return true
+		}
+	}
+	return false
+}
+
+func isSchema1Manifest(contentType string, body []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case mediaTypeDockerSchema1, mediaTypeDockerSchema1Signed:
+		return true
+	case "", "application/json", "text/plain":
+		var manifest manifestEnvelope
+		return json.Unmarshal(body, &manifest) == nil && manifest.SchemaVersion == 1
+	}
+	return false
+}
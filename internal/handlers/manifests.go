@@ -5,28 +5,67 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"io"
 	"net/http"
 
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/events"
+	"github.com/sdko-org/registry-proxy/internal/storage"
 	"github.com/sirupsen/logrus"
 )
 
 func (h *ProxyHandler) handleManifest(w http.ResponseWriter, r *http.Request, image, reference string) {
 	ctx := context.Background()
-	cacheKey := fmt.Sprintf("manifests/%s/%s", image, reference)
+	cacheKey := storage.ManifestKey(image, reference)
+	indexFilter := h.indexFilterFor(r, reference)
+	passthrough := h.passthrough(image)
+	if passthrough {
+		indexFilter = nil
+	}
+
+	if r.Method == http.MethodHead && indexFilter == nil && !passthrough {
+		if info, err := h.storage.Stat(ctx, cacheKey); err == nil && h.allowedManifestType(info.MediaType) && acceptsManifest(r.Header.Get("Accept"), info.MediaType) {
+			w.Header().Set("Content-Type", info.MediaType)
+			w.Header().Set("Docker-Content-Digest", info.Digest)
+			w.Header().Set("Content-Length", fmt.Sprint(info.Size))
+			h.markCacheHit(w, info)
+			h.touchManifest(ctx, cacheKey)
+			w.WriteHeader(http.StatusOK)
+			h.publishEvent(events.TypeCacheHit, "manifest", image, reference, info.Digest, "metadata", info.Size)
+			return
+		}
+	}
 
-	content, digest, mediaType, err := h.storage.Get(ctx, cacheKey)
-	if err == nil {
+	var content []byte
+	var digest, mediaType string
+	var info *storage.ObjectInfo
+	cached := false
+	if !passthrough {
+		var err error
+		content, info, err = storage.ReadObject(ctx, h.storage, cacheKey)
+		if err == nil {
+			digest, mediaType = info.Digest, info.MediaType
+		}
+		cached = err == nil && h.allowedManifestType(mediaType) && acceptsManifest(r.Header.Get("Accept"), mediaType)
+	}
+	if cached {
 		h.log.WithFields(logrus.Fields{
 			"image":     image,
 			"reference": reference,
 			"source":    "s3",
 		}).Info("Serving manifest from cache")
+		h.platforms.cacheable(digest, content)
+		content, digest = h.rewriteIndex(ctx, indexFilter, image, reference, content, digest, mediaType)
 		w.Header().Set("Content-Type", mediaType)
 		w.Header().Set("Docker-Content-Digest", digest)
 		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		h.markCacheHit(w, info)
+		h.touchManifest(ctx, cacheKey)
 		w.WriteHeader(http.StatusOK)
 		w.Write(content)
+		h.publishEvent(events.TypeCacheHit, "manifest", image, reference, digest, "s3", int64(len(content)))
+		h.recordPull(r, image, reference, digest)
+		h.prefetchLayers(image, reference, content)
 		return
 	}
 
@@ -35,32 +74,115 @@ func (h *ProxyHandler) handleManifest(w http.ResponseWriter, r *http.Request, im
 		"reference": reference,
 		"source":    "dockerhub",
 	}).Info("Fetching manifest from upstream")
-	resp, err := h.dhClient.GetManifest(ctx, image, reference, r.Header.Get("Accept"))
+	h.publishEvent(events.TypeCacheMiss, "manifest", image, reference, "", "", 0)
+	resp, err := h.dhClient.GetManifest(ctx, image, reference, r.Header.Get("Accept"), dockerhub.ConditionalHeaders(r))
 	if err != nil {
+		h.publishError("manifest", image, reference, http.StatusBadGateway, err.Error())
 		http.Error(w, "Failed to fetch manifest", http.StatusBadGateway)
 		return
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		h.log.WithFields(logrus.Fields{
+			"image":     image,
+			"reference": reference,
+		}).Debug("Upstream manifest not modified, relaying 304")
+		h.markCacheMiss(w, h.dhClient.UpstreamHost(image))
+		forwardResponse(w, resp)
+		return
+	}
 	if resp.StatusCode != http.StatusOK {
+		h.publishError("manifest", image, reference, resp.StatusCode, "upstream returned non-200 status")
 		forwardResponse(w, resp)
 		return
 	}
 
-	body, _ := io.ReadAll(resp.Body)
+	body, err := h.readManifest(resp, reference)
+	recordUpstreamBytes(w, int64(len(body)))
+	if err != nil {
+		h.log.WithFields(logrus.Fields{
+			"image":     image,
+			"reference": reference,
+			"error":     err,
+		}).Warn("Rejecting invalid upstream manifest")
+		h.publishError("manifest", image, reference, http.StatusBadGateway, err.Error())
+		writeRegistryError(w, http.StatusBadGateway, "MANIFEST_INVALID", "upstream returned an invalid manifest")
+		return
+	}
 	mediaType = resp.Header.Get("Content-Type")
 	digest = resp.Header.Get("Docker-Content-Digest")
+	preserved := h.preservedHeaders(resp.Header)
 	if digest == "" {
 		hash := sha256.Sum256(body)
 		digest = "sha256:" + hex.EncodeToString(hash[:])
 	}
 
-	if err := h.storage.Put(ctx, cacheKey, body, digest, mediaType, h.cfg.ManifestCacheTTL); err != nil {
+	schema1 := isSchema1Manifest(mediaType, body)
+	if schema1 && h.cfg.Schema1Manifests == config.Schema1Reject {
+		h.log.WithFields(logrus.Fields{
+			"image":     image,
+			"reference": reference,
+		}).Warn("Rejecting deprecated schema1 manifest")
+		h.publishError("manifest", image, reference, http.StatusBadRequest, "schema1 manifest rejected")
+		writeRegistryError(w, http.StatusBadRequest, "UNSUPPORTED", "image uses a deprecated schema1 manifest; rebuild and push it with a current client")
+		return
+	}
+	allowed := schema1 || h.allowedManifestType(mediaType)
+	if !allowed && h.cfg.UnknownManifestTypes == config.UnknownManifestReject {
+		h.log.WithFields(logrus.Fields{
+			"image":        image,
+			"reference":    reference,
+			"content_type": mediaType,
+		}).Warn("Rejecting upstream manifest with unsupported media type")
+		h.publishError("manifest", image, reference, http.StatusBadGateway, "unsupported manifest media type")
+		writeRegistryError(w, http.StatusBadGateway, "MANIFEST_INVALID", "upstream returned an unsupported manifest media type")
+		return
+	}
+
+	uncachedSchema1 := schema1 && h.cfg.Schema1Manifests == config.Schema1Passthrough
+	cacheable := !passthrough && allowed && !uncachedSchema1 && h.platforms.cacheable(digest, body)
+	if passthrough {
+		h.log.WithFields(logrus.Fields{
+			"image":     image,
+			"reference": reference,
+		}).Debug("Pass-through repository, not caching manifest")
+	} else if !allowed {
+		h.log.WithFields(logrus.Fields{
+			"image":        image,
+			"reference":    reference,
+			"content_type": mediaType,
+		}).Info("Relaying manifest with unsupported media type without caching")
+	} else if uncachedSchema1 {
+		h.log.WithFields(logrus.Fields{
+			"image":     image,
+			"reference": reference,
+		}).Info("Relaying deprecated schema1 manifest without caching")
+	} else if !cacheable {
+		h.log.WithFields(logrus.Fields{
+			"image":     image,
+			"reference": reference,
+			"digest":    digest,
+		}).Debug("Skipping cache for excluded platform manifest")
+	} else if err := h.storage.Put(ctx, cacheKey, body, digest, mediaType, h.manifestTTL(body)); err != nil {
 		h.log.WithError(err).Error("Failed to cache manifest")
+	} else {
+		h.storeHeaders(ctx, cacheKey, preserved)
+		h.linkReferences(ctx, cacheKey, image, body)
+		h.recordCached(image, int64(len(body)))
+		h.enqueueSBOM(image, reference, digest, mediaType, body)
 	}
 
+	if cacheable {
+		h.prefetchLayers(image, reference, body)
+	}
+	body, digest = h.rewriteIndex(ctx, indexFilter, image, reference, body, digest, mediaType)
 	w.Header().Set("Content-Type", mediaType)
 	w.Header().Set("Docker-Content-Digest", digest)
+	h.replayHeaders(w, preserved)
+	h.markCacheMiss(w, h.dhClient.UpstreamHost(image))
 	w.WriteHeader(resp.StatusCode)
 	w.Write(body)
+	h.publishEvent(events.TypeUpstreamFetch, "manifest", image, reference, digest, "dockerhub", int64(len(body)))
+	h.recordPull(r, image, reference, digest)
 }
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+type memoryStore struct {
+	threshold int64
+	limit     int64
+	used      atomic.Int64
+	blobs     sync.Map
+}
+
+func newMemoryStore(threshold, limit int64) *memoryStore {
+	if threshold <= 0 || limit <= 0 {
+		return nil
+	}
+	return &memoryStore{threshold: threshold, limit: limit}
+}
+
+func (m *memoryStore) reserve(size int64) bool {
+	if m == nil || size <= 0 || size > m.threshold {
+		return false
+	}
+	for {
+		used := m.used.Load()
+		if used+size > m.limit {
+			return false
+		}
+		if m.used.CompareAndSwap(used, used+size) {
+			return true
+		}
+	}
+}
+
+func (m *memoryStore) release(size int64) {
+	m.used.Add(-size)
+}
+
+func (m *memoryStore) get(digest string) ([]byte, bool) {
+	if m == nil {
+		return nil, false
+	}
+	data, ok := m.blobs.Load(digest)
+	if !ok {
+		return nil, false
+	}
+	return data.([]byte), true
+}
+
+func (m *memoryStore) forget(digest string) {
+	if m == nil {
+		return
+	}
+	m.blobs.Delete(digest)
+}
+
+func (h *ProxyHandler) serveFromMemory(w http.ResponseWriter, digest string) bool {
+	data, ok := h.memory.get(digest)
+	if !ok {
+		return false
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"digest": digest,
+		"source": "memory",
+	}).Info("Serving blob from memory buffer")
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	h.markCacheHit(w, nil)
+	w.Write(data)
+	return true
+}
+
+func (h *ProxyHandler) cacheFromMemory(image, digest string, data []byte, headers string) {
+	h.memory.blobs.Store(digest, data)
+
+	go func() {
+		defer func() {
+			h.memory.blobs.Delete(digest)
+			h.memory.release(int64(len(data)))
+		}()
+		log := h.log.WithFields(logrus.Fields{
+			"digest": digest,
+			"size":   len(data),
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		cacheKey := storage.BlobKey(image, digest)
+		if err := h.storage.Put(ctx, cacheKey, data, digest, "application/octet-stream", h.cfg.BlobCacheTTL); err != nil {
+			log.WithError(err).Warn("Failed to store buffered blob, spilling to temporary storage")
+			h.spillToTemp(image, digest, data, headers, log)
+			return
+		}
+		h.storeHeaders(ctx, cacheKey, headers)
+		h.announcer.Announce(digest, int64(len(data)))
+		h.recordCached(image, int64(len(data)))
+	}()
+}
+
+func (h *ProxyHandler) spillToTemp(image, digest string, data []byte, headers string, log *logrus.Entry) {
+	path := filepath.Join(h.tempDir, safeFilename(digest))
+	f, err := os.OpenFile(path+partialSuffix, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		log.WithError(err).Warn("Failed to create temporary blob")
+		return
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		log.WithError(err).Warn("Failed to write temporary blob")
+		return
+	}
+	if err := h.commitTempFile(f, path, int64(len(data))); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		log.WithError(err).Warn("Failed to commit temporary blob")
+		return
+	}
+	h.enqueueCacheWrite(image, digest, path, headers)
+}
@@ -24,14 +24,64 @@ type loggingResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
 	bytesSent  int
+	username   string
+	started    bool
+
+	repository  string
+	reference   string
+	resource    string
+	cacheStatus string
+	original    string
+
+	tenant string
+
+	upstreamBytes int
+}
+
+func accessRecord(w http.ResponseWriter) *loggingResponseWriter {
+	for {
+		switch rw := w.(type) {
+		case *loggingResponseWriter:
+			return rw
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+func (lrw *loggingResponseWriter) bytesByOrigin() (cached, upstream int) {
+	switch lrw.cacheStatus {
+	case cacheStatusHit, cacheStatusStale:
+		cached = lrw.bytesSent
+	case cacheStatusMiss:
+		upstream = lrw.bytesSent
+	}
+	if lrw.upstreamBytes > 0 {
+		upstream = lrw.upstreamBytes
+	}
+	return cached, upstream
 }
 
 func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	lrw.statusCode = code
+	lrw.started = true
 	lrw.ResponseWriter.WriteHeader(code)
 }
 
+func (lrw *loggingResponseWriter) Flush() {
+	if flusher, ok := lrw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
+
 func (lrw *loggingResponseWriter) Write(b []byte) (int, error) {
+	lrw.started = true
 	n, err := lrw.ResponseWriter.Write(b)
 	lrw.bytesSent += n
 	return n, err
@@ -61,8 +111,28 @@ func LoggingMiddleware(logger *logrus.Logger, db *gorm.DB) func(http.Handler) ht
 					"bytes":      lrw.bytesSent,
 					"user_agent": r.UserAgent(),
 				}
+				if lrw.username != "" {
+					fields["username"] = lrw.username
+				}
+				if lrw.tenant != "" {
+					fields["tenant"] = lrw.tenant
+				}
+				if lrw.repository != "" {
+					fields["repository"] = lrw.repository
+					fields["reference"] = lrw.reference
+					fields["resource"] = lrw.resource
+				}
+				if lrw.original != "" {
+					fields["rewritten_from"] = lrw.original
+				}
+				if lrw.cacheStatus != "" {
+					fields["cache"] = lrw.cacheStatus
+				}
 
 				logEntry.WithFields(fields).Info("Request processed")
+				if db == nil {
+					return
+				}
 
 				go func() {
 					ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -77,11 +147,22 @@ func LoggingMiddleware(logger *logrus.Logger, db *gorm.DB) func(http.Handler) ht
 						ClientIP:  getClientIP(r),
 						UserAgent: r.UserAgent(),
 						BytesSent: lrw.bytesSent,
+						Username:  lrw.username,
+						Tenant:    lrw.tenant,
+
+						Repository:  lrw.repository,
+						Reference:   lrw.reference,
+						Resource:    lrw.resource,
+						CacheStatus: lrw.cacheStatus,
 					}
+					entry.CacheBytes, entry.UpstreamBytes = lrw.bytesByOrigin()
 
 					if err := db.WithContext(ctx).Create(&entry).Error; err != nil {
 						logEntry.WithError(err).Warn("Failed to save access log")
 					}
+					if err := rollupUsage(ctx, db, entry); err != nil {
+						logEntry.WithError(err).Warn("Failed to record usage rollup")
+					}
 				}()
 			}()
 
@@ -119,6 +200,26 @@ func RateLimitMiddleware(cfg *config.Config) func(http.Handler) http.Handler {
 	}
 }
 
+func RequestLimitsMiddleware(cfg *config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(r.RequestURI) > cfg.MaxURLLength {
+				http.Error(w, "Request URI too long", http.StatusRequestURITooLong)
+				return
+			}
+			if cfg.MaxBodyBytes > 0 {
+				if r.ContentLength > cfg.MaxBodyBytes {
+					http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxBodyBytes)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func getClientIP(r *http.Request) string {
 	ip := r.Header.Get("X-Forwarded-For")
 	if ip == "" {
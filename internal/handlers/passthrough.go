@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/events"
+	"github.com/sdko-org/registry-proxy/internal/qos"
+	"github.com/sdko-org/registry-proxy/internal/throttle"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *ProxyHandler) passthrough(image string) bool {
+	for _, pattern := range h.cfg.PassthroughRepositories {
+		if config.MatchPattern(pattern, image) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *ProxyHandler) passthroughBlob(w http.ResponseWriter, r *http.Request, image, digest string) {
+	ctx := context.Background()
+	h.publishEvent(events.TypeCacheMiss, "blob", image, "", digest, "", 0)
+	class := qos.ClassFromContext(r.Context())
+	release, err := h.scheduler.Acquire(r.Context(), class)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	h.log.WithFields(logrus.Fields{
+		"image":  image,
+		"digest": digest,
+		"source": "dockerhub",
+	}).Info("Streaming pass-through blob from upstream")
+	resp, err := h.dhClient.GetBlob(ctx, image, digest, dockerhub.ConditionalHeaders(r))
+	if err != nil {
+		h.publishError("blob", image, digest, http.StatusBadGateway, err.Error())
+		http.Error(w, "Blob fetch failed", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	upstream := h.dhClient.UpstreamHost(image)
+	if resp.StatusCode == http.StatusNotModified {
+		h.markCacheMiss(w, upstream)
+		forwardResponse(w, resp)
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		h.publishError("blob", image, digest, resp.StatusCode, "upstream returned non-200 status")
+		forwardResponse(w, resp)
+		return
+	}
+
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.Header().Set("Docker-Content-Digest", digest)
+	if resp.ContentLength >= 0 {
+		w.Header().Set("Content-Length", resp.Header.Get("Content-Length"))
+	}
+	h.replayHeaders(w, h.preservedHeaders(resp.Header))
+	h.markCacheMiss(w, upstream)
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(w, hash), throttle.NewReader(ctx, h.scheduler.Reader(r.Context(), class, resp.Body), h.throttle.Upstream()))
+	recordUpstreamBytes(w, written)
+	if err != nil {
+		h.publishError("blob", image, digest, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if calculated := "sha256:" + hex.EncodeToString(hash.Sum(nil)); calculated != digest {
+		h.log.WithFields(logrus.Fields{
+			"expected": digest,
+			"actual":   calculated,
+		}).Error("Pass-through blob digest mismatch")
+		h.publishError("blob", image, digest, http.StatusBadGateway, "digest mismatch")
+		return
+	}
+	h.publishEvent(events.TypeUpstreamFetch, "blob", image, "", digest, "dockerhub", written)
+}
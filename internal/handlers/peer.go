@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/peer"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const peerDigestPageSize = 5000
+
+func (h *ProxyHandler) PeerDigests(w http.ResponseWriter, r *http.Request) {
+	if !peer.Authorized(r, h.cfg.PeerCredential()) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	log := h.log.WithField("operation", "peer_digests")
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			http.Error(w, "Invalid since", http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+
+	var entries []models.RegistryCache
+	if err := h.db.WithContext(r.Context()).
+		Select("digest, size_bytes, stored_at").
+		Where("type = ? AND stored_at > ? AND expires_at > ?", "blob", since, time.Now()).
+		Order("stored_at").
+		Limit(peerDigestPageSize).
+		Find(&entries).Error; err != nil {
+		log.WithError(err).Error("Peer digest query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	list := peer.DigestList{Digests: make([]peer.Advertisement, 0, len(entries)), Next: since}
+	for _, entry := range entries {
+		list.Digests = append(list.Digests, peer.Advertisement{
+			Digest:   entry.Digest,
+			Size:     entry.SizeBytes,
+			StoredAt: entry.StoredAt,
+		})
+		list.Next = entry.StoredAt
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		log.WithError(err).Error("Failed to encode peer digest response")
+	}
+}
+
+func (h *ProxyHandler) PeerBlob(w http.ResponseWriter, r *http.Request) {
+	if !peer.Authorized(r, h.cfg.PeerCredential()) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.serveBlobByDigest(w, r, mux.Vars(r)["digest"], "peer_blob")
+}
+
+func (h *ProxyHandler) P2PBlob(w http.ResponseWriter, r *http.Request) {
+	if h.cfg.P2PSeedURL == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if _, token := h.cfg.P2PCredentials(); !peer.Authorized(r, token) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	digest := mux.Vars(r)["digest"]
+	if !validDigestRegex.MatchString(digest) {
+		http.Error(w, "Invalid digest format", http.StatusBadRequest)
+		return
+	}
+	held, err := h.digestQuarantined(r.Context(), digest)
+	if err != nil {
+		h.log.WithError(err).Error("Quarantine check failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if held {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	h.serveBlobByDigest(w, r, digest, "p2p_blob")
+}
+
+func (h *ProxyHandler) serveBlobByDigest(w http.ResponseWriter, r *http.Request, digest, operation string) {
+	if !validDigestRegex.MatchString(digest) {
+		http.Error(w, "Invalid digest format", http.StatusBadRequest)
+		return
+	}
+	log := h.log.WithFields(logrus.Fields{
+		"operation": operation,
+		"digest":    digest,
+	})
+
+	var entry models.RegistryCache
+	result := h.db.WithContext(r.Context()).
+		Select("key").
+		Where("type = ? AND digest = ? AND expires_at > ?", "blob", digest, time.Now()).
+		Limit(1).
+		Find(&entry)
+	if result.Error != nil {
+		log.WithError(result.Error).Error("Blob lookup failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodHead && h.serveCachedBlobHead(w, r, entry.Key, digest) {
+		return
+	}
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && h.serveCachedBlobRange(w, r, entry.Key, digest, rangeHeader) {
+		return
+	}
+
+	if streamer, ok := h.storage.(storage.Streamer); ok {
+		body, info, err := streamer.GetStream(r.Context(), entry.Key)
+		if err != nil {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		defer body.Close()
+
+		w.Header().Set("Content-Type", info.MediaType)
+		w.Header().Set("Docker-Content-Digest", digest)
+		if info.Size >= 0 {
+			w.Header().Set("Content-Length", fmt.Sprint(info.Size))
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, body); err != nil {
+			log.WithError(err).Warn("Blob stream interrupted")
+		}
+		return
+	}
+
+	content, _, mediaType, err := h.storage.Get(r.Context(), entry.Key)
+	if err != nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+func (h *ProxyHandler) PeerStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"peers": h.peers.Status(),
+	}); err != nil {
+		h.log.WithError(err).Error("Failed to encode peer status response")
+	}
+}
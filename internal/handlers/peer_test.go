@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestP2PBlobFailsClosed(t *testing.T) {
+	logger, _ := logtest.NewNullLogger()
+	store, err := storage.NewDiskStorage(logger, t.TempDir(), 1<<30, 1<<30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		serveToken string
+		header     string
+	}{
+		{"no serve token", "", ""},
+		{"no serve token, empty bearer", "", "Bearer "},
+		{"missing header", "seed-secret", ""},
+		{"wrong token", "seed-secret", "Bearer guess"},
+	} {
+		cfg := &config.Config{TempDir: t.TempDir(), P2PSeedURL: "http://seed.invalid", P2PServeToken: tc.serveToken}
+		ph := NewProxyHandler(logger, cfg, store, dockerhub.NewClient(logger, cfg), nil, nil, nil, nil, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/p2p/blobs/"+inflightDigest, nil)
+		req = mux.SetURLVars(req, map[string]string{"digest": inflightDigest})
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		rec := httptest.NewRecorder()
+		ph.P2PBlob(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want 401", tc.name, rec.Code)
+		}
+	}
+}
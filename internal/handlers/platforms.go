@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	platformSkipTTL        = 24 * time.Hour
+	platformSkipMaxEntries = 100000
+)
+
+type platformSpec struct {
+	os           string
+	architecture string
+	variant      string
+}
+
+type manifestPlatform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant"`
+}
+
+type manifestDescriptor struct {
+	MediaType string            `json:"mediaType"`
+	Digest    string            `json:"digest"`
+	Size      int64             `json:"size"`
+	Platform  *manifestPlatform `json:"platform,omitempty"`
+}
+
+type manifestDocument struct {
+	Manifests []manifestDescriptor `json:"manifests"`
+	Config    manifestDescriptor   `json:"config"`
+	Layers    []manifestDescriptor `json:"layers"`
+}
+
+type platformFilter struct {
+	allowed []platformSpec
+	mu      sync.Mutex
+	skipped map[string]time.Time
+}
+
+func newPlatformFilter(platforms []string) *platformFilter {
+	if len(platforms) == 0 {
+		return nil
+	}
+
+	f := &platformFilter{skipped: make(map[string]time.Time)}
+	for _, p := range platforms {
+		parts := strings.SplitN(strings.ToLower(p), "/", 3)
+		spec := platformSpec{os: parts[0]}
+		if len(parts) > 1 {
+			spec.architecture = parts[1]
+		}
+		if len(parts) > 2 {
+			spec.variant = parts[2]
+		}
+		f.allowed = append(f.allowed, spec)
+	}
+	return f
+}
+
+func (f *platformFilter) allows(p *manifestPlatform) bool {
+	for _, spec := range f.allowed {
+		if spec.os != strings.ToLower(p.OS) {
+			continue
+		}
+		if spec.architecture != "" && spec.architecture != strings.ToLower(p.Architecture) {
+			continue
+		}
+		if spec.variant != "" && spec.variant != strings.ToLower(p.Variant) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func (f *platformFilter) permits(p *manifestPlatform) bool {
+	return f == nil || p == nil || f.allows(p)
+}
+
+func (p *manifestPlatform) String() string {
+	if p.Variant != "" {
+		return p.OS + "/" + p.Architecture + "/" + p.Variant
+	}
+	return p.OS + "/" + p.Architecture
+}
+
+func (f *platformFilter) cacheable(digest string, body []byte) bool {
+	if f == nil {
+		return true
+	}
+
+	var doc manifestDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return true
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(doc.Manifests) > 0 {
+		for _, child := range doc.Manifests {
+			if child.Platform == nil || f.allows(child.Platform) {
+				delete(f.skipped, child.Digest)
+			} else {
+				f.mark(child.Digest)
+			}
+		}
+		return true
+	}
+
+	blobs := append([]manifestDescriptor{doc.Config}, doc.Layers...)
+	if !f.isSkipped(digest) {
+		for _, blob := range blobs {
+			delete(f.skipped, blob.Digest)
+		}
+		return true
+	}
+
+	for _, blob := range blobs {
+		if blob.Digest != "" {
+			f.mark(blob.Digest)
+		}
+	}
+	return false
+}
+
+func (f *platformFilter) skipBlob(digest string) bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.isSkipped(digest)
+}
+
+func (f *platformFilter) isSkipped(digest string) bool {
+	expiry, ok := f.skipped[digest]
+	if !ok {
+		return false
+	}
+	if time.Now().After(expiry) {
+		delete(f.skipped, digest)
+		return false
+	}
+	return true
+}
+
+func (f *platformFilter) mark(digest string) {
+	if len(f.skipped) >= platformSkipMaxEntries {
+		now := time.Now()
+		for d, expiry := range f.skipped {
+			if now.After(expiry) {
+				delete(f.skipped, d)
+			}
+		}
+	}
+	f.skipped[digest] = time.Now().Add(platformSkipTTL)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/jobs"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *ProxyHandler) prefetchLayers(image, reference string, content []byte) {
+	if !h.cfg.PrefetchLayers || h.jobs == nil || h.db == nil || h.passthrough(image) {
+		return
+	}
+
+	var manifest struct {
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+		Layers []struct {
+			Digest string `json:"digest"`
+			Size   int64  `json:"size"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil || len(manifest.Layers) == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		log := h.log.WithFields(logrus.Fields{
+			"operation": "prefetch_layers",
+			"image":     image,
+			"reference": reference,
+		})
+
+		digests := make([]string, 0, len(manifest.Layers)+1)
+		if manifest.Config.Digest != "" {
+			digests = append(digests, manifest.Config.Digest)
+		}
+		for _, layer := range manifest.Layers {
+			if h.cfg.PrefetchMaxLayerSize > 0 && layer.Size > h.cfg.PrefetchMaxLayerSize {
+				continue
+			}
+			if h.cfg.MaxCacheBlobSize > 0 && layer.Size > h.cfg.MaxCacheBlobSize {
+				continue
+			}
+			digests = append(digests, layer.Digest)
+		}
+
+		queued := 0
+		for _, digest := range digests {
+			if !validDigestRegex.MatchString(digest) {
+				continue
+			}
+			if _, err := h.storage.Stat(ctx, storage.BlobKey(image, digest)); err == nil {
+				continue
+			}
+			if _, downloading := h.downloadMap.Load(digest); downloading {
+				continue
+			}
+
+			payload := cacheWritePayload{Image: image, Digest: digest}
+			raw, _ := json.Marshal(payload)
+			var pending int64
+			if err := h.db.WithContext(ctx).Model(&models.Job{}).
+				Where("type = ? AND payload = ? AND status IN ?", jobs.TypeCacheWrite, string(raw), []string{jobs.StatusPending, jobs.StatusRunning}).
+				Count(&pending).Error; err != nil || pending > 0 {
+				continue
+			}
+			if _, err := h.jobs.Enqueue(ctx, jobs.TypeCacheWrite, payload); err != nil {
+				log.WithError(err).Warn("Failed to enqueue layer prefetch")
+				return
+			}
+			queued++
+		}
+
+		if queued > 0 {
+			log.WithFields(logrus.Fields{
+				"layers":       len(manifest.Layers),
+				"queued_blobs": queued,
+			}).Info("Queued layer prefetch")
+		}
+	}()
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *ProxyHandler) preservedHeaders(upstream http.Header) string {
+	if len(h.cfg.PreservedHeaders) == 0 {
+		return ""
+	}
+
+	selected := make(http.Header)
+	for _, name := range h.cfg.PreservedHeaders {
+		if values := upstream.Values(name); len(values) > 0 {
+			selected[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	if len(selected) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(selected)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func (h *ProxyHandler) storeHeaders(ctx context.Context, cacheKey, headers string) {
+	store, ok := h.storage.(storage.HeaderStore)
+	if len(h.cfg.PreservedHeaders) == 0 || !ok {
+		return
+	}
+	if err := store.StoreHeaders(ctx, cacheKey, headers); err != nil {
+		h.log.WithFields(logrus.Fields{
+			"operation": "store_headers",
+			"key":       cacheKey,
+			"error":     err,
+		}).Warn("Failed to store preserved upstream headers")
+	}
+}
+
+func (h *ProxyHandler) replayHeaders(w http.ResponseWriter, headers string) {
+	if headers == "" || len(h.cfg.PreservedHeaders) == 0 {
+		return
+	}
+
+	var stored http.Header
+	if err := json.Unmarshal([]byte(headers), &stored); err != nil {
+		h.log.WithError(err).Debug("Ignoring malformed preserved headers")
+		return
+	}
+	for _, name := range h.cfg.PreservedHeaders {
+		if values := stored.Values(name); len(values) > 0 {
+			w.Header()[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+}
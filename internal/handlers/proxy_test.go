@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sdko-org/registry-proxy/internal/testing/fakeregistry"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestProxy(t *testing.T, fake *fakeregistry.Registry) http.Handler {
+	t.Helper()
+	_, proxy := newUpstreamProxy(t, fake.Host(), fake.Client().Transport, nil)
+	return proxy
+}
+
+func newUpstreamProxy(t *testing.T, upstream string, transport http.RoundTripper, configure func(*config.Config), middlewares ...func(*logrus.Logger, *config.Config) func(http.Handler) http.Handler) (*ProxyHandler, http.Handler) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cfg := &config.Config{
+		TempDir:           t.TempDir(),
+		DockerHubRegistry: upstream,
+		TagCacheTTL:       time.Hour,
+		ManifestCacheTTL:  time.Hour,
+		BlobCacheTTL:      time.Hour,
+		MaxManifestSize:   4 << 20,
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	store, err := storage.NewDiskStorage(logger, t.TempDir(), 1<<30, 1<<30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := dockerhub.NewClientWithTransport(logger, cfg, transport)
+	ph := NewProxyHandler(logger, cfg, store, client, nil, nil, nil, nil, nil)
+	r := mux.NewRouter()
+	for _, middleware := range middlewares {
+		r.Use(middleware(logger, cfg))
+	}
+	RegisterRoutes(r, ph, nil)
+	return ph, r
+}
+
+func pull(t *testing.T, handler http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept", fakeregistry.MediaTypeDockerManifest)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestManifestPullThroughFakeRegistry(t *testing.T) {
+	fake := fakeregistry.New(fakeregistry.Options{TokenAuth: true})
+	defer fake.Close()
+	image := fake.AddImage("library/alpine", "3.20", fakeregistry.Layer(map[string]string{"etc/alpine-release": "3.20.0"}))
+	proxy := newTestProxy(t, fake)
+
+	rec := pull(t, proxy, "/v2/alpine/manifests/3.20")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if digest := rec.Header().Get("Docker-Content-Digest"); digest != image.Digest {
+		t.Fatalf("digest = %q, want %q", digest, image.Digest)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), image.Manifest) {
+		t.Fatal("manifest body differs from upstream")
+	}
+
+	rec = pull(t, proxy, "/v2/alpine/manifests/3.20")
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), image.Manifest) {
+		t.Fatalf("cached pull: status = %d", rec.Code)
+	}
+	if n := fake.Requests("manifests"); n != 1 {
+		t.Fatalf("upstream manifest requests = %d, want the second pull served from cache", n)
+	}
+
+	rec = pull(t, proxy, "/v2/alpine/manifests/"+image.Digest)
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), image.Manifest) {
+		t.Fatalf("pull by digest: status = %d", rec.Code)
+	}
+
+	if rec := pull(t, proxy, "/v2/alpine/manifests/missing"); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown tag: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestBlobPullThroughFakeRegistry(t *testing.T) {
+	fake := fakeregistry.New(fakeregistry.Options{TokenAuth: true})
+	defer fake.Close()
+	layer := fakeregistry.Layer(map[string]string{"bin/app": "#!/bin/sh\necho hello\n"})
+	image := fake.AddImage("team/app", "v1", layer)
+	proxy := newTestProxy(t, fake)
+	path := "/v2/team/app/blobs/" + image.Layers[0].Digest
+
+	for i := 0; i < 2; i++ {
+		rec := pull(t, proxy, path)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("pull %d: status = %d: %s", i, rec.Code, rec.Body)
+		}
+		if !bytes.Equal(rec.Body.Bytes(), layer) {
+			t.Fatalf("pull %d: blob body differs from upstream", i)
+		}
+	}
+	if n := fake.Requests("blobs"); n != 1 {
+		t.Fatalf("upstream blob requests = %d, want the second pull served from cache", n)
+	}
+
+	fake.FailNext(http.StatusServiceUnavailable)
+	if rec := pull(t, proxy, "/v2/team/app/blobs/"+fakeregistry.Digest([]byte("absent"))); rec.Code < 400 {
+		t.Fatalf("failed upstream: status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sdko-org/registry-proxy/internal/auth"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const pullTokenUsageInterval = time.Minute
+
+type pullTokenResponse struct {
+	models.PullToken
+	Repositories []string `json:"repositories"`
+	Token        string   `json:"token,omitempty"`
+}
+
+func LookupPullToken(logger *logrus.Logger, db *gorm.DB) auth.PullTokenLookup {
+	log := logger.WithField("component", "pull_tokens")
+
+	return func(ctx context.Context, hash string) (*auth.Principal, error) {
+		var token models.PullToken
+		err := db.WithContext(ctx).
+			Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", hash, time.Now()).
+			Take(&token).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, auth.ErrInvalidCredentials
+		}
+		if err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+
+		if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > pullTokenUsageInterval {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := db.WithContext(ctx).Model(&models.PullToken{}).
+					Where("id = ?", token.ID).
+					Update("last_used_at", time.Now()).Error; err != nil {
+					log.WithError(err).Warn("Failed to record pull token usage")
+				}
+			}()
+		}
+
+		return &auth.Principal{
+			Subject:      "pull-token:" + token.Name,
+			Method:       "pull_token",
+			Repositories: splitPullTokenRepositories(token.Repositories),
+		}, nil
+	}
+}
+
+func (h *ProxyHandler) ListPullTokens(w http.ResponseWriter, r *http.Request) {
+	query := h.db.WithContext(r.Context()).Order("created_at DESC")
+	if r.URL.Query().Get("all") != "true" {
+		query = query.Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+	}
+	var tokens []models.PullToken
+	if err := query.Find(&tokens).Error; err != nil {
+		h.log.WithError(err).Error("Failed to list pull tokens")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]pullTokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		response = append(response, pullTokenResponse{PullToken: token, Repositories: splitPullTokenRepositories(token.Repositories)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// pullTokenPattern validates a repository scope for a pull token and resolves
+// single-segment names and globs ("nginx", "nginx*") into library/, matching
+// the normalized names CanPull is checked against. "*" and "**" keep meaning
+// every repository.
+func pullTokenPattern(pattern string) (string, error) {
+	pattern = strings.TrimSpace(pattern)
+	if _, err := path.Match(pattern, ""); pattern == "" || strings.Contains(pattern, ",") || err != nil {
+		return "", fmt.Errorf("invalid repository pattern %q", pattern)
+	}
+	if !strings.ContainsAny(pattern, "*?[") && !validRepositoryName(pattern) {
+		return "", fmt.Errorf("invalid repository %q", pattern)
+	}
+	if pattern == "*" || pattern == "**" {
+		return pattern, nil
+	}
+	return normalizeImageName(pattern), nil
+}
+
+func (h *ProxyHandler) CreatePullToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name         string   `json:"name"`
+		Repositories []string `json:"repositories"`
+		TTL          string   `json:"ttl"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 255 {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Repositories) == 0 {
+		http.Error(w, "repositories is required", http.StatusBadRequest)
+		return
+	}
+	repositories := make([]string, 0, len(req.Repositories))
+	for _, pattern := range req.Repositories {
+		pattern, err := pullTokenPattern(pattern)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		repositories = append(repositories, pattern)
+	}
+
+	ttl := h.cfg.PullTokenDefaultTTL
+	if req.TTL != "" {
+		parsed, err := time.ParseDuration(req.TTL)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid ttl", http.StatusBadRequest)
+			return
+		}
+		ttl = parsed
+	}
+	if ttl > h.cfg.PullTokenMaxTTL {
+		http.Error(w, fmt.Sprintf("ttl must not exceed %s", h.cfg.PullTokenMaxTTL), http.StatusBadRequest)
+		return
+	}
+
+	secret, err := auth.GeneratePullToken()
+	if err != nil {
+		h.log.WithError(err).Error("Failed to generate pull token")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	var createdBy string
+	if lrw := accessRecord(w); lrw != nil {
+		createdBy = lrw.username
+	}
+	token := models.PullToken{
+		Name:         req.Name,
+		TokenHash:    auth.HashPullToken(secret),
+		Repositories: strings.Join(repositories, ","),
+		CreatedBy:    createdBy,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+	if err := h.db.WithContext(r.Context()).Create(&token).Error; err != nil {
+		h.log.WithError(err).Error("Failed to create pull token")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"operation":    "pull_token",
+		"id":           token.ID,
+		"name":         token.Name,
+		"repositories": repositories,
+		"expires_at":   token.ExpiresAt,
+	}).Info("Pull token created")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(pullTokenResponse{PullToken: token, Repositories: repositories, Token: secret})
+}
+
+func (h *ProxyHandler) RevokePullToken(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid token id", http.StatusBadRequest)
+		return
+	}
+
+	result := h.db.WithContext(r.Context()).Model(&models.PullToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		h.log.WithError(result.Error).Error("Failed to revoke pull token")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Pull token not found", http.StatusNotFound)
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"operation": "pull_token",
+		"id":        id,
+	}).Info("Pull token revoked")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func splitPullTokenRepositories(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
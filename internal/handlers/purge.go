@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sdko-org/registry-proxy/internal/cache"
+)
+
+func TriggerPurge(purger *cache.CachePurger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := purger.Trigger(); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func PurgeStatus(purger *cache.CachePurger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(purger.Status())
+	}
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+func isPushRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return true
+	}
+	return strings.Contains(r.URL.Path, "/blobs/uploads")
+}
+
+func (h *ProxyHandler) rejectPush(w http.ResponseWriter, r *http.Request) {
+	h.log.WithFields(logrus.Fields{
+		"operation": "reject_push",
+		"method":    r.Method,
+		"path":      r.URL.Path,
+		"client_ip": getClientIP(r),
+	}).Info("Rejected write request to pull-only proxy")
+
+	if h.cfg.PushRejectStatus == http.StatusUnauthorized {
+		writeRegistryError(w, http.StatusUnauthorized, "DENIED", "registry proxy is pull-only; push to the upstream registry instead")
+		return
+	}
+
+	w.Header().Set("Allow", registryAllowedMethods)
+	writeRegistryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "registry proxy is pull-only; push to the upstream registry instead")
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sdko-org/registry-proxy/internal/auth"
+)
+
+func (h *ProxyHandler) classify(r *http.Request) string {
+	subject := ""
+	if principal := auth.PrincipalFromContext(r.Context()); principal != nil {
+		subject = principal.Subject
+	}
+	return h.classes.Classify(r, getClientIP(r), subject)
+}
+
+func (h *ProxyHandler) QoSStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(h.scheduler.Stats()); err != nil {
+		h.log.WithError(err).Error("Failed to encode QoS stats response")
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/jobs"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	approvalPending  = "pending"
+	approvalApproved = "approved"
+	approvalRejected = "rejected"
+)
+
+func (h *ProxyHandler) quarantined(ctx context.Context, image, reference string) (bool, error) {
+	if !h.cfg.QuarantineEnabled || h.db == nil {
+		return false, nil
+	}
+	for _, pattern := range h.cfg.QuarantineExempt {
+		if config.MatchPattern(pattern, image) {
+			return false, nil
+		}
+	}
+
+	var approval models.RepositoryApproval
+	err := h.db.WithContext(ctx).Where("repository = ?", image).Take(&approval).Error
+	if err == nil {
+		return approval.Status != approvalApproved, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, fmt.Errorf("database error: %w", err)
+	}
+
+	approval = models.RepositoryApproval{
+		Repository: image,
+		Status:     approvalPending,
+		Reference:  reference,
+		FirstSeen:  time.Now(),
+	}
+	result := h.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&approval)
+	if result.Error != nil {
+		return false, fmt.Errorf("database error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return h.quarantined(ctx, image, reference)
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"operation":  "quarantine",
+		"repository": image,
+		"reference":  reference,
+	}).Warn("New repository quarantined pending approval")
+	if h.jobs != nil && reference != "" {
+		if _, err := h.jobs.Enqueue(ctx, jobs.TypePrewarm, prewarmPayload{Image: image, Reference: reference}); err != nil {
+			h.log.WithError(err).Warn("Failed to enqueue quarantine prewarm")
+		}
+	}
+	return true, nil
+}
+
+// A blob served by digest is held unless at least one repository it is
+// cached under has been approved.
+func (h *ProxyHandler) digestQuarantined(ctx context.Context, digest string) (bool, error) {
+	if !h.cfg.QuarantineEnabled || h.db == nil {
+		return false, nil
+	}
+
+	var keys []string
+	if err := h.db.WithContext(ctx).
+		Model(&models.RegistryCache{}).
+		Where("type = ? AND digest = ? AND expires_at > ?", "blob", digest, time.Now()).
+		Pluck("key", &keys).Error; err != nil {
+		return false, fmt.Errorf("database error: %w", err)
+	}
+	for _, key := range keys {
+		held, err := h.quarantined(ctx, storage.ParseKey(key).Repository, "")
+		if err != nil || !held {
+			return held, err
+		}
+	}
+	return len(keys) > 0, nil
+}
+
+func (h *ProxyHandler) Quarantine(w http.ResponseWriter, r *http.Request) {
+	query := h.db.WithContext(r.Context()).Order("first_seen DESC")
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var approvals []models.RepositoryApproval
+	if err := query.Find(&approvals).Error; err != nil {
+		h.log.WithError(err).Error("Failed to list quarantined repositories")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"repositories": approvals,
+	})
+}
+
+func (h *ProxyHandler) ApproveRepository(w http.ResponseWriter, r *http.Request) {
+	h.decideRepository(w, r, approvalApproved)
+}
+
+func (h *ProxyHandler) RejectRepository(w http.ResponseWriter, r *http.Request) {
+	h.decideRepository(w, r, approvalRejected)
+}
+
+func (h *ProxyHandler) decideRepository(w http.ResponseWriter, r *http.Request, status string) {
+	repository := r.URL.Query().Get("repository")
+	if repository == "" {
+		http.Error(w, "repository is required", http.StatusBadRequest)
+		return
+	}
+	repository = normalizeImageName(repository)
+
+	var decidedBy string
+	if lrw := accessRecord(w); lrw != nil {
+		decidedBy = lrw.username
+	}
+	now := time.Now()
+	approval := models.RepositoryApproval{
+		Repository: repository,
+		Status:     status,
+		FirstSeen:  now,
+		DecidedAt:  &now,
+		DecidedBy:  decidedBy,
+	}
+	err := h.db.WithContext(r.Context()).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "repository"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "decided_at", "decided_by"}),
+	}).Create(&approval).Error
+	if err != nil {
+		h.log.WithError(err).Error("Failed to update repository approval")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"operation":  "quarantine",
+		"repository": repository,
+		"status":     status,
+		"decided_by": decidedBy,
+	}).Info("Repository quarantine decision recorded")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(approval)
+}
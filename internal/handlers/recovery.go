@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+var panicsRecovered atomic.Uint64
+
+func RecoveryMiddleware(logger *logrus.Logger) func(http.Handler) http.Handler {
+	log := logger.WithField("component", "http_middleware")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+				panicsRecovered.Add(1)
+
+				fields := logrus.Fields{
+					"panic":     fmt.Sprint(recovered),
+					"stack":     string(debug.Stack()),
+					"method":    r.Method,
+					"path":      r.URL.Path,
+					"query":     r.URL.RawQuery,
+					"client_ip": getClientIP(r),
+				}
+				lrw := accessRecord(w)
+				if lrw != nil {
+					if lrw.username != "" {
+						fields["username"] = lrw.username
+					}
+					if lrw.repository != "" {
+						fields["repository"] = lrw.repository
+						fields["reference"] = lrw.reference
+					}
+				}
+				log.WithFields(fields).Error("Recovered from handler panic")
+
+				if lrw != nil && lrw.started {
+					panic(http.ErrAbortHandler)
+				}
+				writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", "internal server error")
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
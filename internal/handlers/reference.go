@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"regexp"
+	"strings"
+)
+
+const maxRepositoryNameLength = 255
+
+var (
+	pathComponentRegex = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+	domainRegex        = regexp.MustCompile(`^(?:(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])(?:\.(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9]))*|\[[a-fA-F0-9:]+\])(?::[0-9]+)?$`)
+	tagRegex           = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestRegex        = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[[:xdigit:]]{32,}$`)
+)
+
+type registryRoute struct {
+	name         string
+	resourceType string
+	reference    string
+}
+
+func validRepositoryName(name string) bool {
+	if name == "" || len(name) > maxRepositoryNameLength {
+		return false
+	}
+
+	components := strings.Split(name, "/")
+	if host := components[0]; len(components) > 1 && (strings.ContainsAny(host, ".:[") || host == "localhost") {
+		if !domainRegex.MatchString(host) {
+			return false
+		}
+		components = components[1:]
+	}
+	for _, component := range components {
+		if !pathComponentRegex.MatchString(component) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigestReference(reference string) bool {
+	return strings.Contains(reference, ":")
+}
+
+func validTag(tag string) bool {
+	return tagRegex.MatchString(tag)
+}
+
+func normalizeDigest(digest string) (string, bool) {
+	if !digestRegex.MatchString(digest) {
+		return "", false
+	}
+	algorithm, encoded, _ := strings.Cut(digest, ":")
+	switch algorithm {
+	case "sha256", "sha512":
+		return algorithm + ":" + strings.ToLower(encoded), true
+	}
+	return digest, true
+}
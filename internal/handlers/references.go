@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/cache"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm/clause"
+)
+
+func (h *ProxyHandler) linkReferences(ctx context.Context, manifestKey, image string, body []byte) {
+	if h.db == nil {
+		return
+	}
+	var manifest manifestEnvelope
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return
+	}
+
+	now := time.Now()
+	var references []models.CacheReference
+	add := func(childKey, kind string) {
+		references = append(references, models.CacheReference{
+			ParentKey: manifestKey,
+			ChildKey:  childKey,
+			Kind:      kind,
+			CreatedAt: now,
+		})
+	}
+	for _, child := range manifest.Manifests {
+		if child.Digest != "" {
+			add(storage.ManifestKey(image, child.Digest), models.ReferenceManifest)
+		}
+	}
+	if manifest.Config != nil && manifest.Config.Digest != "" {
+		add(storage.BlobKey(image, manifest.Config.Digest), models.ReferenceConfig)
+	}
+	for _, layer := range manifest.Layers {
+		if layer.Digest != "" {
+			add(storage.BlobKey(image, layer.Digest), models.ReferenceLayer)
+		}
+	}
+	if len(references) == 0 {
+		return
+	}
+
+	if err := h.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&references).Error; err != nil {
+		h.log.WithFields(logrus.Fields{
+			"operation": "link_references",
+			"key":       manifestKey,
+			"error":     err,
+		}).Warn("Failed to record manifest references")
+		return
+	}
+	h.touchManifest(ctx, manifestKey)
+}
+
+func (h *ProxyHandler) touchManifest(ctx context.Context, manifestKey string) {
+	if h.db == nil {
+		return
+	}
+	err := h.db.WithContext(ctx).Exec(`
+		UPDATE registry_cache SET last_access = ?, expires_at = GREATEST(registry_cache.expires_at, manifest.expires_at)
+		FROM registry_cache AS manifest
+		WHERE manifest.key = ? AND (registry_cache.key = manifest.key OR registry_cache.key IN (
+			SELECT child_key FROM cache_references WHERE parent_key = ? AND kind IN ?
+		))`, time.Now(), manifestKey, manifestKey, []string{models.ReferenceConfig, models.ReferenceLayer}).Error
+	if err != nil {
+		h.log.WithFields(logrus.Fields{
+			"operation": "touch_manifest",
+			"key":       manifestKey,
+			"error":     err,
+		}).Warn("Failed to update manifest and referenced blob access time")
+	}
+}
+
+func (h *ProxyHandler) CacheConsistency(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "cache_consistency")
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 1000 {
+			http.Error(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	report, err := cache.CheckConsistency(r.Context(), h.db, limit)
+	if err != nil {
+		log.WithError(err).Error("Cache consistency check failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.WithError(err).Error("Failed to encode cache consistency response")
+	}
+}
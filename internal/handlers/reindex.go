@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sdko-org/registry-proxy/internal/jobs"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *ProxyHandler) Reindex(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.storage.(storage.Reindexer); !ok {
+		http.Error(w, "Storage backend does not support reindexing", http.StatusNotImplemented)
+		return
+	}
+
+	job, err := h.jobs.Enqueue(r.Context(), jobs.TypeReindex, struct{}{})
+	if err != nil {
+		h.log.WithError(err).Error("Failed to enqueue reindex job")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+func (h *ProxyHandler) ReindexIfEmpty(ctx context.Context) {
+	log := h.log.WithField("operation", "reindex")
+	if _, ok := h.storage.(storage.Reindexer); !ok || h.db == nil {
+		return
+	}
+
+	var count int64
+	if err := h.db.WithContext(ctx).Unscoped().Model(&models.RegistryCache{}).Count(&count).Error; err != nil {
+		log.WithError(err).Warn("Failed to count cache entries, skipping startup reindex")
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	if _, err := h.jobs.Enqueue(ctx, jobs.TypeReindex, struct{}{}); err != nil {
+		log.WithError(err).Warn("Failed to enqueue startup reindex")
+		return
+	}
+	log.Info("Cache index is empty, scheduled rebuild from object storage")
+}
+
+func (h *ProxyHandler) runReindexJob(ctx context.Context, job *models.Job) error {
+	reindexer, ok := h.storage.(storage.Reindexer)
+	if !ok {
+		return fmt.Errorf("storage backend does not support reindexing")
+	}
+
+	result, err := reindexer.Reindex(ctx)
+	if err != nil {
+		return err
+	}
+	h.log.WithFields(logrus.Fields{
+		"operation": "reindex",
+		"job_id":    job.ID,
+		"restored":  result.Restored,
+		"failed":    len(result.Failed),
+	}).Info("Reindex job completed")
+	return nil
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/cache"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var repositorySortColumns = map[string]string{
+	"last_pulled": "last_pulled DESC",
+	"pull_count":  "pull_count DESC",
+	"size":        "total_cached_bytes DESC",
+	"first_seen":  "first_seen DESC",
+	"name":        "name ASC",
+}
+
+func (h *ProxyHandler) updateRepository(ctx context.Context, image string, pulls, cachedBytes int64) error {
+	now := time.Now().UTC()
+	repository := models.Repository{
+		Name:             normalizeImageName(image),
+		FirstSeen:        now,
+		PullCount:        pulls,
+		TotalCachedBytes: cachedBytes,
+		UpdatedAt:        now,
+	}
+	updates := map[string]interface{}{
+		"pull_count":         gorm.Expr("repositories.pull_count + ?", pulls),
+		"total_cached_bytes": gorm.Expr("repositories.total_cached_bytes + ?", cachedBytes),
+		"updated_at":         now,
+	}
+	if pulls > 0 {
+		repository.LastPulled = now
+		updates["last_pulled"] = now
+	}
+
+	return h.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.Assignments(updates),
+	}).Create(&repository).Error
+}
+
+func (h *ProxyHandler) recordCached(image string, size int64) {
+	if h.db == nil || size <= 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		if err := h.updateRepository(ctx, image, 0, size); err != nil {
+			h.log.WithFields(logrus.Fields{
+				"repository": image,
+				"error":      err,
+			}).Warn("Failed to record cached bytes")
+		}
+	}()
+}
+
+func (h *ProxyHandler) Repositories(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "repositories")
+	query := r.URL.Query()
+
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = "last_pulled"
+	}
+	order, ok := repositorySortColumns[sort]
+	if !ok {
+		http.Error(w, "Invalid sort", http.StatusBadRequest)
+		return
+	}
+
+	limit := 100
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 1000 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	db := h.db.WithContext(r.Context()).Model(&models.Repository{})
+	if pattern := query.Get("repository"); pattern != "" {
+		db = db.Where("name ~ ?", "^"+cache.PatternExpr(pattern)+"$")
+	}
+	if v := query.Get("idle"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid idle", http.StatusBadRequest)
+			return
+		}
+		db = db.Where("last_pulled IS NULL OR last_pulled < ?", time.Now().Add(-d))
+	}
+
+	repositories := []models.Repository{}
+	if err := db.Order(order).Limit(limit).Find(&repositories).Error; err != nil {
+		log.WithError(err).Error("Repository query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"repositories": repositories,
+	}); err != nil {
+		log.WithError(err).Error("Failed to encode repositories response")
+	}
+}
@@ -1,12 +1,36 @@
 package handlers
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 )
 
+type registryError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Detail  interface{} `json:"detail,omitempty"`
+}
+
+func writeRegistryError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string][]registryError{
+		"errors": {{Code: code, Message: message}},
+	})
+}
+
 func forwardResponse(w http.ResponseWriter, resp *http.Response) {
+	if resp.StatusCode == http.StatusUnauthorized {
+		writeRegistryError(w, http.StatusNotFound, "NAME_UNKNOWN", "repository does not exist or may require authorization")
+		return
+	}
+
 	for k, v := range resp.Header {
+		if http.CanonicalHeaderKey(k) == "Www-Authenticate" {
+			continue
+		}
 		w.Header()[k] = v
 	}
 	w.WriteHeader(resp.StatusCode)
@@ -14,6 +38,10 @@ func forwardResponse(w http.ResponseWriter, resp *http.Response) {
 }
 
 func HandleV2Check(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
 	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write([]byte("{}"))
+	}
 }
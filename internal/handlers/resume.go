@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	downloadCheckpointInterval = 5 * time.Second
+	resumableDownloadMaxAge    = 24 * time.Hour
+)
+
+type resumedDownload struct {
+	file   *os.File
+	hash   hash.Hash
+	offset int64
+	resp   *http.Response
+}
+
+type downloadTracker struct {
+	h              *ProxyHandler
+	file           *os.File
+	row            models.BlobDownload
+	written        int64
+	lastCheckpoint time.Time
+}
+
+func (h *ProxyHandler) trackDownload(image, digest string, file *os.File, offset int64, resp *http.Response) *downloadTracker {
+	if h.db == nil || !h.cfg.ResumeDownloads {
+		return nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	if strings.HasPrefix(etag, "W/") {
+		etag = ""
+	}
+	size := offset + resp.ContentLength
+	if resp.ContentLength < 0 {
+		size = 0
+	}
+	now := time.Now().UTC()
+	t := &downloadTracker{
+		h:    h,
+		file: file,
+		row: models.BlobDownload{
+			Instance:     h.instance,
+			Digest:       digest,
+			Repository:   image,
+			TempPath:     file.Name(),
+			BytesWritten: offset,
+			Size:         size,
+			ETag:         etag,
+			StartedAt:    now,
+			UpdatedAt:    now,
+		},
+		written:        offset,
+		lastCheckpoint: now,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := h.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "instance"}, {Name: "digest"}},
+		DoUpdates: clause.AssignmentColumns([]string{"repository", "temp_path", "bytes_written", "size", "etag", "updated_at"}),
+	}).Create(&t.row).Error
+	if err != nil {
+		h.log.WithFields(logrus.Fields{
+			"digest": digest,
+			"error":  err,
+		}).Warn("Failed to record download state")
+		return nil
+	}
+	return t
+}
+
+func (t *downloadTracker) Write(p []byte) (int, error) {
+	t.written += int64(len(p))
+	if time.Since(t.lastCheckpoint) >= downloadCheckpointInterval {
+		t.checkpoint()
+	}
+	return len(p), nil
+}
+
+func (t *downloadTracker) checkpoint() {
+	t.lastCheckpoint = time.Now()
+	if err := t.file.Sync(); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := t.h.db.WithContext(ctx).Model(&models.BlobDownload{}).
+		Where("instance = ? AND digest = ?", t.row.Instance, t.row.Digest).
+		Updates(map[string]interface{}{"bytes_written": t.written, "updated_at": time.Now().UTC()}).Error
+	if err != nil {
+		t.h.log.WithFields(logrus.Fields{
+			"digest": t.row.Digest,
+			"error":  err,
+		}).Debug("Failed to checkpoint download state")
+	}
+}
+
+func (t *downloadTracker) finish() {
+	if t == nil {
+		return
+	}
+	t.h.forgetDownload(t.row.Digest)
+}
+
+func (h *ProxyHandler) forgetDownload(digest string) {
+	if h.db == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	h.db.WithContext(ctx).Where("instance = ? AND digest = ?", h.instance, digest).Delete(&models.BlobDownload{})
+}
+
+func (h *ProxyHandler) resumeBlob(ctx context.Context, image, digest, partialPath string) *resumedDownload {
+	if h.db == nil || !h.cfg.ResumeDownloads {
+		return nil
+	}
+
+	var row models.BlobDownload
+	err := h.db.WithContext(ctx).Where("instance = ? AND digest = ?", h.instance, digest).First(&row).Error
+	if err != nil || row.BytesWritten <= 0 || row.TempPath != partialPath {
+		return nil
+	}
+	log := h.log.WithFields(logrus.Fields{
+		"operation": "resume_download",
+		"digest":    digest,
+		"offset":    row.BytesWritten,
+	})
+
+	abandon := func(reason string) *resumedDownload {
+		log.WithField("reason", reason).Info("Discarding interrupted download")
+		os.Remove(partialPath)
+		h.forgetDownload(digest)
+		return nil
+	}
+
+	file, err := os.OpenFile(partialPath, os.O_RDWR, 0600)
+	if err != nil {
+		return abandon("partial file missing")
+	}
+	if err := file.Truncate(row.BytesWritten); err != nil {
+		file.Close()
+		return abandon("truncate failed")
+	}
+	sum := sha256.New()
+	if n, err := io.Copy(sum, file); err != nil || n != row.BytesWritten {
+		file.Close()
+		return abandon("partial file shorter than recorded")
+	}
+
+	resp, err := h.dhClient.GetBlobRange(ctx, image, digest, row.BytesWritten, row.ETag)
+	if err != nil {
+		file.Close()
+		return abandon("range request failed")
+	}
+	if resp.StatusCode != http.StatusPartialContent || !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", row.BytesWritten)) {
+		resp.Body.Close()
+		file.Close()
+		return abandon(fmt.Sprintf("upstream answered range request with status %d", resp.StatusCode))
+	}
+
+	log.Info("Resuming interrupted download")
+	return &resumedDownload{file: file, hash: sum, offset: row.BytesWritten, resp: resp}
+}
+
+func (h *ProxyHandler) replayPartial(w io.Writer, path string, offset int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.CopyN(w, f, offset)
+	return err
+}
+
+func (h *ProxyHandler) resumableDownloads() map[string]bool {
+	paths := make(map[string]bool)
+	if h.db == nil || !h.cfg.ResumeDownloads {
+		return paths
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var rows []models.BlobDownload
+	if err := h.db.WithContext(ctx).Where("instance = ?", h.instance).Find(&rows).Error; err != nil {
+		h.log.WithError(err).Warn("Failed to load interrupted downloads")
+		return paths
+	}
+	for _, row := range rows {
+		if filepath.Dir(row.TempPath) != filepath.Clean(h.tempDir) {
+			h.forgetDownload(row.Digest)
+			continue
+		}
+		if _, err := os.Stat(row.TempPath); err != nil || time.Since(row.UpdatedAt) > resumableDownloadMaxAge {
+			h.forgetDownload(row.Digest)
+			continue
+		}
+		paths[filepath.Base(row.TempPath)] = true
+	}
+	return paths
+}
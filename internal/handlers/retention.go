@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sdko-org/registry-proxy/internal/cache"
+	"github.com/sdko-org/registry-proxy/internal/models"
+)
+
+func (h *ProxyHandler) Retention(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "retention")
+
+	usages, err := cache.RetentionUsages(r.Context(), h.db, h.cfg.RetentionRules)
+	if err != nil {
+		log.WithError(err).Error("Retention usage query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"rules": usages,
+	}
+	if repository := r.URL.Query().Get("repository"); repository != "" {
+		repository = normalizeImageName(repository)
+		response["repository"] = repository
+		if rule := cache.RetentionRuleFor(h.cfg.RetentionRules, repository); rule != nil {
+			response["matched_rule"] = rule.Pattern
+		}
+		var record []models.Repository
+		if err := h.db.WithContext(r.Context()).Where("name = ?", repository).Limit(1).Find(&record).Error; err != nil {
+			log.WithError(err).Error("Repository lookup failed")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if len(record) > 0 {
+			response["metadata"] = record[0]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.WithError(err).Error("Failed to encode retention response")
+	}
+}
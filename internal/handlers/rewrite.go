@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+)
+
+func (h *ProxyHandler) rewriteImage(image string) (string, bool) {
+	for _, rewrite := range h.cfg.ImageRewrites {
+		if rewrite.Regex != nil {
+			if match := rewrite.Regex.FindStringSubmatchIndex(image); match != nil {
+				return rewriteTarget(string(rewrite.Regex.ExpandString(nil, rewrite.Replacement, image, match))), true
+			}
+			continue
+		}
+
+		if prefix := rewrite.Pattern; strings.HasSuffix(prefix, "/") {
+			if rest, ok := strings.CutPrefix(image, prefix); ok {
+				return rewriteTarget(rewrite.Replacement + rest), true
+			}
+			continue
+		}
+		if normalizeImageName(rewrite.Pattern) == image {
+			return rewriteTarget(rewrite.Replacement), true
+		}
+	}
+	return image, false
+}
+
+func rewriteTarget(image string) string {
+	if registry, rest, found := strings.Cut(image, "/"); found && dockerhub.IsDockerHub(registry) {
+		image = rest
+	}
+	return normalizeImageName(image)
+}
@@ -1,12 +1,71 @@
 package handlers
 
 import (
+	"net/http"
+
 	"github.com/gorilla/mux"
+	"github.com/sdko-org/registry-proxy/internal/cache"
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/p2p"
+	"github.com/sdko-org/registry-proxy/internal/peer"
+	"github.com/sdko-org/registry-proxy/internal/ui"
 )
 
-func RegisterRoutes(r *mux.Router, ph *ProxyHandler) {
-	r.HandleFunc("/v2/", HandleV2Check).Methods("GET")
+func RegisterRoutes(r *mux.Router, ph *ProxyHandler, purger *cache.CachePurger) {
+	r.MethodNotAllowedHandler = methodNotAllowed(r)
+	r.HandleFunc("/v2/", HandleV2Check).Methods("GET", "HEAD")
+	r.HandleFunc("/v2", HandleV2Check).Methods("GET", "HEAD")
 	r.HandleFunc("/v2/_catalog", HandleCatalog).Methods("GET")
-	r.HandleFunc("/admin/cache/invalidate", ph.InvalidateCache).Methods("POST")
-	r.PathPrefix("/v2/").Handler(ph)
+	if ph.db != nil {
+		r.HandleFunc("/admin/cache/invalidate", ph.InvalidateCache).Methods("POST")
+		r.HandleFunc("/admin/cache/restore", ph.RestoreCache).Methods("POST")
+		r.HandleFunc("/admin/cache/reindex", ph.Reindex).Methods("POST")
+		r.HandleFunc("/admin/cache/consistency", ph.CacheConsistency).Methods("GET")
+		r.HandleFunc("/admin/cache/purge", TriggerPurge(purger)).Methods("POST")
+		r.HandleFunc("/admin/cache/purge/status", PurgeStatus(purger)).Methods("GET")
+		r.HandleFunc("/admin/jobs", ph.ListJobs).Methods("GET")
+		r.HandleFunc("/admin/jobs", ph.EnqueueJob).Methods("POST")
+		r.HandleFunc("/admin/jobs/{id:[0-9]+}/retry", ph.RetryJob).Methods("POST")
+		r.HandleFunc("/admin/stats/top-images", ph.TopImages).Methods("GET")
+		r.HandleFunc("/admin/repositories", ph.Repositories).Methods("GET")
+		r.HandleFunc("/admin/stats/quotas", ph.QuotaUsage).Methods("GET")
+		r.HandleFunc("/admin/tenants", ph.Tenants).Methods("GET")
+		r.HandleFunc("/admin/chargeback", ph.Chargeback).Methods("GET")
+		r.HandleFunc("/admin/stats/summary", ph.CacheSummary).Methods("GET")
+		r.HandleFunc("/admin/export/lockfile", ph.ExportLockfile).Methods("GET")
+		r.HandleFunc("/admin/logs", ph.AccessLogs).Methods("GET")
+		r.HandleFunc("/admin/retention", ph.Retention).Methods("GET")
+		r.HandleFunc("/admin/simulate", ph.Simulate).Methods("GET")
+		r.HandleFunc("/admin/quarantine", ph.Quarantine).Methods("GET")
+		r.HandleFunc("/admin/drift", ph.Drift).Methods("GET")
+		r.HandleFunc("/admin/quarantine/approve", ph.ApproveRepository).Methods("POST")
+		r.HandleFunc("/admin/quarantine/reject", ph.RejectRepository).Methods("POST")
+		if ph.cfg.PullTokensEnabled {
+			r.HandleFunc("/admin/pull-tokens", ph.ListPullTokens).Methods("GET")
+			r.HandleFunc("/admin/pull-tokens", ph.CreatePullToken).Methods("POST")
+			r.HandleFunc("/admin/pull-tokens/{id:[0-9]+}/revoke", ph.RevokePullToken).Methods("POST")
+		}
+		r.HandleFunc(peer.DigestsPath, ph.PeerDigests).Methods("GET")
+		r.HandleFunc(peer.BlobsPath+"{digest}", ph.PeerBlob).Methods("GET")
+		r.HandleFunc(p2p.BlobsPath+"{digest}", ph.P2PBlob).Methods("GET", "HEAD")
+	}
+	r.HandleFunc("/admin/stats/bandwidth", ph.BandwidthStats).Methods("GET")
+	r.HandleFunc("/admin/stats/qos", ph.QoSStats).Methods("GET")
+	r.HandleFunc("/admin/stats/runtime", ph.RuntimeStats).Methods("GET")
+	r.HandleFunc("/admin/events", ph.Events).Methods("GET")
+	r.HandleFunc("/admin/peers", ph.PeerStatus).Methods("GET")
+	r.HandleFunc("/admin/upstreams", ph.UpstreamStatus).Methods("GET")
+	r.HandleFunc("/admin/selftest", ph.SelfTest).Methods("POST")
+	r.Handle("/ui", http.RedirectHandler("/ui/", http.StatusMovedPermanently)).Methods("GET")
+	r.PathPrefix("/ui/").Handler(ui.Handler()).Methods("GET", "HEAD")
+	if ph.cfg.SearchProxy {
+		r.HandleFunc("/v1/search", ph.Search(dockerhub.SearchURL, "q")).Methods("GET")
+		r.HandleFunc("/v2/search/repositories/", ph.Search(dockerhub.HubSearchURL, "query")).Methods("GET")
+		r.HandleFunc("/v2/search/repositories", ph.Search(dockerhub.HubSearchURL, "query")).Methods("GET")
+	}
+	r.HandleFunc("/v2/{name:.+}/tags/list", ph.registryEndpoint("tags")).Methods("GET", "HEAD")
+	r.HandleFunc("/v2/{name:.+}/manifests/{reference}", ph.registryEndpoint("manifests")).Methods("GET", "HEAD")
+	r.HandleFunc("/v2/{name:.+}/blobs/{reference}", ph.registryEndpoint("blobs")).Methods("GET", "HEAD")
+	r.HandleFunc("/v2/{name:.+}/referrers/{reference}", ph.registryEndpoint("referrers")).Methods("GET", "HEAD")
+	r.PathPrefix("/v2/").HandlerFunc(ph.unmatchedRegistryPath)
 }
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type runtimeStats struct {
+	SampledAt      time.Time `json:"sampled_at"`
+	Goroutines     int       `json:"goroutines"`
+	HeapAlloc      uint64    `json:"heap_alloc_bytes"`
+	HeapInuse      uint64    `json:"heap_inuse_bytes"`
+	HeapObjects    uint64    `json:"heap_objects"`
+	Sys            uint64    `json:"sys_bytes"`
+	GCCycles       uint32    `json:"gc_cycles"`
+	GCPauseTotal   string    `json:"gc_pause_total"`
+	OpenFDs        int       `json:"open_fds"`
+	TempFiles      int       `json:"temp_dir_files"`
+	TempPartials   int       `json:"temp_dir_partials"`
+	TempBytes      int64     `json:"temp_dir_bytes"`
+	MemoryBuffered int64     `json:"memory_buffer_bytes"`
+	Panics         uint64    `json:"panics_recovered"`
+}
+
+func (h *ProxyHandler) SampleRuntime(ctx context.Context, interval time.Duration) {
+	log := h.log.WithField("operation", "runtime_stats")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		stats := h.sampleRuntime()
+		h.sampled.Store(stats)
+		log.WithFields(logrus.Fields{
+			"goroutines":     stats.Goroutines,
+			"heap_alloc":     stats.HeapAlloc,
+			"open_fds":       stats.OpenFDs,
+			"temp_dir_bytes": stats.TempBytes,
+		}).Debug("Sampled runtime stats")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *ProxyHandler) sampleRuntime() *runtimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := &runtimeStats{
+		SampledAt:    time.Now().UTC(),
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    mem.HeapAlloc,
+		HeapInuse:    mem.HeapInuse,
+		HeapObjects:  mem.HeapObjects,
+		Sys:          mem.Sys,
+		GCCycles:     mem.NumGC,
+		GCPauseTotal: time.Duration(mem.PauseTotalNs).String(),
+		OpenFDs:      -1,
+		Panics:       panicsRecovered.Load(),
+	}
+	if fds, err := os.ReadDir("/proc/self/fd"); err == nil {
+		stats.OpenFDs = len(fds)
+	}
+	if h.memory != nil {
+		stats.MemoryBuffered = h.memory.used.Load()
+	}
+
+	entries, err := os.ReadDir(h.tempDir)
+	if err != nil {
+		return stats
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		stats.TempFiles++
+		stats.TempBytes += info.Size()
+		if strings.HasSuffix(entry.Name(), partialSuffix) || strings.Contains(entry.Name(), ".job-") {
+			stats.TempPartials++
+		}
+	}
+	return stats
+}
+
+func (h *ProxyHandler) RuntimeStats(w http.ResponseWriter, r *http.Request) {
+	stats := h.sampled.Load()
+	if stats == nil || r.URL.Query().Get("fresh") == "true" {
+		stats = h.sampleRuntime()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		h.log.WithError(err).Error("Failed to encode runtime stats response")
+	}
+}
@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anchore/syft/syft"
+	"github.com/anchore/syft/syft/format/cyclonedxjson"
+	"github.com/anchore/syft/syft/format/spdxjson"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/jobs"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	mediaTypeSPDX       = "application/spdx+json"
+	mediaTypeCycloneDX  = "application/vnd.cyclonedx+json"
+	mediaTypeOCIEmpty   = "application/vnd.oci.empty.v1+json"
+	ociEmptyDigest      = "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	sbomTempDirPrefix   = "sbom-"
+	annotationCreatedAt = "org.opencontainers.image.created"
+)
+
+type sbomPayload struct {
+	Image     string `json:"image"`
+	Reference string `json:"reference"`
+	Digest    string `json:"digest"`
+}
+
+type ociDescriptor struct {
+	MediaType    string            `json:"mediaType"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+type ociArtifactManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Subject       *ociDescriptor    `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+func (h *ProxyHandler) sbomMediaType() string {
+	if h.cfg.SBOMFormat == config.SBOMFormatCycloneDX {
+		return mediaTypeCycloneDX
+	}
+	return mediaTypeSPDX
+}
+
+func (h *ProxyHandler) enqueueSBOM(image, reference, digest, mediaType string, content []byte) {
+	if !h.cfg.SBOMGeneration || h.jobs == nil || h.db == nil || h.passthrough(image) {
+		return
+	}
+	if mediaType != mediaTypeOCIManifest && mediaType != mediaTypeDockerManifest {
+		return
+	}
+	var manifest struct {
+		manifestEnvelope
+		Subject *manifestDescriptor `json:"subject"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil || manifest.Subject != nil || manifest.ArtifactType != "" {
+		return
+	}
+	if manifest.Config == nil || manifest.Config.MediaType == mediaTypeOCIEmpty || len(manifest.Layers) == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if h.hasSBOM(ctx, image, digest) {
+			return
+		}
+		if _, err := h.jobs.Enqueue(ctx, jobs.TypeSBOM, sbomPayload{Image: image, Reference: reference, Digest: digest}); err != nil {
+			h.log.WithFields(logrus.Fields{
+				"operation": "sbom",
+				"image":     image,
+				"digest":    digest,
+				"error":     err,
+			}).Warn("Failed to enqueue SBOM generation")
+		}
+	}()
+}
+
+func (h *ProxyHandler) hasSBOM(ctx context.Context, image, digest string) bool {
+	index, err := h.loadReferrers(ctx, image, digest)
+	if err != nil {
+		return false
+	}
+	artifactType := h.sbomMediaType()
+	for _, referrer := range index.Manifests {
+		if referrer.ArtifactType == artifactType {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *ProxyHandler) loadReferrers(ctx context.Context, image, digest string) (*ociIndex, error) {
+	content, _, _, err := h.storage.Get(ctx, storage.ReferrersKey(image, digest))
+	if err != nil {
+		return nil, err
+	}
+	var index ociIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, err
+	}
+	return &index, nil
+}
+
+func (h *ProxyHandler) runSBOMJob(ctx context.Context, job *models.Job) error {
+	var payload sbomPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return fmt.Errorf("decode payload: %w", err)
+	}
+	if payload.Image == "" || !validDigestRegex.MatchString(payload.Digest) {
+		return fmt.Errorf("image and a valid digest are required")
+	}
+	if payload.Reference == "" {
+		payload.Reference = payload.Digest
+	}
+
+	log := h.log.WithFields(logrus.Fields{
+		"operation": "sbom",
+		"image":     payload.Image,
+		"reference": payload.Reference,
+		"digest":    payload.Digest,
+	})
+	if h.hasSBOM(ctx, payload.Image, payload.Digest) {
+		log.Debug("SBOM already stored, skipping")
+		return nil
+	}
+
+	body, digest, mediaType, err := h.storage.Get(ctx, storage.ManifestKey(payload.Image, payload.Reference))
+	if err != nil {
+		log.WithError(err).Info("Manifest no longer cached, skipping SBOM")
+		return nil
+	}
+	if digest != payload.Digest {
+		log.WithField("cached_digest", digest).Info("Manifest changed since it was queued, skipping SBOM")
+		return nil
+	}
+	var manifest manifestEnvelope
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return fmt.Errorf("manifest parse failed: %w", err)
+	}
+	if manifest.Config == nil || len(manifest.Layers) == 0 {
+		log.Info("Manifest is not an image, skipping SBOM")
+		return nil
+	}
+	var total int64
+	for _, layer := range manifest.Layers {
+		total += layer.Size
+	}
+	if total > h.cfg.SBOMMaxImageSize {
+		log.WithField("size", total).Info("Image exceeds SBOM_MAX_IMAGE_SIZE, skipping SBOM")
+		return nil
+	}
+
+	dir, err := os.MkdirTemp(h.tempDir, sbomTempDirPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	blobs := append([]manifestDescriptor{*manifest.Config}, manifest.Layers...)
+	if err := h.writeImageLayout(ctx, dir, payload.Image, manifestDescriptor{MediaType: mediaType, Digest: digest, Size: int64(len(body))}, body, blobs, job.Attempts >= job.MaxAttempts); err != nil {
+		if errors.Is(err, errBlobTooLarge) {
+			log.WithError(err).Info("Image layer is not cacheable, skipping SBOM")
+			return nil
+		}
+		return err
+	}
+
+	document, err := h.generateSBOM(ctx, dir)
+	if err != nil {
+		return fmt.Errorf("sbom generation failed: %w", err)
+	}
+	subject := ociDescriptor{MediaType: mediaType, Digest: digest, Size: int64(len(body))}
+	artifactDigest, err := h.storeSBOM(ctx, payload.Image, subject, document)
+	if err != nil {
+		return err
+	}
+	log.WithFields(logrus.Fields{
+		"artifact": artifactDigest,
+		"size":     len(document),
+	}).Info("Stored SBOM")
+	return nil
+}
+
+func (h *ProxyHandler) writeImageLayout(ctx context.Context, dir, image string, desc manifestDescriptor, manifest []byte, blobs []manifestDescriptor, lastAttempt bool) error {
+	blobDir := filepath.Join(dir, "blobs", "sha256")
+	if err := os.MkdirAll(blobDir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0o644); err != nil {
+		return err
+	}
+	index, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"manifests":     []manifestDescriptor{desc},
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.json"), index, 0o644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(blobDir, strings.TrimPrefix(desc.Digest, "sha256:")), manifest, 0o644); err != nil {
+		return err
+	}
+
+	for _, blob := range blobs {
+		if !validDigestRegex.MatchString(blob.Digest) || !strings.HasPrefix(blob.Digest, "sha256:") {
+			return fmt.Errorf("unsupported blob digest %q", blob.Digest)
+		}
+		key := storage.BlobKey(image, blob.Digest)
+		if _, err := h.storage.Stat(ctx, key); err != nil {
+			if err := h.writeBlobToCache(ctx, cacheWritePayload{Image: image, Digest: blob.Digest}, lastAttempt); err != nil {
+				return err
+			}
+			if _, err := h.storage.Stat(ctx, key); err != nil {
+				return fmt.Errorf("blob %s: %w", blob.Digest, errBlobTooLarge)
+			}
+		}
+		if err := h.copyBlob(ctx, key, filepath.Join(blobDir, strings.TrimPrefix(blob.Digest, "sha256:"))); err != nil {
+			return fmt.Errorf("blob %s: %w", blob.Digest, err)
+		}
+	}
+	return nil
+}
+
+func (h *ProxyHandler) copyBlob(ctx context.Context, key, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if streamer, ok := h.storage.(storage.Streamer); ok {
+		body, _, err := streamer.GetStream(ctx, key)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		_, err = io.Copy(f, body)
+		return err
+	}
+	content, _, _, err := h.storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	return err
+}
+
+func (h *ProxyHandler) generateSBOM(ctx context.Context, layoutDir string) ([]byte, error) {
+	src, err := syft.GetSource(ctx, layoutDir, syft.DefaultGetSourceConfig().WithSources("oci-dir"))
+	if err != nil {
+		return nil, fmt.Errorf("open image: %w", err)
+	}
+	defer src.Close()
+
+	document, err := syft.CreateSBOM(ctx, src, syft.DefaultCreateSBOMConfig().WithTool("registry-proxy", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	var encoder sbom.FormatEncoder
+	if h.cfg.SBOMFormat == config.SBOMFormatCycloneDX {
+		encoder, err = cyclonedxjson.NewFormatEncoderWithConfig(cyclonedxjson.DefaultEncoderConfig())
+	} else {
+		encoder, err = spdxjson.NewFormatEncoderWithConfig(spdxjson.DefaultEncoderConfig())
+	}
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := encoder.Encode(&buf, *document); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (h *ProxyHandler) storeSBOM(ctx context.Context, image string, subject ociDescriptor, document []byte) (string, error) {
+	artifactType := h.sbomMediaType()
+	documentDigest := sha256Digest(document)
+	if err := h.storage.Put(ctx, storage.BlobKey(image, documentDigest), document, documentDigest, artifactType, h.cfg.BlobCacheTTL); err != nil {
+		return "", fmt.Errorf("sbom cache failed: %w", err)
+	}
+	if err := h.storage.Put(ctx, storage.BlobKey(image, ociEmptyDigest), []byte("{}"), ociEmptyDigest, mediaTypeOCIEmpty, h.cfg.BlobCacheTTL); err != nil {
+		return "", fmt.Errorf("sbom config cache failed: %w", err)
+	}
+
+	created := time.Now().UTC().Format(time.RFC3339)
+	manifest, err := json.Marshal(ociArtifactManifest{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeOCIManifest,
+		ArtifactType:  artifactType,
+		Config:        ociDescriptor{MediaType: mediaTypeOCIEmpty, Digest: ociEmptyDigest, Size: 2},
+		Layers:        []ociDescriptor{{MediaType: artifactType, Digest: documentDigest, Size: int64(len(document))}},
+		Subject:       &subject,
+		Annotations:   map[string]string{annotationCreatedAt: created},
+	})
+	if err != nil {
+		return "", err
+	}
+	manifestDigest := sha256Digest(manifest)
+	manifestKey := storage.ManifestKey(image, manifestDigest)
+	if err := h.storage.Put(ctx, manifestKey, manifest, manifestDigest, mediaTypeOCIManifest, h.cfg.ManifestCacheTTL); err != nil {
+		return "", fmt.Errorf("sbom manifest cache failed: %w", err)
+	}
+	h.linkReferences(ctx, manifestKey, image, manifest)
+
+	index, err := h.loadReferrers(ctx, image, subject.Digest)
+	if err != nil {
+		index = &ociIndex{SchemaVersion: 2, MediaType: mediaTypeOCIIndex}
+	}
+	index.Manifests = append(index.Manifests, ociDescriptor{
+		MediaType:    mediaTypeOCIManifest,
+		Digest:       manifestDigest,
+		Size:         int64(len(manifest)),
+		ArtifactType: artifactType,
+		Annotations:  map[string]string{annotationCreatedAt: created},
+	})
+	content, err := json.Marshal(index)
+	if err != nil {
+		return "", err
+	}
+	indexKey := storage.ReferrersKey(image, subject.Digest)
+	if err := h.storage.Put(ctx, indexKey, content, sha256Digest(content), mediaTypeOCIIndex, h.cfg.ManifestCacheTTL); err != nil {
+		return "", fmt.Errorf("referrers index cache failed: %w", err)
+	}
+	h.linkReferences(ctx, indexKey, image, content)
+	return manifestDigest, nil
+}
+
+func sha256Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func (h *ProxyHandler) handleReferrers(w http.ResponseWriter, r *http.Request, image, digest string) {
+	ctx := r.Context()
+	index := h.upstreamReferrers(ctx, image, digest)
+	if local, err := h.loadReferrers(ctx, image, digest); err == nil {
+		index.Manifests = mergeReferrers(local.Manifests, index.Manifests)
+	}
+	if index.Manifests == nil {
+		index.Manifests = []ociDescriptor{}
+	}
+	index.SchemaVersion, index.MediaType = 2, mediaTypeOCIIndex
+
+	if artifactType := r.URL.Query().Get("artifactType"); artifactType != "" {
+		filtered := []ociDescriptor{}
+		for _, referrer := range index.Manifests {
+			if referrer.ArtifactType == artifactType {
+				filtered = append(filtered, referrer)
+			}
+		}
+		index.Manifests = filtered
+		w.Header().Set("OCI-Filters-Applied", "artifactType")
+	}
+
+	w.Header().Set("Content-Type", mediaTypeOCIIndex)
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		json.NewEncoder(w).Encode(index)
+	}
+}
+
+// mergeReferrers combines locally generated referrers with the upstream's own
+// (signatures, attestations), keeping the first descriptor seen per digest.
+func mergeReferrers(local, upstream []ociDescriptor) []ociDescriptor {
+	seen := make(map[string]bool, len(local)+len(upstream))
+	merged := make([]ociDescriptor, 0, len(local)+len(upstream))
+	for _, referrer := range append(local, upstream...) {
+		if seen[referrer.Digest] {
+			continue
+		}
+		seen[referrer.Digest] = true
+		merged = append(merged, referrer)
+	}
+	return merged
+}
+
+func (h *ProxyHandler) upstreamReferrers(ctx context.Context, image, digest string) *ociIndex {
+	index := &ociIndex{}
+	if h.passthrough(image) || h.dhClient == nil {
+		return index
+	}
+	resp, err := h.dhClient.GetPage(ctx, h.dhClient.RepositoryURL(image, "referrers/"+digest))
+	if err != nil {
+		h.log.WithFields(logrus.Fields{
+			"operation": "referrers",
+			"image":     image,
+			"digest":    digest,
+			"error":     err,
+		}).Debug("Upstream referrers lookup failed")
+		return index
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return index
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, h.cfg.MaxManifestSize)).Decode(index); err != nil {
+		return &ociIndex{}
+	}
+	return index
+}
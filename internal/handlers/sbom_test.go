@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/testing/fakeregistry"
+)
+
+func newSBOMProxy(t *testing.T, fake *fakeregistry.Registry) (*ProxyHandler, http.Handler) {
+	t.Helper()
+	return newUpstreamProxy(t, fake.Host(), fake.Client().Transport, func(cfg *config.Config) {
+		cfg.SBOMGeneration = true
+		cfg.SBOMFormat = config.SBOMFormatSPDX
+		cfg.SBOMMaxImageSize = 1 << 30
+	})
+}
+
+func pullAccept(t *testing.T, handler http.Handler, path, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept", accept)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSBOMJobStoresReferrer(t *testing.T) {
+	fake := fakeregistry.New(fakeregistry.Options{})
+	defer fake.Close()
+	image := fake.AddImage("library/alpine", "3.20", fakeregistry.Layer(map[string]string{
+		"etc/os-release":       "ID=alpine\nVERSION_ID=3.20.0\n",
+		"lib/apk/db/installed": "P:musl\nV:1.2.5-r0\nA:x86_64\n\nP:busybox\nV:1.36.1-r29\nA:x86_64\n\n",
+		"etc/alpine-release":   "3.20.0",
+	}))
+	ph, proxy := newSBOMProxy(t, fake)
+
+	if rec := pull(t, proxy, "/v2/alpine/manifests/3.20"); rec.Code != http.StatusOK {
+		t.Fatalf("manifest pull: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	payload, _ := json.Marshal(sbomPayload{Image: "library/alpine", Reference: "3.20", Digest: image.Digest})
+	job := &models.Job{Payload: string(payload), MaxAttempts: 3}
+	if err := ph.runSBOMJob(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+	if err := ph.runSBOMJob(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := pullAccept(t, proxy, "/v2/alpine/referrers/"+image.Digest, mediaTypeOCIIndex)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != mediaTypeOCIIndex {
+		t.Fatalf("referrers: status = %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var index ociIndex
+	if err := json.Unmarshal(rec.Body.Bytes(), &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Manifests) != 1 || index.Manifests[0].ArtifactType != mediaTypeSPDX {
+		t.Fatalf("expected one SPDX referrer, got %+v", index.Manifests)
+	}
+
+	rec = pullAccept(t, proxy, "/v2/alpine/manifests/"+index.Manifests[0].Digest, mediaTypeOCIManifest)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("artifact manifest: status = %d: %s", rec.Code, rec.Body)
+	}
+	var artifact ociArtifactManifest
+	if err := json.Unmarshal(rec.Body.Bytes(), &artifact); err != nil {
+		t.Fatal(err)
+	}
+	if artifact.Subject == nil || artifact.Subject.Digest != image.Digest || len(artifact.Layers) != 1 {
+		t.Fatalf("unexpected artifact manifest %s", rec.Body)
+	}
+
+	rec = pullAccept(t, proxy, "/v2/alpine/blobs/"+artifact.Layers[0].Digest, "*/*")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("sbom blob: status = %d", rec.Code)
+	}
+	for _, pkg := range []string{`"musl"`, `"busybox"`, `"SPDXRef-DOCUMENT"`} {
+		if !strings.Contains(rec.Body.String(), pkg) {
+			t.Errorf("SBOM does not mention %s", pkg)
+		}
+	}
+}
+
+func TestReferrersMergesUpstreamSignatures(t *testing.T) {
+	const cosignType = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	fake := fakeregistry.New(fakeregistry.Options{})
+	defer fake.Close()
+	image := fake.AddImage("library/nginx", "1.27", fakeregistry.Layer(map[string]string{"etc/hostname": "nginx"}))
+	signature := fake.AddReferrer("library/nginx", image.Digest, cosignType, []byte(`{"critical":{}}`))
+	ph, proxy := newSBOMProxy(t, fake)
+
+	subject := ociDescriptor{MediaType: fakeregistry.MediaTypeDockerManifest, Digest: image.Digest, Size: int64(len(image.Manifest))}
+	sbom, err := ph.storeSBOM(context.Background(), "library/nginx", subject, []byte(`{"spdxVersion":"SPDX-2.3"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{sbom, signature}},
+		{"?artifactType=" + url.QueryEscape(mediaTypeSPDX), []string{sbom}},
+		{"?artifactType=" + url.QueryEscape(cosignType), []string{signature}},
+	} {
+		rec := pullAccept(t, proxy, "/v2/nginx/referrers/"+image.Digest+tc.query, mediaTypeOCIIndex)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: status = %d", tc.query, rec.Code)
+		}
+		var index ociIndex
+		if err := json.Unmarshal(rec.Body.Bytes(), &index); err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, referrer := range index.Manifests {
+			got = append(got, referrer.Digest)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%q: referrers = %v, want %v", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestReferrersEndpoint(t *testing.T) {
+	fake := fakeregistry.New(fakeregistry.Options{})
+	defer fake.Close()
+	ph, proxy := newSBOMProxy(t, fake)
+
+	subject := ociDescriptor{MediaType: mediaTypeOCIManifest, Digest: sha256Digest([]byte("image")), Size: 5}
+	if _, err := ph.storeSBOM(context.Background(), "library/nginx", subject, []byte(`{"spdxVersion":"SPDX-2.3"}`)); err != nil {
+		t.Fatal(err)
+	}
+	tag := strings.Replace(subject.Digest, ":", "-", 1)
+	image := fake.AddImage("library/nginx", tag, fakeregistry.Layer(map[string]string{"etc/hostname": "nginx"}))
+	if rec := pull(t, proxy, "/v2/nginx/manifests/"+tag); rec.Code != http.StatusOK || rec.Header().Get("Docker-Content-Digest") != image.Digest {
+		t.Fatalf("tag %s: status = %d, digest %q", tag, rec.Code, rec.Header().Get("Docker-Content-Digest"))
+	}
+
+	for _, tc := range []struct {
+		path     string
+		want     int
+		filtered bool
+	}{
+		{"/v2/nginx/referrers/" + subject.Digest, 1, false},
+		{"/v2/nginx/referrers/" + subject.Digest + "?artifactType=" + url.QueryEscape(mediaTypeSPDX), 1, true},
+		{"/v2/nginx/referrers/" + subject.Digest + "?artifactType=" + url.QueryEscape(mediaTypeCycloneDX), 0, true},
+		{"/v2/nginx/referrers/" + sha256Digest([]byte("other")), 0, false},
+	} {
+		rec := pullAccept(t, proxy, tc.path, mediaTypeOCIIndex)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", tc.path, rec.Code)
+		}
+		var index ociIndex
+		if err := json.Unmarshal(rec.Body.Bytes(), &index); err != nil {
+			t.Fatal(err)
+		}
+		if index.Manifests == nil || len(index.Manifests) != tc.want {
+			t.Errorf("%s: got %d referrers, want %d", tc.path, len(index.Manifests), tc.want)
+		}
+		if got := rec.Header().Get("OCI-Filters-Applied") == "artifactType"; got != tc.filtered {
+			t.Errorf("%s: filter header = %v, want %v", tc.path, got, tc.filtered)
+		}
+	}
+
+	if rec := pullAccept(t, proxy, "/v2/nginx/referrers/latest", mediaTypeOCIIndex); rec.Code != http.StatusBadRequest {
+		t.Fatalf("tag reference: status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	searchMaxCachedQueries = 1000
+	searchMaxBodyBytes     = 1 << 20
+)
+
+type searchCache struct {
+	mu      sync.Mutex
+	entries map[string]searchEntry
+}
+
+type searchEntry struct {
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+}
+
+func (c *searchCache) get(key string) (searchEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return searchEntry{}, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return searchEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *searchCache) store(key string, entry searchEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= searchMaxCachedQueries {
+		now := time.Now()
+		for k, cached := range c.entries {
+			if now.After(cached.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= searchMaxCachedQueries {
+			c.entries = make(map[string]searchEntry)
+		}
+	}
+	c.entries[key] = entry
+}
+
+func (h *ProxyHandler) Search(upstream, queryParam string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		log := h.log.WithFields(logrus.Fields{
+			"operation": "search",
+			"upstream":  upstream,
+			"query":     query.Get(queryParam),
+		})
+		if query.Get(queryParam) == "" {
+			http.Error(w, queryParam+" is required", http.StatusBadRequest)
+			return
+		}
+
+		target := upstream + "?" + query.Encode()
+		if entry, ok := h.search.get(target); ok {
+			log.Debug("Serving search results from cache")
+			w.Header().Set("Content-Type", entry.contentType)
+			h.markCacheHit(w, nil)
+			w.WriteHeader(entry.status)
+			w.Write(entry.body)
+			return
+		}
+
+		resp, err := h.dhClient.Search(r.Context(), target)
+		if err != nil {
+			log.WithError(err).Error("Search request failed")
+			http.Error(w, "Search failed", http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, searchMaxBodyBytes+1))
+		if err != nil || len(body) > searchMaxBodyBytes {
+			log.WithError(err).Error("Failed to read search response")
+			http.Error(w, "Search failed", http.StatusBadGateway)
+			return
+		}
+
+		entry := searchEntry{
+			status:      resp.StatusCode,
+			contentType: resp.Header.Get("Content-Type"),
+			body:        body,
+			expiresAt:   time.Now().Add(h.cfg.SearchCacheTTL),
+		}
+		if resp.StatusCode == http.StatusOK && h.cfg.SearchCacheTTL > 0 {
+			h.search.store(target, entry)
+		}
+
+		log.WithField("status_code", resp.StatusCode).Debug("Proxied search request")
+		w.Header().Set("Content-Type", entry.contentType)
+		h.markCacheMiss(w, resp.Request.URL.Host)
+		w.WriteHeader(entry.status)
+		w.Write(body)
+	}
+}
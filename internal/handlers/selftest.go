@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type selftestStage struct {
+	Name     string `json:"name"`
+	Digest   string `json:"digest,omitempty"`
+	Bytes    int64  `json:"bytes"`
+	Cache    string `json:"cache,omitempty"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+type selftestResult struct {
+	Image    string          `json:"image"`
+	Platform string          `json:"platform"`
+	OK       bool            `json:"ok"`
+	Duration string          `json:"duration"`
+	Stages   []selftestStage `json:"stages"`
+}
+
+type selftest struct {
+	client  *http.Client
+	baseURL string
+	auth    string
+	result  *selftestResult
+}
+
+func (h *ProxyHandler) SelfTest(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "selftest")
+
+	image := r.URL.Query().Get("image")
+	if image == "" {
+		image = h.cfg.SelfTestImage
+	}
+	platform := r.URL.Query().Get("platform")
+	if platform == "" {
+		platform = "linux/amd64"
+	}
+	baseURL, ok := h.selftestBaseURL()
+	if !ok {
+		http.Error(w, "No registry listener to test against", http.StatusServiceUnavailable)
+		return
+	}
+
+	start := time.Now()
+	t := &selftest{
+		client: &http.Client{
+			Timeout:   2 * time.Minute,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		},
+		baseURL: baseURL,
+		auth:    r.Header.Get("Authorization"),
+		result:  &selftestResult{Image: image, Platform: platform},
+	}
+	t.result.OK = t.run(image, platform)
+	t.result.Duration = time.Since(start).String()
+
+	log.WithFields(logrus.Fields{
+		"image":    image,
+		"ok":       t.result.OK,
+		"duration": time.Since(start),
+	}).Info("Self-test completed")
+
+	status := http.StatusOK
+	if !t.result.OK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(t.result)
+}
+
+func (h *ProxyHandler) selftestBaseURL() (string, bool) {
+	scheme, addr := "http", h.cfg.ListenHTTP
+	if addr == "" {
+		scheme, addr = "https", h.cfg.ListenHTTPS
+	}
+	if addr == "" {
+		return "", false
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", false
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return scheme + "://" + net.JoinHostPort(host, port), true
+}
+
+func (t *selftest) run(image, platform string) bool {
+	name, reference := splitImageReference(image)
+
+	body, ok := t.fetch("manifest", name, "manifests", reference, "")
+	if !ok {
+		return false
+	}
+	var doc manifestDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.fail(fmt.Errorf("decode manifest: %w", err))
+		return false
+	}
+
+	if len(doc.Manifests) > 0 {
+		filter := newPlatformFilter([]string{platform})
+		var child string
+		for _, descriptor := range doc.Manifests {
+			if descriptor.Platform != nil && filter.allows(descriptor.Platform) {
+				child = descriptor.Digest
+				break
+			}
+		}
+		if child == "" {
+			t.fail(fmt.Errorf("no manifest for platform %s", platform))
+			return false
+		}
+		if body, ok = t.fetch("platform_manifest", name, "manifests", child, child); !ok {
+			return false
+		}
+		doc = manifestDocument{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			t.fail(fmt.Errorf("decode platform manifest: %w", err))
+			return false
+		}
+	}
+
+	if _, ok := t.fetch("config", name, "blobs", doc.Config.Digest, doc.Config.Digest); !ok {
+		return false
+	}
+	for i, layer := range doc.Layers {
+		if _, ok := t.fetch(fmt.Sprintf("layer_%d", i), name, "blobs", layer.Digest, layer.Digest); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *selftest) fetch(stageName, name, resource, reference, expected string) ([]byte, bool) {
+	start := time.Now()
+	stage := selftestStage{Name: stageName}
+	defer func() {
+		stage.Duration = time.Since(start).String()
+		t.result.Stages = append(t.result.Stages, stage)
+	}()
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v2/%s/%s/%s", t.baseURL, name, resource, reference), nil)
+	if err != nil {
+		stage.Error = err.Error()
+		return nil, false
+	}
+	req.Header.Set("Accept", prewarmAccept)
+	if t.auth != "" {
+		req.Header.Set("Authorization", t.auth)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		stage.Error = err.Error()
+		return nil, false
+	}
+	defer resp.Body.Close()
+	stage.Cache = resp.Header.Get("X-Cache")
+	if resp.StatusCode != http.StatusOK {
+		stage.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		return nil, false
+	}
+
+	hasher := sha256.New()
+	var body []byte
+	if resource == "manifests" {
+		if body, err = io.ReadAll(io.TeeReader(resp.Body, hasher)); err == nil {
+			stage.Bytes = int64(len(body))
+		}
+	} else {
+		stage.Bytes, err = io.Copy(hasher, resp.Body)
+	}
+	if err != nil {
+		stage.Error = err.Error()
+		return nil, false
+	}
+
+	stage.Digest = "sha256:" + hex.EncodeToString(hasher.Sum(nil))
+	if expected == "" {
+		expected = resp.Header.Get("Docker-Content-Digest")
+	}
+	if expected != "" && strings.HasPrefix(expected, "sha256:") && expected != stage.Digest {
+		stage.Error = fmt.Sprintf("digest mismatch: expected %s", expected)
+		return nil, false
+	}
+	return body, true
+}
+
+func (t *selftest) fail(err error) {
+	t.result.Stages = append(t.result.Stages, selftestStage{Name: "resolve", Duration: "0s", Error: err.Error()})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/peer"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	shardForwardedHeader = "X-Registry-Proxy-Forwarded-By"
+	shardTokenHeader     = "X-Registry-Proxy-Shard-Token"
+)
+
+func newShardRing(cfg *config.Config) *peer.Ring {
+	if len(cfg.ShardMembers) == 0 {
+		return nil
+	}
+	return peer.NewRing(cfg.ShardMembers, cfg.ShardSelf, cfg.ShardVirtualNodes)
+}
+
+func shardKey(route *registryRoute, image, reference string) string {
+	switch route.resourceType {
+	case "blobs":
+		return reference
+	case "manifests":
+		return storage.ManifestKey(image, reference)
+	}
+	return ""
+}
+
+func (h *ProxyHandler) shardForwarded(r *http.Request) bool {
+	if r.Header.Get(shardForwardedHeader) == "" {
+		return false
+	}
+	token := h.cfg.PeerCredential()
+	return token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(shardTokenHeader)), []byte(token)) == 1
+}
+
+func (h *ProxyHandler) forwardToShardOwner(w http.ResponseWriter, r *http.Request, key string) bool {
+	if h.shards == nil || key == "" || h.shardForwarded(r) || h.shards.IsLocal(key) {
+		return false
+	}
+
+	owner := h.shards.Owner(key)
+	target, err := url.Parse(owner)
+	if err != nil {
+		h.log.WithFields(logrus.Fields{
+			"owner": owner,
+			"error": err,
+		}).Warn("Invalid shard owner URL, serving locally")
+		return false
+	}
+
+	failed := false
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = h.forwarder
+	proxy.Director = func(req *http.Request) {
+		req.URL.Scheme = target.Scheme
+		req.URL.Host = target.Host
+		req.Header.Set("X-Forwarded-Host", req.Host)
+		req.Host = target.Host
+		req.Header.Set(shardForwardedHeader, h.shards.Self())
+		req.Header.Set(shardTokenHeader, h.cfg.PeerCredential())
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		failed = true
+		h.log.WithFields(logrus.Fields{
+			"owner": owner,
+			"key":   key,
+			"error": err,
+		}).Warn("Shard owner unreachable, serving locally")
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"owner": owner,
+		"key":   key,
+	}).Debug("Forwarding request to shard owner")
+	proxy.ServeHTTP(w, r)
+	return !failed
+}
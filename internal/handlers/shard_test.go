@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestShardForwardIgnoresClientSetHeader(t *testing.T) {
+	var gotToken, gotBy string
+	owner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotToken, gotBy = r.Header.Get(shardTokenHeader), r.Header.Get(shardForwardedHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer owner.Close()
+
+	logger, _ := logtest.NewNullLogger()
+	self := "http://self.invalid"
+	cfg := &config.Config{
+		TempDir:             t.TempDir(),
+		ShardSelf:           self,
+		ShardMembers:        []string{self, owner.URL},
+		ShardVirtualNodes:   128,
+		ShardForwardTimeout: 5 * time.Second,
+		PeerToken:           "peer-secret",
+	}
+	store, err := storage.NewDiskStorage(logger, t.TempDir(), 1<<30, 1<<30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ph := NewProxyHandler(logger, cfg, store, dockerhub.NewClient(logger, cfg), nil, nil, nil, nil, nil)
+
+	key := ""
+	for i := 0; key == ""; i++ {
+		if candidate := fmt.Sprintf("sha256:%064d", i); !ph.shards.IsLocal(candidate) {
+			key = candidate
+		}
+	}
+
+	for _, tc := range []struct {
+		name      string
+		token     string
+		forwarded bool
+	}{
+		{"forged header", "", true},
+		{"wrong token", "guess", true},
+		{"peer token", "peer-secret", false},
+	} {
+		gotToken, gotBy = "", ""
+		req := httptest.NewRequest(http.MethodGet, "/v2/library/nginx/blobs/"+key, nil)
+		req.Header.Set(shardForwardedHeader, owner.URL)
+		if tc.token != "" {
+			req.Header.Set(shardTokenHeader, tc.token)
+		}
+		rec := httptest.NewRecorder()
+		if got := ph.forwardToShardOwner(rec, req, key); got != tc.forwarded {
+			t.Errorf("%s: forwarded = %v, want %v", tc.name, got, tc.forwarded)
+		}
+		if tc.forwarded && (gotToken != "peer-secret" || gotBy != self) {
+			t.Errorf("%s: owner saw token %q from %q", tc.name, gotToken, gotBy)
+		}
+	}
+}
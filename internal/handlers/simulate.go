@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/cache"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+)
+
+type simulatedObject struct {
+	Kind      string     `json:"kind"`
+	Key       string     `json:"key"`
+	Digest    string     `json:"digest,omitempty"`
+	Platform  string     `json:"platform,omitempty"`
+	Status    string     `json:"status"`
+	Size      int64      `json:"size"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Cacheable bool       `json:"cacheable"`
+}
+
+type simulation struct {
+	Image                  string            `json:"image"`
+	Repository             string            `json:"repository"`
+	Reference              string            `json:"reference"`
+	Verdict                string            `json:"verdict"`
+	Manifest               *simulatedObject  `json:"manifest,omitempty"`
+	Children               []simulatedObject `json:"children,omitempty"`
+	SelectedPlatform       string            `json:"selected_platform,omitempty"`
+	Blobs                  []simulatedObject `json:"blobs,omitempty"`
+	EstimatedUpstreamBytes int64             `json:"estimated_upstream_bytes"`
+	RetentionRule          string            `json:"retention_rule,omitempty"`
+	Notes                  []string          `json:"notes,omitempty"`
+}
+
+func splitImageReference(image string) (string, string) {
+	if name, digest, ok := strings.Cut(image, "@"); ok {
+		return name, digest
+	}
+	if idx := strings.LastIndex(image, ":"); idx > strings.LastIndex(image, "/") {
+		return image[:idx], image[idx+1:]
+	}
+	return image, "latest"
+}
+
+func (h *ProxyHandler) Simulate(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "simulate")
+
+	image := r.URL.Query().Get("image")
+	if image == "" {
+		http.Error(w, "image is required", http.StatusBadRequest)
+		return
+	}
+	platform := r.URL.Query().Get("platform")
+	if platform == "" {
+		platform = "linux/amd64"
+	}
+
+	name, reference := splitImageReference(image)
+	sim := &simulation{Image: image, Reference: reference}
+
+	repository, ok := h.resolveNamespace(r, name)
+	if !ok {
+		sim.Verdict = "rejected: upstream namespace not allowed"
+		h.writeSimulation(w, sim)
+		return
+	}
+	sim.Repository = repository
+	if rule := cache.RetentionRuleFor(h.cfg.RetentionRules, repository); rule != nil {
+		sim.RetentionRule = rule.Pattern
+	}
+
+	manifest, err := h.simulateObject(r, "manifest", storage.ManifestKey(repository, reference))
+	if err != nil {
+		log.WithError(err).Error("Simulation lookup failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	sim.Manifest = manifest
+
+	if manifest.Status != "hit" {
+		sim.Verdict = "miss: manifest would be fetched from upstream"
+		sim.Notes = append(sim.Notes, "manifest is not cached; layer sizes are unknown without an upstream fetch")
+		h.writeSimulation(w, sim)
+		return
+	}
+
+	doc, err := h.cachedManifest(r, manifest.Key)
+	if err != nil {
+		sim.Verdict = "miss: cached manifest unreadable"
+		sim.Notes = append(sim.Notes, err.Error())
+		h.writeSimulation(w, sim)
+		return
+	}
+
+	if len(doc.Manifests) > 0 {
+		var selected *simulatedObject
+		for _, child := range doc.Manifests {
+			obj, err := h.simulateObject(r, "manifest", storage.ManifestKey(repository, child.Digest))
+			if err != nil {
+				log.WithError(err).Error("Simulation lookup failed")
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			obj.Digest = child.Digest
+			obj.Cacheable = h.platforms.permits(child.Platform)
+			if child.Platform != nil {
+				obj.Platform = child.Platform.String()
+			}
+			if obj.Size < 0 {
+				obj.Size = child.Size
+			}
+			sim.Children = append(sim.Children, *obj)
+			if selected == nil && strings.EqualFold(obj.Platform, platform) {
+				selected = &sim.Children[len(sim.Children)-1]
+			}
+		}
+
+		if selected == nil {
+			sim.Verdict = "no manifest for platform " + platform
+			h.writeSimulation(w, sim)
+			return
+		}
+		sim.SelectedPlatform = selected.Platform
+		if selected.Status != "hit" {
+			sim.EstimatedUpstreamBytes += selected.Size
+			sim.Verdict = "partial: index cached, platform manifest would be fetched from upstream"
+			sim.Notes = append(sim.Notes, "platform manifest is not cached; layer sizes are unknown without an upstream fetch")
+			if !selected.Cacheable {
+				sim.Notes = append(sim.Notes, "platform "+selected.Platform+" is excluded by CACHE_PLATFORMS and would be proxied uncached")
+			}
+			h.writeSimulation(w, sim)
+			return
+		}
+		if doc, err = h.cachedManifest(r, selected.Key); err != nil {
+			sim.Verdict = "miss: cached platform manifest unreadable"
+			sim.Notes = append(sim.Notes, err.Error())
+			h.writeSimulation(w, sim)
+			return
+		}
+	}
+
+	descriptors := append([]manifestDescriptor{doc.Config}, doc.Layers...)
+	hits := 0
+	for _, d := range descriptors {
+		if d.Digest == "" {
+			continue
+		}
+		obj, err := h.simulateObject(r, "blob", storage.BlobKey(repository, d.Digest))
+		if err != nil {
+			log.WithError(err).Error("Simulation lookup failed")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		obj.Digest = d.Digest
+		obj.Cacheable = !h.platforms.skipBlob(d.Digest)
+		if obj.Size < 0 {
+			obj.Size = d.Size
+		}
+		if obj.Status == "hit" {
+			hits++
+		} else {
+			sim.EstimatedUpstreamBytes += d.Size
+		}
+		sim.Blobs = append(sim.Blobs, *obj)
+	}
+
+	switch {
+	case hits == len(sim.Blobs):
+		sim.Verdict = "hit: pull would be served entirely from cache"
+	case hits == 0:
+		sim.Verdict = "miss: all blobs would be fetched from upstream"
+	default:
+		sim.Verdict = "partial: some blobs would be fetched from upstream"
+	}
+	h.writeSimulation(w, sim)
+}
+
+func (h *ProxyHandler) simulateObject(r *http.Request, kind, key string) (*simulatedObject, error) {
+	obj := &simulatedObject{Kind: kind, Key: key, Status: "miss", Size: -1, Cacheable: true}
+
+	var entry models.RegistryCache
+	result := h.db.WithContext(r.Context()).Unscoped().Where("key = ?", key).Limit(1).Find(&entry)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return obj, nil
+	}
+
+	obj.Digest = entry.Digest
+	obj.Size = entry.SizeBytes
+	obj.ExpiresAt = &entry.ExpiresAt
+	switch {
+	case entry.DeletedAt.Valid:
+		obj.Status = "invalidated"
+	case time.Now().After(entry.ExpiresAt):
+		obj.Status = "expired"
+	default:
+		obj.Status = "hit"
+	}
+	return obj, nil
+}
+
+func (h *ProxyHandler) cachedManifest(r *http.Request, key string) (*manifestDocument, error) {
+	content, _, _, err := h.storage.Get(r.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	var doc manifestDocument
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (h *ProxyHandler) writeSimulation(w http.ResponseWriter, sim *simulation) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(sim); err != nil {
+		h.log.WithError(err).Error("Failed to encode simulation response")
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/cache"
+	"github.com/sdko-org/registry-proxy/internal/events"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type topImage struct {
+	Repository string    `json:"repository"`
+	Tag        string    `json:"tag,omitempty"`
+	Digest     string    `json:"digest,omitempty"`
+	Pulls      int64     `json:"pulls"`
+	LastPull   time.Time `json:"last_pull"`
+}
+
+func (h *ProxyHandler) recordPull(r *http.Request, image, reference, digest string) {
+	if r.Method != http.MethodGet {
+		return
+	}
+	h.publishEvent(events.TypePull, "manifest", image, reference, digest, "", 0)
+	if h.db == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		now := time.Now().UTC()
+		counter := models.PullCounter{
+			Repository: normalizeImageName(image),
+			Reference:  reference,
+			Digest:     digest,
+			Day:        now.Truncate(24 * time.Hour),
+			Count:      1,
+			LastPull:   now,
+		}
+
+		err := h.db.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "repository"}, {Name: "reference"}, {Name: "digest"}, {Name: "day"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"count":     gorm.Expr("pull_counters.count + 1"),
+				"last_pull": now,
+			}),
+		}).Create(&counter).Error
+		if err == nil {
+			err = h.updateRepository(ctx, image, 1, 0)
+		}
+		if err != nil {
+			h.log.WithFields(logrus.Fields{
+				"repository": counter.Repository,
+				"reference":  reference,
+				"error":      err,
+			}).Warn("Failed to record pull")
+		}
+	}()
+}
+
+func (h *ProxyHandler) TopImages(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "top_images")
+
+	window := 7 * 24 * time.Hour
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid window", http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 1000 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	var columns, group string
+	by := r.URL.Query().Get("by")
+	switch by {
+	case "", "repository":
+		by, columns, group = "repository", "repository", "repository"
+	case "tag":
+		columns, group = "repository, reference AS tag", "repository, reference"
+	case "digest":
+		columns, group = "repository, digest", "repository, digest"
+	default:
+		http.Error(w, "by must be repository, tag or digest", http.StatusBadRequest)
+		return
+	}
+
+	since := time.Now().UTC().Add(-window).Truncate(24 * time.Hour)
+	query := h.db.WithContext(r.Context()).
+		Model(&models.PullCounter{}).
+		Select(columns+", SUM(count) AS pulls, MAX(last_pull) AS last_pull").
+		Where("day >= ?", since)
+	if by == "tag" {
+		query = query.Where("reference NOT LIKE ?", "sha256:%")
+	}
+
+	var results []topImage
+	if err := query.
+		Group(group).
+		Order("pulls DESC").
+		Limit(limit).
+		Scan(&results).Error; err != nil {
+		log.WithError(err).Error("Top images query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"window": window.String(),
+		"since":  since,
+		"by":     by,
+		"images": results,
+	}); err != nil {
+		log.WithError(err).Error("Failed to encode top images response")
+	}
+}
+
+func (h *ProxyHandler) QuotaUsage(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "quota_usage")
+
+	usages, err := cache.NamespaceUsages(r.Context(), h.db, h.cfg.NamespaceQuotas)
+	if err != nil {
+		log.WithError(err).Error("Namespace usage query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"namespaces": usages,
+	}); err != nil {
+		log.WithError(err).Error("Failed to encode quota usage response")
+	}
+}
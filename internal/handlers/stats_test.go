@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sdko-org/registry-proxy/internal/events"
+	"github.com/sdko-org/registry-proxy/internal/testing/fakeregistry"
+)
+
+func TestOnlyManifestGETsCountAsPulls(t *testing.T) {
+	fake := fakeregistry.New(fakeregistry.Options{})
+	defer fake.Close()
+	fake.AddImage("library/alpine", "3.20", fakeregistry.Layer(map[string]string{"etc/alpine-release": "3.20.0"}))
+	ph, proxy := newUpstreamProxy(t, fake.Host(), fake.Client().Transport, nil)
+
+	for _, tc := range []struct {
+		method string
+		pulls  uint64
+	}{
+		{http.MethodHead, 0},
+		{http.MethodGet, 1},
+		{http.MethodHead, 1},
+		{http.MethodGet, 2},
+	} {
+		req := httptest.NewRequest(tc.method, "/v2/alpine/manifests/3.20", nil)
+		req.Header.Set("Accept", fakeregistry.MediaTypeDockerManifest)
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", tc.method, rec.Code)
+		}
+		if got := ph.events.Counts()[events.TypePull]; got != tc.pulls {
+			t.Fatalf("after %s: %d pulls recorded, want %d", tc.method, got, tc.pulls)
+		}
+	}
+}
+
+func TestTopImagesRejectsUnknownBreakdown(t *testing.T) {
+	fake := fakeregistry.New(fakeregistry.Options{})
+	defer fake.Close()
+	ph, _ := newUpstreamProxy(t, fake.Host(), fake.Client().Transport, nil)
+	rec := httptest.NewRecorder()
+	ph.TopImages(rec, httptest.NewRequest(http.MethodGet, "/admin/stats/top-images?by=layer", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
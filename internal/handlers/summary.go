@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sdko-org/registry-proxy/internal/events"
+	"github.com/sdko-org/registry-proxy/internal/models"
+)
+
+type cacheClassUsage struct {
+	Type      string `json:"type"`
+	Entries   int64  `json:"entries"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+type compressionUsage struct {
+	Entries        int64 `json:"entries"`
+	SizeBytes      int64 `json:"size_bytes"`
+	CompressedSize int64 `json:"compressed_bytes"`
+	SavedBytes     int64 `json:"saved_bytes"`
+}
+
+func (h *ProxyHandler) CacheSummary(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "cache_summary")
+
+	var classes []cacheClassUsage
+	if err := h.db.WithContext(r.Context()).
+		Model(&models.RegistryCache{}).
+		Select("type, COUNT(*) AS entries, COALESCE(SUM(GREATEST(size_bytes, 0)), 0) AS size_bytes").
+		Group("type").
+		Scan(&classes).Error; err != nil {
+		log.WithError(err).Error("Cache summary query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var compression compressionUsage
+	if err := h.db.WithContext(r.Context()).
+		Model(&models.RegistryCache{}).
+		Select("COUNT(*) AS entries, COALESCE(SUM(size_bytes), 0) AS size_bytes, COALESCE(SUM(compressed_size), 0) AS compressed_size").
+		Where("compression <> ''").
+		Scan(&compression).Error; err != nil {
+		log.WithError(err).Error("Compression summary query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	compression.SavedBytes = compression.SizeBytes - compression.CompressedSize
+
+	var entries, sizeBytes int64
+	for _, c := range classes {
+		entries += c.Entries
+		sizeBytes += c.SizeBytes
+	}
+
+	counts := h.events.Counts()
+	hits, misses := counts[events.TypeCacheHit], counts[events.TypeCacheMiss]
+	hitRatio := 0.0
+	if hits+misses > 0 {
+		hitRatio = float64(hits) / float64(hits+misses)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries":       entries,
+		"size_bytes":    sizeBytes,
+		"classes":       classes,
+		"compression":   compression,
+		"since":         h.events.StartedAt(),
+		"events":        counts,
+		"hit_ratio":     hitRatio,
+		"recent_errors": h.events.RecentErrors(),
+	}); err != nil {
+		log.WithError(err).Error("Failed to encode cache summary response")
+	}
+}
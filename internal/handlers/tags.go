@@ -3,16 +3,22 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
 
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
 	"github.com/sdko-org/registry-proxy/internal/models"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm/clause"
 )
 
+const maxTagPages = 100
+
+var errTagCacheDisabled = errors.New("tag cache requires a database")
+
 func (h *ProxyHandler) handleTagsList(w http.ResponseWriter, r *http.Request, image string) {
 	ctx := context.Background()
 	log := h.log.WithFields(logrus.Fields{
@@ -23,9 +29,12 @@ func (h *ProxyHandler) handleTagsList(w http.ResponseWriter, r *http.Request, im
 	log.Debug("Handling tags list request")
 
 	var cachedTag models.TagCache
-	err := h.db.WithContext(ctx).
-		Where("repository = ? AND expires_at > ?", image, time.Now()).
-		First(&cachedTag).Error
+	err := errTagCacheDisabled
+	if h.db != nil {
+		err = h.db.WithContext(ctx).
+			Where("repository = ? AND expires_at > ?", image, time.Now()).
+			First(&cachedTag).Error
+	}
 
 	if err == nil && time.Since(cachedTag.StoredAt) < h.cfg.TagCacheTTL/2 {
 		log.WithFields(logrus.Fields{
@@ -76,6 +85,7 @@ func (h *ProxyHandler) handleTagsList(w http.ResponseWriter, r *http.Request, im
 	}
 
 	body, _ := io.ReadAll(resp.Body)
+	recordUpstreamBytes(w, int64(len(body)))
 	etag := resp.Header.Get("ETag")
 	lastModified, _ := time.Parse(time.RFC1123, resp.Header.Get("Last-Modified"))
 
@@ -94,9 +104,22 @@ func (h *ProxyHandler) handleTagsList(w http.ResponseWriter, r *http.Request, im
 		http.Error(w, "Invalid tags response", http.StatusBadGateway)
 		return
 	}
+	next, err := dockerhub.NextPageURL(resp)
+	if err == nil && next != "" {
+		err = h.fetchRemainingTags(ctx, next, &tagsResponse.Tags)
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch paginated tags")
+		http.Error(w, "Failed to fetch tags", http.StatusBadGateway)
+		return
+	}
+	if next != "" {
+		body, _ = json.Marshal(tagsResponse)
+	}
 
 	log.WithField("tag_count", len(tagsResponse.Tags)).Info("Caching new tags list")
 	h.cacheTags(image, body, etag, lastModified)
+	recordCacheStatus(w, cacheStatusMiss)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
@@ -105,6 +128,36 @@ func (h *ProxyHandler) handleTagsList(w http.ResponseWriter, r *http.Request, im
 	w.Write(body)
 }
 
+func (h *ProxyHandler) fetchRemainingTags(ctx context.Context, next string, tags *[]string) error {
+	for pages := 1; next != ""; pages++ {
+		if pages >= maxTagPages {
+			return fmt.Errorf("more than %d pages of tags", maxTagPages)
+		}
+
+		resp, err := h.dhClient.GetPage(ctx, next)
+		if err != nil {
+			return err
+		}
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("upstream returned status %d", resp.StatusCode)
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(&page)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if next, err = dockerhub.NextPageURL(resp); err != nil {
+			return err
+		}
+		*tags = append(*tags, page.Tags...)
+	}
+	return nil
+}
+
 func (h *ProxyHandler) serveCachedTags(w http.ResponseWriter, cachedTag *models.TagCache) {
 	h.log.WithFields(logrus.Fields{
 		"repository":  cachedTag.Repository,
@@ -114,6 +167,7 @@ func (h *ProxyHandler) serveCachedTags(w http.ResponseWriter, cachedTag *models.
 		"tag_count":   len(cachedTag.Tags),
 		"source":      "cache",
 	}).Info("Serving tags from cache")
+	recordCacheStatus(w, cacheStatusHit)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
@@ -129,8 +183,7 @@ func (h *ProxyHandler) validateTagsWithUpstream(ctx context.Context, image strin
 		"etag":       cachedTag.ETag,
 	})
 
-	req, _ := http.NewRequest("GET",
-		fmt.Sprintf("https://registry-1.docker.io/v2/%s/tags/list", image), nil)
+	req, _ := http.NewRequest("GET", h.dhClient.RepositoryURL(image, "tags/list"), nil)
 	req.Header.Set("If-None-Match", cachedTag.ETag)
 
 	log.Debug("Sending conditional request to upstream")
@@ -158,6 +211,9 @@ func (h *ProxyHandler) validateTagsWithUpstream(ctx context.Context, image strin
 }
 
 func (h *ProxyHandler) cacheTags(image string, body []byte, etag string, lastModified time.Time) {
+	if h.db == nil {
+		return
+	}
 	log := h.log.WithFields(logrus.Fields{
 		"repository":    image,
 		"operation":     "cache_tags",
@@ -178,7 +234,7 @@ func (h *ProxyHandler) cacheTags(image string, body []byte, etag string, lastMod
 	log.Debug("Storing tags in cache")
 	err := h.db.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "repository"}},
-		DoUpdates: clause.AssignmentColumns([]string{"tags", "etag", "last_modified", "expires_at", "stored_at"}),
+		DoUpdates: clause.AssignmentColumns([]string{"tags", "etag", "last_modified", "expires_at", "stored_at", "deleted_at"}),
 	}).Create(&tagEntry).Error
 
 	if err != nil {
@@ -188,33 +244,6 @@ func (h *ProxyHandler) cacheTags(image string, body []byte, etag string, lastMod
 	}
 }
 
-func (h *ProxyHandler) InvalidateCache(w http.ResponseWriter, r *http.Request) {
-	log := h.log.WithField("operation", "cache_invalidation")
-	image := r.URL.Query().Get("image")
-	digest := r.URL.Query().Get("digest")
-
-	if image != "" {
-		log = log.WithField("repository", image)
-		result := h.db.Where("repository = ?", image).Delete(&models.TagCache{})
-		if result.Error != nil {
-			log.WithError(result.Error).Error("Tag cache invalidation failed")
-		} else {
-			log.WithField("rows_affected", result.RowsAffected).Info("Invalidated tag cache")
-		}
-	}
-	if digest != "" {
-		log = log.WithField("digest", digest)
-		result := h.db.Where("digest = ?", digest).Delete(&models.RegistryCache{})
-		if result.Error != nil {
-			log.WithError(result.Error).Error("Registry cache invalidation failed")
-		} else {
-			log.WithField("rows_affected", result.RowsAffected).Info("Invalidated registry cache")
-		}
-	}
-
-	w.WriteHeader(http.StatusOK)
-}
-
 func HandleCatalog(w http.ResponseWriter, r *http.Request) {
 	log := logrus.WithFields(logrus.Fields{
 		"operation": "catalog",
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+)
+
+// pagedRegistry serves tags/list in pages of two behind a Basic challenge, the
+// way Quay and Harbor do. link renders the Link header for the page after last.
+func pagedRegistry(t *testing.T, tags []string, link func(server *httptest.Server, last string) string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "robot" || password != "s3cret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="quay"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v2/org/app/tags/list" {
+			http.NotFound(w, r)
+			return
+		}
+		start := 0
+		if last := r.URL.Query().Get("last"); last != "" {
+			start = slices.Index(tags, last) + 1
+		}
+		end := min(start+2, len(tags))
+		if end < len(tags) {
+			w.Header().Set("Link", link(server, tags[end-1]))
+		}
+		json.NewEncoder(w).Encode(map[string]any{"name": "org/app", "tags": tags[start:end]})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func pagedProxy(t *testing.T, server *httptest.Server) http.Handler {
+	host := strings.TrimPrefix(server.URL, "https://")
+	_, proxy := newUpstreamProxy(t, host, server.Client().Transport, func(cfg *config.Config) {
+		cfg.RegistryCredentialMap = host + "=robot:s3cret"
+	})
+	return proxy
+}
+
+func TestTagsListFollowsLinkPagination(t *testing.T) {
+	tags := []string{"1.0", "1.1", "2.0", "2.1", "3.0"}
+	page := 0
+	server := pagedRegistry(t, tags, func(server *httptest.Server, last string) string {
+		page++
+		if page%2 == 0 {
+			return fmt.Sprintf(`<%s/v2/org/app/tags/list?last=%s&n=2>; rel="next"`, server.URL, last)
+		}
+		return fmt.Sprintf(`</v2/org/app/tags/list?last=%s&n=2>; rel="next"`, last)
+	})
+
+	rec := pull(t, pagedProxy(t, server), "/v2/org/app/tags/list")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var got struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got.Tags, tags) {
+		t.Fatalf("tags = %v, want %v", got.Tags, tags)
+	}
+}
+
+func TestTagsListRejectsBadLinks(t *testing.T) {
+	tests := map[string]func(server *httptest.Server, last string) string{
+		"malformed": func(_ *httptest.Server, last string) string {
+			return fmt.Sprintf(`/v2/org/app/tags/list?last=%s; rel="next"`, last)
+		},
+		"other host": func(_ *httptest.Server, last string) string {
+			return fmt.Sprintf(`<https://attacker.test/v2/org/app/tags/list?last=%s>; rel="next"`, last)
+		},
+	}
+	for name, link := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := pagedRegistry(t, []string{"1.0", "1.1", "2.0"}, link)
+			rec := pull(t, pagedProxy(t, server), "/v2/org/app/tags/list")
+			if rec.Code != http.StatusBadGateway {
+				t.Fatalf("status = %d, want 502 instead of a truncated tag list", rec.Code)
+			}
+		})
+	}
+}
+
+func TestTagsListWithoutBasicCredentials(t *testing.T) {
+	server := pagedRegistry(t, []string{"1.0"}, nil)
+	host := strings.TrimPrefix(server.URL, "https://")
+	_, proxy := newUpstreamProxy(t, host, server.Client().Transport, nil)
+
+	rec := pull(t, proxy, "/v2/org/app/tags/list")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want the upstream 401 reported as NAME_UNKNOWN", rec.Code)
+	}
+	if challenge := rec.Header().Get("WWW-Authenticate"); challenge != "" {
+		t.Fatalf("upstream challenge %q leaked to the client", challenge)
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const partialSuffix = ".partial"
+
+func (h *ProxyHandler) commitTempFile(f *os.File, path string, size int64) error {
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	h.verified.Store(path, size)
+	return nil
+}
+
+func (h *ProxyHandler) removeTempFile(path string) {
+	h.verified.Delete(path)
+	os.Remove(path)
+}
+
+func (h *ProxyHandler) verifyTempFile(f *os.File, digest string) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if size, ok := h.verified.Load(f.Name()); ok && size.(int64) == fi.Size() {
+		return true
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return false
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return false
+	}
+	if "sha256:"+hex.EncodeToString(hash.Sum(nil)) != digest {
+		return false
+	}
+	h.verified.Store(f.Name(), fi.Size())
+	return true
+}
+
+func (h *ProxyHandler) recoverTempFiles() {
+	log := h.log.WithField("operation", "recover_temp_files")
+
+	entries, err := os.ReadDir(h.tempDir)
+	if err != nil {
+		log.WithError(err).Warn("Failed to list temporary directory")
+		return
+	}
+
+	resumable := h.resumableDownloads()
+	var removed int
+	var complete []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() && strings.HasPrefix(name, sbomTempDirPrefix) {
+			if err := os.RemoveAll(filepath.Join(h.tempDir, name)); err == nil {
+				removed++
+			}
+			continue
+		}
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if resumable[name] {
+			continue
+		}
+		if strings.HasSuffix(name, partialSuffix) || strings.Contains(name, ".job-") {
+			if err := os.Remove(filepath.Join(h.tempDir, name)); err == nil {
+				removed++
+			}
+			continue
+		}
+		complete = append(complete, name)
+	}
+	if removed > 0 {
+		log.WithField("removed", removed).Info("Removed interrupted downloads from temporary directory")
+	}
+	if len(resumable) > 0 {
+		log.WithField("resumable", len(resumable)).Info("Keeping interrupted downloads for resumption")
+	}
+	if len(complete) == 0 {
+		return
+	}
+
+	go func() {
+		for _, name := range complete {
+			digest := strings.Replace(name, "_", ":", 1)
+			if !validDigestRegex.MatchString(digest) {
+				continue
+			}
+			path := filepath.Join(h.tempDir, name)
+			f, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			valid := h.verifyTempFile(f, digest)
+			f.Close()
+			if !valid {
+				log.WithFields(logrus.Fields{
+					"digest": digest,
+					"path":   path,
+				}).Warn("Removing temporary blob that failed digest verification")
+				h.removeTempFile(path)
+			}
+		}
+	}()
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/auth"
+	"github.com/sdko-org/registry-proxy/internal/cache"
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+type tenantTraffic struct {
+	Tenant        string `json:"-"`
+	Requests      int64  `json:"requests"`
+	BytesSent     int64  `json:"bytes_sent"`
+	CacheBytes    int64  `json:"cache_bytes"`
+	UpstreamBytes int64  `json:"upstream_bytes"`
+}
+
+type tenantReport struct {
+	cache.TenantUsage
+	RateLimit int           `json:"rate_limit,omitempty"`
+	Traffic   tenantTraffic `json:"traffic"`
+}
+
+// Runs after AuthMiddleware so tenants can be matched on the authenticated
+// subject as well as the Host header.
+func TenantMiddleware(logger *logrus.Logger, cfg *config.Config) func(http.Handler) http.Handler {
+	limiters := make(map[string]*rate.Limiter)
+	for _, tenant := range cfg.Tenants {
+		if tenant.RateLimit > 0 {
+			limiters[tenant.Name] = rate.NewLimiter(rate.Limit(float64(tenant.RateLimit)/cfg.RateLimitWindow.Seconds()), tenant.RateLimit)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		if len(cfg.Tenants) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v2" && !strings.HasPrefix(r.URL.Path, "/v2/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var subject string
+			if principal := auth.PrincipalFromContext(r.Context()); principal != nil {
+				subject = principal.Subject
+			}
+			tenant := cfg.TenantFor(r.Host, subject)
+			if tenant == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if lrw := accessRecord(w); lrw != nil {
+				lrw.tenant = tenant.Name
+			}
+
+			if limiter := limiters[tenant.Name]; limiter != nil && !limiter.Allow() {
+				writeRegistryError(w, http.StatusTooManyRequests, "TOOMANYREQUESTS", fmt.Sprintf("tenant %s rate limit exceeded", tenant.Name))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (h *ProxyHandler) tenantAllows(w http.ResponseWriter, r *http.Request, repository string) bool {
+	var subject string
+	if principal := auth.PrincipalFromContext(r.Context()); principal != nil {
+		subject = principal.Subject
+	}
+	tenant := h.cfg.TenantFor(r.Host, subject)
+	if tenant == nil || len(tenant.Namespaces) == 0 || tenantOwns(tenant, repository) {
+		return true
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"operation":  "tenant_namespaces",
+		"tenant":     tenant.Name,
+		"repository": repository,
+	}).Info("Repository outside tenant namespaces")
+	writeRegistryError(w, http.StatusForbidden, "DENIED", "repository is outside the tenant's namespaces")
+	return false
+}
+
+func tenantOwns(tenant *config.Tenant, repository string) bool {
+	for _, pattern := range tenant.Namespaces {
+		if config.MatchPattern(pattern, repository) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *ProxyHandler) Tenants(w http.ResponseWriter, r *http.Request) {
+	log := h.log.WithField("operation", "tenant_usage")
+
+	window := 24 * time.Hour
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid window", http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+
+	usages, err := cache.TenantUsages(r.Context(), h.db, h.cfg.Tenants)
+	if err != nil {
+		log.WithError(err).Error("Tenant usage query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var traffic []tenantTraffic
+	if err := h.db.WithContext(r.Context()).
+		Model(&models.AccessLog{}).
+		Select("tenant, COUNT(*) AS requests, COALESCE(SUM(bytes_sent), 0) AS bytes_sent, "+
+			"COALESCE(SUM(cache_bytes), 0) AS cache_bytes, COALESCE(SUM(upstream_bytes), 0) AS upstream_bytes").
+		Where("timestamp >= ? AND tenant <> ''", time.Now().Add(-window)).
+		Group("tenant").
+		Scan(&traffic).Error; err != nil {
+		log.WithError(err).Error("Tenant traffic query failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	byTenant := make(map[string]tenantTraffic, len(traffic))
+	for _, t := range traffic {
+		byTenant[t.Tenant] = t
+	}
+
+	reports := make([]tenantReport, 0, len(usages))
+	for i, usage := range usages {
+		reports = append(reports, tenantReport{
+			TenantUsage: usage,
+			RateLimit:   h.cfg.Tenants[i].RateLimit,
+			Traffic:     byTenant[usage.Tenant],
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"window":  window.String(),
+		"tenants": reports,
+	}); err != nil {
+		log.WithError(err).Error("Failed to encode tenant usage response")
+	}
+}
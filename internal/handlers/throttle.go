@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sdko-org/registry-proxy/internal/throttle"
+)
+
+const throttleChunkSize = 32 * 1024
+
+type throttledResponseWriter struct {
+	http.ResponseWriter
+	ctx      context.Context
+	limiters []*throttle.Limiter
+}
+
+func (h *ProxyHandler) throttleResponse(w http.ResponseWriter, r *http.Request, image string) http.ResponseWriter {
+	var limiters []*throttle.Limiter
+	for _, l := range []*throttle.Limiter{h.throttle.Client(getClientIP(r)), h.throttle.Repository(image)} {
+		if l != nil {
+			limiters = append(limiters, l)
+		}
+	}
+	if len(limiters) == 0 {
+		return w
+	}
+	return &throttledResponseWriter{ResponseWriter: w, ctx: r.Context(), limiters: limiters}
+}
+
+func (tw *throttledResponseWriter) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		end := written + throttleChunkSize
+		if end > len(b) {
+			end = len(b)
+		}
+		if err := throttle.WaitAll(tw.ctx, end-written, tw.limiters...); err != nil {
+			return written, err
+		}
+		n, err := tw.ResponseWriter.Write(b[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func (tw *throttledResponseWriter) Flush() {
+	if flusher, ok := tw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (tw *throttledResponseWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+func (h *ProxyHandler) BandwidthStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(h.throttle.Stats()); err != nil {
+		h.log.WithError(err).Error("Failed to encode bandwidth stats response")
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/p2p"
+	"github.com/sdko-org/registry-proxy/internal/peer"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	routeClassAPI    = "api"
+	routeClassBlob   = "blob"
+	routeClassStream = "stream"
+)
+
+func routeClass(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/v2/") && strings.Contains(path, "/blobs/"),
+		strings.HasPrefix(path, peer.BlobsPath),
+		strings.HasPrefix(path, p2p.BlobsPath):
+		return routeClassBlob
+	case path == "/admin/events":
+		return routeClassStream
+	}
+	return routeClassAPI
+}
+
+func RouteTimeoutsMiddleware(logger *logrus.Logger, cfg *config.Config) func(http.Handler) http.Handler {
+	log := logger.WithField("component", "http_middleware")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var timeout time.Duration
+			switch routeClass(r.URL.Path) {
+			case routeClassAPI:
+				timeout = cfg.APITimeout
+			case routeClassBlob:
+				timeout = cfg.BlobTimeout
+			}
+
+			var deadline time.Time
+			if timeout > 0 {
+				deadline = time.Now().Add(timeout)
+			}
+			rc := http.NewResponseController(w)
+			if err := rc.SetWriteDeadline(deadline); err != nil {
+				log.WithFields(logrus.Fields{
+					"path":  r.URL.Path,
+					"error": err,
+				}).Debug("Failed to set write deadline")
+			}
+			if err := rc.SetReadDeadline(deadline); err != nil {
+				log.WithFields(logrus.Fields{
+					"path":  r.URL.Path,
+					"error": err,
+				}).Debug("Failed to set read deadline")
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
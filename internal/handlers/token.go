@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/auth"
+	"github.com/sirupsen/logrus"
+)
+
+type tokenResponse struct {
+	Token       string    `json:"token"`
+	AccessToken string    `json:"access_token"`
+	ExpiresIn   int       `json:"expires_in"`
+	IssuedAt    time.Time `json:"issued_at"`
+}
+
+func TokenHandler(logger *logrus.Logger, authenticators []auth.Authenticator, issuer *auth.TokenIssuer) http.HandlerFunc {
+	log := logger.WithField("component", "token_service")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		scopes := params["scope"]
+		if r.Method == http.MethodPost {
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, "Invalid form", http.StatusBadRequest)
+				return
+			}
+			switch r.PostForm.Get("grant_type") {
+			case "password":
+				r.SetBasicAuth(r.PostForm.Get("username"), r.PostForm.Get("password"))
+			default:
+				writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type")
+				return
+			}
+			params = r.PostForm
+			scopes = strings.Fields(r.PostForm.Get("scope"))
+		}
+
+		if service := params.Get("service"); service != "" && service != issuer.Service() {
+			writeRegistryError(w, http.StatusBadRequest, "UNSUPPORTED", "unknown service")
+			return
+		}
+
+		var principal *auth.Principal
+		for _, authenticator := range authenticators {
+			p, err := authenticator.Authenticate(r)
+			if err == nil {
+				principal = p
+				break
+			}
+			if !errors.Is(err, auth.ErrNoCredentials) {
+				log.WithFields(logrus.Fields{
+					"authenticator": authenticator.Name(),
+					"client_ip":     getClientIP(r),
+					"error":         err,
+				}).Debug("Token authentication failed")
+			}
+		}
+		if principal == nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry-proxy"`)
+			writeRegistryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "authentication required")
+			return
+		}
+
+		var granted []auth.Scope
+		for _, value := range scopes {
+			for _, item := range strings.Fields(value) {
+				scope, err := auth.ParseScope(item)
+				if err != nil || scope.Type != "repository" || !validRepositoryName(scope.Name) {
+					writeRegistryError(w, http.StatusBadRequest, "UNSUPPORTED", fmt.Sprintf("invalid scope %q", item))
+					return
+				}
+				scope.Name = normalizeImageName(scope.Name)
+				actions := []string{}
+				if containsString(scope.Actions, "pull") && principal.CanPull(scope.Name) {
+					actions = append(actions, "pull")
+				}
+				scope.Actions = actions
+				granted = append(granted, scope)
+			}
+		}
+
+		now := time.Now().UTC()
+		token, expires, err := issuer.Issue(principal.Subject, principal.Method, granted, now)
+		if err != nil {
+			log.WithError(err).Error("Failed to issue token")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if lrw, ok := w.(*loggingResponseWriter); ok {
+			lrw.username = principal.Subject
+		}
+		log.WithFields(logrus.Fields{
+			"subject": principal.Subject,
+			"scopes":  len(granted),
+		}).Debug("Issued registry token")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(tokenResponse{
+			Token:       token,
+			AccessToken: token,
+			ExpiresIn:   int(expires.Sub(now).Seconds()),
+			IssuedAt:    now,
+		})
+	}
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenRealm(r *http.Request, configured string) string {
+	if configured != "" {
+		return configured
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + "/token"
+}
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (h *ProxyHandler) UpstreamStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"upstreams": h.dhClient.UpstreamStatuses(),
+	}); err != nil {
+		h.log.WithError(err).Error("Failed to encode upstream status response")
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *ProxyHandler) vanityImage(r *http.Request, name string) (string, bool) {
+	host := strings.ToLower(r.Host)
+	if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" && h.shardForwarded(r) {
+		host = strings.ToLower(forwarded)
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	var route *config.VanityRoute
+	var rest string
+	for i := range h.cfg.VanityRoutes {
+		candidate := &h.cfg.VanityRoutes[i]
+		if candidate.Host != "" && candidate.Host != host {
+			continue
+		}
+		remainder := name
+		if candidate.Prefix != "" {
+			var ok bool
+			if remainder, ok = strings.CutPrefix(name, candidate.Prefix+"/"); !ok {
+				continue
+			}
+		}
+		if route == nil || len(candidate.Prefix) > len(route.Prefix) || (candidate.Host != "" && route.Host == "" && len(candidate.Prefix) == len(route.Prefix)) {
+			route, rest = candidate, remainder
+		}
+	}
+	if route == nil {
+		return "", false
+	}
+
+	image := route.Upstream + "/" + rest
+	if registry, namespace, _ := strings.Cut(route.Upstream, "/"); dockerhub.IsDockerHub(registry) {
+		image = rest
+		if namespace != "" {
+			image = namespace + "/" + rest
+		}
+		image = normalizeImageName(image)
+	}
+
+	h.log.WithFields(logrus.Fields{
+		"operation": "vanity_route",
+		"host":      host,
+		"name":      name,
+		"image":     image,
+	}).Debug("Mapped request through vanity route")
+	return image, true
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+var testVanityRoutes = []config.VanityRoute{
+	{Host: "images.example", Upstream: "docker.io/myorg"},
+	{Prefix: "hub", Upstream: "docker.io"},
+	{Prefix: "gh", Upstream: "ghcr.io"},
+}
+
+func TestResolveNamespace(t *testing.T) {
+	logger, _ := logtest.NewNullLogger()
+	cfg := &config.Config{
+		TempDir:          t.TempDir(),
+		MirrorNamespaces: []string{"ghcr.io", "quay.io"},
+		VanityRoutes:     testVanityRoutes,
+	}
+	store, err := storage.NewDiskStorage(logger, t.TempDir(), 1<<30, 1<<30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ph := NewProxyHandler(logger, cfg, store, dockerhub.NewClient(logger, cfg), nil, nil, nil, nil, nil)
+
+	for _, tc := range []struct {
+		host string
+		path string
+		name string
+		want string
+		ok   bool
+	}{
+		{"proxy.example", "/v2/nginx/tags/list", "nginx", "library/nginx", true},
+		{"proxy.example", "/v2/myorg/app/tags/list", "myorg/app", "myorg/app", true},
+		{"proxy.example", "/v2/nginx/tags/list?ns=docker.io", "nginx", "library/nginx", true},
+		{"proxy.example", "/v2/org/app/tags/list?ns=ghcr.io", "org/app", "ghcr.io/org/app", true},
+		{"proxy.example", "/v2/quay.io/org/app/tags/list", "quay.io/org/app", "quay.io/org/app", true},
+		{"proxy.example", "/v2/org/app/tags/list?ns=gcr.io", "org/app", "", false},
+		{"proxy.example", "/v2/gcr.io/org/app/tags/list", "gcr.io/org/app", "", false},
+		{"images.example", "/v2/app/tags/list", "app", "myorg/app", true},
+		{"images.example:5000", "/v2/app/tags/list", "app", "myorg/app", true},
+		{"proxy.example", "/v2/hub/alpine/tags/list", "hub/alpine", "library/alpine", true},
+		{"proxy.example", "/v2/gh/org/app/tags/list", "gh/org/app", "ghcr.io/org/app", true},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Host = tc.host
+		got, ok := ph.resolveNamespace(req, tc.name)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("%s%s: resolveNamespace = %q, %v; want %q, %v", tc.host, tc.path, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestVanityRoutesRequireTargetGrant(t *testing.T) {
+	proxy := newAuthProxy(t, grantAuthenticator{
+		"raw":    {"library/app", "hub/**"},
+		"target": {"myorg/app", "library/alpine"},
+	}, func(cfg *config.Config) {
+		cfg.VanityRoutes = testVanityRoutes
+	})
+	digest := sha256Digest([]byte("image"))
+
+	for _, tc := range []struct {
+		host string
+		path string
+		user string
+		want int
+	}{
+		{"images.example", "/v2/app/referrers/" + digest, "raw", http.StatusForbidden},
+		{"proxy.example", "/v2/hub/alpine/referrers/" + digest, "raw", http.StatusForbidden},
+		{"images.example", "/v2/app/referrers/" + digest, "target", http.StatusOK},
+		{"proxy.example", "/v2/hub/alpine/referrers/" + digest, "target", http.StatusOK},
+	} {
+		if rec := authorizedPull(proxy, tc.path, tc.user, tc.host); rec.Code != tc.want {
+			t.Errorf("%s%s as %q: status = %d, want %d", tc.host, tc.path, tc.user, rec.Code, tc.want)
+		}
+	}
+}
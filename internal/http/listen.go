@@ -0,0 +1,59 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const listenFDsStart = 3
+
+func inheritedListeners() (map[string]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	var names []string
+	if value := os.Getenv("LISTEN_FDNAMES"); value != "" {
+		names = strings.Split(value, ":")
+	}
+
+	listeners := make(map[string]net.Listener, count)
+	for i := 0; i < count; i++ {
+		name := strconv.Itoa(i)
+		if i < len(names) && names[i] != "" && names[i] != "unknown" {
+			name = names[i]
+		}
+
+		file := os.NewFile(uintptr(listenFDsStart+i), name)
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("socket activation fd %d (%s): %w", listenFDsStart+i, name, err)
+		}
+		listeners[name] = listener
+	}
+	return listeners, nil
+}
+
+func listen(addr string, reusePort bool) (net.Listener, error) {
+	var lc net.ListenConfig
+	if reusePort {
+		lc.Control = setReusePort
+	}
+	return lc.Listen(context.Background(), "tcp", addr)
+}
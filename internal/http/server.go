@@ -1,6 +1,7 @@
 package httpserver
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -8,7 +9,10 @@ import (
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"math/big"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -51,35 +55,130 @@ func generateSelfSignedCert() (tls.Certificate, error) {
 	return tls.X509KeyPair(certPEM, keyPEM)
 }
 
-func StartServers(logger *logrus.Logger, handler http.Handler) {
-	go func() {
-		httpServer := &http.Server{
-			Addr:    ":8443",
-			Handler: handler,
-		}
-		logger.WithField("port", 8443).Info("Starting HTTP server")
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.WithError(err).Fatal("HTTP server failed")
-		}
-	}()
+type Listener struct {
+	Name    string
+	Addr    string
+	TLS     bool
+	Handler http.Handler
 
-	go func() {
-		cert, err := generateSelfSignedCert()
-		if err != nil {
-			logger.WithError(err).Fatal("Failed to generate self-signed certificate")
-		}
+	MaxHeaderBytes    int
+	ReadHeaderTimeout time.Duration
+	IdleTimeout       time.Duration
+
+	ReusePort bool
+}
+
+type Servers struct {
+	logger  *logrus.Logger
+	servers []*server
+}
+
+type server struct {
+	name   string
+	http   *http.Server
+	active atomic.Int64
+}
 
-		httpsServer := &http.Server{
-			Addr:    ":9443",
-			Handler: handler,
-			TLSConfig: &tls.Config{
-				Certificates: []tls.Certificate{cert},
-			},
+func StartServers(logger *logrus.Logger, listeners []Listener) *Servers {
+	inherited, err := inheritedListeners()
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to use socket activation listeners")
+	}
+
+	group := &Servers{logger: logger}
+	for _, listener := range listeners {
+		ln, activated := inherited[listener.Name]
+		delete(inherited, listener.Name)
+		if !activated {
+			if listener.Addr == "" {
+				continue
+			}
+			if ln, err = listen(listener.Addr, listener.ReusePort); err != nil {
+				logger.WithError(err).WithFields(logrus.Fields{
+					"listener": listener.Name,
+					"addr":     listener.Addr,
+				}).Fatal("Failed to bind listener")
+			}
 		}
 
-		logger.WithField("port", 9443).Info("Starting HTTPS server")
-		if err := httpsServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
-			logger.WithError(err).Fatal("HTTPS server failed")
+		srv := newServer(listener)
+		group.servers = append(group.servers, srv)
+		go serve(logger, listener, srv, ln, activated)
+	}
+
+	for name, ln := range inherited {
+		logger.WithFields(logrus.Fields{
+			"listener": name,
+			"addr":     ln.Addr().String(),
+		}).Warn("Ignoring socket activation listener with unknown name")
+		ln.Close()
+	}
+	return group
+}
+
+func (g *Servers) Shutdown(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, srv := range g.servers {
+		wg.Add(1)
+		go func(srv *server) {
+			defer wg.Done()
+			log := g.logger.WithField("listener", srv.name)
+			log.WithField("active_connections", srv.active.Load()).Info("Draining connections")
+
+			if err := srv.http.Shutdown(ctx); err != nil {
+				log.WithError(err).WithField("active_connections", srv.active.Load()).Warn("Drain timeout exceeded, closing remaining connections")
+				srv.http.Close()
+			}
+		}(srv)
+	}
+	wg.Wait()
+}
+
+func newServer(listener Listener) *server {
+	srv := &server{name: listener.Name}
+	srv.http = &http.Server{
+		Handler:           listener.Handler,
+		MaxHeaderBytes:    listener.MaxHeaderBytes,
+		ReadHeaderTimeout: listener.ReadHeaderTimeout,
+		IdleTimeout:       listener.IdleTimeout,
+		ConnState: func(conn net.Conn, state http.ConnState) {
+			switch state {
+			case http.StateNew:
+				srv.active.Add(1)
+			case http.StateClosed, http.StateHijacked:
+				srv.active.Add(-1)
+			}
+		},
+	}
+	return srv
+}
+
+func serve(logger *logrus.Logger, listener Listener, srv *server, ln net.Listener, activated bool) {
+	log := logger.WithFields(logrus.Fields{
+		"listener":         listener.Name,
+		"addr":             ln.Addr().String(),
+		"socket_activated": activated,
+		"reuse_port":       listener.ReusePort && !activated,
+	})
+
+	if !listener.TLS {
+		log.Info("Starting HTTP server")
+		if err := srv.http.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Fatal("HTTP server failed")
 		}
-	}()
+		return
+	}
+
+	cert, err := generateSelfSignedCert()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to generate self-signed certificate")
+	}
+	srv.http.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+
+	log.Info("Starting HTTPS server")
+	if err := srv.http.ServeTLS(ln, "", ""); err != nil && err != http.ErrServerClosed {
+		log.WithError(err).Fatal("HTTPS server failed")
+	}
 }
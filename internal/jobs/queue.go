@@ -0,0 +1,284 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusDead    = "dead"
+)
+
+const (
+	TypeCacheWrite = "cache_write"
+	TypePrewarm    = "prewarm"
+	TypeGC         = "gc"
+	TypeReindex    = "reindex"
+	TypeSBOM       = "sbom"
+)
+
+var ErrUnavailable = errors.New("job queue unavailable")
+
+type HandlerFunc func(ctx context.Context, job *models.Job) error
+
+type Config struct {
+	Workers      int
+	MaxAttempts  int
+	PollInterval time.Duration
+	LockTimeout  time.Duration
+	Retention    time.Duration
+}
+
+type Queue struct {
+	db       *gorm.DB
+	cfg      Config
+	log      *logrus.Entry
+	worker   string
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+	wake     chan struct{}
+}
+
+func NewQueue(logger *logrus.Logger, db *gorm.DB, cfg Config) *Queue {
+	hostname, _ := os.Hostname()
+	return &Queue{
+		db:       db,
+		cfg:      cfg,
+		log:      logger.WithField("component", "job_queue"),
+		worker:   fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		handlers: make(map[string]HandlerFunc),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+func (q *Queue) Register(jobType string, handler HandlerFunc) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}) (*models.Job, error) {
+	if q == nil {
+		return nil, ErrUnavailable
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode payload: %w", err)
+	}
+
+	job := &models.Job{
+		Type:        jobType,
+		Payload:     string(raw),
+		Status:      StatusPending,
+		MaxAttempts: q.cfg.MaxAttempts,
+		RunAt:       time.Now(),
+	}
+	if err := q.db.WithContext(ctx).Create(job).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+func (q *Queue) Backlog(ctx context.Context, jobType string) (int64, error) {
+	if q == nil {
+		return 0, ErrUnavailable
+	}
+	var count int64
+	if err := q.db.WithContext(ctx).Model(&models.Job{}).
+		Where("type = ? AND status IN ?", jobType, []string{StatusPending, StatusRunning}).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	return count, nil
+}
+
+func (q *Queue) Retry(ctx context.Context, id uint) error {
+	result := q.db.WithContext(ctx).Model(&models.Job{}).
+		Where("id = ? AND status = ?", id, StatusDead).
+		Updates(map[string]interface{}{
+			"status":   StatusPending,
+			"attempts": 0,
+			"run_at":   time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("database error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (q *Queue) Start(ctx context.Context) {
+	if q == nil {
+		return
+	}
+	q.log.WithFields(logrus.Fields{
+		"workers": q.cfg.Workers,
+		"worker":  q.worker,
+	}).Info("Starting job queue")
+
+	for i := 0; i < q.cfg.Workers; i++ {
+		go q.work(ctx)
+	}
+
+	ticker := time.NewTicker(q.cfg.LockTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			q.reclaimStale(ctx)
+			q.cleanupFinished(ctx)
+		case <-ctx.Done():
+			q.log.Info("Stopping job queue")
+			return
+		}
+	}
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for {
+		job, err := q.claim(ctx)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			q.log.WithError(err).Warn("Failed to claim job")
+		}
+		if job != nil {
+			q.process(ctx, job)
+			continue
+		}
+
+		select {
+		case <-q.wake:
+		case <-time.After(q.cfg.PollInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (q *Queue) claim(ctx context.Context) (*models.Job, error) {
+	var job models.Job
+	result := q.db.WithContext(ctx).Raw(`
+		UPDATE jobs SET status = ?, locked_by = ?, locked_at = NOW(), attempts = attempts + 1, updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = ? AND run_at <= NOW()
+			ORDER BY run_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING *`, StatusRunning, q.worker, StatusPending).Scan(&job)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &job, nil
+}
+
+func (q *Queue) process(ctx context.Context, job *models.Job) {
+	log := q.log.WithFields(logrus.Fields{
+		"job_id":   job.ID,
+		"job_type": job.Type,
+		"attempt":  job.Attempts,
+	})
+
+	q.mu.RLock()
+	handler, ok := q.handlers[job.Type]
+	q.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("no handler registered for job type %q", job.Type)
+		job.Attempts = job.MaxAttempts
+	} else {
+		jobCtx, cancel := context.WithTimeout(ctx, q.cfg.LockTimeout)
+		err = handler(jobCtx, job)
+		cancel()
+	}
+
+	updates := map[string]interface{}{
+		"locked_by":  "",
+		"updated_at": time.Now(),
+	}
+	switch {
+	case err == nil:
+		updates["status"] = StatusDone
+		updates["last_error"] = ""
+		log.Debug("Job completed")
+	case job.Attempts >= job.MaxAttempts:
+		updates["status"] = StatusDead
+		updates["last_error"] = err.Error()
+		log.WithError(err).Error("Job moved to dead letter")
+	default:
+		backoff := time.Duration(job.Attempts*job.Attempts) * 10 * time.Second
+		updates["status"] = StatusPending
+		updates["last_error"] = err.Error()
+		updates["run_at"] = time.Now().Add(backoff)
+		log.WithError(err).WithField("retry_in", backoff).Warn("Job failed, scheduling retry")
+	}
+
+	// Fence on the claim: if the lock timed out and reclaimStale handed the
+	// job to another worker, this late result must not overwrite its state.
+	result := q.db.Model(&models.Job{}).
+		Where("id = ? AND status = ? AND locked_by = ? AND locked_at = ?", job.ID, StatusRunning, q.worker, job.LockedAt).
+		Updates(updates)
+	if result.Error != nil {
+		log.WithError(result.Error).Error("Failed to update job status")
+		return
+	}
+	if result.RowsAffected == 0 {
+		log.Warn("Job lease lost before completion, discarding result")
+	}
+}
+
+func (q *Queue) reclaimStale(ctx context.Context) {
+	result := q.db.WithContext(ctx).Model(&models.Job{}).
+		Where("status = ? AND locked_at < ?", StatusRunning, time.Now().Add(-q.cfg.LockTimeout)).
+		Updates(map[string]interface{}{
+			"status":    StatusPending,
+			"locked_by": "",
+			"run_at":    time.Now(),
+		})
+	if result.Error != nil {
+		q.log.WithError(result.Error).Warn("Failed to reclaim stale jobs")
+		return
+	}
+	if result.RowsAffected > 0 {
+		q.log.WithField("count", result.RowsAffected).Warn("Reclaimed stale jobs")
+	}
+}
+
+func (q *Queue) cleanupFinished(ctx context.Context) {
+	result := q.db.WithContext(ctx).
+		Where("status = ? AND updated_at < ?", StatusDone, time.Now().Add(-q.cfg.Retention)).
+		Delete(&models.Job{})
+	if result.Error != nil {
+		q.log.WithError(result.Error).Warn("Failed to clean up finished jobs")
+		return
+	}
+	if result.RowsAffected > 0 {
+		q.log.WithField("count", result.RowsAffected).Debug("Cleaned up finished jobs")
+	}
+}
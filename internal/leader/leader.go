@@ -0,0 +1,118 @@
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type Elector struct {
+	db       *gorm.DB
+	name     string
+	holder   string
+	ttl      time.Duration
+	log      *logrus.Entry
+	isLeader atomic.Bool
+}
+
+func NewElector(logger *logrus.Logger, db *gorm.DB, name string, ttl time.Duration) *Elector {
+	holder := holderID()
+	return &Elector{
+		db:     db,
+		name:   name,
+		holder: holder,
+		ttl:    ttl,
+		log: logger.WithFields(logrus.Fields{
+			"component": "leader_election",
+			"lease":     name,
+			"holder":    holder,
+		}),
+	}
+}
+
+func holderID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+func (e *Elector) IsLeader() bool {
+	if e == nil {
+		return true
+	}
+	return e.isLeader.Load()
+}
+
+func (e *Elector) Holder() string {
+	if e == nil {
+		return ""
+	}
+	return e.holder
+}
+
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	e.log.Info("Starting leader election")
+	e.tryAcquire(ctx)
+
+	for {
+		select {
+		case <-ticker.C:
+			e.tryAcquire(ctx)
+		case <-ctx.Done():
+			e.release()
+			return
+		}
+	}
+}
+
+func (e *Elector) tryAcquire(ctx context.Context) {
+	result := e.db.WithContext(ctx).Exec(`
+		INSERT INTO leases (name, holder, expires_at)
+		VALUES (?, ?, NOW() + (? * INTERVAL '1 millisecond'))
+		ON CONFLICT (name) DO UPDATE
+		SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE leases.holder = EXCLUDED.holder OR leases.expires_at < NOW()`,
+		e.name, e.holder, e.ttl.Milliseconds())
+
+	acquired := result.Error == nil && result.RowsAffected == 1
+	if result.Error != nil {
+		e.log.WithError(result.Error).Warn("Lease renewal failed")
+	}
+
+	if was := e.isLeader.Swap(acquired); was != acquired {
+		if acquired {
+			e.log.Info("Acquired leadership")
+		} else {
+			e.log.Warn("Lost leadership")
+		}
+	}
+}
+
+func (e *Elector) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	e.isLeader.Store(false)
+	if err := e.db.WithContext(ctx).
+		Where("name = ? AND holder = ?", e.name, e.holder).
+		Delete(&models.Lease{}).Error; err != nil {
+		e.log.WithError(err).Warn("Failed to release lease")
+		return
+	}
+	e.log.Info("Released leadership")
+}
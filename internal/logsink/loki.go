@@ -0,0 +1,152 @@
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type LokiConfig struct {
+	URL           string
+	Labels        map[string]string
+	Tenant        string
+	Credentials   func() (string, string)
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+type lokiLine struct {
+	level string
+	ts    time.Time
+	line  string
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type LokiHook struct {
+	cfg        LokiConfig
+	httpClient *http.Client
+	formatter  logrus.Formatter
+	queue      chan lokiLine
+}
+
+func NewLokiHook(cfg LokiConfig) *LokiHook {
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	if !strings.HasSuffix(cfg.URL, "/loki/api/v1/push") {
+		cfg.URL += "/loki/api/v1/push"
+	}
+	return &LokiHook{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		formatter:  &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano},
+		queue:      make(chan lokiLine, cfg.BatchSize*10),
+	}
+}
+
+func (h *LokiHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *LokiHook) Fire(entry *logrus.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	select {
+	case h.queue <- lokiLine{level: entry.Level.String(), ts: entry.Time, line: strings.TrimSuffix(string(line), "\n")}:
+	default:
+	}
+	return nil
+}
+
+func (h *LokiHook) Start(ctx context.Context) {
+	ticker := time.NewTicker(h.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []lokiLine
+	for {
+		select {
+		case line := <-h.queue:
+			batch = append(batch, line)
+			if len(batch) >= h.cfg.BatchSize {
+				h.push(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				h.push(batch)
+				batch = nil
+			}
+		case <-ctx.Done():
+			if len(batch) > 0 {
+				h.push(batch)
+			}
+			return
+		}
+	}
+}
+
+func (h *LokiHook) push(batch []lokiLine) {
+	streams := make(map[string]*lokiStream)
+	for _, line := range batch {
+		stream, ok := streams[line.level]
+		if !ok {
+			labels := make(map[string]string, len(h.cfg.Labels)+1)
+			for key, value := range h.cfg.Labels {
+				labels[key] = value
+			}
+			labels["level"] = line.level
+			stream = &lokiStream{Stream: labels}
+			streams[line.level] = stream
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(line.ts.UnixNano(), 10), line.line})
+	}
+
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, stream := range streams {
+		payload.Streams = append(payload.Streams, stream)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "loki: encode push request: %v\n", err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "loki: build push request: %v\n", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.cfg.Tenant != "" {
+		req.Header.Set("X-Scope-OrgID", h.cfg.Tenant)
+	}
+	if h.cfg.Credentials != nil {
+		if user, password := h.cfg.Credentials(); user != "" || password != "" {
+			req.SetBasicAuth(user, password)
+		}
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "loki: push failed: %v\n", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		fmt.Fprintf(os.Stderr, "loki: push returned status %d, dropped %d lines\n", resp.StatusCode, len(batch))
+	}
+}
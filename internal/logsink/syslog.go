@@ -0,0 +1,169 @@
+package logsink
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const syslogEnterpriseID = "32473"
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "daemon": 3, "auth": 4,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+type SyslogConfig struct {
+	URL      string
+	Facility string
+	AppName  string
+}
+
+type SyslogHook struct {
+	network  string
+	addr     string
+	facility int
+	appName  string
+	hostname string
+	mu       sync.Mutex
+	conn     net.Conn
+}
+
+func NewSyslogHook(cfg SyslogConfig) (*SyslogHook, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid syslog URL %q", cfg.URL)
+	}
+	if u.Scheme != "udp" && u.Scheme != "tcp" {
+		return nil, fmt.Errorf("unsupported syslog scheme %q, use udp or tcp", u.Scheme)
+	}
+	facility, ok := syslogFacilities[cfg.Facility]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", cfg.Facility)
+	}
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	h := &SyslogHook{
+		network:  u.Scheme,
+		addr:     u.Host,
+		facility: facility,
+		appName:  cfg.AppName,
+		hostname: hostname,
+	}
+	if err := h.dial(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *SyslogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *SyslogHook) Fire(entry *logrus.Entry) error {
+	msg := h.format(entry)
+	if h.network == "tcp" {
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conn == nil {
+		if err := h.dial(); err != nil {
+			return err
+		}
+	}
+	h.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	if _, err := h.conn.Write([]byte(msg)); err != nil {
+		h.conn.Close()
+		h.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (h *SyslogHook) dial() error {
+	conn, err := net.DialTimeout(h.network, h.addr, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("syslog dial failed: %w", err)
+	}
+	h.conn = conn
+	return nil
+}
+
+func (h *SyslogHook) format(entry *logrus.Entry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d - ",
+		h.facility*8+syslogSeverity(entry.Level),
+		entry.Time.UTC().Format(time.RFC3339Nano),
+		h.hostname,
+		h.appName,
+		os.Getpid(),
+	)
+
+	if len(entry.Data) == 0 {
+		b.WriteString("-")
+	} else {
+		keys := make([]string, 0, len(entry.Data))
+		for key := range entry.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		b.WriteString("[fields@" + syslogEnterpriseID)
+		for _, key := range keys {
+			fmt.Fprintf(&b, " %s=\"%s\"", sdName(key), sdEscape(fmt.Sprint(entry.Data[key])))
+		}
+		b.WriteString("]")
+	}
+
+	b.WriteString(" ")
+	b.WriteString(entry.Message)
+	return b.String()
+}
+
+func syslogSeverity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel:
+		return 0
+	case logrus.FatalLevel:
+		return 2
+	case logrus.ErrorLevel:
+		return 3
+	case logrus.WarnLevel:
+		return 4
+	case logrus.InfoLevel:
+		return 6
+	}
+	return 7
+}
+
+func sdName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+func sdEscape(value string) string {
+	return sdEscaper.Replace(value)
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type AccessLog struct {
@@ -14,29 +16,51 @@ type AccessLog struct {
 	ClientIP  string `gorm:"type:varchar(45);not null"`
 	UserAgent string `gorm:"type:text"`
 	BytesSent int    `gorm:"not null;default:0"`
+	Username  string `gorm:"type:varchar(255);index"`
+	Tenant    string `gorm:"type:varchar(64);index"`
+
+	Repository    string `gorm:"type:varchar(255);index"`
+	Reference     string `gorm:"type:varchar(255)"`
+	Resource      string `gorm:"type:varchar(16)"`
+	CacheStatus   string `gorm:"type:varchar(8);index"`
+	CacheBytes    int    `gorm:"not null;default:0"`
+	UpstreamBytes int    `gorm:"not null;default:0"`
 }
 
 type RegistryCache struct {
-	Key          string    `gorm:"primaryKey;type:varchar(512);not null"`
-	Type         string    `gorm:"type:varchar(20);not null;index"`
-	Digest       string    `gorm:"type:varchar(128);not null"`
-	MediaType    string    `gorm:"type:varchar(128);not null"`
-	StoredAt     time.Time `gorm:"index;not null"`
-	ExpiresAt    time.Time `gorm:"index;not null"`
-	LastAccess   time.Time `gorm:"index;not null"`
-	SizeBytes    int64     `gorm:"not null;default:-1"`
-	LastModified time.Time `gorm:"index"`
-	ETag         string    `gorm:"type:varchar(128)"`
+	Key          string         `gorm:"primaryKey;type:varchar(512);not null"`
+	Type         string         `gorm:"type:varchar(20);not null;index"`
+	Digest       string         `gorm:"type:varchar(128);not null"`
+	MediaType    string         `gorm:"type:varchar(128);not null"`
+	StoredAt     time.Time      `gorm:"index;not null"`
+	ExpiresAt    time.Time      `gorm:"index;not null"`
+	LastAccess   time.Time      `gorm:"index;not null"`
+	SizeBytes    int64          `gorm:"not null;default:-1"`
+	LastModified time.Time      `gorm:"index"`
+	ETag         string         `gorm:"type:varchar(128)"`
+	Bucket       string         `gorm:"type:varchar(255)"`
+	Headers      string         `gorm:"type:text"`
+	Encrypted    bool           `gorm:"not null;default:false"`
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+
+	Compression    string `gorm:"type:varchar(16)"`
+	CompressedSize int64  `gorm:"not null;default:0"`
+}
+
+type InlineObject struct {
+	Key     string `gorm:"primaryKey;type:varchar(512);not null"`
+	Content []byte `gorm:"type:bytea;not null"`
 }
 
 type TagCache struct {
-	ID           uint      `gorm:"primaryKey;autoIncrement"`
-	Repository   string    `gorm:"type:varchar(255);not null;index"`
-	Tags         string    `gorm:"type:text;not null"`
-	ETag         string    `gorm:"type:varchar(128);not null"`
-	LastModified time.Time `gorm:"index;not null"`
-	ExpiresAt    time.Time `gorm:"index;not null"`
-	StoredAt     time.Time `gorm:"index;not null"`
+	ID           uint           `gorm:"primaryKey;autoIncrement"`
+	Repository   string         `gorm:"type:varchar(255);not null;index"`
+	Tags         string         `gorm:"type:text;not null"`
+	ETag         string         `gorm:"type:varchar(128);not null"`
+	LastModified time.Time      `gorm:"index;not null"`
+	ExpiresAt    time.Time      `gorm:"index;not null"`
+	StoredAt     time.Time      `gorm:"index;not null"`
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
 }
 
 func (RegistryCache) TableName() string {
@@ -50,3 +74,146 @@ func (TagCache) TableName() string {
 func (AccessLog) TableName() string {
 	return "access_logs"
 }
+
+type PullCounter struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	Repository string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_pull_counter_bucket"`
+	Reference  string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_pull_counter_bucket"`
+	Digest     string    `gorm:"type:varchar(128);not null;uniqueIndex:idx_pull_counter_bucket"`
+	Day        time.Time `gorm:"type:date;not null;index;uniqueIndex:idx_pull_counter_bucket"`
+	Count      int64     `gorm:"not null;default:0"`
+	LastPull   time.Time `gorm:"index;not null"`
+}
+
+func (PullCounter) TableName() string {
+	return "pull_counters"
+}
+
+type Lease struct {
+	Name      string    `gorm:"primaryKey;type:varchar(128);not null"`
+	Holder    string    `gorm:"type:varchar(255);not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+func (Lease) TableName() string {
+	return "leases"
+}
+
+type Job struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Type        string    `gorm:"type:varchar(64);not null;index" json:"type"`
+	Payload     string    `gorm:"type:text;not null" json:"payload"`
+	Status      string    `gorm:"type:varchar(20);not null;index" json:"status"`
+	Attempts    int       `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int       `gorm:"not null;default:5" json:"max_attempts"`
+	RunAt       time.Time `gorm:"index;not null" json:"run_at"`
+	LockedBy    string    `gorm:"type:varchar(255)" json:"locked_by,omitempty"`
+	LockedAt    time.Time `json:"locked_at"`
+	LastError   string    `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt   time.Time `gorm:"index;not null" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"not null" json:"updated_at"`
+}
+
+func (Job) TableName() string {
+	return "jobs"
+}
+
+type RepositoryApproval struct {
+	Repository string     `gorm:"primaryKey;type:varchar(255);not null" json:"repository"`
+	Status     string     `gorm:"type:varchar(16);not null;index" json:"status"`
+	Reference  string     `gorm:"type:varchar(255)" json:"reference"`
+	FirstSeen  time.Time  `gorm:"index;not null" json:"first_seen"`
+	DecidedAt  *time.Time `json:"decided_at,omitempty"`
+	DecidedBy  string     `gorm:"type:varchar(255)" json:"decided_by,omitempty"`
+}
+
+func (RepositoryApproval) TableName() string {
+	return "repository_approvals"
+}
+
+type PullToken struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Name         string     `gorm:"type:varchar(255);not null;index" json:"name"`
+	TokenHash    string     `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	Repositories string     `gorm:"type:text;not null" json:"-"`
+	CreatedBy    string     `gorm:"type:varchar(255)" json:"created_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    time.Time  `gorm:"index;not null" json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+}
+
+func (PullToken) TableName() string {
+	return "pull_tokens"
+}
+
+type TagDrift struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Repository     string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_tag_drift" json:"repository"`
+	Tag            string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_tag_drift" json:"tag"`
+	Kind           string    `gorm:"type:varchar(16);not null;index" json:"kind"`
+	CachedDigest   string    `gorm:"type:varchar(128);not null" json:"cached_digest"`
+	UpstreamDigest string    `gorm:"type:varchar(128)" json:"upstream_digest,omitempty"`
+	DetectedAt     time.Time `gorm:"index;not null" json:"detected_at"`
+	CheckedAt      time.Time `gorm:"not null" json:"checked_at"`
+}
+
+func (TagDrift) TableName() string {
+	return "tag_drift"
+}
+
+type BlobDownload struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Instance     string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_blob_download" json:"instance"`
+	Digest       string    `gorm:"type:varchar(128);not null;uniqueIndex:idx_blob_download" json:"digest"`
+	Repository   string    `gorm:"type:varchar(255);not null" json:"repository"`
+	TempPath     string    `gorm:"type:text;not null" json:"temp_path"`
+	BytesWritten int64     `gorm:"not null;default:0" json:"bytes_written"`
+	Size         int64     `gorm:"not null;default:0" json:"size"`
+	ETag         string    `gorm:"type:varchar(255)" json:"etag,omitempty"`
+	StartedAt    time.Time `gorm:"not null" json:"started_at"`
+	UpdatedAt    time.Time `gorm:"not null" json:"updated_at"`
+}
+
+type Repository struct {
+	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name             string    `gorm:"type:varchar(255);not null;uniqueIndex" json:"name"`
+	FirstSeen        time.Time `gorm:"not null" json:"first_seen"`
+	LastPulled       time.Time `gorm:"index" json:"last_pulled"`
+	PullCount        int64     `gorm:"not null;default:0" json:"pull_count"`
+	TotalCachedBytes int64     `gorm:"not null;default:0" json:"total_cached_bytes"`
+	UpdatedAt        time.Time `gorm:"not null" json:"updated_at"`
+}
+
+const (
+	ReferenceManifest = "manifest"
+	ReferenceConfig   = "config"
+	ReferenceLayer    = "layer"
+)
+
+type CacheReference struct {
+	ParentKey string    `gorm:"primaryKey;type:varchar(512);not null" json:"parent_key"`
+	ChildKey  string    `gorm:"primaryKey;type:varchar(512);not null;index" json:"child_key"`
+	Kind      string    `gorm:"type:varchar(16);not null" json:"kind"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (CacheReference) TableName() string {
+	return "cache_references"
+}
+
+type UsageRollup struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	Day           time.Time `gorm:"type:date;not null;index;uniqueIndex:idx_usage_rollup_bucket" json:"day"`
+	Repository    string    `gorm:"type:varchar(255);not null;index;uniqueIndex:idx_usage_rollup_bucket" json:"repository"`
+	Identity      string    `gorm:"type:varchar(255);not null;index;uniqueIndex:idx_usage_rollup_bucket" json:"identity"`
+	Tenant        string    `gorm:"type:varchar(64);not null;default:'';uniqueIndex:idx_usage_rollup_bucket" json:"tenant,omitempty"`
+	Requests      int64     `gorm:"not null;default:0" json:"requests"`
+	CacheBytes    int64     `gorm:"not null;default:0" json:"cache_bytes"`
+	UpstreamBytes int64     `gorm:"not null;default:0" json:"upstream_bytes"`
+	LastSeen      time.Time `gorm:"not null" json:"last_seen"`
+}
+
+func (UsageRollup) TableName() string {
+	return "usage_rollups"
+}
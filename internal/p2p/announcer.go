@@ -0,0 +1,129 @@
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const BlobsPath = "/p2p/blobs/"
+
+type Config struct {
+	AnnounceURL   string
+	SeedURL       string
+	Token         func() string
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+type Blob struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+	URL    string `json:"url"`
+}
+
+type announcement struct {
+	Seed  string `json:"seed"`
+	Blobs []Blob `json:"blobs"`
+}
+
+type Announcer struct {
+	cfg        Config
+	httpClient *http.Client
+	log        *logrus.Entry
+	queue      chan Blob
+}
+
+func NewAnnouncer(logger *logrus.Logger, cfg Config) *Announcer {
+	cfg.SeedURL = strings.TrimSuffix(cfg.SeedURL, "/")
+	return &Announcer{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		log:        logger.WithField("component", "p2p_announcer"),
+		queue:      make(chan Blob, cfg.BatchSize*4),
+	}
+}
+
+func (a *Announcer) BlobURL(digest string) string {
+	return a.cfg.SeedURL + BlobsPath + digest
+}
+
+func (a *Announcer) Announce(digest string, size int64) {
+	if a == nil {
+		return
+	}
+	select {
+	case a.queue <- Blob{Digest: digest, Size: size, URL: a.BlobURL(digest)}:
+	default:
+		a.log.WithField("digest", digest).Warn("Announcement queue full, dropping blob")
+	}
+}
+
+func (a *Announcer) Start(ctx context.Context) {
+	a.log.WithFields(logrus.Fields{
+		"announce_url": a.cfg.AnnounceURL,
+		"seed_url":     a.cfg.SeedURL,
+	}).Info("Starting P2P announcer")
+
+	ticker := time.NewTicker(a.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []Blob
+	for {
+		select {
+		case blob := <-a.queue:
+			batch = append(batch, blob)
+			if len(batch) < a.cfg.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		if len(batch) == 0 {
+			continue
+		}
+		if err := a.send(ctx, batch); err != nil {
+			a.log.WithFields(logrus.Fields{
+				"blobs": len(batch),
+				"error": err,
+			}).Warn("Failed to announce blobs")
+		} else {
+			a.log.WithField("blobs", len(batch)).Debug("Announced blobs to P2P network")
+		}
+		batch = nil
+	}
+}
+
+func (a *Announcer) send(ctx context.Context, blobs []Blob) error {
+	body, err := json.Marshal(announcement{Seed: a.cfg.SeedURL, Blobs: blobs})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", a.cfg.AnnounceURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := a.cfg.Token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
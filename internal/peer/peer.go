@@ -0,0 +1,243 @@
+package peer
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DigestsPath = "/peer/digests"
+	BlobsPath   = "/peer/blobs/"
+)
+
+type Config struct {
+	Peers        []string
+	Token        func() string
+	SyncInterval time.Duration
+	Timeout      time.Duration
+}
+
+type Advertisement struct {
+	Digest   string    `json:"digest"`
+	Size     int64     `json:"size"`
+	StoredAt time.Time `json:"stored_at"`
+}
+
+type DigestList struct {
+	Digests []Advertisement `json:"digests"`
+	Next    time.Time       `json:"next"`
+}
+
+type PeerStatus struct {
+	URL      string    `json:"url"`
+	Digests  int       `json:"digests"`
+	LastSync time.Time `json:"last_sync"`
+	LastErr  string    `json:"last_error,omitempty"`
+}
+
+type peerState struct {
+	url      string
+	cursor   time.Time
+	digests  map[string]struct{}
+	lastSync time.Time
+	lastErr  string
+}
+
+type Cluster struct {
+	cfg        Config
+	httpClient *http.Client
+	log        *logrus.Entry
+
+	mu    sync.RWMutex
+	peers []*peerState
+}
+
+func NewCluster(logger *logrus.Logger, cfg Config) *Cluster {
+	c := &Cluster{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		log:        logger.WithField("component", "peer_cluster"),
+	}
+	for _, u := range cfg.Peers {
+		c.peers = append(c.peers, &peerState{
+			url:     strings.TrimSuffix(u, "/"),
+			digests: make(map[string]struct{}),
+		})
+	}
+	return c
+}
+
+func (c *Cluster) Start(ctx context.Context) {
+	c.log.WithField("peers", len(c.peers)).Info("Starting peer digest sync")
+
+	ticker := time.NewTicker(c.cfg.SyncInterval)
+	defer ticker.Stop()
+	for {
+		for _, p := range c.peers {
+			c.sync(ctx, p)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Cluster) sync(ctx context.Context, p *peerState) {
+	c.mu.RLock()
+	cursor := p.cursor
+	c.mu.RUnlock()
+
+	added := 0
+	for {
+		list, err := c.fetchDigests(ctx, p.url, cursor)
+		if err != nil {
+			c.mu.Lock()
+			p.lastErr = err.Error()
+			c.mu.Unlock()
+			c.log.WithFields(logrus.Fields{
+				"peer":  p.url,
+				"error": err,
+			}).Warn("Peer digest sync failed")
+			return
+		}
+
+		c.mu.Lock()
+		for _, ad := range list.Digests {
+			p.digests[ad.Digest] = struct{}{}
+		}
+		if list.Next.After(p.cursor) {
+			p.cursor = list.Next
+		}
+		p.lastSync = time.Now()
+		p.lastErr = ""
+		c.mu.Unlock()
+
+		added += len(list.Digests)
+		if len(list.Digests) == 0 || !list.Next.After(cursor) {
+			break
+		}
+		cursor = list.Next
+	}
+
+	if added > 0 {
+		c.log.WithFields(logrus.Fields{
+			"peer":    p.url,
+			"digests": added,
+		}).Debug("Synced peer digests")
+	}
+}
+
+func (c *Cluster) fetchDigests(ctx context.Context, peerURL string, since time.Time) (*DigestList, error) {
+	query := url.Values{}
+	if !since.IsZero() {
+		query.Set("since", since.UTC().Format(time.RFC3339Nano))
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", peerURL+DigestsPath+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var list DigestList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decode digest list: %w", err)
+	}
+	return &list, nil
+}
+
+func (c *Cluster) FetchBlob(ctx context.Context, digest string) (*http.Response, string, error) {
+	if c == nil {
+		return nil, "", nil
+	}
+
+	c.mu.RLock()
+	var candidates []*peerState
+	for _, p := range c.peers {
+		if _, ok := p.digests[digest]; ok {
+			candidates = append(candidates, p)
+		}
+	}
+	c.mu.RUnlock()
+
+	for _, p := range candidates {
+		req, err := http.NewRequestWithContext(ctx, "GET", p.url+BlobsPath+digest, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		c.authorize(req)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			c.log.WithFields(logrus.Fields{
+				"peer":   p.url,
+				"digest": digest,
+				"error":  err,
+			}).Warn("Peer blob fetch failed")
+			continue
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, p.url, nil
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			c.forget(p, digest)
+		}
+	}
+	return nil, "", nil
+}
+
+func (c *Cluster) forget(p *peerState, digest string) {
+	c.mu.Lock()
+	delete(p.digests, digest)
+	c.mu.Unlock()
+}
+
+func (c *Cluster) authorize(req *http.Request) {
+	if token := c.cfg.Token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func Authorized(r *http.Request, token string) bool {
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+func (c *Cluster) Status() []PeerStatus {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	statuses := make([]PeerStatus, 0, len(c.peers))
+	for _, p := range c.peers {
+		statuses = append(statuses, PeerStatus{
+			URL:      p.url,
+			Digests:  len(p.digests),
+			LastSync: p.lastSync,
+			LastErr:  p.lastErr,
+		})
+	}
+	return statuses
+}
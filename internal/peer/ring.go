@@ -0,0 +1,64 @@
+package peer
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Ring struct {
+	self   string
+	hashes []uint64
+	owners map[uint64]string
+}
+
+func NewRing(members []string, self string, vnodes int) *Ring {
+	r := &Ring{
+		self:   strings.TrimSuffix(self, "/"),
+		owners: make(map[uint64]string),
+	}
+	for _, member := range members {
+		member = strings.TrimSuffix(member, "/")
+		for i := 0; i < vnodes; i++ {
+			h := ringHash(fmt.Sprintf("%s#%d", member, i))
+			if _, taken := r.owners[h]; taken {
+				continue
+			}
+			r.owners[h] = member
+			r.hashes = append(r.hashes, h)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+func ringHash(value string) uint64 {
+	sum := sha256.Sum256([]byte(value))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+func (r *Ring) Owner(key string) string {
+	if r == nil || len(r.hashes) == 0 {
+		return ""
+	}
+	h := ringHash(key)
+	idx := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if idx == len(r.hashes) {
+		idx = 0
+	}
+	return r.owners[r.hashes[idx]]
+}
+
+func (r *Ring) Self() string {
+	if r == nil {
+		return ""
+	}
+	return r.self
+}
+
+func (r *Ring) IsLocal(key string) bool {
+	owner := r.Owner(key)
+	return owner == "" || owner == r.self
+}
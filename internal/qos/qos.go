@@ -0,0 +1,144 @@
+package qos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+)
+
+const (
+	ClassHigh   = "high"
+	ClassNormal = "normal"
+	ClassLow    = "low"
+)
+
+var classes = []string{ClassLow, ClassNormal, ClassHigh}
+
+func priority(class string) int {
+	for i, c := range classes {
+		if c == class {
+			return i
+		}
+	}
+	return 1
+}
+
+func ValidClass(class string) bool {
+	for _, c := range classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+type matcher struct {
+	kind    string
+	name    string
+	value   string
+	network *net.IPNet
+}
+
+type Rule struct {
+	Class    string
+	matchers []matcher
+}
+
+func ParseRules(value string) ([]Rule, error) {
+	var rules []Rule
+	for _, item := range strings.Split(value, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		class, spec, ok := strings.Cut(item, "=")
+		class = strings.TrimSpace(class)
+		if !ok || !ValidClass(class) {
+			return nil, fmt.Errorf("rule %q must be high|normal|low=matcher[,matcher]", item)
+		}
+
+		rule := Rule{Class: class}
+		for _, m := range strings.Split(spec, ",") {
+			kind, arg, ok := strings.Cut(strings.TrimSpace(m), ":")
+			if !ok || arg == "" {
+				return nil, fmt.Errorf("matcher %q must be kind:value", m)
+			}
+			switch kind {
+			case "cidr":
+				_, network, err := net.ParseCIDR(arg)
+				if err != nil {
+					return nil, fmt.Errorf("invalid cidr %q: %w", arg, err)
+				}
+				rule.matchers = append(rule.matchers, matcher{kind: kind, network: network})
+			case "header":
+				name, headerValue, ok := strings.Cut(arg, "=")
+				if !ok || name == "" {
+					return nil, fmt.Errorf("header matcher %q must be header:Name=value", m)
+				}
+				rule.matchers = append(rule.matchers, matcher{kind: kind, name: http.CanonicalHeaderKey(name), value: headerValue})
+			case "user":
+				if _, err := path.Match(arg, ""); err != nil {
+					return nil, fmt.Errorf("invalid user pattern %q: %w", arg, err)
+				}
+				rule.matchers = append(rule.matchers, matcher{kind: kind, value: arg})
+			default:
+				return nil, errors.New("matcher kind must be cidr, header or user")
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (m matcher) matches(r *http.Request, ip net.IP, subject string) bool {
+	switch m.kind {
+	case "cidr":
+		return ip != nil && m.network.Contains(ip)
+	case "header":
+		return r.Header.Get(m.name) == m.value
+	case "user":
+		return subject != "" && config.MatchPattern(m.value, subject)
+	}
+	return false
+}
+
+type Classifier struct {
+	rules        []Rule
+	defaultClass string
+}
+
+func NewClassifier(rules []Rule, defaultClass string) *Classifier {
+	return &Classifier{rules: rules, defaultClass: defaultClass}
+}
+
+func (c *Classifier) Classify(r *http.Request, clientIP, subject string) string {
+	ip := net.ParseIP(clientIP)
+	for _, rule := range c.rules {
+		for _, m := range rule.matchers {
+			if m.matches(r, ip, subject) {
+				return rule.Class
+			}
+		}
+	}
+	return c.defaultClass
+}
+
+type contextKey struct{}
+
+func WithClass(ctx context.Context, class string) context.Context {
+	return context.WithValue(ctx, contextKey{}, class)
+}
+
+func ClassFromContext(ctx context.Context) string {
+	if class, ok := ctx.Value(contextKey{}).(string); ok {
+		return class
+	}
+	return ClassNormal
+}
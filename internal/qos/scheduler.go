@@ -0,0 +1,203 @@
+package qos
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+type ClassStats struct {
+	Class   string `json:"class"`
+	Active  int    `json:"active"`
+	Waiting int    `json:"waiting"`
+	Granted uint64 `json:"granted"`
+	Waited  string `json:"waited"`
+	Yielded uint64 `json:"yielded_bytes"`
+}
+
+type SchedulerStats struct {
+	Slots   int          `json:"slots"`
+	Active  int          `json:"active"`
+	Classes []ClassStats `json:"classes"`
+}
+
+type waiter struct {
+	ready chan struct{}
+	since time.Time
+}
+
+type Scheduler struct {
+	slots int
+	yield *rate.Limiter
+
+	mu      sync.Mutex
+	active  int
+	running [3]int
+	waiters [3][]*waiter
+	granted [3]uint64
+	waited  [3]time.Duration
+	yielded [3]uint64
+}
+
+func NewScheduler(slots int, yieldBytesPerSecond int64) *Scheduler {
+	burst := yieldBytesPerSecond
+	if burst > 1024*1024 {
+		burst = 1024 * 1024
+	}
+	return &Scheduler{
+		slots: slots,
+		yield: rate.NewLimiter(rate.Limit(yieldBytesPerSecond), int(burst)),
+	}
+}
+
+func (s *Scheduler) Acquire(ctx context.Context, class string) (func(), error) {
+	if s == nil {
+		return func() {}, nil
+	}
+	p := priority(class)
+	start := time.Now()
+
+	s.mu.Lock()
+	if s.active < s.slots && s.queued() == 0 {
+		s.grant(p, start)
+		s.mu.Unlock()
+		return s.releaser(p), nil
+	}
+	w := &waiter{ready: make(chan struct{}), since: start}
+	s.waiters[p] = append(s.waiters[p], w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return s.releaser(p), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		removed := s.remove(p, w)
+		s.mu.Unlock()
+		if !removed {
+			s.releaser(p)()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Scheduler) grant(p int, queuedAt time.Time) {
+	s.active++
+	s.running[p]++
+	s.granted[p]++
+	s.waited[p] += time.Since(queuedAt)
+}
+
+func (s *Scheduler) queued() int {
+	n := 0
+	for _, w := range s.waiters {
+		n += len(w)
+	}
+	return n
+}
+
+func (s *Scheduler) remove(p int, target *waiter) bool {
+	for i, w := range s.waiters[p] {
+		if w == target {
+			s.waiters[p] = append(s.waiters[p][:i], s.waiters[p][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scheduler) releaser(p int) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.active--
+			s.running[p]--
+			for s.active < s.slots {
+				next := -1
+				for q := len(s.waiters) - 1; q >= 0; q-- {
+					if len(s.waiters[q]) > 0 {
+						next = q
+						break
+					}
+				}
+				if next < 0 {
+					break
+				}
+				w := s.waiters[next][0]
+				s.waiters[next] = s.waiters[next][1:]
+				s.grant(next, w.since)
+				close(w.ready)
+			}
+		})
+	}
+}
+
+func (s *Scheduler) contended(p int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active < s.slots && s.queued() == 0 {
+		return false
+	}
+	for q := p + 1; q < len(s.running); q++ {
+		if s.running[q] > 0 || len(s.waiters[q]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scheduler) Reader(ctx context.Context, class string, r io.Reader) io.Reader {
+	if s == nil {
+		return r
+	}
+	return &yieldingReader{ctx: ctx, r: r, s: s, p: priority(class)}
+}
+
+type yieldingReader struct {
+	ctx context.Context
+	r   io.Reader
+	s   *Scheduler
+	p   int
+}
+
+func (y *yieldingReader) Read(b []byte) (int, error) {
+	if burst := y.s.yield.Burst(); len(b) > burst {
+		b = b[:burst]
+	}
+	n, err := y.r.Read(b)
+	if n > 0 && y.s.contended(y.p) {
+		y.s.mu.Lock()
+		y.s.yielded[y.p] += uint64(n)
+		y.s.mu.Unlock()
+		if waitErr := y.s.yield.WaitN(y.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+func (s *Scheduler) Stats() SchedulerStats {
+	if s == nil {
+		return SchedulerStats{Classes: []ClassStats{}}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := SchedulerStats{Slots: s.slots, Active: s.active}
+	for p := len(classes) - 1; p >= 0; p-- {
+		stats.Classes = append(stats.Classes, ClassStats{
+			Class:   classes[p],
+			Active:  s.running[p],
+			Waiting: len(s.waiters[p]),
+			Granted: s.granted[p],
+			Waited:  s.waited[p].Round(time.Millisecond).String(),
+			Yielded: s.yielded[p],
+		})
+	}
+	return stats
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sirupsen/logrus"
+)
+
+const maxDeleteObjects = 1000
+
+func (s *S3Storage) DeleteBatch(ctx context.Context, keys []string) (int, []string, error) {
+	log := s.log.WithFields(logrus.Fields{
+		"operation": "delete_batch",
+		"count":     len(keys),
+	})
+	if len(keys) == 0 {
+		return 0, nil, nil
+	}
+
+	buckets, err := s.meta.known(ctx, keys)
+	if err != nil {
+		return 0, nil, fmt.Errorf("database error: %w", err)
+	}
+
+	var deleted, failed, inline []string
+	grouped := make(map[*bucketTarget][]string)
+	for _, key := range keys {
+		if buckets[key] == inlineBucket {
+			inline = append(inline, key)
+			continue
+		}
+		target := s.bucket(buckets[key])
+		grouped[target] = append(grouped[target], key)
+	}
+
+	if err := s.dropInline(ctx, inline); err != nil {
+		log.WithError(err).Error("Failed to delete inline objects")
+		failed = append(failed, inline...)
+	} else {
+		deleted = append(deleted, inline...)
+	}
+
+	for target, targetKeys := range grouped {
+		for start := 0; start < len(targetKeys); start += maxDeleteObjects {
+			end := start + maxDeleteObjects
+			if end > len(targetKeys) {
+				end = len(targetKeys)
+			}
+			chunk := targetKeys[start:end]
+
+			if s.quirks.noBatchDelete.Load() {
+				ok, bad := s.deleteEach(ctx, target, chunk, log)
+				deleted = append(deleted, ok...)
+				failed = append(failed, bad...)
+				continue
+			}
+
+			objects := make([]types.ObjectIdentifier, 0, len(chunk))
+			for _, key := range chunk {
+				objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+			}
+
+			out, err := target.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: aws.String(target.name),
+				Delete: &types.Delete{
+					Objects: objects,
+					Quiet:   aws.Bool(true),
+				},
+			})
+			if err != nil && s.quirks.batchDeleteUnsupported(err) {
+				log.WithField("bucket", target.name).Warn("S3 backend does not support batch delete, deleting objects individually")
+				ok, bad := s.deleteEach(ctx, target, chunk, log)
+				deleted = append(deleted, ok...)
+				failed = append(failed, bad...)
+				continue
+			}
+			if err != nil {
+				s.logS3ErrorDetails(err, log.WithField("bucket", target.name))
+				failed = append(failed, chunk...)
+				continue
+			}
+
+			chunkFailed := make(map[string]bool, len(out.Errors))
+			for _, e := range out.Errors {
+				key := aws.ToString(e.Key)
+				chunkFailed[key] = true
+				log.WithFields(logrus.Fields{
+					"key":     key,
+					"code":    aws.ToString(e.Code),
+					"message": aws.ToString(e.Message),
+				}).Warn("S3 batch delete failed for object")
+			}
+			for _, key := range chunk {
+				if chunkFailed[key] {
+					failed = append(failed, key)
+				} else {
+					deleted = append(deleted, key)
+				}
+			}
+		}
+	}
+
+	if len(deleted) > 0 {
+		if err := s.meta.remove(ctx, deleted); err != nil {
+			log.WithError(err).Error("Failed to delete registry cache entries")
+			return 0, keys, fmt.Errorf("database delete failed: %w", err)
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"deleted": len(deleted),
+		"failed":  len(failed),
+	}).Debug("Batch delete finished")
+	return len(deleted), failed, nil
+}
+
+func (s *S3Storage) deleteEach(ctx context.Context, target *bucketTarget, keys []string, log *logrus.Entry) ([]string, []string) {
+	var deleted, failed []string
+	for _, key := range keys {
+		_, err := target.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(target.name),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			s.logS3ErrorDetails(err, log.WithField("key", key))
+			failed = append(failed, key)
+			continue
+		}
+		deleted = append(deleted, key)
+	}
+	return deleted, failed
+}
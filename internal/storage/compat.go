@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aws/smithy-go"
+	"github.com/sdko-org/registry-proxy/internal/config"
+)
+
+type s3Quirks struct {
+	tagging        bool
+	sse            bool
+	checksums      bool
+	storageClasses []string
+	noBatchDelete  atomic.Bool
+}
+
+func quirksFor(backend string) *s3Quirks {
+	switch backend {
+	case config.S3BackendMinIO:
+		return &s3Quirks{tagging: true, sse: true, checksums: true, storageClasses: []string{"STANDARD", "REDUCED_REDUNDANCY"}}
+	case config.S3BackendSeaweedFS:
+		return &s3Quirks{tagging: true, storageClasses: []string{}}
+	case config.S3BackendGeneric:
+		return &s3Quirks{storageClasses: []string{}}
+	}
+	return &s3Quirks{tagging: true, sse: true, checksums: true}
+}
+
+func (q *s3Quirks) storageClass(class string) string {
+	if class == "" || q.storageClasses == nil {
+		return class
+	}
+	for _, supported := range q.storageClasses {
+		if strings.EqualFold(class, supported) {
+			return supported
+		}
+	}
+	return ""
+}
+
+func (q *s3Quirks) batchDeleteUnsupported(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "NotImplemented", "MethodNotAllowed", "InvalidRequest":
+		q.noBatchDelete.Store(true)
+		return true
+	}
+	return false
+}
+
+func metadataValue(metadata map[string]string, name string) string {
+	if value, ok := metadata[name]; ok {
+		return value
+	}
+	for key, value := range metadata {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/klauspost/compress/zstd"
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	compressionMetadata      = "Proxy-Compression"
+	uncompressedSizeMetadata = "Proxy-Uncompressed-Size"
+)
+
+var compressedMagic = [][]byte{
+	{0x1f, 0x8b},
+	{0x28, 0xb5, 0x2f, 0xfd},
+	{'B', 'Z', 'h'},
+	{0xfd, '7', 'z', 'X', 'Z', 0x00},
+}
+
+type compressReader struct {
+	src   io.Reader
+	zw    *zstd.Encoder
+	buf   bytes.Buffer
+	chunk []byte
+	size  int64
+	out   int64
+	done  bool
+	err   error
+}
+
+func (s *S3Storage) compressBody(key string, body io.Reader, size int64) (io.Reader, *compressReader) {
+	if s.cfg.StorageCompression != config.CompressionZstd || size <= 0 || ParseKey(key).Class != "blob" {
+		return body, nil
+	}
+
+	head := make([]byte, 6)
+	n, _ := io.ReadFull(body, head)
+	head = head[:n]
+	if seeker, ok := body.(io.Seeker); !ok || n == 0 {
+		body = io.MultiReader(bytes.NewReader(head), body)
+	} else if _, err := seeker.Seek(int64(-n), io.SeekCurrent); err != nil {
+		body = io.MultiReader(bytes.NewReader(head), body)
+	}
+	for _, magic := range compressedMagic {
+		if bytes.HasPrefix(head, magic) {
+			return body, nil
+		}
+	}
+
+	r := &compressReader{src: body, chunk: make([]byte, 32*1024), size: size}
+	zw, err := zstd.NewWriter(&r.buf, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(s.cfg.StorageCompressionLevel)), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return body, nil
+	}
+	r.zw = zw
+	return r, r
+}
+
+func (r *compressReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		n, err := r.src.Read(r.chunk)
+		if n > 0 {
+			if _, werr := r.zw.Write(r.chunk[:n]); werr != nil {
+				r.err = werr
+				continue
+			}
+		}
+		if err == io.EOF {
+			r.err = r.zw.Close()
+			r.done = true
+		} else if err != nil {
+			r.err = err
+		}
+	}
+	n, _ := r.buf.Read(p)
+	r.out += int64(n)
+	return n, nil
+}
+
+func (r *compressReader) annotate(metadata map[string]string) {
+	if r == nil {
+		return
+	}
+	metadata[compressionMetadata] = config.CompressionZstd
+	metadata[uncompressedSizeMetadata] = strconv.FormatInt(r.size, 10)
+}
+
+func (r *compressReader) codec() string {
+	if r == nil {
+		return ""
+	}
+	return config.CompressionZstd
+}
+
+func (r *compressReader) compressedSize() int64 {
+	if r == nil {
+		return 0
+	}
+	return r.out
+}
+
+func decompress(body io.ReadCloser, codec string) (io.ReadCloser, error) {
+	switch codec {
+	case "":
+		return body, nil
+	case config.CompressionZstd:
+		zr, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			body.Close()
+			return nil, fmt.Errorf("open compressed object: %w", err)
+		}
+		return zstdBody{zr, body}, nil
+	case config.CompressionGzip:
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			body.Close()
+			return nil, fmt.Errorf("open compressed object: %w", err)
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{zr, body}, nil
+	}
+	body.Close()
+	return nil, fmt.Errorf("unsupported object compression %q", codec)
+}
+
+type zstdBody struct {
+	*zstd.Decoder
+	body io.ReadCloser
+}
+
+func (b zstdBody) Close() error {
+	b.Decoder.Close()
+	return b.body.Close()
+}
+
+func objectSize(metadata map[string]string, length int64) int64 {
+	if metadataValue(metadata, compressionMetadata) != "" {
+		size, err := strconv.ParseInt(metadataValue(metadata, uncompressedSizeMetadata), 10, 64)
+		if err != nil {
+			return -1
+		}
+		return size
+	}
+	if isEncrypted(metadata) {
+		return plaintextSize(length)
+	}
+	return length
+}
+
+func compressedSize(metadata map[string]string, length int64) int64 {
+	if metadataValue(metadata, compressionMetadata) == "" {
+		return 0
+	}
+	if isEncrypted(metadata) {
+		return plaintextSize(length)
+	}
+	return length
+}
+
+func (s *S3Storage) getCompressedRange(ctx context.Context, target *bucketTarget, entry *models.RegistryCache, offset, length int64) (io.ReadCloser, error) {
+	resp, err := target.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(target.name),
+		Key:    aws.String(entry.Key),
+	})
+	if err != nil {
+		s.logS3ErrorDetails(err, s.log.WithFields(logrus.Fields{"operation": "get_range", "key": entry.Key}))
+		return nil, fmt.Errorf("s3 get failed: %w", err)
+	}
+	body, err := s.openObject(resp.Body, isEncrypted(resp.Metadata))
+	if err != nil {
+		return nil, err
+	}
+	if body, err = decompress(body, entry.Compression); err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, body, offset); err != nil {
+		body.Close()
+		return nil, fmt.Errorf("decompress range: %w", err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(body, length), body}, nil
+}
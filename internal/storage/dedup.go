@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+func (s *S3Storage) dedupe(ctx context.Context, key, digest, mediaType string, expiresAt time.Time, log *logrus.Entry) (bool, error) {
+	if !s.cfg.S3Dedup || digest == "" || ParseKey(key).Class != "blob" {
+		return false, nil
+	}
+
+	source, err := s.meta.find(ctx, key)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, fmt.Errorf("database error: %w", err)
+	}
+	if source == nil || source.Digest != digest || source.Bucket == inlineBucket {
+		if source, err = s.meta.findDigest(ctx, digest, key); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return false, nil
+			}
+			return false, fmt.Errorf("database error: %w", err)
+		}
+	}
+
+	from := s.bucket(source.Bucket)
+	head, err := from.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(from.name),
+		Key:    aws.String(source.Key),
+	})
+	if err != nil {
+		log.WithField("source", source.Key).Debug("Deduplication source missing from S3, uploading")
+		return false, nil
+	}
+	encrypted := isEncrypted(head.Metadata)
+	if encrypted != (s.encryption != nil) {
+		log.WithField("source", source.Key).Debug("Deduplication source encryption differs, uploading")
+		return false, nil
+	}
+	stored := aws.ToInt64(head.ContentLength)
+	size := objectSize(head.Metadata, stored)
+	if size < 0 {
+		return false, nil
+	}
+	compression := metadataValue(head.Metadata, compressionMetadata)
+
+	target := from
+	if source.Key != key {
+		target = s.targetFor(key, mediaType, size)
+		if target != from || stored > config.S3MaxPartSize {
+			return false, nil
+		}
+	}
+	if (source.Key != key || s.db == nil) && stored <= config.S3MaxPartSize {
+		input := s.copyInput(target, source.Key, key, digest, mediaType, expiresAt)
+		if compression != "" {
+			input.Metadata[compressionMetadata] = compression
+			input.Metadata[uncompressedSizeMetadata] = metadataValue(head.Metadata, uncompressedSizeMetadata)
+		}
+		if _, err := target.client.CopyObject(ctx, input); err != nil {
+			s.logS3ErrorDetails(err, log)
+			return false, nil
+		}
+	}
+
+	entry := models.RegistryCache{
+		Key:          key,
+		Type:         "blob",
+		Digest:       digest,
+		MediaType:    mediaType,
+		StoredAt:     time.Now(),
+		ExpiresAt:    expiresAt,
+		LastAccess:   time.Now(),
+		SizeBytes:    size,
+		LastModified: time.Now(),
+		Bucket:       target.name,
+		Encrypted:    encrypted,
+
+		Compression:    compression,
+		CompressedSize: compressedSize(head.Metadata, stored),
+	}
+	if err := s.meta.upsert(ctx, &entry, []string{
+		"type", "digest", "media_type", "expires_at",
+		"last_access", "size_bytes", "last_modified", "bucket",
+		"deleted_at", "encrypted", "compression", "compressed_size",
+	}); err != nil {
+		return false, fmt.Errorf("database error: %w", err)
+	}
+
+	log.WithFields(logrus.Fields{
+		"source": source.Key,
+		"size":   size,
+	}).Debug("Skipped upload of already stored digest")
+	return true, nil
+}
+
+func (s *S3Storage) copyInput(target *bucketTarget, sourceKey, key, digest, mediaType string, expiresAt time.Time) *s3.CopyObjectInput {
+	upload := s.uploadInput(target, key, nil, digest, mediaType, expiresAt)
+	input := &s3.CopyObjectInput{
+		Bucket:               aws.String(target.name),
+		Key:                  aws.String(key),
+		CopySource:           aws.String((&url.URL{Path: target.name + "/" + sourceKey}).EscapedPath()),
+		ContentType:          upload.ContentType,
+		Metadata:             upload.Metadata,
+		MetadataDirective:    types.MetadataDirectiveReplace,
+		StorageClass:         upload.StorageClass,
+		ServerSideEncryption: upload.ServerSideEncryption,
+		SSEKMSKeyId:          upload.SSEKMSKeyId,
+	}
+	if upload.Tagging != nil {
+		input.Tagging = upload.Tagging
+		input.TaggingDirective = types.TaggingDirectiveReplace
+	}
+	return input
+}
@@ -0,0 +1,428 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type diskMetadata struct {
+	Key       string    `json:"key"`
+	Digest    string    `json:"digest"`
+	MediaType string    `json:"media_type"`
+	Size      int64     `json:"size"`
+	StoredAt  time.Time `json:"stored_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Headers   string    `json:"headers,omitempty"`
+}
+
+func (m *diskMetadata) info() *ObjectInfo {
+	return &ObjectInfo{
+		Digest:    m.Digest,
+		MediaType: m.MediaType,
+		Size:      m.Size,
+		StoredAt:  m.StoredAt,
+		ExpiresAt: m.ExpiresAt,
+		Headers:   m.Headers,
+	}
+}
+
+type DiskStorage struct {
+	dir           string
+	maxBytes      int64
+	maxObjectSize int64
+	log           *logrus.Entry
+	mu            sync.Mutex
+	usedBytes     int64
+}
+
+func NewDiskStorage(logger *logrus.Logger, dir string, maxBytes, maxObjectSize int64) (*DiskStorage, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create disk cache dir: %w", err)
+	}
+
+	d := &DiskStorage{
+		dir:           dir,
+		maxBytes:      maxBytes,
+		maxObjectSize: maxObjectSize,
+		log:           logger.WithField("component", "disk_storage"),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read disk cache dir: %w", err)
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".blob") {
+			if fi, err := e.Info(); err == nil {
+				d.usedBytes += fi.Size()
+			}
+		}
+	}
+
+	d.log.WithFields(logrus.Fields{
+		"dir":        dir,
+		"used_bytes": d.usedBytes,
+		"max_bytes":  maxBytes,
+	}).Info("Disk cache initialized")
+	return d, nil
+}
+
+func (d *DiskStorage) paths(key string) (string, string) {
+	sum := sha256.Sum256([]byte(key))
+	base := filepath.Join(d.dir, hex.EncodeToString(sum[:]))
+	return base + ".blob", base + ".json"
+}
+
+func (d *DiskStorage) readMetadata(path string) (*diskMetadata, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var meta diskMetadata
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+func (d *DiskStorage) Get(ctx context.Context, key string) ([]byte, string, string, error) {
+	blobPath, metaPath := d.paths(key)
+
+	meta, err := d.readMetadata(metaPath)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("cache miss")
+	}
+	if meta.Key != key {
+		return nil, "", "", fmt.Errorf("cache miss")
+	}
+	if time.Now().After(meta.ExpiresAt) {
+		d.Delete(ctx, key)
+		return nil, "", "", fmt.Errorf("cache expired")
+	}
+
+	content, err := os.ReadFile(blobPath)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("read failed: %w", err)
+	}
+
+	now := time.Now()
+	os.Chtimes(metaPath, now, now)
+	return content, meta.Digest, meta.MediaType, nil
+}
+
+func (d *DiskStorage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	_, metaPath := d.paths(key)
+
+	meta, err := d.readMetadata(metaPath)
+	if err != nil || meta.Key != key {
+		return nil, fmt.Errorf("cache miss")
+	}
+	if time.Now().After(meta.ExpiresAt) {
+		return nil, fmt.Errorf("cache expired")
+	}
+	return meta.info(), nil
+}
+
+func (d *DiskStorage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	info, err := d.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if offset+length > info.Size {
+		return nil, fmt.Errorf("range exceeds object size")
+	}
+
+	blobPath, _ := d.paths(key)
+	f, err := os.Open(blobPath)
+	if err != nil {
+		return nil, fmt.Errorf("read failed: %w", err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(f, offset, length), f}, nil
+}
+
+func (d *DiskStorage) Put(ctx context.Context, key string, content []byte, digest, mediaType string, ttl time.Duration) error {
+	return d.PutStream(ctx, key, bytes.NewReader(content), digest, mediaType, ttl)
+}
+
+func (d *DiskStorage) PutStream(ctx context.Context, key string, content io.Reader, digest, mediaType string, ttl time.Duration) error {
+	tmp, err := d.createTemp()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, io.LimitReader(content, d.maxObjectSize+1))
+	tmp.Close()
+	if err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+	now := time.Now()
+	return d.commit(tmp.Name(), written, diskMetadata{
+		Key:       key,
+		Digest:    digest,
+		MediaType: mediaType,
+		StoredAt:  now,
+		ExpiresAt: now.Add(ttl),
+	})
+}
+
+// GetStream opens the cached file for key instead of reading it into memory.
+func (d *DiskStorage) GetStream(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	blobPath, metaPath := d.paths(key)
+
+	meta, err := d.readMetadata(metaPath)
+	if err != nil || meta.Key != key {
+		return nil, nil, fmt.Errorf("cache miss")
+	}
+	if time.Now().After(meta.ExpiresAt) {
+		d.Delete(ctx, key)
+		return nil, nil, fmt.Errorf("cache expired")
+	}
+
+	f, err := os.Open(blobPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read failed: %w", err)
+	}
+
+	now := time.Now()
+	os.Chtimes(metaPath, now, now)
+	return f, meta.info(), nil
+}
+
+func (d *DiskStorage) createTemp() (*os.File, error) {
+	tmp, err := os.CreateTemp(d.dir, ".incoming-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temp file: %w", err)
+	}
+	return tmp, nil
+}
+
+// commit moves a fully written temp file into place as the entry described
+// by meta.
+func (d *DiskStorage) commit(tmpPath string, written int64, meta diskMetadata) error {
+	blobPath, metaPath := d.paths(meta.Key)
+	if written > d.maxObjectSize {
+		return fmt.Errorf("object exceeds disk cache object limit")
+	}
+
+	d.evict(written)
+
+	meta.Size = written
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	previous := d.fileSize(blobPath)
+	if err := os.Rename(tmpPath, blobPath); err != nil {
+		return fmt.Errorf("rename failed: %w", err)
+	}
+	if err := os.WriteFile(metaPath, raw, 0600); err != nil {
+		os.Remove(blobPath)
+		return fmt.Errorf("write metadata failed: %w", err)
+	}
+
+	d.mu.Lock()
+	d.usedBytes += written - previous
+	d.mu.Unlock()
+	return nil
+}
+
+// diskFill passes a remote body through to the caller while copying it into a
+// disk cache temp file, and commits the file once the body reaches EOF with
+// the expected size. A body closed early or a failed write leaves no entry.
+type diskFill struct {
+	io.ReadCloser
+	disk    *DiskStorage
+	tmp     *os.File
+	meta    diskMetadata
+	size    int64
+	written int64
+	err     error
+	log     *logrus.Entry
+}
+
+func (d *DiskStorage) fill(body io.ReadCloser, key string, info *ObjectInfo, ttl time.Duration, log *logrus.Entry) io.ReadCloser {
+	tmp, err := d.createTemp()
+	if err != nil {
+		log.WithError(err).Warn("Failed to populate disk cache")
+		return body
+	}
+	storedAt := info.StoredAt
+	if storedAt.IsZero() {
+		storedAt = time.Now()
+	}
+	return &diskFill{
+		ReadCloser: body,
+		disk:       d,
+		tmp:        tmp,
+		meta: diskMetadata{
+			Key:       key,
+			Digest:    info.Digest,
+			MediaType: info.MediaType,
+			StoredAt:  storedAt,
+			ExpiresAt: time.Now().Add(ttl),
+			Headers:   info.Headers,
+		},
+		size: info.Size,
+		log:  log,
+	}
+}
+
+func (f *diskFill) Read(p []byte) (int, error) {
+	n, err := f.ReadCloser.Read(p)
+	if n > 0 && f.tmp != nil && f.err == nil {
+		if _, werr := f.tmp.Write(p[:n]); werr != nil {
+			f.err = fmt.Errorf("write failed: %w", werr)
+		}
+		f.written += int64(n)
+	}
+	if err == io.EOF && f.tmp != nil {
+		f.finish()
+	}
+	return n, err
+}
+
+func (f *diskFill) finish() {
+	tmp := f.tmp
+	f.tmp = nil
+	defer os.Remove(tmp.Name())
+	tmp.Close()
+
+	err := f.err
+	if err == nil && f.written != f.size {
+		err = fmt.Errorf("read %d of %d bytes", f.written, f.size)
+	}
+	if err == nil {
+		err = f.disk.commit(tmp.Name(), f.written, f.meta)
+	}
+	if err != nil {
+		f.log.WithError(err).Warn("Failed to populate disk cache")
+	}
+}
+
+func (f *diskFill) Close() error {
+	if f.tmp != nil {
+		f.tmp.Close()
+		os.Remove(f.tmp.Name())
+		f.tmp = nil
+	}
+	return f.ReadCloser.Close()
+}
+
+func (d *DiskStorage) Delete(ctx context.Context, key string) error {
+	blobPath, metaPath := d.paths(key)
+	size := d.fileSize(blobPath)
+
+	os.Remove(metaPath)
+	if err := os.Remove(blobPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("disk delete failed: %w", err)
+	}
+
+	d.mu.Lock()
+	d.usedBytes -= size
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *DiskStorage) UpdateLastAccess(ctx context.Context, key string) error {
+	_, metaPath := d.paths(key)
+	now := time.Now()
+	return os.Chtimes(metaPath, now, now)
+}
+
+func (d *DiskStorage) StoreHeaders(ctx context.Context, key, headers string) error {
+	_, metaPath := d.paths(key)
+	meta, err := d.readMetadata(metaPath)
+	if err != nil || meta.Key != key {
+		return fmt.Errorf("cache miss")
+	}
+	meta.Headers = headers
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(metaPath, raw, 0600)
+}
+
+func (d *DiskStorage) fileSize(path string) int64 {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+func (d *DiskStorage) evict(incoming int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.usedBytes+incoming <= d.maxBytes {
+		return
+	}
+
+	type candidate struct {
+		base   string
+		access time.Time
+		size   int64
+	}
+
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		d.log.WithError(err).Warn("Failed to list disk cache for eviction")
+		return
+	}
+
+	var candidates []candidate
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		base := filepath.Join(d.dir, strings.TrimSuffix(e.Name(), ".json"))
+		candidates = append(candidates, candidate{
+			base:   base,
+			access: fi.ModTime(),
+			size:   d.fileSize(base + ".blob"),
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].access.Before(candidates[j].access)
+	})
+
+	evicted := 0
+	for _, c := range candidates {
+		if d.usedBytes+incoming <= d.maxBytes {
+			break
+		}
+		os.Remove(c.base + ".json")
+		os.Remove(c.base + ".blob")
+		d.usedBytes -= c.size
+		evicted++
+	}
+
+	d.log.WithFields(logrus.Fields{
+		"evicted":    evicted,
+		"used_bytes": d.usedBytes,
+	}).Debug("Evicted disk cache entries")
+}
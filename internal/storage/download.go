@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type Streamer interface {
+	GetStream(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+}
+
+type RangeGetter interface {
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+}
+
+// ReadObject reads a whole object together with its cache metadata, using
+// GetStream where the backend supports it since Get returns no ObjectInfo.
+func ReadObject(ctx context.Context, s Storage, key string) ([]byte, *ObjectInfo, error) {
+	streamer, ok := s.(Streamer)
+	if !ok {
+		content, digest, mediaType, err := s.Get(ctx, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		return content, &ObjectInfo{Digest: digest, MediaType: mediaType, Size: int64(len(content))}, nil
+	}
+
+	body, info, err := streamer.GetStream(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer body.Close()
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read failed: %w", err)
+	}
+	info.Size = int64(len(content))
+	return content, info, nil
+}
+
+type rangeChunk struct {
+	data []byte
+	err  error
+}
+
+type rangeReader struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	target  *bucketTarget
+	key     string
+	size    int64
+	chunk   int64
+	retries int
+	results []chan rangeChunk
+	slots   chan struct{}
+	next    int
+	current []byte
+	err     error
+}
+
+func newRangeReader(ctx context.Context, target *bucketTarget, key string, size, chunkSize int64, concurrency, retries int) *rangeReader {
+	ctx, cancel := context.WithCancel(ctx)
+	count := int((size + chunkSize - 1) / chunkSize)
+
+	r := &rangeReader{
+		ctx:     ctx,
+		cancel:  cancel,
+		target:  target,
+		key:     key,
+		size:    size,
+		chunk:   chunkSize,
+		retries: retries,
+		results: make([]chan rangeChunk, count),
+		slots:   make(chan struct{}, concurrency),
+	}
+	for i := range r.results {
+		r.results[i] = make(chan rangeChunk, 1)
+	}
+
+	go r.dispatch()
+	return r
+}
+
+func (r *rangeReader) dispatch() {
+	for i := range r.results {
+		select {
+		case r.slots <- struct{}{}:
+		case <-r.ctx.Done():
+			return
+		}
+		go func(i int) {
+			data, err := r.fetch(i)
+			r.results[i] <- rangeChunk{data: data, err: err}
+		}(i)
+	}
+}
+
+func (r *rangeReader) fetch(i int) ([]byte, error) {
+	start := int64(i) * r.chunk
+	end := start + r.chunk - 1
+	if end >= r.size {
+		end = r.size - 1
+	}
+	expected := end - start + 1
+
+	var lastErr error
+	for attempt := 0; attempt < r.retries; attempt++ {
+		resp, err := r.target.client.GetObject(r.ctx, &s3.GetObjectInput{
+			Bucket: aws.String(r.target.name),
+			Key:    aws.String(r.key),
+			Range:  aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+		})
+		if err != nil {
+			lastErr = err
+			if r.ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if int64(len(data)) != expected {
+			return nil, fmt.Errorf("range %d-%d returned %d bytes, expected %d", start, end, len(data), expected)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("s3 ranged get failed: %w", lastErr)
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	for len(r.current) == 0 {
+		if r.next >= len(r.results) {
+			r.err = io.EOF
+			return 0, io.EOF
+		}
+
+		select {
+		case result := <-r.results[r.next]:
+			<-r.slots
+			r.next++
+			if result.err != nil {
+				r.err = result.err
+				r.cancel()
+				return 0, r.err
+			}
+			r.current = result.data
+		case <-r.ctx.Done():
+			r.err = r.ctx.Err()
+			return 0, r.err
+		}
+	}
+
+	n := copy(p, r.current)
+	r.current = r.current[n:]
+	return n, nil
+}
+
+func (r *rangeReader) Close() error {
+	r.cancel()
+	return nil
+}
@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	encryptionMetadata = "Proxy-Encryption"
+	encryptionScheme   = "aes-256-gcm-v1"
+	encryptionSegment  = 64 * 1024
+	encryptionSaltSize = 32
+	encryptionTagSize  = 16
+)
+
+var errNoEncryptionKey = errors.New("object is encrypted but no storage encryption key is configured")
+
+type objectCipher struct {
+	master []byte
+}
+
+func newObjectCipher(awsCfg aws.Config, cfg *config.Config) (*objectCipher, error) {
+	switch {
+	case cfg.StorageEncryptionKey != "":
+		key, err := base64.StdEncoding.DecodeString(cfg.StorageEncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("decode encryption key: %w", err)
+		}
+		return &objectCipher{master: key}, nil
+	case cfg.StorageEncryptionKMSKey != "":
+		blob, err := base64.StdEncoding.DecodeString(cfg.StorageEncryptionKMSKey)
+		if err != nil {
+			return nil, fmt.Errorf("decode kms ciphertext: %w", err)
+		}
+		out, err := kms.NewFromConfig(awsCfg).Decrypt(context.Background(), &kms.DecryptInput{CiphertextBlob: blob})
+		if err != nil {
+			return nil, fmt.Errorf("kms decrypt failed: %w", err)
+		}
+		if len(out.Plaintext) != 32 {
+			return nil, fmt.Errorf("kms data key is %d bytes, expected 32", len(out.Plaintext))
+		}
+		return &objectCipher{master: out.Plaintext}, nil
+	}
+	return nil, nil
+}
+
+func (c *objectCipher) aead(salt []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, c.master)
+	mac.Write(salt)
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (c *objectCipher) encrypt(src io.Reader) io.Reader {
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return &encryptReader{err: fmt.Errorf("generate salt: %w", err)}
+	}
+	aead, err := c.aead(salt)
+	if err != nil {
+		return &encryptReader{err: err}
+	}
+	return &encryptReader{
+		src:   src,
+		aead:  aead,
+		plain: make([]byte, encryptionSegment+1),
+		out:   salt,
+	}
+}
+
+func (c *objectCipher) decrypt(body io.ReadCloser, salt []byte, first uint64) (io.ReadCloser, error) {
+	if salt == nil {
+		salt = make([]byte, encryptionSaltSize)
+		if _, err := io.ReadFull(body, salt); err != nil {
+			body.Close()
+			return nil, fmt.Errorf("read encryption header: %w", err)
+		}
+	}
+	aead, err := c.aead(salt)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return &decryptReader{
+		body:   body,
+		aead:   aead,
+		index:  first,
+		sealed: make([]byte, encryptionSegment+encryptionTagSize+1),
+	}, nil
+}
+
+func segmentNonce(index uint64, final bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce, index)
+	if final {
+		nonce[8] = 1
+	}
+	return nonce
+}
+
+func encryptedSize(size int64) int64 {
+	if size < 0 {
+		return size
+	}
+	segments := (size + encryptionSegment - 1) / encryptionSegment
+	if segments == 0 {
+		segments = 1
+	}
+	return encryptionSaltSize + size + segments*encryptionTagSize
+}
+
+func plaintextSize(size int64) int64 {
+	size -= encryptionSaltSize
+	if size < encryptionTagSize {
+		return 0
+	}
+	segments := (size + encryptionSegment + encryptionTagSize - 1) / (encryptionSegment + encryptionTagSize)
+	return size - segments*encryptionTagSize
+}
+
+func isEncrypted(metadata map[string]string) bool {
+	return metadataValue(metadata, encryptionMetadata) == encryptionScheme
+}
+
+func encryptionHeaderRange() *string {
+	return aws.String(fmt.Sprintf("bytes=0-%d", encryptionSaltSize-1))
+}
+
+type encryptReader struct {
+	src   io.Reader
+	aead  cipher.AEAD
+	index uint64
+	plain []byte
+	held  int
+	out   []byte
+	done  bool
+	err   error
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.err = r.seal()
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+func (r *encryptReader) seal() error {
+	n, err := io.ReadFull(r.src, r.plain[r.held:])
+	total := r.held + n
+	switch {
+	case err == nil:
+		r.out = r.aead.Seal(nil, segmentNonce(r.index, false), r.plain[:encryptionSegment], nil)
+		r.plain[0] = r.plain[encryptionSegment]
+		r.held = 1
+		r.index++
+		return nil
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		r.out = r.aead.Seal(nil, segmentNonce(r.index, true), r.plain[:total], nil)
+		r.done = true
+		return nil
+	default:
+		return err
+	}
+}
+
+type decryptReader struct {
+	body   io.ReadCloser
+	aead   cipher.AEAD
+	index  uint64
+	sealed []byte
+	held   int
+	out    []byte
+	done   bool
+	err    error
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.err = r.open()
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+func (r *decryptReader) open() error {
+	n, err := io.ReadFull(r.body, r.sealed[r.held:])
+	total := r.held + n
+	size := encryptionSegment + encryptionTagSize
+	final := false
+	switch {
+	case err == nil:
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		final = true
+		size = total
+	default:
+		return err
+	}
+
+	plain, err := r.aead.Open(nil, segmentNonce(r.index, final), r.sealed[:size], nil)
+	if err != nil {
+		return fmt.Errorf("decrypt segment %d: %w", r.index, err)
+	}
+	r.out = plain
+	r.index++
+	if final {
+		r.done = true
+		return nil
+	}
+	r.sealed[0] = r.sealed[size]
+	r.held = 1
+	return nil
+}
+
+func (r *decryptReader) Close() error {
+	return r.body.Close()
+}
+
+func (s *S3Storage) openObject(body io.ReadCloser, encrypted bool) (io.ReadCloser, error) {
+	if !encrypted {
+		return body, nil
+	}
+	if s.encryption == nil {
+		body.Close()
+		return nil, errNoEncryptionKey
+	}
+	return s.encryption.decrypt(body, nil, 0)
+}
+
+func (s *S3Storage) storedSize(size int64) int64 {
+	if s.encryption == nil {
+		return size
+	}
+	return encryptedSize(size)
+}
+
+func (s *S3Storage) getEncryptedRange(ctx context.Context, target *bucketTarget, key string, offset, length int64) (io.ReadCloser, error) {
+	if s.encryption == nil {
+		return nil, errNoEncryptionKey
+	}
+	log := s.log.WithFields(logrus.Fields{"operation": "get_range", "key": key})
+
+	header, err := target.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(target.name),
+		Key:    aws.String(key),
+		Range:  encryptionHeaderRange(),
+	})
+	if err != nil {
+		s.logS3ErrorDetails(err, log)
+		return nil, fmt.Errorf("s3 ranged get failed: %w", err)
+	}
+	salt := make([]byte, encryptionSaltSize)
+	_, err = io.ReadFull(header.Body, salt)
+	header.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read encryption header: %w", err)
+	}
+
+	sealed := int64(encryptionSegment + encryptionTagSize)
+	first := offset / encryptionSegment
+	last := (offset + length - 1) / encryptionSegment
+	start := encryptionSaltSize + first*sealed
+	end := encryptionSaltSize + (last+1)*sealed
+
+	// One byte past the last segment tells the reader whether it is final.
+	resp, err := target.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(target.name),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+	})
+	if err != nil {
+		s.logS3ErrorDetails(err, log)
+		return nil, fmt.Errorf("s3 ranged get failed: %w", err)
+	}
+	body, err := s.encryption.decrypt(resp.Body, salt, uint64(first))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, body, offset-first*encryptionSegment); err != nil {
+		body.Close()
+		return nil, fmt.Errorf("decrypt range: %w", err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(body, length), body}, nil
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+)
+
+var testEncryptionKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+
+func randomContent(t *testing.T, size int) []byte {
+	t.Helper()
+	content := make([]byte, size)
+	if _, err := rand.Read(content); err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func sealContent(t *testing.T, c *objectCipher, content []byte) []byte {
+	t.Helper()
+	sealed, err := io.ReadAll(c.encrypt(bytes.NewReader(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sealed
+}
+
+func openContent(c *objectCipher, sealed []byte) ([]byte, error) {
+	body, err := c.decrypt(io.NopCloser(bytes.NewReader(sealed)), nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	c := &objectCipher{master: bytes.Repeat([]byte{7}, 32)}
+
+	for _, size := range []int{0, 1, encryptionSegment - 1, encryptionSegment, encryptionSegment + 1, 3*encryptionSegment + 123, 4 * encryptionSegment} {
+		content := randomContent(t, size)
+		sealed := sealContent(t, c, content)
+		if int64(len(sealed)) != encryptedSize(int64(size)) {
+			t.Errorf("size %d: sealed %d bytes, encryptedSize %d", size, len(sealed), encryptedSize(int64(size)))
+		}
+		if got := plaintextSize(int64(len(sealed))); got != int64(size) {
+			t.Errorf("size %d: plaintextSize = %d", size, got)
+		}
+		opened, err := openContent(c, sealed)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(opened, content) {
+			t.Fatalf("size %d: decrypted content differs", size)
+		}
+	}
+}
+
+func TestEncryptionRejectsTampering(t *testing.T) {
+	c := &objectCipher{master: bytes.Repeat([]byte{7}, 32)}
+	sealedSegment := encryptionSegment + encryptionTagSize
+	sealed := sealContent(t, c, randomContent(t, 3*encryptionSegment+100))
+	header, segments := sealed[:encryptionSaltSize], sealed[encryptionSaltSize:]
+
+	reordered := append([]byte{}, header...)
+	reordered = append(reordered, segments[sealedSegment:2*sealedSegment]...)
+	reordered = append(reordered, segments[:sealedSegment]...)
+	reordered = append(reordered, segments[2*sealedSegment:]...)
+
+	flipped := append([]byte{}, sealed...)
+	flipped[encryptionSaltSize+10] ^= 1
+
+	for name, tampered := range map[string][]byte{
+		"truncated final segment":   sealed[:len(sealed)-1],
+		"truncated mid segment":     sealed[:encryptionSaltSize+sealedSegment+100],
+		"dropped final segment":     sealed[:encryptionSaltSize+3*sealedSegment],
+		"dropped trailing segments": sealed[:encryptionSaltSize+sealedSegment],
+		"reordered segments":        reordered,
+		"flipped bit":               flipped,
+		"header only":               header,
+	} {
+		if _, err := openContent(c, tampered); err == nil {
+			t.Errorf("%s: decryption succeeded", name)
+		}
+	}
+
+	other := &objectCipher{master: bytes.Repeat([]byte{8}, 32)}
+	if _, err := openContent(other, sealed); err == nil {
+		t.Error("decryption with a different key succeeded")
+	}
+}
+
+func TestS3EncryptedRoundTripAndRanges(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	s := newTestS3Storage(t, srv.URL, func(cfg *config.Config) {
+		cfg.StorageEncryptionKey = testEncryptionKey
+	})
+	if s.encryption == nil {
+		t.Fatal("storage encryption not configured")
+	}
+
+	content := randomContent(t, 3*encryptionSegment+123)
+	key := BlobKey("library/nginx", "sha256:abc")
+	if err := s.Put(context.Background(), key, content, "sha256:abc", "application/octet-stream", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	for path, stored := range fake.objects {
+		if bytes.Contains(stored, content[:64]) {
+			t.Fatalf("%s: stored object contains plaintext", path)
+		}
+	}
+	got, _, _, err := s.Get(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatal("decrypted object differs from the original")
+	}
+
+	for _, tc := range []struct {
+		offset int64
+		length int64
+	}{
+		{0, 1},
+		{0, encryptionSegment},
+		{encryptionSegment - 1, 2},
+		{encryptionSegment, encryptionSegment},
+		{100, 2*encryptionSegment + 5},
+		{3 * encryptionSegment, 123},
+		{int64(len(content)) - 1, 1},
+	} {
+		body, err := s.getEncryptedRange(context.Background(), s.primary, key, tc.offset, tc.length)
+		if err != nil {
+			t.Fatalf("range %d+%d: %v", tc.offset, tc.length, err)
+		}
+		part, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			t.Fatalf("range %d+%d: %v", tc.offset, tc.length, err)
+		}
+		if !bytes.Equal(part, content[tc.offset:tc.offset+tc.length]) {
+			t.Errorf("range %d+%d: got %d bytes that differ from the original", tc.offset, tc.length, len(part))
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/aws/smithy-go/middleware"
+	"github.com/sdko-org/registry-proxy/internal/config"
+)
+
+func injectS3Latency(cfg *config.Config) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		if cfg.FaultS3Latency <= 0 || cfg.FaultS3LatencyRate <= 0 {
+			return nil
+		}
+		return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("FaultS3Latency", func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			if rand.Float64() < cfg.FaultS3LatencyRate {
+				timer := time.NewTimer(cfg.FaultS3Latency)
+				defer timer.Stop()
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+				}
+			}
+			return next.HandleFinalize(ctx, in)
+		}), middleware.After)
+	}
+}
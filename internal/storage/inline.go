@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const inlineBucket = "postgres:inline"
+
+func (s *S3Storage) inlineEligible(size int64) bool {
+	return s.db != nil && s.cfg.InlineMaxSize > 0 && size >= 0 && size <= s.cfg.InlineMaxSize
+}
+
+func (s *S3Storage) putInline(ctx context.Context, entry *models.RegistryCache, content []byte) error {
+	entry.Bucket = inlineBucket
+	entry.SizeBytes = int64(len(content))
+
+	var previous models.RegistryCache
+	s.db.WithContext(ctx).Unscoped().Select("bucket").Where("key = ?", entry.Key).Limit(1).Find(&previous)
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"content"}),
+		}).Create(&models.InlineObject{Key: entry.Key, Content: content}).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"type", "digest", "media_type", "expires_at",
+				"last_access", "size_bytes", "last_modified", "bucket",
+				"deleted_at", "encrypted", "compression", "compressed_size",
+			}),
+		}).Create(entry).Error
+	})
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	if previous.Bucket != "" && previous.Bucket != inlineBucket {
+		target := s.bucket(previous.Bucket)
+		if _, err := target.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(target.name),
+			Key:    aws.String(entry.Key),
+		}); err != nil {
+			s.log.WithField("key", entry.Key).WithError(err).Warn("Failed to remove object superseded by inline copy")
+		}
+	}
+	return nil
+}
+
+func (s *S3Storage) getInline(ctx context.Context, key string) ([]byte, error) {
+	var object models.InlineObject
+	if err := s.db.WithContext(ctx).Where("key = ?", key).First(&object).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return object.Content, nil
+}
+
+func (s *S3Storage) getInlineRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	content, err := s.getInline(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || offset > int64(len(content)) {
+		return nil, fmt.Errorf("range start %d outside object of %d bytes", offset, len(content))
+	}
+	end := offset + length
+	if end > int64(len(content)) {
+		end = int64(len(content))
+	}
+	return io.NopCloser(bytes.NewReader(content[offset:end])), nil
+}
+
+func (s *S3Storage) dropInline(ctx context.Context, keys []string) error {
+	if s.db == nil || len(keys) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Where("key IN ?", keys).Delete(&models.InlineObject{}).Error
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const KeySchemaVersion = 1
+
+type CacheKey struct {
+	Version    int
+	Class      string
+	Repository string
+	Reference  string
+}
+
+func keyPrefix(version int) string {
+	return fmt.Sprintf("v%d/", version)
+}
+
+func ClassPrefix(class string) string {
+	return keyPrefix(KeySchemaVersion) + class + "s/"
+}
+
+func BlobKey(image, digest string) string {
+	return ClassPrefix("blob") + image + "/" + digest
+}
+
+func ManifestKey(image, reference string) string {
+	return ClassPrefix("manifest") + image + "/" + reference
+}
+
+func ReferrersKey(image, digest string) string {
+	return ClassPrefix("referrer") + image + "/" + digest
+}
+
+func isManifestClass(class string) bool {
+	return class == "manifest" || class == "referrer"
+}
+
+func ParseKey(key string) CacheKey {
+	var parsed CacheKey
+
+	if strings.HasPrefix(key, "v") {
+		if idx := strings.Index(key, "/"); idx > 1 {
+			if version, err := strconv.Atoi(key[1:idx]); err == nil {
+				parsed.Version = version
+				key = key[idx+1:]
+			}
+		}
+	}
+
+	parts := strings.Split(key, "/")
+	parsed.Class = strings.TrimSuffix(parts[0], "s")
+	if len(parts) >= 3 {
+		parsed.Repository = strings.Join(parts[1:len(parts)-1], "/")
+		parsed.Reference = parts[len(parts)-1]
+	}
+	return parsed
+}
+
+func (k CacheKey) String() string {
+	return fmt.Sprintf("%s%ss/%s/%s", keyPrefix(k.Version), k.Class, k.Repository, k.Reference)
+}
+
+func CurrentKey(key string) (string, bool) {
+	parsed := ParseKey(key)
+	if parsed.Version == KeySchemaVersion || parsed.Repository == "" {
+		return key, false
+	}
+	parsed.Version = KeySchemaVersion
+	return parsed.String(), true
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type metadataStore interface {
+	find(ctx context.Context, key string) (*models.RegistryCache, error)
+	known(ctx context.Context, keys []string) (map[string]string, error)
+	findDigest(ctx context.Context, digest, exclude string) (*models.RegistryCache, error)
+	upsert(ctx context.Context, entry *models.RegistryCache, columns []string) error
+	insert(ctx context.Context, entry *models.RegistryCache) error
+	update(ctx context.Context, key, column string, value interface{}) error
+	remove(ctx context.Context, keys []string) error
+}
+
+type dbMetadata struct {
+	db *gorm.DB
+}
+
+func (m *dbMetadata) find(ctx context.Context, key string) (*models.RegistryCache, error) {
+	var entry models.RegistryCache
+	if err := m.db.WithContext(ctx).Where("key = ?", key).First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (m *dbMetadata) known(ctx context.Context, keys []string) (map[string]string, error) {
+	var entries []models.RegistryCache
+	if err := m.db.WithContext(ctx).Unscoped().
+		Select("key", "bucket").
+		Where("key IN ?", keys).
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	buckets := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		buckets[entry.Key] = entry.Bucket
+	}
+	return buckets, nil
+}
+
+func (m *dbMetadata) findDigest(ctx context.Context, digest, exclude string) (*models.RegistryCache, error) {
+	var entry models.RegistryCache
+	if err := m.db.WithContext(ctx).
+		Where("digest = ? AND type = ? AND key <> ? AND bucket <> ?", digest, "blob", exclude, inlineBucket).
+		Where("expires_at > ?", time.Now()).
+		Order("last_access DESC").
+		First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (m *dbMetadata) upsert(ctx context.Context, entry *models.RegistryCache, columns []string) error {
+	return m.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(entry).Error
+}
+
+func (m *dbMetadata) insert(ctx context.Context, entry *models.RegistryCache) error {
+	return m.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(entry).Error
+}
+
+func (m *dbMetadata) update(ctx context.Context, key, column string, value interface{}) error {
+	return m.db.WithContext(ctx).Model(&models.RegistryCache{}).
+		Where("key = ?", key).
+		Update(column, value).Error
+}
+
+func (m *dbMetadata) remove(ctx context.Context, keys []string) error {
+	return m.db.WithContext(ctx).Unscoped().Where("key IN ?", keys).Delete(&models.RegistryCache{}).Error
+}
+
+type memoryIndex struct {
+	mu      sync.RWMutex
+	entries map[string]models.RegistryCache
+}
+
+func newMemoryIndex() *memoryIndex {
+	return &memoryIndex{entries: make(map[string]models.RegistryCache)}
+}
+
+func (m *memoryIndex) find(_ context.Context, key string) (*models.RegistryCache, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &entry, nil
+}
+
+func (m *memoryIndex) known(_ context.Context, keys []string) (map[string]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	buckets := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if entry, ok := m.entries[key]; ok {
+			buckets[key] = entry.Bucket
+		}
+	}
+	return buckets, nil
+}
+
+func (m *memoryIndex) findDigest(_ context.Context, digest, exclude string) (*models.RegistryCache, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	now := time.Now()
+	for key, entry := range m.entries {
+		if key != exclude && entry.Digest == digest && entry.Type == "blob" && now.Before(entry.ExpiresAt) {
+			return &entry, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryIndex) upsert(_ context.Context, entry *models.RegistryCache, _ []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *entry
+	if existing, ok := m.entries[entry.Key]; ok {
+		stored.StoredAt = existing.StoredAt
+		stored.Headers = existing.Headers
+		if stored.SizeBytes < 0 {
+			stored.SizeBytes = existing.SizeBytes
+		}
+	}
+	m.entries[entry.Key] = stored
+	return nil
+}
+
+func (m *memoryIndex) insert(_ context.Context, entry *models.RegistryCache) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.entries[entry.Key]; !ok {
+		m.entries[entry.Key] = *entry
+	}
+	return nil
+}
+
+func (m *memoryIndex) update(_ context.Context, key, column string, value interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil
+	}
+
+	switch column {
+	case "last_access":
+		entry.LastAccess, ok = value.(time.Time)
+	case "size_bytes":
+		entry.SizeBytes, ok = value.(int64)
+	case "headers":
+		entry.Headers, ok = value.(string)
+	default:
+		return fmt.Errorf("unsupported column %q", column)
+	}
+	if !ok {
+		return fmt.Errorf("invalid value for column %q", column)
+	}
+	m.entries[key] = entry
+	return nil
+}
+
+func (m *memoryIndex) remove(_ context.Context, keys []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+func (m *memoryIndex) expired(now time.Time, limit int) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var keys []string
+	for key, entry := range m.entries {
+		if now.After(entry.ExpiresAt) {
+			keys = append(keys, key)
+			if len(keys) >= limit {
+				break
+			}
+		}
+	}
+	return keys
+}
+
+func (s *S3Storage) SweepExpired(ctx context.Context, interval time.Duration, batchSize int) {
+	index, ok := s.meta.(*memoryIndex)
+	if !ok {
+		return
+	}
+	log := s.log.WithField("operation", "sweep_expired")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			keys := index.expired(time.Now(), batchSize)
+			if len(keys) == 0 {
+				continue
+			}
+			deleted, failed, err := s.DeleteBatch(ctx, keys)
+			if err != nil {
+				log.WithError(err).Error("Failed to delete expired objects")
+				continue
+			}
+			log.WithFields(logrus.Fields{
+				"deleted": deleted,
+				"failed":  len(failed),
+			}).Info("Swept expired cache entries")
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+func (s *S3Storage) MigrateKey(ctx context.Context, oldKey, newKey string, keepSource bool) error {
+	log := s.log.WithFields(logrus.Fields{
+		"operation": "migrate_key",
+		"old_key":   oldKey,
+		"new_key":   newKey,
+	})
+
+	var entry models.RegistryCache
+	if err := s.db.WithContext(ctx).Where("key = ?", oldKey).First(&entry).Error; err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	if entry.Bucket == inlineBucket {
+		return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			migrated := entry
+			migrated.Key = newKey
+			if err := tx.Create(&migrated).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.InlineObject{}).Where("key = ?", oldKey).Update("key", newKey).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Where("key = ?", oldKey).Delete(&models.RegistryCache{}).Error
+		})
+	}
+
+	target := s.bucket(entry.Bucket)
+	input := &s3.CopyObjectInput{
+		Bucket:     aws.String(target.name),
+		Key:        aws.String(newKey),
+		CopySource: aws.String((&url.URL{Path: target.name + "/" + oldKey}).EscapedPath()),
+	}
+	if s.cfg.S3SSE != "" && s.quirks.sse {
+		input.ServerSideEncryption = types.ServerSideEncryption(s.cfg.S3SSE)
+		if s.cfg.S3SSEKMSKeyID != "" {
+			input.SSEKMSKeyId = aws.String(s.cfg.S3SSEKMSKeyID)
+		}
+	}
+	if target.storageClass != "" {
+		input.StorageClass = types.StorageClass(target.storageClass)
+	}
+
+	if _, err := target.client.CopyObject(ctx, input); err != nil {
+		s.logS3ErrorDetails(err, log)
+		return fmt.Errorf("s3 copy failed: %w", err)
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		migrated := entry
+		migrated.Key = newKey
+		if err := tx.Create(&migrated).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("key = ?", oldKey).Delete(&models.RegistryCache{}).Error
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to rewrite cache entry")
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	if keepSource {
+		return nil
+	}
+
+	if _, err := target.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(target.name),
+		Key:    aws.String(oldKey),
+	}); err != nil {
+		log.WithError(err).Warn("Failed to delete migrated source object")
+	}
+	return nil
+}
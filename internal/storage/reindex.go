@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/sdko-org/registry-proxy/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+const expiresAtMetadata = "Expires-At"
+
+type Reindexer interface {
+	Reindex(ctx context.Context) (*ReindexResult, error)
+}
+
+type ReindexResult struct {
+	Scanned  int      `json:"scanned"`
+	Restored int      `json:"restored"`
+	Skipped  int      `json:"skipped"`
+	Failed   []string `json:"failed,omitempty"`
+}
+
+func (s *S3Storage) Reindex(ctx context.Context) (*ReindexResult, error) {
+	result := &ReindexResult{}
+	targets := []*bucketTarget{s.primary}
+	if s.large != nil && s.large.name != s.primary.name {
+		targets = append(targets, s.large)
+	}
+
+	for _, target := range targets {
+		for _, class := range []string{"blob", "manifest", "referrer"} {
+			if err := s.reindexPrefix(ctx, target, class, result); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"operation": "reindex",
+		"scanned":   result.Scanned,
+		"restored":  result.Restored,
+		"skipped":   result.Skipped,
+		"failed":    len(result.Failed),
+	}).Info("Cache index rebuilt from object storage")
+	return result, nil
+}
+
+func (s *S3Storage) reindexPrefix(ctx context.Context, target *bucketTarget, class string, result *ReindexResult) error {
+	log := s.log.WithFields(logrus.Fields{
+		"operation": "reindex",
+		"bucket":    target.name,
+		"class":     class,
+	})
+
+	pages := s3.NewListObjectsV2Paginator(target.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(target.name),
+		Prefix: aws.String(ClassPrefix(class)),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			s.logS3ErrorDetails(err, log)
+			return fmt.Errorf("s3 list failed: %w", err)
+		}
+
+		keys := make([]string, 0, len(page.Contents))
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+		result.Scanned += len(keys)
+
+		indexed, err := s.meta.known(ctx, keys)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			if _, ok := indexed[key]; ok {
+				result.Skipped++
+				continue
+			}
+			if err := s.restoreEntry(ctx, target, class, object); err != nil {
+				log.WithFields(logrus.Fields{"key": key, "error": err}).Warn("Failed to restore cache entry")
+				result.Failed = append(result.Failed, key)
+				continue
+			}
+			result.Restored++
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return ctx.Err()
+}
+
+func (s *S3Storage) restoreEntry(ctx context.Context, target *bucketTarget, class string, object types.Object) error {
+	key := aws.ToString(object.Key)
+	parsed := ParseKey(key)
+	if parsed.Repository == "" {
+		return fmt.Errorf("unrecognised key layout")
+	}
+
+	head, err := target.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(target.name),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 head failed: %w", err)
+	}
+
+	digest := metadataValue(head.Metadata, "Docker-Content-Digest")
+	if digest == "" && class == "blob" {
+		digest = parsed.Reference
+	}
+	if digest == "" {
+		return fmt.Errorf("object has no digest metadata")
+	}
+
+	ttl := s.cfg.BlobCacheTTL
+	if isManifestClass(class) {
+		ttl = s.cfg.ManifestCacheTTL
+	}
+	storedAt := aws.ToTime(object.LastModified)
+	if storedAt.IsZero() {
+		storedAt = time.Now()
+	}
+	expiresAt := time.Now().Add(ttl)
+	if value := metadataValue(head.Metadata, expiresAtMetadata); value != "" {
+		if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			expiresAt = parsed
+		}
+	}
+
+	stored := aws.ToInt64(object.Size)
+
+	entry := models.RegistryCache{
+		Key:          key,
+		Type:         class,
+		Digest:       digest,
+		MediaType:    aws.ToString(head.ContentType),
+		StoredAt:     storedAt,
+		ExpiresAt:    expiresAt,
+		LastAccess:   time.Now(),
+		SizeBytes:    objectSize(head.Metadata, stored),
+		LastModified: storedAt,
+		Bucket:       target.name,
+		Encrypted:    isEncrypted(head.Metadata),
+
+		Compression:    metadataValue(head.Metadata, compressionMetadata),
+		CompressedSize: compressedSize(head.Metadata, stored),
+	}
+	if err := s.meta.insert(ctx, &entry); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	return nil
+}
@@ -6,80 +6,184 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	"github.com/sdko-org/registry-proxy/internal/config"
 	"github.com/sdko-org/registry-proxy/internal/models"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 type S3Storage struct {
-	client         *s3.S3
-	uploader       *s3manager.Uploader
+	primary        *bucketTarget
+	large          *bucketTarget
 	cfg            *config.Config
 	db             *gorm.DB
+	meta           metadataStore
+	quirks         *s3Quirks
+	errors         atomic.Uint64
 	log            *logrus.Entry
 	activeUploads  sync.Map
 	mu             sync.Mutex
 	partSize       int64
 	maxRetries     int
 	uploadTimeouts map[string]time.Time
+
+	encryption *objectCipher
 }
 
 func NewS3Storage(logger *logrus.Logger, cfg *config.Config, db *gorm.DB) *S3Storage {
-	awsConfig := &aws.Config{
-		Region:           aws.String(cfg.S3Region),
-		Credentials:      credentials.NewStaticCredentials(cfg.S3AccessKey, cfg.S3SecretKey, ""),
-		S3ForcePathStyle: aws.Bool(true),
+	log := logger.WithField("component", "storage")
+
+	options := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(cfg.S3Region),
+		awsconfig.WithRetryer(newRetryer(cfg)),
+	}
+	if cfg.HasStaticS3Credentials() {
+		options = append(options, awsconfig.WithCredentialsProvider(&configCredentialsProvider{cfg: cfg}))
+		log.Info("Using static S3 credentials")
+	} else {
+		log.Info("Using default AWS credential chain for S3")
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), options...)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to load AWS configuration")
+	}
+
+	quirks := quirksFor(cfg.S3Backend)
+	checksums := cfg.S3ChecksumValidation && quirks.checksums
+	if cfg.S3ChecksumValidation && !quirks.checksums {
+		log.WithField("backend", cfg.S3Backend).Warn("S3 backend does not support flexible checksums, ignoring S3_CHECKSUM_VALIDATION")
+	}
+	newClient := func(endpoint string) *s3.Client {
+		return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+			o.UsePathStyle = true
+			o.DisableLogOutputChecksumValidationSkipped = true
+			if endpoint != "" {
+				o.BaseEndpoint = aws.String(endpoint)
+			}
+			o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+			o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+			if checksums {
+				o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenSupported
+				o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenSupported
+			}
+			o.APIOptions = append(o.APIOptions, injectS3Latency(cfg))
+		})
+	}
+
+	storageClass := func(class string) string {
+		supported := quirks.storageClass(class)
+		if supported != class {
+			log.WithFields(logrus.Fields{
+				"backend":       cfg.S3Backend,
+				"storage_class": class,
+			}).Warn("S3 backend does not support storage class, using bucket default")
+		}
+		return supported
+	}
+	if cfg.S3SSE != "" && !quirks.sse {
+		log.WithField("backend", cfg.S3Backend).Warn("S3 backend does not support server-side encryption, ignoring S3_SSE")
 	}
+	if cfg.S3ObjectTagging && !quirks.tagging {
+		log.WithField("backend", cfg.S3Backend).Warn("S3 backend does not support object tagging, ignoring S3_OBJECT_TAGGING")
+	}
+
+	client := newClient(cfg.S3Endpoint)
+	primary := newBucketTarget(client, cfg, cfg.S3Bucket, storageClass(cfg.S3StorageClass))
 
-	if cfg.S3Endpoint != "" {
-		awsConfig.Endpoint = aws.String(cfg.S3Endpoint)
+	var large *bucketTarget
+	if cfg.S3LargeBucket != "" {
+		largeClient := client
+		if cfg.S3LargeEndpoint != "" && cfg.S3LargeEndpoint != cfg.S3Endpoint {
+			largeClient = newClient(cfg.S3LargeEndpoint)
+		}
+		large = newBucketTarget(largeClient, cfg, cfg.S3LargeBucket, storageClass(cfg.S3LargeStorageClass))
+		log.WithFields(logrus.Fields{
+			"bucket":         cfg.S3LargeBucket,
+			"size_threshold": cfg.S3TierSizeThreshold,
+		}).Info("Routing large blobs to separate bucket")
 	}
 
-	sess := session.Must(session.NewSession(awsConfig))
+	encryption, err := newObjectCipher(awsCfg, cfg)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize storage encryption")
+	}
+	if encryption != nil {
+		log.Info("Encrypting cached objects before upload to S3")
+	}
 
-	uploader := s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
-		u.PartSize = 5 * 1024 * 1024
-		u.Concurrency = 3
-		u.LeavePartsOnError = false
-	})
+	var meta metadataStore = &dbMetadata{db: db}
+	if db == nil {
+		meta = newMemoryIndex()
+		log.Info("Keeping cache metadata in memory and S3 object metadata")
+	}
 
 	return &S3Storage{
-		client:         s3.New(sess),
-		uploader:       uploader,
+		primary:        primary,
+		large:          large,
 		cfg:            cfg,
 		db:             db,
-		log:            logger.WithField("component", "storage"),
-		partSize:       10 * 1024 * 1024,
-		maxRetries:     5,
+		meta:           meta,
+		quirks:         quirks,
+		log:            log,
+		partSize:       cfg.S3PartSize,
+		maxRetries:     cfg.S3MaxRetries,
 		uploadTimeouts: make(map[string]time.Time),
+		encryption:     encryption,
 	}
 }
 
-func (s *S3Storage) Get(ctx context.Context, key string) ([]byte, string, string, error) {
-	log := s.log.WithFields(logrus.Fields{
-		"operation": "get",
-		"key":       key,
-	})
+func newRetryer(cfg *config.Config) func() aws.Retryer {
+	attempts := func(o *retry.StandardOptions) {
+		o.MaxAttempts = cfg.S3MaxRetries + 1
+	}
+	return func() aws.Retryer {
+		if cfg.S3RetryMode == config.S3RetryStandard {
+			return retry.NewStandard(attempts)
+		}
+		return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+			o.StandardOptions = append(o.StandardOptions, attempts)
+		})
+	}
+}
+
+// configCredentialsProvider hands out the static keys from cfg. The keys are
+// marked as already expired so the SDK's credentials cache asks again on every
+// request and picks up keys rotated through the secret watcher.
+type configCredentialsProvider struct {
+	cfg *config.Config
+}
+
+func (p *configCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	accessKey, secretKey := p.cfg.S3Credentials()
+	return aws.Credentials{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+		Source:          "RegistryProxyConfig",
+		CanExpire:       true,
+		Expires:         time.Now(),
+	}, nil
+}
 
+func (s *S3Storage) lookup(ctx context.Context, key string, log *logrus.Entry) (*models.RegistryCache, error) {
 	if expiry, exists := s.activeUploads.Load(key); exists {
 		if time.Now().Before(expiry.(time.Time)) {
 			log.Debug("Waiting for active upload completion")
 			for i := 0; i < 10; i++ {
 				time.Sleep(500 * time.Millisecond)
-				var entry models.RegistryCache
-				if err := s.db.WithContext(ctx).Where("key = ?", key).First(&entry).Error; err == nil {
+				if _, err := s.meta.find(ctx, key); err == nil {
 					break
 				}
 			}
@@ -88,19 +192,19 @@ func (s *S3Storage) Get(ctx context.Context, key string) ([]byte, string, string
 		}
 	}
 
-	var entry models.RegistryCache
-	if err := s.db.WithContext(ctx).Where("key = ?", key).First(&entry).Error; err != nil {
+	entry, err := s.meta.find(ctx, key)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Debug("Cache miss")
-			return nil, "", "", fmt.Errorf("cache miss")
+			return nil, fmt.Errorf("cache miss")
 		}
 		log.WithError(err).Error("Database query failed")
-		return nil, "", "", fmt.Errorf("database error: %w", err)
+		return nil, fmt.Errorf("database error: %w", err)
 	}
 
 	if entry.Type == "tag" && time.Since(entry.LastModified) > s.cfg.TagCacheTTL/2 {
 		log.Debug("Stale tag cache")
-		return nil, "", "", fmt.Errorf("stale tag cache")
+		return nil, fmt.Errorf("stale tag cache")
 	}
 
 	if time.Now().After(entry.ExpiresAt) {
@@ -108,37 +212,61 @@ func (s *S3Storage) Get(ctx context.Context, key string) ([]byte, string, string
 		if err := s.Delete(ctx, key); err != nil {
 			log.WithError(err).Error("Failed to delete expired entry")
 		}
-		return nil, "", "", fmt.Errorf("cache expired")
+		return nil, fmt.Errorf("cache expired")
 	}
+	return entry, nil
+}
 
-	resp, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(s.cfg.S3Bucket),
-		Key:    aws.String(key),
+func (s *S3Storage) Get(ctx context.Context, key string) ([]byte, string, string, error) {
+	log := s.log.WithFields(logrus.Fields{
+		"operation": "get",
+		"key":       key,
 	})
+
+	entry, err := s.lookup(ctx, key, log)
 	if err != nil {
-		if awsErr, ok := err.(awserr.Error); ok {
-			log.WithFields(logrus.Fields{
-				"code":    awsErr.Code(),
-				"message": awsErr.Message(),
-			}).Error("S3 get failed")
+		return nil, "", "", err
+	}
 
-			if reqErr, ok := err.(awserr.RequestFailure); ok {
-				log.Errorf("HTTP Status: %d", reqErr.StatusCode())
-				log.Errorf("Request ID: %s", reqErr.RequestID())
-			}
+	if entry.Bucket == inlineBucket {
+		content, err := s.getInline(ctx, key)
+		if err != nil {
+			log.WithError(err).Error("Failed to read inline object")
+			return nil, "", "", err
+		}
+		if err := s.meta.update(ctx, key, "last_access", time.Now()); err != nil {
+			log.WithError(err).Warn("Failed to update last access time")
 		}
+		return content, entry.Digest, entry.MediaType, nil
+	}
+
+	target := s.bucket(entry.Bucket)
+	resp, err := target.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(target.name),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		s.logS3ErrorDetails(err, log)
 		return nil, "", "", fmt.Errorf("s3 get failed: %w", err)
 	}
-	defer resp.Body.Close()
+	body, err := s.openObject(resp.Body, isEncrypted(resp.Metadata))
+	if err == nil {
+		body, err = decompress(body, metadataValue(resp.Metadata, compressionMetadata))
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to open S3 object")
+		return nil, "", "", err
+	}
+	defer body.Close()
 
-	content, err := io.ReadAll(resp.Body)
+	content, err := io.ReadAll(body)
 	if err != nil {
 		log.WithError(err).Error("Failed to read S3 object")
 		return nil, "", "", fmt.Errorf("read failed: %w", err)
 	}
 
-	mediaType := aws.StringValue(resp.ContentType)
-	digest := aws.StringValue(resp.Metadata["Docker-Content-Digest"])
+	mediaType := aws.ToString(resp.ContentType)
+	digest := metadataValue(resp.Metadata, "Docker-Content-Digest")
 	if digest == "" {
 		digest = entry.Digest
 	}
@@ -149,15 +277,147 @@ func (s *S3Storage) Get(ctx context.Context, key string) ([]byte, string, string
 		"media_type": mediaType,
 	}).Debug("Cache hit")
 
-	if err := s.db.WithContext(ctx).Model(&models.RegistryCache{}).
-		Where("key = ?", key).
-		Update("last_access", time.Now()).Error; err != nil {
+	if err := s.meta.update(ctx, key, "last_access", time.Now()); err != nil {
 		log.WithError(err).Warn("Failed to update last access time")
 	}
 
 	return content, digest, mediaType, nil
 }
 
+func (s *S3Storage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	log := s.log.WithFields(logrus.Fields{
+		"operation": "stat",
+		"key":       key,
+	})
+
+	entry, err := s.lookup(ctx, key, log)
+	if err != nil {
+		return nil, err
+	}
+
+	info := entryInfo(entry)
+	if info.Size >= 0 {
+		return info, nil
+	}
+
+	target := s.bucket(entry.Bucket)
+	head, err := target.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(target.name),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		s.logS3ErrorDetails(err, log)
+		return nil, fmt.Errorf("s3 head failed: %w", err)
+	}
+	info.Size = objectSize(head.Metadata, aws.ToInt64(head.ContentLength))
+	if info.MediaType == "" {
+		info.MediaType = aws.ToString(head.ContentType)
+	}
+
+	if err := s.meta.update(ctx, key, "size_bytes", info.Size); err != nil {
+		log.WithError(err).Warn("Failed to backfill object size")
+	}
+	return info, nil
+}
+
+func (s *S3Storage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	entry, err := s.meta.find(ctx, key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		entry, err = &models.RegistryCache{Key: key}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if entry.Bucket == inlineBucket {
+		return s.getInlineRange(ctx, key, offset, length)
+	}
+	target := s.bucket(entry.Bucket)
+	if entry.Compression != "" {
+		return s.getCompressedRange(ctx, target, entry, offset, length)
+	}
+	if entry.Encrypted {
+		return s.getEncryptedRange(ctx, target, key, offset, length)
+	}
+
+	resp, err := target.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(target.name),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+	})
+	if err != nil {
+		s.logS3ErrorDetails(err, s.log.WithFields(logrus.Fields{"operation": "get_range", "key": key}))
+		return nil, fmt.Errorf("s3 ranged get failed: %w", err)
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Storage) GetStream(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	log := s.log.WithFields(logrus.Fields{
+		"operation": "get_stream",
+		"key":       key,
+	})
+
+	entry, err := s.lookup(ctx, key, log)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target := s.bucket(entry.Bucket)
+	info := entryInfo(entry)
+
+	var body io.ReadCloser
+	if entry.Bucket == inlineBucket {
+		content, err := s.getInline(ctx, key)
+		if err != nil {
+			log.WithError(err).Error("Failed to read inline object")
+			return nil, nil, err
+		}
+		body = io.NopCloser(bytes.NewReader(content))
+		info.Size = int64(len(content))
+	} else if entry.SizeBytes > s.partSize && s.cfg.S3DownloadConcurrency > 1 && entry.Compression == "" {
+		log.WithFields(logrus.Fields{
+			"size":        entry.SizeBytes,
+			"concurrency": s.cfg.S3DownloadConcurrency,
+		}).Debug("Using parallel ranged download")
+		if entry.Encrypted {
+			if body, err = s.openObject(newRangeReader(ctx, target, key, encryptedSize(entry.SizeBytes), s.partSize, s.cfg.S3DownloadConcurrency, s.maxRetries), true); err != nil {
+				log.WithError(err).Error("Failed to open S3 object")
+				return nil, nil, err
+			}
+		} else {
+			body = newRangeReader(ctx, target, key, entry.SizeBytes, s.partSize, s.cfg.S3DownloadConcurrency, s.maxRetries)
+		}
+	} else {
+		resp, err := target.client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(target.name),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			s.logS3ErrorDetails(err, log)
+			return nil, nil, fmt.Errorf("s3 get failed: %w", err)
+		}
+		body, err = s.openObject(resp.Body, isEncrypted(resp.Metadata))
+		if err == nil {
+			body, err = decompress(body, metadataValue(resp.Metadata, compressionMetadata))
+		}
+		if err != nil {
+			log.WithError(err).Error("Failed to open S3 object")
+			return nil, nil, err
+		}
+		if info.Size < 0 {
+			info.Size = objectSize(resp.Metadata, aws.ToInt64(resp.ContentLength))
+		}
+		if mediaType := aws.ToString(resp.ContentType); mediaType != "" {
+			info.MediaType = mediaType
+		}
+	}
+
+	if err := s.UpdateLastAccess(ctx, key); err != nil {
+		log.WithError(err).Warn("Failed to update last access time")
+	}
+	return body, info, nil
+}
+
 func (s *S3Storage) Put(ctx context.Context, key string, content []byte, digest, mediaType string, ttl time.Duration) error {
 	log := s.log.WithFields(logrus.Fields{
 		"operation":  "put",
@@ -169,26 +429,50 @@ func (s *S3Storage) Put(ctx context.Context, key string, content []byte, digest,
 
 	cacheType := "blob"
 	actualTTL := ttl
-	switch {
-	case strings.Contains(key, "manifests"):
-		cacheType = "manifest"
-		actualTTL = s.cfg.ManifestCacheTTL
-	case strings.Contains(key, "tags"):
+	switch class := ParseKey(key).Class; class {
+	case "manifest", "referrer":
+		cacheType = class
+		if actualTTL <= 0 {
+			actualTTL = s.cfg.ManifestCacheTTL
+		}
+	case "tag":
 		cacheType = "tag"
 		actualTTL = s.cfg.TagCacheTTL
 	default:
 		actualTTL = s.cfg.BlobCacheTTL
 	}
 
-	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
-		Bucket:      aws.String(s.cfg.S3Bucket),
-		Key:         aws.String(key),
-		Body:        bytes.NewReader(content),
-		ContentType: aws.String(mediaType),
-		Metadata: map[string]*string{
-			"Docker-Content-Digest": aws.String(digest),
-		},
-	})
+	expiresAt := time.Now().Add(actualTTL)
+	if s.inlineEligible(int64(len(content))) {
+		entry := models.RegistryCache{
+			Key:          key,
+			Type:         cacheType,
+			Digest:       digest,
+			MediaType:    mediaType,
+			StoredAt:     time.Now(),
+			ExpiresAt:    expiresAt,
+			LastAccess:   time.Now(),
+			LastModified: time.Now(),
+		}
+		if err := s.putInline(ctx, &entry, content); err != nil {
+			log.WithError(err).Error("Failed to store inline object")
+			return err
+		}
+		log.Debug("Cache entry stored inline")
+		return nil
+	}
+
+	if stored, err := s.dedupe(ctx, key, digest, mediaType, expiresAt, log); err != nil {
+		log.WithError(err).Warn("Deduplication lookup failed, uploading")
+	} else if stored {
+		return nil
+	}
+
+	target := s.targetFor(key, mediaType, int64(len(content)))
+	body, packed := s.compressBody(key, bytes.NewReader(content), int64(len(content)))
+	input := s.uploadInput(target, key, body, digest, mediaType, expiresAt)
+	packed.annotate(input.Metadata)
+	_, err := target.uploader.Upload(ctx, input, s.partSizeFor(s.storedSize(int64(len(content)))))
 
 	if err != nil {
 		s.logS3ErrorDetails(err, log)
@@ -201,22 +485,28 @@ func (s *S3Storage) Put(ctx context.Context, key string, content []byte, digest,
 		Digest:       digest,
 		MediaType:    mediaType,
 		StoredAt:     time.Now(),
-		ExpiresAt:    time.Now().Add(actualTTL),
+		ExpiresAt:    expiresAt,
 		LastAccess:   time.Now(),
 		SizeBytes:    int64(len(content)),
 		LastModified: time.Now(),
+		Bucket:       target.name,
+		Encrypted:    s.encryption != nil,
+
+		Compression:    packed.codec(),
+		CompressedSize: packed.compressedSize(),
 	}
 
-	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
-		Columns: []clause.Column{{Name: "key"}},
-		DoUpdates: clause.AssignmentColumns([]string{
-			"type", "digest", "media_type", "expires_at",
-			"last_access", "size_bytes", "last_modified",
-		}),
-	}).Create(&entry).Error; err != nil {
+	if err := s.meta.upsert(ctx, &entry, []string{
+		"type", "digest", "media_type", "expires_at",
+		"last_access", "size_bytes", "last_modified", "bucket",
+		"deleted_at", "encrypted", "compression", "compressed_size",
+	}); err != nil {
 		log.WithError(err).Error("Failed to upsert cache entry")
 		return fmt.Errorf("database error: %w", err)
 	}
+	if err := s.dropInline(ctx, []string{key}); err != nil {
+		log.WithError(err).Warn("Failed to remove superseded inline object")
+	}
 
 	log.Debug("Cache entry stored")
 	return nil
@@ -239,27 +529,21 @@ func (s *S3Storage) PutStream(ctx context.Context, key string, content io.Reader
 		s.mu.Unlock()
 	}()
 
-	var lastErr error
-	for attempt := 1; attempt <= s.maxRetries; attempt++ {
-		uploadCtx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
-		defer cancel()
-
-		_, err := s.uploader.UploadWithContext(uploadCtx, &s3manager.UploadInput{
-			Bucket:      aws.String(s.cfg.S3Bucket),
-			Key:         aws.String(key),
-			Body:        content,
-			ContentType: aws.String(mediaType),
-			Metadata: map[string]*string{
-				"Docker-Content-Digest": aws.String(digest),
-			},
-		})
-
-		if err == nil {
+	size := readerSize(content)
+	if size > s.cfg.S3MaxBlobSize {
+		log.WithField("size", size).Warn("Object exceeds S3_MAX_BLOB_SIZE, not caching")
+		return fmt.Errorf("object size %d exceeds maximum cacheable size %d", size, s.cfg.S3MaxBlobSize)
+	}
+	if s.inlineEligible(size) {
+		data, err := io.ReadAll(io.LimitReader(content, size+1))
+		if err != nil {
+			return fmt.Errorf("read inline object: %w", err)
+		}
+		if int64(len(data)) == size {
 			cacheType := "blob"
-			if strings.Contains(key, "manifests") {
-				cacheType = "manifest"
+			if class := ParseKey(key).Class; isManifestClass(class) {
+				cacheType = class
 			}
-
 			entry := models.RegistryCache{
 				Key:          key,
 				Type:         cacheType,
@@ -268,53 +552,83 @@ func (s *S3Storage) PutStream(ctx context.Context, key string, content io.Reader
 				StoredAt:     time.Now(),
 				ExpiresAt:    time.Now().Add(ttl),
 				LastAccess:   time.Now(),
-				SizeBytes:    -1,
 				LastModified: time.Now(),
 			}
-
-			if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
-				Columns: []clause.Column{{Name: "key"}},
-				DoUpdates: clause.AssignmentColumns([]string{
-					"type", "digest", "media_type", "expires_at",
-					"last_access", "last_modified",
-				}),
-			}).Create(&entry).Error; err != nil {
-				log.WithError(err).Error("Failed to upsert stream cache entry")
-				return fmt.Errorf("database error: %w", err)
+			if err := s.putInline(ctx, &entry, data); err != nil {
+				log.WithError(err).Error("Failed to store inline object")
+				return err
 			}
-
-			log.Debug("Stream cache entry stored")
+			log.Debug("Stream cache entry stored inline")
 			return nil
 		}
+		content = io.MultiReader(bytes.NewReader(data), content)
+		size = -1
+	}
+	if stored, err := s.dedupe(ctx, key, digest, mediaType, time.Now().Add(ttl), log); err != nil {
+		log.WithError(err).Warn("Deduplication lookup failed, uploading")
+	} else if stored {
+		return nil
+	}
+	target := s.targetFor(key, mediaType, size)
 
-		lastErr = err
+	uploadCtx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	expiresAt := time.Now().Add(ttl)
+	body, packed := s.compressBody(key, content, size)
+	input := s.uploadInput(target, key, body, digest, mediaType, expiresAt)
+	packed.annotate(input.Metadata)
+	if _, err := target.uploader.Upload(uploadCtx, input, s.partSizeFor(s.storedSize(size))); err != nil {
 		s.logS3ErrorDetails(err, log)
+		var respErr *awshttp.ResponseError
+		if errors.As(err, &respErr) && respErr.HTTPStatusCode() == 413 {
+			log.Error("Entity too large - consider reducing part size")
+			return fmt.Errorf("configured part size too large: %w", err)
+		}
+		return fmt.Errorf("upload failed: %w", err)
+	}
 
-		if awsErr, ok := err.(awserr.Error); ok {
-			if awsErr.Code() == "RequestCanceled" {
-				log.Warnf("Upload canceled, retry %d/%d", attempt, s.maxRetries)
-				time.Sleep(time.Duration(attempt) * time.Second)
-				continue
-			}
+	cacheType := "blob"
+	if class := ParseKey(key).Class; isManifestClass(class) {
+		cacheType = class
+	}
 
-			if reqErr, ok := err.(awserr.RequestFailure); ok {
-				if reqErr.StatusCode() == 413 {
-					log.Error("Entity too large - consider reducing part size")
-					return fmt.Errorf("configured part size too large: %w", err)
-				}
-			}
-		}
+	entry := models.RegistryCache{
+		Key:          key,
+		Type:         cacheType,
+		Digest:       digest,
+		MediaType:    mediaType,
+		StoredAt:     time.Now(),
+		ExpiresAt:    expiresAt,
+		LastAccess:   time.Now(),
+		SizeBytes:    size,
+		LastModified: time.Now(),
+		Bucket:       target.name,
+		Encrypted:    s.encryption != nil,
 
-		if !isRetryableError(err) {
-			log.Error("Non-retryable error encountered")
-			break
-		}
+		Compression:    packed.codec(),
+		CompressedSize: packed.compressedSize(),
+	}
+
+	columns := []string{
+		"type", "digest", "media_type", "expires_at",
+		"last_access", "last_modified", "bucket", "deleted_at",
+		"encrypted", "compression", "compressed_size",
+	}
+	if size >= 0 {
+		columns = append(columns, "size_bytes")
+	}
 
-		log.Warnf("Retrying upload (%d/%d)", attempt, s.maxRetries)
-		time.Sleep(time.Duration(attempt*2) * time.Second)
+	if err := s.meta.upsert(ctx, &entry, columns); err != nil {
+		log.WithError(err).Error("Failed to upsert stream cache entry")
+		return fmt.Errorf("database error: %w", err)
+	}
+	if err := s.dropInline(ctx, []string{key}); err != nil {
+		log.WithError(err).Warn("Failed to remove superseded inline object")
 	}
 
-	return fmt.Errorf("upload failed after %d attempts: %w", s.maxRetries, lastErr)
+	log.Debug("Stream cache entry stored")
+	return nil
 }
 
 func (s *S3Storage) Delete(ctx context.Context, key string) error {
@@ -323,23 +637,32 @@ func (s *S3Storage) Delete(ctx context.Context, key string) error {
 		"key":       key,
 	})
 
-	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
-		Bucket: aws.String(s.cfg.S3Bucket),
-		Key:    aws.String(key),
-	})
-	if err != nil {
-		log.WithError(err).Error("S3 delete failed")
-		return fmt.Errorf("s3 delete failed: %w", err)
+	buckets, _ := s.meta.known(ctx, []string{key})
+	if buckets[key] == inlineBucket {
+		if err := s.dropInline(ctx, []string{key}); err != nil {
+			log.WithError(err).Error("Inline delete failed")
+			return fmt.Errorf("database delete failed: %w", err)
+		}
+	} else {
+		target := s.bucket(buckets[key])
+		_, err := target.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(target.name),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			log.WithError(err).Error("S3 delete failed")
+			return fmt.Errorf("s3 delete failed: %w", err)
+		}
 	}
 
-	if strings.Contains(key, "tags/list") {
+	if strings.Contains(key, "tags/list") && s.db != nil {
 		repo := strings.Split(key, "/")[0]
-		if err := s.db.WithContext(ctx).Where("repository = ?", repo).Delete(&models.TagCache{}).Error; err != nil {
+		if err := s.db.WithContext(ctx).Unscoped().Where("repository = ?", repo).Delete(&models.TagCache{}).Error; err != nil {
 			log.WithError(err).Error("Failed to delete tag cache entry")
 			return fmt.Errorf("database delete failed: %w", err)
 		}
 	} else {
-		if err := s.db.WithContext(ctx).Where("key = ?", key).Delete(&models.RegistryCache{}).Error; err != nil {
+		if err := s.meta.remove(ctx, []string{key}); err != nil {
 			log.WithError(err).Error("Failed to delete registry cache entry")
 			return fmt.Errorf("database delete failed: %w", err)
 		}
@@ -350,49 +673,88 @@ func (s *S3Storage) Delete(ctx context.Context, key string) error {
 }
 
 func (s *S3Storage) UpdateLastAccess(ctx context.Context, key string) error {
-	return s.db.WithContext(ctx).Model(&models.RegistryCache{}).
-		Where("key = ?", key).
-		Update("last_access", time.Now()).Error
+	return s.meta.update(ctx, key, "last_access", time.Now())
 }
 
-func (s *S3Storage) logS3ErrorDetails(err error, log *logrus.Entry) {
-	if awsErr, ok := err.(awserr.Error); ok {
-		log = log.WithFields(logrus.Fields{
-			"aws_error_code":    awsErr.Code(),
-			"aws_error_message": awsErr.Message(),
-		})
+func (s *S3Storage) StoreHeaders(ctx context.Context, key, headers string) error {
+	return s.meta.update(ctx, key, "headers", headers)
+}
 
-		if reqErr, ok := err.(awserr.RequestFailure); ok {
-			log = log.WithFields(logrus.Fields{
-				"http_status_code": reqErr.StatusCode(),
-				"aws_request_id":   reqErr.RequestID(),
-			})
-		}
+func entryInfo(entry *models.RegistryCache) *ObjectInfo {
+	return &ObjectInfo{
+		Digest:    entry.Digest,
+		MediaType: entry.MediaType,
+		Size:      entry.SizeBytes,
+		StoredAt:  entry.StoredAt,
+		ExpiresAt: entry.ExpiresAt,
+		Headers:   entry.Headers,
+	}
+}
 
-		if origErr := awsErr.OrigErr(); origErr != nil {
-			log = log.WithField("original_error", origErr.Error())
+func (s *S3Storage) uploadInput(target *bucketTarget, key string, body io.Reader, digest, mediaType string, expiresAt time.Time) *s3.PutObjectInput {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(target.name),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(mediaType),
+		Metadata: map[string]string{
+			"Docker-Content-Digest": digest,
+			expiresAtMetadata:       expiresAt.UTC().Format(time.RFC3339),
+		},
+	}
+
+	if target.storageClass != "" {
+		input.StorageClass = types.StorageClass(target.storageClass)
+	}
+
+	if s.cfg.S3SSE != "" && s.quirks.sse {
+		input.ServerSideEncryption = types.ServerSideEncryption(s.cfg.S3SSE)
+		if s.cfg.S3SSEKMSKeyID != "" {
+			input.SSEKMSKeyId = aws.String(s.cfg.S3SSEKMSKeyID)
 		}
 	}
-	log.Error("S3 operation failed")
-}
 
-func isRetryableError(err error) bool {
-	if awsErr, ok := err.(awserr.Error); ok {
-		switch awsErr.Code() {
-		case "RequestTimeout",
-			"Throttling",
-			"ThrottlingException",
-			"RequestLimitExceeded",
-			"ServiceUnavailable",
-			"InternalError",
-			"EC2RoleRequestError":
-			return true
+	if s.encryption != nil {
+		if body != nil {
+			input.Body = s.encryption.encrypt(body)
 		}
+		input.Metadata[encryptionMetadata] = encryptionScheme
 	}
 
-	if reqErr, ok := err.(awserr.RequestFailure); ok {
-		return reqErr.StatusCode() >= 500
+	if s.cfg.S3ObjectTagging && s.quirks.tagging {
+		parsed := ParseKey(key)
+		tags := url.Values{}
+		tags.Set("class", parsed.Class)
+		if parsed.Repository != "" {
+			tags.Set("repository", parsed.Repository)
+		}
+		input.Tagging = aws.String(tags.Encode())
 	}
 
-	return false
+	return input
+}
+
+func (s *S3Storage) ErrorCount() uint64 {
+	return s.errors.Load()
+}
+
+func (s *S3Storage) logS3ErrorDetails(err error, log *logrus.Entry) {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || (apiErr.ErrorCode() != "NoSuchKey" && apiErr.ErrorCode() != "NotFound") {
+		s.errors.Add(1)
+	}
+	if apiErr != nil {
+		log = log.WithFields(logrus.Fields{
+			"aws_error_code":    apiErr.ErrorCode(),
+			"aws_error_message": apiErr.ErrorMessage(),
+		})
+	}
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		log = log.WithFields(logrus.Fields{
+			"http_status_code": respErr.HTTPStatusCode(),
+			"aws_request_id":   respErr.ServiceRequestID(),
+		})
+	}
+	log.WithError(err).Error("S3 operation failed")
 }
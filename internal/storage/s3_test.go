@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeS3 struct {
+	mu          sync.Mutex
+	objects     map[string][]byte
+	metadata    map[string]http.Header
+	puts        int
+	failPuts    int
+	checksums   []string
+	auths       []string
+	badChecksum bool
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	t.Helper()
+	fake := &fakeS3{objects: make(map[string][]byte), metadata: make(map[string]http.Header)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return fake, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.auths = append(f.auths, r.Header.Get("Authorization"))
+	switch r.Method {
+	case http.MethodPut:
+		f.puts++
+		if f.puts <= f.failPuts {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, `<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>`)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = body
+		f.metadata[r.URL.Path] = http.Header{}
+		for name, values := range r.Header {
+			if strings.HasPrefix(strings.ToLower(name), "x-amz-meta-") {
+				f.metadata[r.URL.Path][name] = values
+			}
+		}
+		f.checksums = append(f.checksums, r.Header.Get("X-Amz-Checksum-Crc32"))
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>`)
+			return
+		}
+		for name, values := range f.metadata[r.URL.Path] {
+			w.Header()[name] = values
+		}
+		if f.badChecksum {
+			w.Header().Set("X-Amz-Checksum-Crc32", "AAAAAA==")
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if r.Header.Get("Range") != "" {
+			var start, end int
+			fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+			end = min(end, len(body)-1)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(body)))
+			w.WriteHeader(http.StatusPartialContent)
+			body = body[start : end+1]
+		}
+		w.Write(body)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestS3Storage(t *testing.T, endpoint string, configure func(*config.Config)) *S3Storage {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cfg := &config.Config{
+		S3Region:              "us-east-1",
+		S3Bucket:              "cache",
+		S3Endpoint:            endpoint,
+		S3AccessKey:           "access",
+		S3SecretKey:           "secret",
+		S3Backend:             config.S3BackendAWS,
+		S3PartSize:            config.S3MinPartSize,
+		S3UploadConcurrency:   1,
+		S3DownloadConcurrency: 1,
+		S3MaxRetries:          3,
+		S3MaxBlobSize:         1 << 30,
+		S3RetryMode:           config.S3RetryAdaptive,
+		S3ChecksumValidation:  true,
+		StorageCompression:    config.CompressionNone,
+		BlobCacheTTL:          time.Hour,
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	return NewS3Storage(logger, cfg, nil)
+}
+
+func TestS3RetriesThrottledUploads(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	fake.failPuts = 2
+	s := newTestS3Storage(t, srv.URL, nil)
+
+	key := BlobKey("library/nginx", "sha256:abc")
+	if err := s.PutStream(context.Background(), key, strings.NewReader("layer"), "sha256:abc", "application/octet-stream", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if fake.puts != 3 {
+		t.Fatalf("expected 3 upload attempts, got %d", fake.puts)
+	}
+	if s.ErrorCount() != 0 {
+		t.Fatalf("retried upload counted %d errors", s.ErrorCount())
+	}
+}
+
+func TestS3GivesUpAfterMaxRetries(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	fake.failPuts = 100
+	s := newTestS3Storage(t, srv.URL, func(cfg *config.Config) {
+		cfg.S3MaxRetries = 1
+		cfg.S3RetryMode = config.S3RetryStandard
+	})
+
+	key := BlobKey("library/nginx", "sha256:abc")
+	if err := s.Put(context.Background(), key, []byte("layer"), "sha256:abc", "application/octet-stream", time.Hour); err == nil {
+		t.Fatal("expected upload to fail")
+	}
+	if fake.puts != 2 {
+		t.Fatalf("expected 2 upload attempts, got %d", fake.puts)
+	}
+}
+
+func TestS3PicksUpRotatedCredentials(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	s := newTestS3Storage(t, srv.URL, nil)
+
+	key := BlobKey("library/nginx", "sha256:abc")
+	if err := s.Put(context.Background(), key, []byte("layer"), "sha256:abc", "application/octet-stream", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	s.cfg.S3AccessKey = "rotated-access"
+	s.cfg.S3SecretKey = "rotated-secret"
+	if _, _, _, err := s.Get(context.Background(), key); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if first := fake.auths[0]; !strings.Contains(first, "Credential=access/") {
+		t.Fatalf("first request signed with %q", first)
+	}
+	if last := fake.auths[len(fake.auths)-1]; !strings.Contains(last, "Credential=rotated-access/") {
+		t.Fatalf("request after rotation signed with %q", last)
+	}
+}
+
+func TestS3UploadChecksums(t *testing.T) {
+	for _, tc := range []struct {
+		backend  string
+		validate bool
+		want     bool
+	}{
+		{config.S3BackendAWS, true, true},
+		{config.S3BackendAWS, false, false},
+		{config.S3BackendGeneric, true, false},
+	} {
+		fake, srv := newFakeS3(t)
+		s := newTestS3Storage(t, srv.URL, func(cfg *config.Config) {
+			cfg.S3Backend = tc.backend
+			cfg.S3ChecksumValidation = tc.validate
+		})
+
+		key := BlobKey("library/nginx", "sha256:abc")
+		if err := s.Put(context.Background(), key, []byte("layer"), "sha256:abc", "application/octet-stream", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if got := fake.checksums[0] != ""; got != tc.want {
+			t.Errorf("backend %s with validation %v: checksum sent = %v, want %v", tc.backend, tc.validate, got, tc.want)
+		}
+	}
+}
+
+func TestS3RejectsCorruptDownload(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	s := newTestS3Storage(t, srv.URL, nil)
+
+	key := BlobKey("library/nginx", "sha256:abc")
+	if err := s.Put(context.Background(), key, []byte("layer"), "sha256:abc", "application/octet-stream", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if content, _, _, err := s.Get(context.Background(), key); err != nil || string(content) != "layer" {
+		t.Fatalf("unexpected read %q: %v", content, err)
+	}
+
+	fake.mu.Lock()
+	fake.badChecksum = true
+	fake.mu.Unlock()
+	if _, _, _, err := s.Get(context.Background(), key); err == nil {
+		t.Fatal("expected checksum mismatch to fail the read")
+	}
+}
+
+func TestS3CompressesBlobsWithZstd(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	s := newTestS3Storage(t, srv.URL, func(cfg *config.Config) {
+		cfg.StorageCompression = config.CompressionZstd
+		cfg.StorageCompressionLevel = 3
+	})
+
+	layer := []byte(strings.Repeat("uncompressed layer content ", 1000))
+	key := BlobKey("library/nginx", "sha256:abc")
+	if err := s.Put(context.Background(), key, layer, "sha256:abc", "application/octet-stream", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.objects) != 1 {
+		t.Fatalf("expected one stored object, got %d", len(fake.objects))
+	}
+	for path, stored := range fake.objects {
+		if !bytes.HasPrefix(stored, []byte{0x28, 0xb5, 0x2f, 0xfd}) || len(stored) >= len(layer) {
+			t.Fatalf("%s: expected a smaller zstd frame, got %d bytes", path, len(stored))
+		}
+	}
+	content, _, _, err := s.Get(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, layer) {
+		t.Fatal("decompressed blob differs from the original")
+	}
+}
@@ -6,10 +6,36 @@ import (
 	"time"
 )
 
+type ObjectInfo struct {
+	Digest    string
+	MediaType string
+	Size      int64
+	StoredAt  time.Time
+	ExpiresAt time.Time
+	Headers   string
+}
+
 type Storage interface {
 	Get(ctx context.Context, key string) ([]byte, string, string, error)
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
 	Put(ctx context.Context, key string, content []byte, digest, mediaType string, ttl time.Duration) error
 	PutStream(ctx context.Context, key string, content io.Reader, digest, mediaType string, ttl time.Duration) error
 	Delete(ctx context.Context, key string) error
 	UpdateLastAccess(ctx context.Context, key string) error
 }
+
+type ErrorCounter interface {
+	ErrorCount() uint64
+}
+
+type HeaderStore interface {
+	StoreHeaders(ctx context.Context, key, headers string) error
+}
+
+type Forgetter interface {
+	Forget(key string)
+}
+
+type BatchDeleter interface {
+	DeleteBatch(ctx context.Context, keys []string) (int, []string, error)
+}
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type TieredStorage struct {
+	local  *DiskStorage
+	remote Storage
+	cfg    *config.Config
+	log    *logrus.Entry
+
+	// validated holds when each disk entry was last confirmed against the
+	// remote, so hits within DiskCacheRevalidateInterval skip the Stat.
+	validated sync.Map
+
+	accessMu sync.Mutex
+	accessed map[string]struct{}
+}
+
+func NewTieredStorage(logger *logrus.Logger, cfg *config.Config, local *DiskStorage, remote Storage) *TieredStorage {
+	return &TieredStorage{
+		local:    local,
+		remote:   remote,
+		cfg:      cfg,
+		log:      logger.WithField("component", "tiered_storage"),
+		accessed: make(map[string]struct{}),
+	}
+}
+
+func (t *TieredStorage) Get(ctx context.Context, key string) ([]byte, string, string, error) {
+	log := t.log.WithFields(logrus.Fields{
+		"operation": "get",
+		"key":       key,
+	})
+
+	if content, digest, mediaType, err := t.local.Get(ctx, key); err == nil && t.current(ctx, key, digest, log) {
+		log.Debug("Disk cache hit")
+		t.touch(key)
+		return content, digest, mediaType, nil
+	}
+
+	content, digest, mediaType, err := t.remote.Get(ctx, key)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if int64(len(content)) <= t.local.maxObjectSize {
+		if err := t.local.Put(ctx, key, content, digest, mediaType, t.localTTL(key)); err != nil {
+			log.WithError(err).Warn("Failed to populate disk cache")
+		}
+	}
+	return content, digest, mediaType, nil
+}
+
+func (t *TieredStorage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := t.remote.Stat(ctx, key)
+	if err != nil {
+		t.evict(ctx, key)
+	}
+	return info, err
+}
+
+func (t *TieredStorage) current(ctx context.Context, key, digest string, log *logrus.Entry) bool {
+	if checked, ok := t.validated.Load(key); ok && time.Since(checked.(time.Time)) < t.cfg.DiskCacheRevalidateInterval {
+		return true
+	}
+	info, err := t.remote.Stat(ctx, key)
+	if err == nil && (info.Digest == "" || info.Digest == digest) {
+		t.validated.Store(key, time.Now())
+		return true
+	}
+	log.Debug("Disk cache entry no longer current, evicting")
+	t.evict(ctx, key)
+	return false
+}
+
+func (t *TieredStorage) evict(ctx context.Context, key string) {
+	t.validated.Delete(key)
+	if err := t.local.Delete(ctx, key); err != nil {
+		t.log.WithFields(logrus.Fields{"key": key, "error": err}).Warn("Failed to delete disk cache entry")
+	}
+}
+
+func (t *TieredStorage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	if info, err := t.local.Stat(ctx, key); err == nil && t.current(ctx, key, info.Digest, t.log.WithField("key", key)) {
+		if body, err := t.local.GetRange(ctx, key, offset, length); err == nil {
+			return body, nil
+		}
+	}
+
+	ranger, ok := t.remote.(RangeGetter)
+	if !ok {
+		return nil, fmt.Errorf("ranged reads not supported by remote storage")
+	}
+	return ranger.GetRange(ctx, key, offset, length)
+}
+
+func (t *TieredStorage) GetStream(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	streamer, ok := t.remote.(Streamer)
+	if !ok {
+		content, digest, mediaType, err := t.Get(ctx, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		return io.NopCloser(bytes.NewReader(content)), &ObjectInfo{Digest: digest, MediaType: mediaType, Size: int64(len(content))}, nil
+	}
+
+	log := t.log.WithField("key", key)
+	if body, info, err := t.local.GetStream(ctx, key); err == nil {
+		if t.current(ctx, key, info.Digest, log) {
+			log.Debug("Disk cache hit")
+			t.touch(key)
+			return body, info, nil
+		}
+		body.Close()
+	}
+
+	body, info, err := streamer.GetStream(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size < 0 || info.Size > t.local.maxObjectSize {
+		return body, info, nil
+	}
+	return t.local.fill(body, key, info, t.localTTL(key), log), info, nil
+}
+
+func (t *TieredStorage) Put(ctx context.Context, key string, content []byte, digest, mediaType string, ttl time.Duration) error {
+	if err := t.remote.Put(ctx, key, content, digest, mediaType, ttl); err != nil {
+		return err
+	}
+	if int64(len(content)) <= t.local.maxObjectSize {
+		if err := t.local.Put(ctx, key, content, digest, mediaType, ttl); err != nil {
+			t.log.WithFields(logrus.Fields{"key": key, "error": err}).Warn("Failed to write disk cache")
+		}
+	}
+	return nil
+}
+
+func (t *TieredStorage) PutStream(ctx context.Context, key string, content io.Reader, digest, mediaType string, ttl time.Duration) error {
+	if err := t.remote.PutStream(ctx, key, content, digest, mediaType, ttl); err != nil {
+		return err
+	}
+
+	seeker, ok := content.(io.ReadSeeker)
+	if !ok {
+		return nil
+	}
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return nil
+	}
+	if size := readerSize(seeker); size < 0 || size > t.local.maxObjectSize {
+		return nil
+	}
+	if err := t.local.PutStream(ctx, key, seeker, digest, mediaType, ttl); err != nil {
+		t.log.WithFields(logrus.Fields{"key": key, "error": err}).Warn("Failed to write disk cache")
+	}
+	return nil
+}
+
+func (t *TieredStorage) Delete(ctx context.Context, key string) error {
+	t.evict(ctx, key)
+	return t.remote.Delete(ctx, key)
+}
+
+// Forget drops the disk copy of a key invalidated on another node, so the
+// next read goes to the remote instead of trusting a recent revalidation.
+func (t *TieredStorage) Forget(key string) {
+	t.evict(context.Background(), key)
+}
+
+func (t *TieredStorage) StoreHeaders(ctx context.Context, key, headers string) error {
+	store, ok := t.remote.(HeaderStore)
+	if !ok {
+		return fmt.Errorf("remote storage does not keep headers")
+	}
+	if err := store.StoreHeaders(ctx, key, headers); err != nil {
+		return err
+	}
+	t.local.StoreHeaders(ctx, key, headers)
+	return nil
+}
+
+func (t *TieredStorage) touch(key string) {
+	t.accessMu.Lock()
+	t.accessed[key] = struct{}{}
+	t.accessMu.Unlock()
+}
+
+// FlushLastAccess writes the access times of disk hits back to the remote
+// index once per interval instead of once per hit.
+func (t *TieredStorage) FlushLastAccess(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			t.flushLastAccess(context.Background())
+			return
+		case <-ticker.C:
+			t.flushLastAccess(ctx)
+		}
+	}
+}
+
+func (t *TieredStorage) flushLastAccess(ctx context.Context) {
+	t.accessMu.Lock()
+	keys := t.accessed
+	t.accessed = make(map[string]struct{}, len(keys))
+	t.accessMu.Unlock()
+
+	for key := range keys {
+		if err := t.remote.UpdateLastAccess(ctx, key); err != nil {
+			t.log.WithFields(logrus.Fields{"key": key, "error": err}).Warn("Failed to update last access time")
+		}
+	}
+}
+
+func (t *TieredStorage) UpdateLastAccess(ctx context.Context, key string) error {
+	t.local.UpdateLastAccess(ctx, key)
+	return t.remote.UpdateLastAccess(ctx, key)
+}
+
+func (t *TieredStorage) localTTL(key string) time.Duration {
+	if isManifestClass(ParseKey(key).Class) {
+		return t.cfg.ManifestCacheTTL
+	}
+	return t.cfg.BlobCacheTTL
+}
+
+func (t *TieredStorage) DeleteBatch(ctx context.Context, keys []string) (int, []string, error) {
+	for _, key := range keys {
+		t.evict(ctx, key)
+	}
+
+	if batch, ok := t.remote.(BatchDeleter); ok {
+		return batch.DeleteBatch(ctx, keys)
+	}
+
+	var failed []string
+	for _, key := range keys {
+		if err := t.remote.Delete(ctx, key); err != nil {
+			failed = append(failed, key)
+		}
+	}
+	return len(keys) - len(failed), failed, nil
+}
+
+func (t *TieredStorage) Reindex(ctx context.Context) (*ReindexResult, error) {
+	reindexer, ok := t.remote.(Reindexer)
+	if !ok {
+		return nil, fmt.Errorf("remote storage does not support reindexing")
+	}
+	return reindexer.Reindex(ctx)
+}
+
+func (t *TieredStorage) ErrorCount() uint64 {
+	if counter, ok := t.remote.(ErrorCounter); ok {
+		return counter.ErrorCount()
+	}
+	return 0
+}
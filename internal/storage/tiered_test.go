@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type mapObject struct {
+	content   []byte
+	digest    string
+	mediaType string
+}
+
+type mapStorage struct {
+	mu       sync.Mutex
+	objects  map[string]mapObject
+	stats    int
+	accesses int
+}
+
+func newMapStorage() *mapStorage {
+	return &mapStorage{objects: make(map[string]mapObject)}
+}
+
+func (m *mapStorage) Get(_ context.Context, key string) ([]byte, string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	object, ok := m.objects[key]
+	if !ok {
+		return nil, "", "", fmt.Errorf("cache miss")
+	}
+	return object.content, object.digest, object.mediaType, nil
+}
+
+func (m *mapStorage) Stat(_ context.Context, key string) (*ObjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats++
+	object, ok := m.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("cache miss")
+	}
+	return &ObjectInfo{Digest: object.digest, MediaType: object.mediaType, Size: int64(len(object.content))}, nil
+}
+
+func (m *mapStorage) Put(_ context.Context, key string, content []byte, digest, mediaType string, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = mapObject{content: content, digest: digest, mediaType: mediaType}
+	return nil
+}
+
+func (m *mapStorage) PutStream(ctx context.Context, key string, content io.Reader, digest, mediaType string, ttl time.Duration) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	return m.Put(ctx, key, data, digest, mediaType, ttl)
+}
+
+func (m *mapStorage) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *mapStorage) UpdateLastAccess(context.Context, string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accesses++
+	return nil
+}
+
+func newTestTiered(t *testing.T) (*TieredStorage, *DiskStorage, *mapStorage) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	local, err := NewDiskStorage(logger, t.TempDir(), 1<<20, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := newMapStorage()
+	cfg := &config.Config{ManifestCacheTTL: time.Hour, BlobCacheTTL: time.Hour, DiskCacheRevalidateInterval: time.Minute}
+	return NewTieredStorage(logger, cfg, local, remote), local, remote
+}
+
+func TestTieredStorageEvictsInvalidatedLocalCopy(t *testing.T) {
+	ctx := context.Background()
+	tiered, local, remote := newTestTiered(t)
+	key := ManifestKey("library/alpine", "latest")
+
+	if err := tiered.Put(ctx, key, []byte("v1"), "sha256:v1", "application/json", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	remote.Delete(ctx, key)
+
+	if _, _, _, err := tiered.Get(ctx, key); err == nil {
+		t.Fatal("Get served a disk copy of an invalidated entry")
+	}
+	if _, err := tiered.Stat(ctx, key); err == nil {
+		t.Fatal("Stat reported an invalidated entry")
+	}
+	if _, _, err := tiered.GetStream(ctx, key); err == nil {
+		t.Fatal("GetStream served a disk copy of an invalidated entry")
+	}
+	if _, err := local.Stat(ctx, key); err == nil {
+		t.Fatal("disk copy was not evicted")
+	}
+}
+
+func TestTieredStorageEvictsReplacedLocalCopy(t *testing.T) {
+	ctx := context.Background()
+	tiered, _, remote := newTestTiered(t)
+	key := ManifestKey("library/alpine", "latest")
+
+	if err := tiered.Put(ctx, key, []byte("v1"), "sha256:v1", "application/json", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	remote.Put(ctx, key, []byte("v2"), "sha256:v2", "application/json", time.Hour)
+
+	content, digest, _, err := tiered.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "v2" || digest != "sha256:v2" {
+		t.Fatalf("got %q (%s), want the current remote copy", content, digest)
+	}
+
+	body, err := tiered.GetRange(ctx, key, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); string(data) != "v2" {
+		t.Fatalf("GetRange served %q, want v2", data)
+	}
+}
+
+func TestTieredStorageServesCurrentLocalCopy(t *testing.T) {
+	ctx := context.Background()
+	tiered, local, remote := newTestTiered(t)
+	key := BlobKey("library/alpine", "sha256:abc")
+
+	if err := tiered.Put(ctx, key, []byte("layer"), "sha256:abc", "application/octet-stream", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	remote.mu.Lock()
+	remote.objects[key] = mapObject{content: []byte("remote"), digest: "sha256:abc"}
+	remote.mu.Unlock()
+
+	content, _, _, err := tiered.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "layer" {
+		t.Fatalf("got %q, want the disk copy", content)
+	}
+	if _, err := local.Stat(ctx, key); err != nil {
+		t.Fatal("current disk copy was evicted")
+	}
+}
+
+func TestTieredStorageRevalidatesOncePerInterval(t *testing.T) {
+	ctx := context.Background()
+	tiered, local, remote := newTestTiered(t)
+	key := ManifestKey("library/alpine", "latest")
+
+	if err := tiered.Put(ctx, key, []byte("v1"), "sha256:v1", "application/json", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, _, _, err := tiered.Get(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if remote.stats != 1 || remote.accesses != 0 {
+		t.Fatalf("three disk hits made %d remote checks and %d access updates, want 1 and 0", remote.stats, remote.accesses)
+	}
+	tiered.flushLastAccess(ctx)
+	if remote.accesses != 1 {
+		t.Fatalf("flush made %d access updates, want 1", remote.accesses)
+	}
+
+	remote.Put(ctx, key, []byte("v2"), "sha256:v2", "application/json", time.Hour)
+	tiered.Forget(key)
+	if _, err := local.Stat(ctx, key); err == nil {
+		t.Fatal("Forget kept the disk copy")
+	}
+	content, _, _, err := tiered.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "v2" {
+		t.Fatalf("got %q after invalidation, want v2", content)
+	}
+}
+
+type streamingMapStorage struct {
+	*mapStorage
+}
+
+func (m streamingMapStorage) GetStream(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	content, digest, mediaType, err := m.Get(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), &ObjectInfo{Digest: digest, MediaType: mediaType, Size: int64(len(content))}, nil
+}
+
+func TestTieredStorageStreamsIntoDiskCache(t *testing.T) {
+	ctx := context.Background()
+	tiered, local, remote := newTestTiered(t)
+	tiered.remote = streamingMapStorage{remote}
+	key := BlobKey("library/alpine", "sha256:abc")
+	remote.Put(ctx, key, []byte("layer"), "sha256:abc", "application/octet-stream", time.Hour)
+
+	body, _, err := tiered.GetStream(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 2)
+	io.ReadFull(body, buf)
+	body.Close()
+	if _, err := local.Stat(ctx, key); err == nil {
+		t.Fatal("partially read stream populated the disk cache")
+	}
+
+	body, _, err = tiered.GetStream(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(body); string(data) != "layer" {
+		t.Fatalf("remote stream served %q", data)
+	}
+	body.Close()
+
+	body, info, err := tiered.GetStream(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if _, ok := body.(*os.File); !ok {
+		t.Fatalf("disk hit served %T, want the cached file", body)
+	}
+	if data, _ := io.ReadAll(body); string(data) != "layer" || info.Digest != "sha256:abc" {
+		t.Fatalf("disk hit served %q (%s)", data, info.Digest)
+	}
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/sdko-org/registry-proxy/internal/config"
+)
+
+type bucketTarget struct {
+	name         string
+	client       *s3.Client
+	uploader     *manager.Uploader
+	storageClass string
+}
+
+func newBucketTarget(client *s3.Client, cfg *config.Config, name, storageClass string) *bucketTarget {
+	return &bucketTarget{
+		name:   name,
+		client: client,
+		uploader: manager.NewUploader(client, func(u *manager.Uploader) {
+			u.PartSize = cfg.S3PartSize
+			u.Concurrency = cfg.S3UploadConcurrency
+			u.LeavePartsOnError = false
+		}),
+		storageClass: storageClass,
+	}
+}
+
+func (s *S3Storage) targetFor(key, mediaType string, size int64) *bucketTarget {
+	if s.large == nil || isManifestClass(ParseKey(key).Class) {
+		return s.primary
+	}
+	for _, smallType := range s.cfg.S3TierSmallMediaTypes {
+		if mediaType == smallType {
+			return s.primary
+		}
+	}
+	if size >= 0 && size < s.cfg.S3TierSizeThreshold {
+		return s.primary
+	}
+	return s.large
+}
+
+func (s *S3Storage) bucket(name string) *bucketTarget {
+	if s.large != nil && name == s.large.name {
+		return s.large
+	}
+	return s.primary
+}
+
+func (s *S3Storage) partSizeFor(size int64) func(*manager.Uploader) {
+	return func(u *manager.Uploader) {
+		if parts := int64(manager.MaxUploadParts); size > s.partSize*parts {
+			u.PartSize = (size + parts - 1) / parts
+		}
+	}
+}
+
+func readerSize(r io.Reader) int64 {
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return -1
+	}
+	current, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1
+	}
+	if _, err := seeker.Seek(current, io.SeekStart); err != nil {
+		return -1
+	}
+	return end - current
+}
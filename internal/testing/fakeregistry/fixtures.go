@@ -0,0 +1,170 @@
+package fakeregistry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"sort"
+)
+
+const (
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeDockerConfig   = "application/vnd.docker.container.image.v1+json"
+	MediaTypeDockerLayer    = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+	MediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+)
+
+type Descriptor struct {
+	MediaType    string    `json:"mediaType"`
+	Digest       string    `json:"digest"`
+	Size         int64     `json:"size"`
+	ArtifactType string    `json:"artifactType,omitempty"`
+	Platform     *Platform `json:"platform,omitempty"`
+}
+
+type Platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+type Image struct {
+	Digest   string
+	Manifest []byte
+	Config   Descriptor
+	Layers   []Descriptor
+}
+
+// Layer builds a gzipped tar layer holding the given files.
+func Layer(files map[string]string) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(files[name])), Typeflag: tar.TypeReg})
+		tw.Write([]byte(files[name]))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// AddImage stores a linux/amd64 schema2 image made of the given layers (see
+// Layer) and tags it.
+func (r *Registry) AddImage(repository, tag string, layers ...[]byte) Image {
+	return r.AddPlatformImage(repository, tag, Platform{OS: "linux", Architecture: "amd64"}, layers...)
+}
+
+func (r *Registry) AddPlatformImage(repository, tag string, platform Platform, layers ...[]byte) Image {
+	image := Image{}
+	diffIDs := []string{}
+	for _, content := range layers {
+		diffIDs = append(diffIDs, Digest(gunzip(content)))
+		image.Layers = append(image.Layers, Descriptor{
+			MediaType: MediaTypeDockerLayer,
+			Digest:    r.AddBlob(content),
+			Size:      int64(len(content)),
+		})
+	}
+
+	config, _ := json.Marshal(map[string]any{
+		"architecture": platform.Architecture,
+		"os":           platform.OS,
+		"variant":      platform.Variant,
+		"config":       map[string]any{},
+		"rootfs":       map[string]any{"type": "layers", "diff_ids": diffIDs},
+	})
+	image.Config = Descriptor{MediaType: MediaTypeDockerConfig, Digest: r.AddBlob(config), Size: int64(len(config))}
+
+	image.Manifest, _ = json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     MediaTypeDockerManifest,
+		"config":        image.Config,
+		"layers":        image.Layers,
+	})
+	image.Digest = r.AddManifest(repository, tag, MediaTypeDockerManifest, image.Manifest)
+	return image
+}
+
+// AddIndex stores a manifest list referencing already added images, one per
+// platform, and tags it.
+func (r *Registry) AddIndex(repository, tag string, platforms map[Platform]Image) string {
+	keys := make([]Platform, 0, len(platforms))
+	for platform := range platforms {
+		keys = append(keys, platform)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Architecture+keys[i].Variant < keys[j].Architecture+keys[j].Variant
+	})
+
+	manifests := []Descriptor{}
+	for _, platform := range keys {
+		image := platforms[platform]
+		platform := platform
+		manifests = append(manifests, Descriptor{
+			MediaType: MediaTypeDockerManifest,
+			Digest:    image.Digest,
+			Size:      int64(len(image.Manifest)),
+			Platform:  &platform,
+		})
+	}
+	index, _ := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     MediaTypeDockerList,
+		"manifests":     manifests,
+	})
+	return r.AddManifest(repository, tag, MediaTypeDockerList, index)
+}
+
+// AddReferrer stores an OCI artifact manifest of the given artifact type whose
+// subject is an already added manifest, and lists it in the subject's
+// referrers index.
+func (r *Registry) AddReferrer(repository, subject, artifactType string, content []byte) string {
+	r.mu.Lock()
+	target := r.manifests[repository][subject]
+	r.mu.Unlock()
+
+	layer := Descriptor{MediaType: artifactType, Digest: r.AddBlob(content), Size: int64(len(content))}
+	empty := []byte("{}")
+	manifest, _ := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     MediaTypeOCIManifest,
+		"artifactType":  artifactType,
+		"config":        Descriptor{MediaType: "application/vnd.oci.empty.v1+json", Digest: r.AddBlob(empty), Size: int64(len(empty))},
+		"layers":        []Descriptor{layer},
+		"subject":       Descriptor{MediaType: target.mediaType, Digest: subject, Size: int64(len(target.content))},
+	})
+	digest := r.AddManifest(repository, "", MediaTypeOCIManifest, manifest)
+
+	r.mu.Lock()
+	if r.referrers[repository] == nil {
+		r.referrers[repository] = make(map[string][]Descriptor)
+	}
+	r.referrers[repository][subject] = append(r.referrers[repository][subject], Descriptor{
+		MediaType:    MediaTypeOCIManifest,
+		Digest:       digest,
+		Size:         int64(len(manifest)),
+		ArtifactType: artifactType,
+	})
+	r.mu.Unlock()
+	return digest
+}
+
+func gunzip(content []byte) []byte {
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return content
+	}
+	var buf bytes.Buffer
+	buf.ReadFrom(gz)
+	return buf.Bytes()
+}
@@ -0,0 +1,373 @@
+package fakeregistry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Options struct {
+	TokenAuth bool
+	Username  string
+	Password  string
+	TokenTTL  time.Duration
+
+	// Manifest GETs allowed per RateLimitWindow before answering 429, as
+	// Docker Hub does for anonymous pulls. 0 disables rate limiting.
+	RateLimit       int
+	RateLimitWindow time.Duration
+}
+
+type manifest struct {
+	mediaType string
+	content   []byte
+	digest    string
+}
+
+type Registry struct {
+	opts   Options
+	server *httptest.Server
+
+	mu          sync.Mutex
+	manifests   map[string]map[string]manifest
+	referrers   map[string]map[string][]Descriptor
+	blobs       map[string][]byte
+	tokens      map[string]time.Time
+	pulls       []time.Time
+	failures    []int
+	requests    map[string]int
+	tokenIssued int
+}
+
+func New(opts Options) *Registry {
+	if opts.TokenTTL <= 0 {
+		opts.TokenTTL = 5 * time.Minute
+	}
+	if opts.RateLimitWindow <= 0 {
+		opts.RateLimitWindow = 6 * time.Hour
+	}
+	r := &Registry{
+		opts:      opts,
+		manifests: make(map[string]map[string]manifest),
+		referrers: make(map[string]map[string][]Descriptor),
+		blobs:     make(map[string][]byte),
+		tokens:    make(map[string]time.Time),
+		requests:  make(map[string]int),
+	}
+	r.server = httptest.NewTLSServer(http.HandlerFunc(r.serveHTTP))
+	return r
+}
+
+func (r *Registry) Close() {
+	r.server.Close()
+}
+
+func (r *Registry) Host() string {
+	return strings.TrimPrefix(r.server.URL, "https://")
+}
+
+func (r *Registry) URL() string {
+	return r.server.URL
+}
+
+func (r *Registry) Client() *http.Client {
+	return r.server.Client()
+}
+
+func (r *Registry) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(r.server.Certificate())
+	return pool
+}
+
+func (r *Registry) AddBlob(content []byte) string {
+	digest := Digest(content)
+	r.mu.Lock()
+	r.blobs[digest] = content
+	r.mu.Unlock()
+	return digest
+}
+
+// AddManifest stores content under its digest and, when tag is non-empty,
+// under the tag as well.
+func (r *Registry) AddManifest(repository, tag, mediaType string, content []byte) string {
+	entry := manifest{mediaType: mediaType, content: content, digest: Digest(content)}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	refs := r.manifests[repository]
+	if refs == nil {
+		refs = make(map[string]manifest)
+		r.manifests[repository] = refs
+	}
+	refs[entry.digest] = entry
+	if tag != "" {
+		refs[tag] = entry
+	}
+	return entry.digest
+}
+
+func (r *Registry) DeleteTag(repository, tag string) {
+	r.mu.Lock()
+	delete(r.manifests[repository], tag)
+	r.mu.Unlock()
+}
+
+// FailNext makes the next len(statuses) registry requests answer with the
+// given statuses, in order.
+func (r *Registry) FailNext(statuses ...int) {
+	r.mu.Lock()
+	r.failures = append(r.failures, statuses...)
+	r.mu.Unlock()
+}
+
+// Requests returns how many requests were served for a kind: "manifests",
+// "blobs", "referrers", "tags" or "token".
+func (r *Registry) Requests(kind string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests[kind]
+}
+
+func (r *Registry) TokensIssued() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tokenIssued
+}
+
+func (r *Registry) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		r.serveToken(w, req)
+		return
+	}
+	if req.URL.Path != "/v2/" && !strings.HasPrefix(req.URL.Path, "/v2/") {
+		http.NotFound(w, req)
+		return
+	}
+	if !r.authorized(req) {
+		realm := r.server.URL + "/token"
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s",service="fakeregistry"`, realm))
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "authentication required")
+		return
+	}
+	if req.URL.Path == "/v2/" {
+		w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	if repository, ok := strings.CutSuffix(path, "/tags/list"); ok {
+		r.count("tags")
+		if r.injectFailure(w) {
+			return
+		}
+		r.serveTags(w, repository)
+		return
+	}
+	if i := strings.LastIndex(path, "/referrers/"); i > 0 {
+		r.count("referrers")
+		if r.injectFailure(w) {
+			return
+		}
+		r.serveReferrers(w, path[:i], path[i+len("/referrers/"):])
+		return
+	}
+	for _, kind := range []string{"manifests", "blobs"} {
+		if i := strings.LastIndex(path, "/"+kind+"/"); i > 0 {
+			repository, reference := path[:i], path[i+len(kind)+2:]
+			r.count(kind)
+			if r.injectFailure(w) {
+				return
+			}
+			if kind == "manifests" {
+				r.serveManifest(w, req, repository, reference)
+			} else {
+				r.serveBlob(w, req, reference)
+			}
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "NAME_UNKNOWN", "repository name not known to registry")
+}
+
+func (r *Registry) serveToken(w http.ResponseWriter, req *http.Request) {
+	r.count("token")
+	if r.opts.Username != "" {
+		user, password, ok := req.BasicAuth()
+		if !ok || user != r.opts.Username || password != r.opts.Password {
+			writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid credentials")
+			return
+		}
+	}
+
+	var raw [16]byte
+	rand.Read(raw[:])
+	token := hex.EncodeToString(raw[:])
+	r.mu.Lock()
+	r.tokens[token] = time.Now().Add(r.opts.TokenTTL)
+	r.tokenIssued++
+	r.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"token":      token,
+		"expires_in": int(r.opts.TokenTTL.Seconds()),
+		"issued_at":  time.Now().UTC(),
+	})
+}
+
+func (r *Registry) authorized(req *http.Request) bool {
+	if !r.opts.TokenAuth {
+		return true
+	}
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	expires, ok := r.tokens[token]
+	return ok && time.Now().Before(expires)
+}
+
+func (r *Registry) serveManifest(w http.ResponseWriter, req *http.Request, repository, reference string) {
+	r.mu.Lock()
+	entry, ok := r.manifests[repository][reference]
+	r.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest unknown")
+		return
+	}
+	if req.Method == http.MethodGet && !r.allowPull(w) {
+		writeError(w, http.StatusTooManyRequests, "TOOMANYREQUESTS", "You have reached your pull rate limit.")
+		return
+	}
+
+	w.Header().Set("Content-Type", entry.mediaType)
+	w.Header().Set("Docker-Content-Digest", entry.digest)
+	w.Header().Set("Etag", `"`+entry.digest+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(entry.content)))
+	if match := req.Header.Get("If-None-Match"); match != "" && strings.Contains(match, entry.digest) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodGet {
+		w.Write(entry.content)
+	}
+}
+
+func (r *Registry) serveBlob(w http.ResponseWriter, req *http.Request, digest string) {
+	r.mu.Lock()
+	content, ok := r.blobs[digest]
+	r.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "BLOB_UNKNOWN", "blob unknown to registry")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", digest)
+	w.Header().Set("Etag", `"`+digest+`"`)
+	http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(content))
+}
+
+func (r *Registry) serveReferrers(w http.ResponseWriter, repository, digest string) {
+	r.mu.Lock()
+	manifests := append([]Descriptor{}, r.referrers[repository][digest]...)
+	r.mu.Unlock()
+	w.Header().Set("Content-Type", MediaTypeOCIIndex)
+	json.NewEncoder(w).Encode(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     MediaTypeOCIIndex,
+		"manifests":     manifests,
+	})
+}
+
+func (r *Registry) serveTags(w http.ResponseWriter, repository string) {
+	r.mu.Lock()
+	refs, ok := r.manifests[repository]
+	tags := []string{}
+	for ref := range refs {
+		if !strings.HasPrefix(ref, "sha256:") {
+			tags = append(tags, ref)
+		}
+	}
+	r.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "NAME_UNKNOWN", "repository name not known to registry")
+		return
+	}
+	sort.Strings(tags)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"name": repository, "tags": tags})
+}
+
+func (r *Registry) allowPull(w http.ResponseWriter) bool {
+	if r.opts.RateLimit <= 0 {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cutoff := time.Now().Add(-r.opts.RateLimitWindow)
+	recent := r.pulls[:0]
+	for _, pulled := range r.pulls {
+		if pulled.After(cutoff) {
+			recent = append(recent, pulled)
+		}
+	}
+	r.pulls = recent
+
+	window := int(r.opts.RateLimitWindow.Seconds())
+	w.Header().Set("RateLimit-Limit", fmt.Sprintf("%d;w=%d", r.opts.RateLimit, window))
+	if len(r.pulls) >= r.opts.RateLimit {
+		w.Header().Set("RateLimit-Remaining", fmt.Sprintf("0;w=%d", window))
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(r.pulls[0].Add(r.opts.RateLimitWindow)).Seconds())+1))
+		return false
+	}
+	r.pulls = append(r.pulls, time.Now())
+	w.Header().Set("RateLimit-Remaining", fmt.Sprintf("%d;w=%d", r.opts.RateLimit-len(r.pulls), window))
+	return true
+}
+
+func (r *Registry) injectFailure(w http.ResponseWriter) bool {
+	r.mu.Lock()
+	if len(r.failures) == 0 {
+		r.mu.Unlock()
+		return false
+	}
+	status := r.failures[0]
+	r.failures = r.failures[1:]
+	r.mu.Unlock()
+	writeError(w, status, "UNKNOWN", http.StatusText(status))
+	return true
+}
+
+func (r *Registry) count(kind string) {
+	r.mu.Lock()
+	r.requests[kind]++
+	r.mu.Unlock()
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"errors": []map[string]string{{"code": code, "message": message}},
+	})
+}
+
+func Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
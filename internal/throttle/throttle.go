@@ -0,0 +1,229 @@
+package throttle
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"golang.org/x/time/rate"
+)
+
+const (
+	maxBurst          = 1024 * 1024
+	clientIdleTimeout = 10 * time.Minute
+)
+
+type Limiter struct {
+	name      string
+	rate      int64
+	limiter   *rate.Limiter
+	bytes     atomic.Int64
+	waitNanos atomic.Int64
+	lastSeen  atomic.Int64
+}
+
+type LimiterStats struct {
+	Name           string  `json:"name"`
+	BytesPerSecond int64   `json:"bytes_per_second"`
+	Bytes          int64   `json:"bytes"`
+	ThrottledFor   string  `json:"throttled_for"`
+	ThrottledRatio float64 `json:"throttled_ratio"`
+}
+
+func newLimiter(name string, bytesPerSecond int64) *Limiter {
+	burst := bytesPerSecond
+	if burst > maxBurst {
+		burst = maxBurst
+	}
+	l := &Limiter{
+		name:    name,
+		rate:    bytesPerSecond,
+		limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), int(burst)),
+	}
+	l.lastSeen.Store(time.Now().UnixNano())
+	return l
+}
+
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	l.bytes.Add(int64(n))
+	l.lastSeen.Store(time.Now().UnixNano())
+
+	start := time.Now()
+	defer func() {
+		l.waitNanos.Add(int64(time.Since(start)))
+	}()
+	for n > 0 {
+		chunk := n
+		if burst := l.limiter.Burst(); chunk > burst {
+			chunk = burst
+		}
+		if err := l.limiter.WaitN(ctx, chunk); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+func (l *Limiter) stats(since time.Time) LimiterStats {
+	waited := time.Duration(l.waitNanos.Load())
+	stats := LimiterStats{
+		Name:           l.name,
+		BytesPerSecond: l.rate,
+		Bytes:          l.bytes.Load(),
+		ThrottledFor:   waited.Round(time.Millisecond).String(),
+	}
+	if elapsed := time.Since(since); elapsed > 0 {
+		stats.ThrottledRatio = waited.Seconds() / elapsed.Seconds()
+	}
+	return stats
+}
+
+type Manager struct {
+	upstream     *Limiter
+	perClient    int64
+	rules        []config.BandwidthRule
+	repositories map[string]*Limiter
+	startedAt    time.Time
+
+	mu      sync.Mutex
+	clients map[string]*Limiter
+}
+
+type Stats struct {
+	Since        time.Time      `json:"since"`
+	Upstream     *LimiterStats  `json:"upstream,omitempty"`
+	PerClient    int64          `json:"per_client_bytes_per_second"`
+	Clients      []LimiterStats `json:"clients"`
+	Repositories []LimiterStats `json:"repositories"`
+}
+
+func NewManager(upstream, perClient int64, rules []config.BandwidthRule) *Manager {
+	m := &Manager{
+		perClient:    perClient,
+		rules:        rules,
+		repositories: make(map[string]*Limiter),
+		clients:      make(map[string]*Limiter),
+		startedAt:    time.Now().UTC(),
+	}
+	if upstream > 0 {
+		m.upstream = newLimiter("upstream", upstream)
+	}
+	for _, rule := range rules {
+		m.repositories[rule.Pattern] = newLimiter(rule.Pattern, rule.BytesPerSecond)
+	}
+	return m
+}
+
+func (m *Manager) Upstream() *Limiter {
+	return m.upstream
+}
+
+func (m *Manager) Client(ip string) *Limiter {
+	if m.perClient <= 0 {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.clients[ip]
+	if !ok {
+		m.evictIdleClients()
+		l = newLimiter(ip, m.perClient)
+		m.clients[ip] = l
+	}
+	return l
+}
+
+func (m *Manager) evictIdleClients() {
+	cutoff := time.Now().Add(-clientIdleTimeout).UnixNano()
+	for ip, l := range m.clients {
+		if l.lastSeen.Load() < cutoff {
+			delete(m.clients, ip)
+		}
+	}
+}
+
+func (m *Manager) Repository(repository string) *Limiter {
+	for _, rule := range m.rules {
+		if config.MatchPattern(rule.Pattern, repository) {
+			return m.repositories[rule.Pattern]
+		}
+	}
+	return nil
+}
+
+func (m *Manager) Stats() Stats {
+	stats := Stats{
+		Since:        m.startedAt,
+		PerClient:    m.perClient,
+		Clients:      []LimiterStats{},
+		Repositories: make([]LimiterStats, 0, len(m.rules)),
+	}
+	if m.upstream != nil {
+		upstream := m.upstream.stats(m.startedAt)
+		stats.Upstream = &upstream
+	}
+	for _, rule := range m.rules {
+		stats.Repositories = append(stats.Repositories, m.repositories[rule.Pattern].stats(m.startedAt))
+	}
+
+	m.mu.Lock()
+	for _, l := range m.clients {
+		stats.Clients = append(stats.Clients, l.stats(m.startedAt))
+	}
+	m.mu.Unlock()
+	return stats
+}
+
+type reader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*Limiter
+}
+
+func NewReader(ctx context.Context, r io.Reader, limiters ...*Limiter) io.Reader {
+	active := activeLimiters(limiters)
+	if len(active) == 0 {
+		return r
+	}
+	return &reader{ctx: ctx, r: r, limiters: active}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > maxBurst {
+		p = p[:maxBurst]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if waitErr := WaitAll(r.ctx, n, r.limiters...); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+func WaitAll(ctx context.Context, n int, limiters ...*Limiter) error {
+	for _, l := range limiters {
+		if err := l.Wait(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func activeLimiters(limiters []*Limiter) []*Limiter {
+	var active []*Limiter
+	for _, l := range limiters {
+		if l != nil {
+			active = append(active, l)
+		}
+	}
+	return active
+}
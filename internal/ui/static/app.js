@@ -0,0 +1,113 @@
+(function () {
+  "use strict";
+
+  function $(id) { return document.getElementById(id); }
+
+  function formatBytes(n) {
+    var units = ["B", "KB", "MB", "GB", "TB"];
+    var i = 0;
+    while (n >= 1024 && i < units.length - 1) { n /= 1024; i++; }
+    return n.toFixed(i === 0 ? 0 : 1) + " " + units[i];
+  }
+
+  function formatTime(value) {
+    var d = new Date(value);
+    return isNaN(d) ? "" : d.toLocaleString();
+  }
+
+  function row(cells, numeric) {
+    var tr = document.createElement("tr");
+    cells.forEach(function (text, i) {
+      var td = document.createElement("td");
+      td.textContent = text;
+      if (numeric && numeric.indexOf(i) >= 0) td.className = "num";
+      tr.appendChild(td);
+    });
+    return tr;
+  }
+
+  function fill(tbody, rows) {
+    tbody.replaceChildren.apply(tbody, rows);
+  }
+
+  function getJSON(url) {
+    return fetch(url, { headers: { Accept: "application/json" } }).then(function (resp) {
+      if (!resp.ok) throw new Error(url + ": " + resp.status);
+      return resp.json();
+    });
+  }
+
+  function errorRow(e) {
+    return row([formatTime(e.time), e.repository || "", String(e.status || ""), e.message || ""], [2]);
+  }
+
+  function refresh() {
+    getJSON("/admin/stats/summary").then(function (s) {
+      var events = s.events || {};
+      $("entries").textContent = s.entries.toLocaleString();
+      $("size").textContent = formatBytes(s.size_bytes);
+      $("hit-ratio").textContent = (s.hit_ratio * 100).toFixed(1) + "%";
+      $("hits").textContent = (events.cache_hit || 0).toLocaleString() + " / " + (events.cache_miss || 0).toLocaleString();
+      fill($("errors"), (s.recent_errors || []).map(errorRow));
+      $("status").textContent = "updated " + new Date().toLocaleTimeString();
+    }).catch(function (err) {
+      $("status").textContent = err.message;
+    });
+
+    getJSON("/admin/stats/top-images?limit=10").then(function (t) {
+      fill($("top-images"), (t.images || []).map(function (img) {
+        return row([img.repository, img.pulls.toLocaleString(), formatTime(img.last_pull)], [1]);
+      }));
+    }).catch(function () {});
+  }
+
+  function watchErrors() {
+    if (!window.EventSource) return;
+    var source = new EventSource("/admin/events?types=error");
+    source.addEventListener("error", function (msg) {
+      if (!msg.data) return;
+      var tbody = $("errors");
+      tbody.insertBefore(errorRow(JSON.parse(msg.data)), tbody.firstChild);
+      while (tbody.children.length > 50) tbody.removeChild(tbody.lastChild);
+    });
+  }
+
+  function submit(form, output, request) {
+    form.addEventListener("submit", function (ev) {
+      ev.preventDefault();
+      output.textContent = "…";
+      request(new FormData(form)).then(function (resp) {
+        return resp.text().then(function (body) {
+          try { body = JSON.stringify(JSON.parse(body), null, 2); } catch (e) { /* plain text */ }
+          output.textContent = resp.status + " " + resp.statusText + "\n" + body;
+          refresh();
+        });
+      }).catch(function (err) {
+        output.textContent = err.message;
+      });
+    });
+  }
+
+  submit($("invalidate-form"), $("invalidate-result"), function (data) {
+    var params = new URLSearchParams();
+    params.set("image", data.get("image"));
+    if (data.get("tag")) params.set("tag", data.get("tag"));
+    params.set("dry_run", data.get("dry_run") ? "true" : "false");
+    return fetch("/admin/cache/invalidate?" + params.toString(), { method: "POST" });
+  });
+
+  submit($("prewarm-form"), $("prewarm-result"), function (data) {
+    return fetch("/admin/jobs", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        type: "prewarm",
+        payload: { image: data.get("image"), reference: data.get("reference") }
+      })
+    });
+  });
+
+  refresh();
+  watchErrors();
+  setInterval(refresh, 10000);
+})();
@@ -0,0 +1,86 @@
+#!/bin/sh
+#
+# Pushes a Helm chart (helm push) and a WASM module (oras push) to a local
+# registry:2 upstream, pulls both through the proxy with helm pull and oras
+# pull, then stops the upstream and pulls again to check they are served from
+# cache. Exits non-zero on failure.
+#
+set -eu
+
+cd "$(dirname "$0")"
+PROXY="registry-proxy:8443/upstream"
+UPSTREAM="registry.test:5000"
+COMPOSE="docker compose -p registry-proxy-artifacts"
+
+cleanup() {
+	$COMPOSE logs registry-proxy > proxy.log 2>&1 || true
+	$COMPOSE --profile tools down -v > /dev/null 2>&1 || true
+	rm -rf work
+}
+trap cleanup EXIT
+
+fail() {
+	echo "FAIL: $*" >&2
+	exit 1
+}
+
+helm() {
+	$COMPOSE run --rm -T helm "$@"
+}
+
+oras() {
+	$COMPOSE run --rm -T oras "$@"
+}
+
+mkdir -p certs work
+if [ ! -f certs/upstream.crt ]; then
+	echo "Generating upstream certificate"
+	docker run --rm -v "$PWD/certs:/certs" docker.io/alpine/openssl req -x509 -newkey rsa:2048 -nodes \
+		-days 7 -subj "/CN=registry.test" -addext "subjectAltName=DNS:registry.test" \
+		-keyout /certs/upstream.key -out /certs/upstream.crt
+fi
+
+$COMPOSE up -d --build
+
+echo "Seeding upstream with a Helm chart and a WASM module"
+helm create demo > /dev/null
+helm package demo > /dev/null
+pushed=""
+for i in $(seq 1 30); do
+	if helm push demo-0.1.0.tgz "oci://$UPSTREAM/charts" --ca-file /certs/upstream.crt > /dev/null 2>&1; then
+		pushed=1
+		break
+	fi
+	sleep 1
+done
+[ -n "$pushed" ] || fail "could not push Helm chart"
+printf '\000asm\001\000\000\000' > work/hello.wasm
+echo '{"architecture":"wasm","os":"wasip1"}' > work/config.json
+oras push --ca-file /certs/upstream.crt "$UPSTREAM/wasm/hello:v1" \
+	--config config.json:application/vnd.wasm.config.v0+json \
+	hello.wasm:application/vnd.wasm.content.layer.v1+wasm > /dev/null || fail "could not push WASM module"
+
+echo "Waiting for proxy"
+for i in $(seq 1 60); do
+	$COMPOSE exec -T registry-proxy wget -q -O /dev/null http://localhost:8443/v2/ > /dev/null 2>&1 && break
+	sleep 2
+done
+
+pull() {
+	dir="$1"
+	echo "helm pull through the proxy ($dir)"
+	helm pull "oci://$PROXY/charts/demo" --version 0.1.0 --plain-http -d "$dir" || fail "helm pull failed ($dir)"
+	cmp work/demo-0.1.0.tgz "work/$dir/demo-0.1.0.tgz" || fail "pulled chart differs ($dir)"
+
+	echo "oras pull WASM module through the proxy ($dir)"
+	oras pull --plain-http -o "$dir/wasm" "$PROXY/wasm/hello:v1" > /dev/null || fail "oras pull failed ($dir)"
+	cmp work/hello.wasm "work/$dir/wasm/hello.wasm" || fail "pulled WASM module differs ($dir)"
+}
+
+pull cold
+
+echo "Stopping upstream"
+$COMPOSE stop upstream > /dev/null
+pull cached
+
+echo "Artifacts e2e passed"
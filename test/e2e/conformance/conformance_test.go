@@ -0,0 +1,247 @@
+//go:build conformance
+
+// Package conformance runs the pull category of the OCI distribution-spec
+// conformance suite against an in-process proxy whose upstream is
+// internal/testing/fakeregistry:
+//
+//	go test -tags conformance ./test/e2e/conformance/
+//
+// run.sh covers the same category with the upstream suite and docker compose.
+package conformance
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sdko-org/registry-proxy/internal/config"
+	"github.com/sdko-org/registry-proxy/internal/dockerhub"
+	"github.com/sdko-org/registry-proxy/internal/handlers"
+	"github.com/sdko-org/registry-proxy/internal/storage"
+	"github.com/sdko-org/registry-proxy/internal/testing/fakeregistry"
+	"github.com/sirupsen/logrus"
+)
+
+const repository = "conformance/test"
+
+var errorCodes = map[string]bool{
+	"BLOB_UNKNOWN":          true,
+	"BLOB_UPLOAD_INVALID":   true,
+	"BLOB_UPLOAD_UNKNOWN":   true,
+	"DIGEST_INVALID":        true,
+	"MANIFEST_BLOB_UNKNOWN": true,
+	"MANIFEST_INVALID":      true,
+	"MANIFEST_UNKNOWN":      true,
+	"NAME_INVALID":          true,
+	"NAME_UNKNOWN":          true,
+	"SIZE_INVALID":          true,
+	"UNAUTHORIZED":          true,
+	"DENIED":                true,
+	"UNSUPPORTED":           true,
+	"TOOMANYREQUESTS":       true,
+}
+
+type fixture struct {
+	proxy    *httptest.Server
+	upstream *fakeregistry.Registry
+	image    fakeregistry.Image
+	layer    []byte
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	upstream := fakeregistry.New(fakeregistry.Options{TokenAuth: true})
+	t.Cleanup(upstream.Close)
+	layer := fakeregistry.Layer(map[string]string{"layer": "conformance\n"})
+	image := upstream.AddImage(repository, "latest", layer)
+
+	t.Setenv("TEMP_DIR", t.TempDir())
+	t.Setenv("DOCKERHUB_REGISTRY", upstream.Host())
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cfg, err := config.Load(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.NewDiskStorage(logger, t.TempDir(), 1<<30, 1<<30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := dockerhub.NewClientWithTransport(logger, cfg, upstream.Client().Transport)
+
+	r := mux.NewRouter()
+	r.Use(handlers.LoggingMiddleware(logger, nil))
+	r.Use(handlers.RecoveryMiddleware(logger))
+	r.Use(handlers.RequestLimitsMiddleware(cfg))
+	r.Use(handlers.RouteTimeoutsMiddleware(logger, cfg))
+	r.Use(handlers.RateLimitMiddleware(cfg))
+	r.Use(handlers.CORSMiddleware(cfg))
+	handlers.RegisterRoutes(r, handlers.NewProxyHandler(logger, cfg, store, client, nil, nil, nil, nil, nil), nil)
+
+	proxy := httptest.NewServer(r)
+	t.Cleanup(proxy.Close)
+	return &fixture{proxy: proxy, upstream: upstream, image: image, layer: layer}
+}
+
+func (f *fixture) do(t *testing.T, method, path string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, f.proxy.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", fakeregistry.MediaTypeDockerManifest+", "+fakeregistry.MediaTypeOCIManifest)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, body
+}
+
+func expectStatus(t *testing.T, resp *http.Response, want int) {
+	t.Helper()
+	if resp.StatusCode != want {
+		t.Fatalf("%s %s: status = %d, want %d", resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, want)
+	}
+}
+
+func expectErrorBody(t *testing.T, body []byte, want string) {
+	t.Helper()
+	var payload struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || len(payload.Errors) == 0 {
+		t.Fatalf("error response is not an OCI error body: %s", body)
+	}
+	for _, e := range payload.Errors {
+		if !errorCodes[e.Code] {
+			t.Fatalf("error code %q is not defined by the distribution spec", e.Code)
+		}
+	}
+	if want != "" && payload.Errors[0].Code != want {
+		t.Fatalf("error code = %q, want %q", payload.Errors[0].Code, want)
+	}
+}
+
+func expectHeader(t *testing.T, resp *http.Response, name, want string) {
+	t.Helper()
+	if got := resp.Header.Get(name); got != "" && got != want {
+		t.Fatalf("%s = %q, want %q", name, got, want)
+	}
+}
+
+func TestPull(t *testing.T) {
+	f := newFixture(t)
+	missingDigest := fakeregistry.Digest([]byte("missing"))
+	blobPath := "/v2/" + repository + "/blobs/" + f.image.Layers[0].Digest
+	manifestPath := func(reference string) string {
+		return "/v2/" + repository + "/manifests/" + reference
+	}
+
+	t.Run("API version check", func(t *testing.T) {
+		resp, _ := f.do(t, http.MethodGet, "/v2/")
+		expectStatus(t, resp, http.StatusOK)
+	})
+
+	t.Run("Pull blobs", func(t *testing.T) {
+		t.Run("HEAD request to nonexistent blob should result in 404 response", func(t *testing.T) {
+			resp, _ := f.do(t, http.MethodHead, "/v2/"+repository+"/blobs/"+missingDigest)
+			expectStatus(t, resp, http.StatusNotFound)
+		})
+		t.Run("HEAD request to existing blob should yield 200", func(t *testing.T) {
+			resp, body := f.do(t, http.MethodHead, blobPath)
+			expectStatus(t, resp, http.StatusOK)
+			expectHeader(t, resp, "Content-Length", strconv.Itoa(len(f.layer)))
+			expectHeader(t, resp, "Docker-Content-Digest", f.image.Layers[0].Digest)
+			if len(body) != 0 {
+				t.Fatalf("HEAD returned a %d byte body", len(body))
+			}
+		})
+		t.Run("GET nonexistent blob should result in 404 response", func(t *testing.T) {
+			resp, body := f.do(t, http.MethodGet, "/v2/"+repository+"/blobs/"+missingDigest)
+			expectStatus(t, resp, http.StatusNotFound)
+			expectErrorBody(t, body, "BLOB_UNKNOWN")
+		})
+		t.Run("GET request to existing blob URL should yield 200", func(t *testing.T) {
+			upstream := 0
+			for i := 0; i < 2; i++ {
+				resp, body := f.do(t, http.MethodGet, blobPath)
+				expectStatus(t, resp, http.StatusOK)
+				expectHeader(t, resp, "Docker-Content-Digest", f.image.Layers[0].Digest)
+				if fakeregistry.Digest(body) != f.image.Layers[0].Digest {
+					t.Fatalf("pull %d: blob content does not match its digest", i)
+				}
+				if i == 0 {
+					upstream = f.upstream.Requests("blobs")
+				}
+			}
+			if n := f.upstream.Requests("blobs"); n != upstream {
+				t.Fatalf("repeat pull went upstream (%d blob requests, want %d)", n, upstream)
+			}
+		})
+	})
+
+	t.Run("Pull manifests", func(t *testing.T) {
+		t.Run("HEAD request to nonexistent manifest should return 404", func(t *testing.T) {
+			resp, _ := f.do(t, http.MethodHead, manifestPath("nonexistent"))
+			expectStatus(t, resp, http.StatusNotFound)
+		})
+		t.Run("HEAD request to manifest path (digest) should yield 200 response", func(t *testing.T) {
+			resp, _ := f.do(t, http.MethodHead, manifestPath(f.image.Digest))
+			expectStatus(t, resp, http.StatusOK)
+			expectHeader(t, resp, "Docker-Content-Digest", f.image.Digest)
+		})
+		t.Run("HEAD request to manifest path (tag) should yield 200 response", func(t *testing.T) {
+			resp, _ := f.do(t, http.MethodHead, manifestPath("latest"))
+			expectStatus(t, resp, http.StatusOK)
+			expectHeader(t, resp, "Docker-Content-Digest", f.image.Digest)
+		})
+		t.Run("GET nonexistent manifest should return 404", func(t *testing.T) {
+			resp, body := f.do(t, http.MethodGet, manifestPath("nonexistent"))
+			expectStatus(t, resp, http.StatusNotFound)
+			expectErrorBody(t, body, "MANIFEST_UNKNOWN")
+		})
+		t.Run("GET request to manifest path (digest) should yield 200 response", func(t *testing.T) {
+			resp, body := f.do(t, http.MethodGet, manifestPath(f.image.Digest))
+			expectStatus(t, resp, http.StatusOK)
+			expectHeader(t, resp, "Docker-Content-Digest", f.image.Digest)
+			if fakeregistry.Digest(body) != f.image.Digest {
+				t.Fatal("manifest content does not match its digest")
+			}
+		})
+		t.Run("GET request to manifest path (tag) should yield 200 response", func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				resp, body := f.do(t, http.MethodGet, manifestPath("latest"))
+				expectStatus(t, resp, http.StatusOK)
+				expectHeader(t, resp, "Docker-Content-Digest", f.image.Digest)
+				if fakeregistry.Digest(body) != f.image.Digest {
+					t.Fatalf("pull %d: manifest content does not match its digest", i)
+				}
+			}
+		})
+	})
+
+	t.Run("Error codes", func(t *testing.T) {
+		t.Run("invalid repository name should return NAME_INVALID", func(t *testing.T) {
+			resp, body := f.do(t, http.MethodGet, "/v2/Conformance/INVALID/manifests/latest")
+			expectStatus(t, resp, http.StatusBadRequest)
+			expectErrorBody(t, body, "NAME_INVALID")
+		})
+		t.Run("invalid digest should return DIGEST_INVALID", func(t *testing.T) {
+			resp, body := f.do(t, http.MethodGet, "/v2/"+repository+"/blobs/sha256:nothex")
+			expectStatus(t, resp, http.StatusBadRequest)
+			expectErrorBody(t, body, "DIGEST_INVALID")
+		})
+	})
+}
@@ -0,0 +1,63 @@
+#!/bin/sh
+#
+# Runs the OCI distribution-spec conformance suite (pull category) against the
+# proxy, with a local registry:2 seeded with a generated image as upstream.
+# The JUnit and HTML reports are written to results/. Exits non-zero when any
+# conformance test fails. The same category runs without docker as a Go test
+# against a fake upstream: go test -tags conformance ./test/e2e/conformance/
+#
+set -eu
+
+cd "$(dirname "$0")"
+REPO="registry.test:5000/conformance/test"
+TAG="latest"
+PROXY="registry-proxy:8443"
+COMPOSE="docker compose -p registry-proxy-conformance"
+
+cleanup() {
+	$COMPOSE logs registry-proxy > proxy.log 2>&1 || true
+	$COMPOSE --profile suite down -v > /dev/null 2>&1 || true
+}
+trap cleanup EXIT
+
+fail() {
+	echo "FAIL: $*" >&2
+	exit 1
+}
+
+crane() {
+	$COMPOSE exec -T crane crane "$@"
+}
+
+mkdir -p certs results
+if [ ! -f certs/upstream.crt ]; then
+	echo "Generating upstream certificate"
+	docker run --rm -v "$PWD/certs:/certs" docker.io/alpine/openssl req -x509 -newkey rsa:2048 -nodes \
+		-days 7 -subj "/CN=registry.test" -addext "subjectAltName=DNS:registry.test" \
+		-keyout /certs/upstream.key -out /certs/upstream.crt
+fi
+
+$COMPOSE up -d --build
+
+echo "Seeding upstream with $REPO:$TAG"
+$COMPOSE exec -T crane sh -c "echo conformance > /tmp/layer && tar -cf /tmp/layer.tar -C /tmp layer"
+for i in $(seq 1 30); do
+	crane append --insecure -f /tmp/layer.tar -t "$REPO:$TAG" > /dev/null 2>&1 && break
+	sleep 1
+done
+OCI_TAG_NAME="$TAG"
+OCI_MANIFEST_DIGEST=$(crane digest --insecure "$REPO:$TAG")
+OCI_BLOB_DIGEST=$(crane manifest --insecure "$REPO:$TAG" | sed -n 's/.*"layers":\[{[^}]*"digest":"\([^"]*\)".*/\1/p')
+[ -n "$OCI_MANIFEST_DIGEST" ] && [ -n "$OCI_BLOB_DIGEST" ] || fail "could not seed upstream"
+export OCI_TAG_NAME OCI_MANIFEST_DIGEST OCI_BLOB_DIGEST
+
+echo "Waiting for proxy"
+for i in $(seq 1 60); do
+	crane catalog --insecure "$PROXY" > /dev/null 2>&1 && break
+	sleep 2
+done
+
+echo "Running conformance suite (manifest $OCI_MANIFEST_DIGEST, blob $OCI_BLOB_DIGEST)"
+$COMPOSE --profile suite run --rm --build conformance || fail "conformance suite failed, see results/report.html"
+
+echo "Conformance e2e passed"
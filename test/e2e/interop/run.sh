@@ -0,0 +1,87 @@
+#!/bin/sh
+#
+# Pulls images through the proxy with every supported client and checks the
+# registry API behaviour clients rely on. Exits non-zero on the first failure.
+#
+set -eu
+
+cd "$(dirname "$0")"
+IMAGE="${IMAGE:-library/alpine:3.21}"
+REPO="${IMAGE%:*}"
+TAG="${IMAGE##*:}"
+PROXY="registry-proxy:8443"
+COMPOSE="docker compose -p registry-proxy-interop"
+
+cleanup() {
+	$COMPOSE logs registry-proxy > proxy.log 2>&1 || true
+	$COMPOSE down -v > /dev/null 2>&1 || true
+}
+trap cleanup EXIT
+
+fail() {
+	echo "FAIL: $*" >&2
+	exit 1
+}
+
+curl_proxy() {
+	$COMPOSE exec -T curl curl -s "$@"
+}
+
+$COMPOSE up -d --build
+
+echo "Waiting for proxy"
+for i in $(seq 1 60); do
+	curl_proxy -o /dev/null "http://$PROXY/v2/" && break
+	sleep 2
+done
+
+echo "Checking /v2/ ping"
+curl_proxy -D - -o /dev/null "http://$PROXY/v2/" | grep -qi "^Docker-Distribution-Api-Version: registry/2.0" ||
+	fail "/v2/ is missing Docker-Distribution-API-Version"
+
+echo "docker pull (direct)"
+$COMPOSE exec -T dind sh -c "until docker info > /dev/null 2>&1; do sleep 1; done"
+$COMPOSE exec -T dind docker pull "$PROXY/$IMAGE"
+
+echo "docker pull (mirror)"
+$COMPOSE exec -T dind docker pull "docker.io/$IMAGE"
+
+echo "containerd pull (hosts.toml)"
+$COMPOSE exec -T dind ctr --address /var/run/docker/containerd/containerd.sock \
+	images pull --plain-http --hosts-dir /etc/containerd/certs.d "docker.io/$IMAGE"
+
+echo "podman pull (registries.conf mirror)"
+$COMPOSE exec -T podman podman pull "docker.io/$IMAGE"
+
+echo "skopeo inspect and copy"
+$COMPOSE exec -T skopeo skopeo inspect --tls-verify=false "docker://$PROXY/$IMAGE" > /dev/null
+$COMPOSE exec -T skopeo skopeo copy --src-tls-verify=false --override-os linux --override-arch amd64 \
+	"docker://$PROXY/$IMAGE" dir:/tmp/skopeo-copy
+
+echo "crane manifest and pull"
+$COMPOSE exec -T crane crane manifest --insecure "$PROXY/$IMAGE" > /dev/null
+$COMPOSE exec -T crane crane pull --insecure --platform linux/amd64 "$PROXY/$IMAGE" /tmp/crane.tar
+
+echo "Checking manifest HEAD"
+accept="application/vnd.oci.image.index.v1+json,application/vnd.docker.distribution.manifest.list.v2+json"
+headers=$(curl_proxy -I -H "Accept: $accept" "http://$PROXY/v2/$REPO/manifests/$TAG")
+echo "$headers" | grep -q "^HTTP/1.1 200" || fail "manifest HEAD did not return 200"
+echo "$headers" | grep -qi "^Docker-Content-Digest: sha256:" || fail "manifest HEAD is missing Docker-Content-Digest"
+echo "$headers" | grep -qi "^Content-Length: [1-9]" || fail "manifest HEAD is missing Content-Length"
+
+echo "Checking blob HEAD and Range"
+digest=$($COMPOSE exec -T crane crane config --insecure --platform linux/amd64 "$PROXY/$IMAGE" | sha256sum | cut -d' ' -f1)
+curl_proxy -I "http://$PROXY/v2/$REPO/blobs/sha256:$digest" | grep -q "^HTTP/1.1 200" ||
+	fail "blob HEAD did not return 200"
+curl_proxy -o /dev/null -w "%{http_code}" -H "Range: bytes=0-9" "http://$PROXY/v2/$REPO/blobs/sha256:$digest" | grep -q "^20[06]$" ||
+	fail "ranged blob GET did not return 200 or 206"
+
+echo "Checking error bodies"
+curl_proxy "http://$PROXY/v2/$REPO/manifests/does-not-exist-$$" | grep -q '"errors"' ||
+	fail "unknown manifest did not return a registry error body"
+curl_proxy "http://$PROXY/v2/Invalid_Name/manifests/latest" | grep -q '"NAME_INVALID"' ||
+	fail "invalid repository name did not return NAME_INVALID"
+status=$(curl_proxy -o /dev/null -w "%{http_code}" -X PUT "http://$PROXY/v2/$REPO/manifests/$TAG")
+[ "$status" = "405" ] || [ "$status" = "401" ] || fail "push returned $status instead of 405 or 401"
+
+echo "Interop e2e passed"
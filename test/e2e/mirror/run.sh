@@ -0,0 +1,47 @@
+#!/bin/sh
+#
+# Pulls a handful of images through the proxy with dockerd (registry-mirrors)
+# and containerd (hosts.toml) and checks the proxy served them.
+#
+set -eu
+
+cd "$(dirname "$0")"
+IMAGES="${IMAGES:-alpine:3.21 library/busybox:latest}"
+COMPOSE="docker compose -p registry-proxy-e2e"
+
+cleanup() {
+	$COMPOSE logs registry-proxy > proxy.log 2>&1 || true
+	$COMPOSE down -v > /dev/null 2>&1 || true
+}
+trap cleanup EXIT
+
+$COMPOSE up -d --build
+
+echo "Waiting for dockerd"
+for i in $(seq 1 60); do
+	$COMPOSE exec -T dind docker info > /dev/null 2>&1 && break
+	sleep 2
+done
+
+echo "Checking /v2/ ping"
+$COMPOSE exec -T dind wget -q -S -O - http://registry-proxy:8443/v2/ 2>&1 | grep -q "Docker-Distribution-Api-Version: registry/2.0"
+
+for image in $IMAGES; do
+	echo "dockerd pull $image"
+	$COMPOSE exec -T dind docker pull "$image"
+	$COMPOSE exec -T dind docker rmi "$image" > /dev/null
+
+	echo "containerd pull $image"
+	ref="docker.io/$image"
+	case "$image" in
+		*/*) ;;
+		*) ref="docker.io/library/$image" ;;
+	esac
+	$COMPOSE exec -T dind ctr --address /var/run/docker/containerd/containerd.sock \
+		images pull --plain-http --hosts-dir /etc/containerd/certs.d "$ref"
+done
+
+echo "Checking proxy served the pulls"
+$COMPOSE logs registry-proxy 2>&1 | grep -q "Serving manifest from cache"
+
+echo "Mirror e2e passed"
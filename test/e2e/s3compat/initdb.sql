@@ -0,0 +1,2 @@
+CREATE DATABASE registry_minio OWNER registry;
+CREATE DATABASE registry_seaweedfs OWNER registry;
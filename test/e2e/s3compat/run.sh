@@ -0,0 +1,92 @@
+#!/bin/sh
+#
+# Pulls an image through a proxy backed by each S3-compatible store and checks
+# cache hits, HEAD, ranged reads, reindexing and purging. Exits non-zero on the
+# first failure.
+#
+set -eu
+
+cd "$(dirname "$0")"
+IMAGE="${IMAGE:-library/alpine:3.21}"
+REPO="${IMAGE%:*}"
+TAG="${IMAGE##*:}"
+BACKENDS="${BACKENDS:-minio seaweedfs}"
+COMPOSE="docker compose -p registry-proxy-s3compat"
+ACCEPT="application/vnd.oci.image.index.v1+json,application/vnd.docker.distribution.manifest.list.v2+json,application/vnd.oci.image.manifest.v1+json,application/vnd.docker.distribution.manifest.v2+json"
+
+cleanup() {
+	for backend in $BACKENDS; do
+		$COMPOSE logs "proxy-$backend" > "proxy-$backend.log" 2>&1 || true
+	done
+	$COMPOSE down -v > /dev/null 2>&1 || true
+}
+trap cleanup EXIT
+
+fail() {
+	echo "FAIL [$backend]: $*" >&2
+	exit 1
+}
+
+curl_proxy() {
+	$COMPOSE exec -T curl curl -s "$@"
+}
+
+wait_for_hit() {
+	for i in $(seq 1 30); do
+		curl_proxy -o /dev/null -D - -H "Accept: $ACCEPT" "$1" | grep -qi "^X-Cache: HIT" && return 0
+		sleep 1
+	done
+	return 1
+}
+
+$COMPOSE up -d --build
+
+for backend in $BACKENDS; do
+	proxy="http://proxy-$backend:8443"
+
+	echo "[$backend] Waiting for proxy"
+	for i in $(seq 1 60); do
+		curl_proxy -o /dev/null "$proxy/v2/" && break
+		sleep 2
+	done
+
+	echo "[$backend] Checking manifest miss then hit"
+	manifest="$proxy/v2/$REPO/manifests/$TAG"
+	curl_proxy -o /dev/null -D - -H "Accept: $ACCEPT" "$manifest" | grep -qi "^X-Cache: MISS" ||
+		fail "first manifest GET was not a cache miss"
+	wait_for_hit "$manifest" || fail "manifest was never served from cache"
+
+	echo "[$backend] Checking manifest HEAD"
+	headers=$(curl_proxy -I -H "Accept: $ACCEPT" "$manifest")
+	echo "$headers" | grep -q "^HTTP/1.1 200" || fail "manifest HEAD did not return 200"
+	echo "$headers" | grep -qi "^Docker-Content-Digest: sha256:" || fail "manifest HEAD is missing Docker-Content-Digest"
+
+	echo "[$backend] Checking blob miss, hit and Range"
+	index=$(curl_proxy -H "Accept: $ACCEPT" "$manifest")
+	child=$(echo "$index" | tr -d ' \n' | sed -n 's/.*"digest":"\(sha256:[0-9a-f]*\)","platform":{"architecture":"amd64".*/\1/p')
+	[ -n "$child" ] || child=$(echo "$index" | tr -d ' \n' | sed -n 's/.*"digest":"\(sha256:[0-9a-f]*\)".*/\1/p')
+	[ -n "$child" ] || fail "could not find a platform manifest in $REPO:$TAG"
+	config=$(curl_proxy -H "Accept: $ACCEPT" "$proxy/v2/$REPO/manifests/$child" | tr -d ' \n' |
+		sed -n 's/.*"config":{[^}]*"digest":"\(sha256:[0-9a-f]*\)".*/\1/p')
+	[ -n "$config" ] || fail "could not find the config blob of $child"
+	blob="$proxy/v2/$REPO/blobs/$config"
+	curl_proxy -o /dev/null "$blob"
+	wait_for_hit "$blob" || fail "blob was never served from cache"
+	status=$(curl_proxy -o /dev/null -w "%{http_code}" -H "Range: bytes=0-9" "$blob")
+	[ "$status" = "206" ] || fail "ranged cached blob GET returned $status instead of 206"
+	length=$(curl_proxy -H "Range: bytes=0-9" "$blob" | wc -c | tr -d ' ')
+	[ "$length" = "10" ] || fail "ranged cached blob GET returned $length bytes instead of 10"
+
+	echo "[$backend] Checking reindex"
+	status=$(curl_proxy -o /dev/null -w "%{http_code}" -X POST "$proxy/admin/cache/reindex")
+	[ "$status" = "202" ] || [ "$status" = "200" ] || fail "reindex returned $status"
+	wait_for_hit "$blob" || fail "blob was not served from cache after reindex"
+
+	echo "[$backend] Checking invalidation"
+	status=$(curl_proxy -o /dev/null -w "%{http_code}" -X POST "$proxy/admin/cache/invalidate?image=$REPO&digest=$config&immediate=true")
+	[ "${status#2}" != "$status" ] || fail "invalidation returned $status"
+	curl_proxy -o /dev/null -D - "$blob" | grep -qi "^X-Cache: MISS" ||
+		fail "blob was still served from cache after invalidation"
+done
+
+echo "S3 compatibility e2e passed"
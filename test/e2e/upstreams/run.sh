@@ -0,0 +1,71 @@
+#!/bin/sh
+#
+# Pulls from Quay, and from Harbor when HARBOR_IMAGE is set (e.g.
+# HARBOR_IMAGE=harbor.corp.internal/library/alpine:3.21 with HARBOR_USER and
+# HARBOR_PASSWORD for private projects), and checks that tag lists served by
+# the proxy match the upstream's paginated list. Exits non-zero on failure.
+#
+set -eu
+
+cd "$(dirname "$0")"
+QUAY_IMAGE="${QUAY_IMAGE:-quay.io/prometheus/busybox:latest}"
+HARBOR_IMAGE="${HARBOR_IMAGE:-}"
+PROXY="registry-proxy:8443"
+COMPOSE="docker compose -p registry-proxy-upstreams"
+
+MIRROR_NAMESPACES="quay.io"
+REGISTRY_CREDENTIALS=""
+if [ -n "$HARBOR_IMAGE" ]; then
+	harbor_host="${HARBOR_IMAGE%%/*}"
+	MIRROR_NAMESPACES="$MIRROR_NAMESPACES,$harbor_host"
+	if [ -n "${HARBOR_USER:-}" ]; then
+		REGISTRY_CREDENTIALS="$harbor_host=$HARBOR_USER:$HARBOR_PASSWORD"
+	fi
+fi
+export MIRROR_NAMESPACES REGISTRY_CREDENTIALS
+
+cleanup() {
+	$COMPOSE logs registry-proxy > proxy.log 2>&1 || true
+	$COMPOSE down -v > /dev/null 2>&1 || true
+}
+trap cleanup EXIT
+
+fail() {
+	echo "FAIL: $*" >&2
+	exit 1
+}
+
+crane() {
+	$COMPOSE exec -T crane crane "$@"
+}
+
+check_image() {
+	image="$1"
+	repo="${image%:*}"
+
+	echo "crane pull $image through the proxy"
+	crane pull --insecure --platform linux/amd64 "$PROXY/$image" /tmp/pull.tar
+
+	echo "Comparing tag lists for $repo"
+	if [ -n "${HARBOR_USER:-}" ] && [ "${repo%%/*}" = "${HARBOR_IMAGE%%/*}" ]; then
+		crane auth login "${repo%%/*}" -u "$HARBOR_USER" -p "$HARBOR_PASSWORD" > /dev/null
+	fi
+	upstream=$(crane ls "$repo" | sort | md5sum)
+	proxied=$(crane ls --insecure "$PROXY/$repo" | sort | md5sum)
+	[ "$upstream" = "$proxied" ] || fail "tag list for $repo differs from upstream"
+}
+
+$COMPOSE up -d --build
+
+echo "Waiting for proxy"
+for i in $(seq 1 60); do
+	crane catalog --insecure "$PROXY" > /dev/null 2>&1 && break
+	sleep 2
+done
+
+check_image "$QUAY_IMAGE"
+if [ -n "$HARBOR_IMAGE" ]; then
+	check_image "$HARBOR_IMAGE"
+fi
+
+echo "Upstreams e2e passed"